@@ -0,0 +1,211 @@
+package claudecode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/mcp"
+)
+
+const (
+	// mcpServerName is the name under which Bear's MCP server is registered
+	// in the CLI, so its tools are referred to as "mcp__bear__<tool>".
+	mcpServerName = "bear"
+
+	approvalPromptToolName = "approval_prompt"
+	// permissionPromptTool is the value of the `--permission-prompt-tool` CLI
+	// flag.
+	permissionPromptTool = "mcp__" + mcpServerName + "__" + approvalPromptToolName
+
+	// permissionModeDefault makes the CLI ask the permission prompt tool for
+	// every tool call that is neither pre-approved nor denied by a rule.
+	permissionModeDefault = "default"
+)
+
+// toolApprover answers the permission prompts of the CLI by asking the user
+// through the tool approval handler. It remembers the scopes, as returned by
+// alwaysAllowScope, that the user always allows for the rest of the session.
+type toolApprover struct {
+	mu                  sync.Mutex
+	handler             func(ai.ToolApprovalRequest) ai.ToolApprovalDecision
+	alwaysAllowedScopes map[string]bool
+}
+
+func newToolApprover() *toolApprover {
+	return &toolApprover{alwaysAllowedScopes: map[string]bool{}}
+}
+
+func (a *toolApprover) setHandler(handler func(ai.ToolApprovalRequest) ai.ToolApprovalDecision) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handler = handler
+}
+
+// permissionPromptRequest is the input that the CLI passes to the permission
+// prompt tool.
+type permissionPromptRequest struct {
+	ToolName  string          `json:"tool_name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+}
+
+// permissionPromptResponse is the JSON text that the permission prompt tool
+// returns to the CLI.
+type permissionPromptResponse struct {
+	// Behavior can be "allow" or "deny".
+	Behavior string `json:"behavior"`
+	// Used when Behavior is "allow".
+	UpdatedInput json.RawMessage `json:"updatedInput,omitempty"`
+	// Used when Behavior is "deny".
+	Message string `json:"message,omitempty"`
+}
+
+// alwaysAllowScope returns the scope that an "always allow" decision on the
+// request covers. For Bash and the file writing tools it is limited to the same
+// command or file, since allowing the tool as a whole after approving `ls`
+// would approve `rm -rf` as well. A call whose command or file cannot be told
+// is scoped to its exact input.
+func alwaysAllowScope(request permissionPromptRequest) string {
+	var input struct {
+		Command      string `json:"command"`
+		FilePath     string `json:"file_path"`
+		NotebookPath string `json:"notebook_path"`
+	}
+	var target *string
+	switch request.ToolName {
+	case bashTool:
+		target = &input.Command
+	case "Edit", "MultiEdit", "Write":
+		target = &input.FilePath
+	case "NotebookEdit":
+		target = &input.NotebookPath
+	default:
+		return request.ToolName
+	}
+
+	if err := json.Unmarshal(request.Input, &input); err != nil {
+		log.Warning(fmt.Sprintf("failed to parse the input of %v: %v", request.ToolName, err))
+	}
+	if *target == "" {
+		return fmt.Sprintf("%v(%s)", request.ToolName, request.Input)
+	}
+	return fmt.Sprintf("%v(%v)", request.ToolName, *target)
+}
+
+func (a *toolApprover) tool() mcp.Tool {
+	return mcp.Tool{
+		Name:        approvalPromptToolName,
+		Description: "Asks the user to approve or deny a tool call.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"tool_name":   map[string]any{"type": "string"},
+				"input":       map[string]any{"type": "object"},
+				"tool_use_id": map[string]any{"type": "string"},
+			},
+			"required": []string{"tool_name", "input"},
+		},
+		Handler: a.handlePermissionPrompt,
+	}
+}
+
+func (a *toolApprover) handlePermissionPrompt(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var request permissionPromptRequest
+	if err := json.Unmarshal(arguments, &request); err != nil {
+		return "", fmt.Errorf("invalid permission prompt request: %w", err)
+	}
+
+	decision := a.decide(ctx, request)
+	log.Info(fmt.Sprintf("tool approval decision: tool=%v, toolUseID=%v, decision=%v", request.ToolName, request.ToolUseID, decision))
+
+	response := permissionPromptResponse{
+		Behavior: "deny",
+		Message:  "The user denied this tool call.",
+	}
+	if decision != ai.ToolApprovalDecisionDeny {
+		response = permissionPromptResponse{
+			Behavior:     "allow",
+			UpdatedInput: request.Input,
+		}
+	}
+
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal permission prompt response: %w", err)
+	}
+	return string(responseBytes), nil
+}
+
+func (a *toolApprover) decide(ctx context.Context, request permissionPromptRequest) ai.ToolApprovalDecision {
+	scope := alwaysAllowScope(request)
+	a.mu.Lock()
+	handler := a.handler
+	alwaysAllowed := a.alwaysAllowedScopes[scope]
+	a.mu.Unlock()
+
+	if alwaysAllowed {
+		return ai.ToolApprovalDecisionApprove
+	}
+	if handler == nil {
+		log.Warning(fmt.Sprintf("no tool approval handler is set; denying tool call: %v", request.ToolName))
+		return ai.ToolApprovalDecisionDeny
+	}
+
+	input, err := convertJSONToYAML(request.Input)
+	if err != nil {
+		log.Warning(fmt.Sprintf("failed to convert tool input from JSON to YAML: %v", err))
+		input = string(request.Input)
+	}
+
+	// The handler waits for the user, so run it in its own goroutine to be
+	// able to give up when the CLI abandons the request. The handler is told
+	// through Expired, so that it does not keep asking the user.
+	decisionCh := make(chan ai.ToolApprovalDecision, 1)
+	go func() {
+		decisionCh <- handler(ai.ToolApprovalRequest{
+			ToolName:         request.ToolName,
+			Input:            normalizeNewlines(input),
+			AlwaysAllowScope: scope,
+			Expired:          ctx.Done(),
+		})
+	}()
+
+	select {
+	case decision := <-decisionCh:
+		if decision == ai.ToolApprovalDecisionAlwaysAllow {
+			a.mu.Lock()
+			a.alwaysAllowedScopes[scope] = true
+			a.mu.Unlock()
+		}
+		return decision
+	case <-ctx.Done():
+		log.Warning(fmt.Sprintf("tool approval request abandoned: %v", request.ToolName))
+		return ai.ToolApprovalDecisionDeny
+	}
+}
+
+// startMCPServer starts the MCP server that serves the permission prompt tool
+// and the artifact tools for the duration of a single query. It returns nil if
+// neither interactive tool approval nor an artifact store is enabled.
+func (c *Client) startMCPServer() (*mcp.Server, error) {
+	var tools []mcp.Tool
+	if c.interactiveToolApproval {
+		tools = append(tools, c.toolApprover.tool())
+	}
+	if c.artifactStore != nil {
+		tools = append(tools, c.artifactTools()...)
+	}
+	if len(tools) == 0 {
+		return nil, nil
+	}
+
+	server := mcp.NewServer(tools)
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
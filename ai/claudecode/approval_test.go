@@ -0,0 +1,204 @@
+package claudecode
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+func decodePermissionPromptResponse(t *testing.T, text string) permissionPromptResponse {
+	t.Helper()
+	var response permissionPromptResponse
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		t.Fatalf("invalid permission prompt response %q: %v", text, err)
+	}
+	return response
+}
+
+func TestToolApprover_ApproveReturnsOriginalInput(t *testing.T) {
+	approver := newToolApprover()
+	var received ai.ToolApprovalRequest
+	approver.setHandler(func(request ai.ToolApprovalRequest) ai.ToolApprovalDecision {
+		received = request
+		return ai.ToolApprovalDecisionApprove
+	})
+
+	text, err := approver.handlePermissionPrompt(context.Background(), json.RawMessage(
+		`{"tool_name":"Bash","input":{"command":"make test"},"tool_use_id":"toolu_1"}`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.ToolName != "Bash" {
+		t.Errorf("expected tool name Bash, got %q", received.ToolName)
+	}
+	if !strings.Contains(received.Input, "command: make test") {
+		t.Errorf("expected YAML input, got %q", received.Input)
+	}
+	response := decodePermissionPromptResponse(t, text)
+	if response.Behavior != "allow" {
+		t.Errorf("expected allow, got %q", response.Behavior)
+	}
+	if string(response.UpdatedInput) != `{"command":"make test"}` {
+		t.Errorf("expected the original input, got %s", response.UpdatedInput)
+	}
+}
+
+func TestToolApprover_Deny(t *testing.T) {
+	approver := newToolApprover()
+	approver.setHandler(func(ai.ToolApprovalRequest) ai.ToolApprovalDecision {
+		return ai.ToolApprovalDecisionDeny
+	})
+
+	text, err := approver.handlePermissionPrompt(context.Background(), json.RawMessage(
+		`{"tool_name":"Bash","input":{"command":"rm -rf build"}}`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	response := decodePermissionPromptResponse(t, text)
+	if response.Behavior != "deny" || response.Message == "" {
+		t.Errorf("expected deny with a message, got %+v", response)
+	}
+}
+
+func TestToolApprover_AlwaysAllowIsRememberedPerScope(t *testing.T) {
+	approver := newToolApprover()
+	var asked []string
+	approver.setHandler(func(request ai.ToolApprovalRequest) ai.ToolApprovalDecision {
+		asked = append(asked, request.AlwaysAllowScope)
+		return ai.ToolApprovalDecisionAlwaysAllow
+	})
+
+	requests := []string{
+		`{"tool_name":"Bash","input":{"command":"ls"}}`,
+		`{"tool_name":"Bash","input":{"command":"ls"}}`,
+		`{"tool_name":"Bash","input":{"command":"rm -rf /"}}`,
+		`{"tool_name":"Write","input":{"file_path":"/tmp/x","content":"a"}}`,
+		`{"tool_name":"Write","input":{"file_path":"/tmp/x","content":"b"}}`,
+		`{"tool_name":"Write","input":{"file_path":"/tmp/y","content":"a"}}`,
+		`{"tool_name":"WebFetch","input":{"url":"https://a.example"}}`,
+		`{"tool_name":"WebFetch","input":{"url":"https://b.example"}}`,
+	}
+	for _, request := range requests {
+		if _, err := approver.handlePermissionPrompt(context.Background(), json.RawMessage(request)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := []string{"Bash(ls)", "Bash(rm -rf /)", "Write(/tmp/x)", "Write(/tmp/y)", "WebFetch"}
+	if !slices.Equal(asked, expected) {
+		t.Errorf("expected the user to be asked once per scope %v, got %v", expected, asked)
+	}
+}
+
+func TestAlwaysAllowScope_WithoutTargetIsExactInput(t *testing.T) {
+	scope := alwaysAllowScope(permissionPromptRequest{
+		ToolName: "Bash",
+		Input:    json.RawMessage(`{"script":"ls"}`),
+	})
+
+	if scope != `Bash({"script":"ls"})` {
+		t.Errorf("expected the scope to be the exact input, got %q", scope)
+	}
+}
+
+func TestToolApprover_DeniesWithoutHandler(t *testing.T) {
+	approver := newToolApprover()
+
+	text, err := approver.handlePermissionPrompt(context.Background(), json.RawMessage(
+		`{"tool_name":"Bash","input":{"command":"ls"}}`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response := decodePermissionPromptResponse(t, text); response.Behavior != "deny" {
+		t.Errorf("expected deny, got %q", response.Behavior)
+	}
+}
+
+func TestToolApprover_DeniesWhenRequestIsAbandoned(t *testing.T) {
+	approver := newToolApprover()
+	release := make(chan struct{})
+	defer close(release)
+	approver.setHandler(func(ai.ToolApprovalRequest) ai.ToolApprovalDecision {
+		<-release
+		return ai.ToolApprovalDecisionApprove
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	text, err := approver.handlePermissionPrompt(ctx, json.RawMessage(
+		`{"tool_name":"Bash","input":{"command":"ls"}}`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if response := decodePermissionPromptResponse(t, text); response.Behavior != "deny" {
+		t.Errorf("expected deny, got %q", response.Behavior)
+	}
+}
+
+func TestToolApprover_InvalidRequest(t *testing.T) {
+	approver := newToolApprover()
+
+	if _, err := approver.handlePermissionPrompt(context.Background(), json.RawMessage(`not json`)); err == nil {
+		t.Error("expected error for an invalid request")
+	}
+}
+
+func TestBuildCommand_InteractiveToolApproval(t *testing.T) {
+	c, err := NewClient("", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.EnableInteractiveToolApproval()
+
+	server, err := c.startMCPServer()
+	if err != nil {
+		t.Fatalf("failed to start MCP server: %v", err)
+	}
+	if server == nil {
+		t.Fatal("expected an MCP server with interactive tool approval")
+	}
+	defer server.Close()
+
+	cmd := buildCommand(c, "/tmp/prompt.md", "{}", "/tmp/mcp.json")
+
+	if got := commandArgValue(cmd.Args, "--permission-mode"); got != permissionModeDefault {
+		t.Errorf("expected permission mode %q, got %q", permissionModeDefault, got)
+	}
+	if got := commandArgValue(cmd.Args, "--permission-prompt-tool"); got != permissionPromptTool {
+		t.Errorf("expected permission prompt tool %q, got %q", permissionPromptTool, got)
+	}
+	if got := commandArgValue(cmd.Args, "--mcp-config"); got != "/tmp/mcp.json" {
+		t.Errorf("expected the MCP config file, got %q", got)
+	}
+	allowed := strings.Split(commandArgValue(cmd.Args, "--allowedTools"), ",")
+	if slices.Contains(allowed, "Bash") {
+		t.Errorf("Bash must not be pre-approved with interactive approval, got %v", allowed)
+	}
+}
+
+func TestStartMCPServer_DisabledByDefault(t *testing.T) {
+	c, err := NewClient("", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server, err := c.startMCPServer()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server != nil {
+		t.Error("expected no MCP server without interactive approval")
+	}
+}
@@ -0,0 +1,200 @@
+package claudecode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/mcp"
+)
+
+// The artifact tools that Bear's MCP server exposes when an artifact store is
+// set. They are always pre-approved because they only touch the session
+// artifacts.
+const (
+	getApprovedSpecToolName = "get_approved_spec"
+	getTaskToolName         = "get_task"
+	getHandoffToolName      = "get_handoff"
+	recordDecisionToolName  = "record_decision"
+	reportProgressToolName  = "report_progress"
+)
+
+// mcpToolName returns the name under which the CLI exposes a tool of Bear's
+// MCP server to the agent.
+func mcpToolName(tool string) string {
+	return "mcp__" + mcpServerName + "__" + tool
+}
+
+func (c *Client) SetArtifactStore(store ai.ArtifactStore) {
+	c.artifactStore = store
+}
+
+// artifactToolNames returns the names of the artifact tools as the agent sees
+// them, or nil if no artifact store is set.
+func (c *Client) artifactToolNames() []string {
+	if c.artifactStore == nil {
+		return nil
+	}
+
+	var names []string
+	for _, tool := range c.artifactTools() {
+		names = append(names, mcpToolName(tool.Name))
+	}
+	return names
+}
+
+// artifactTools returns the artifact tools of the agent. Only a coding agent
+// gets the tools that take a task ID, because the agents before it have no
+// task that the ID could refer to.
+func (c *Client) artifactTools() []mcp.Tool {
+	tools := []mcp.Tool{
+		{
+			Name:        getApprovedSpecToolName,
+			Description: "Returns the spec that the user approved for this session. Its requirements and acceptance criteria have IDs such as REQ-01 and AC-02: mention the IDs that a change implements in its commit summary, and put the ID of the criterion that a test verifies in the test name, for example TestExport_AC02.",
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+			Handler: func(context.Context, json.RawMessage) (string, error) {
+				return c.artifactStore.ApprovedSpec()
+			},
+		},
+	}
+	if !c.taskTools {
+		return tools
+	}
+	return append(tools, c.taskArtifactTools()...)
+}
+
+func (c *Client) taskArtifactTools() []mcp.Tool {
+	taskIDSchema := map[string]any{
+		"type":        "string",
+		"description": `The task ID as written in the plan, for example "TASK-01".`,
+	}
+
+	return []mcp.Tool{
+		{
+			Name:        getTaskToolName,
+			Description: "Returns the plan section that describes a task, including the IDs of the spec requirements and acceptance criteria that it implements.",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"task_id": taskIDSchema},
+				"required":   []string{"task_id"},
+			},
+			Handler: func(_ context.Context, arguments json.RawMessage) (string, error) {
+				taskID, err := parseTaskIDArgument(arguments)
+				if err != nil {
+					return "", err
+				}
+				return c.artifactStore.Task(taskID)
+			},
+		},
+		{
+			Name:        getHandoffToolName,
+			Description: "Returns the handoff report that the agent of a finished task left for the tasks that depend on it.",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"task_id": taskIDSchema},
+				"required":   []string{"task_id"},
+			},
+			Handler: func(_ context.Context, arguments json.RawMessage) (string, error) {
+				taskID, err := parseTaskIDArgument(arguments)
+				if err != nil {
+					return "", err
+				}
+				return c.artifactStore.Handoff(taskID)
+			},
+		},
+		{
+			Name:        recordDecisionToolName,
+			Description: "Records a design decision and its rationale so that later tasks and reviewers know why it was made.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"task_id":   taskIDSchema,
+					"decision":  map[string]any{"type": "string"},
+					"rationale": map[string]any{"type": "string"},
+				},
+				"required": []string{"task_id", "decision", "rationale"},
+			},
+			Handler: c.handleRecordDecision,
+		},
+		{
+			Name:        reportProgressToolName,
+			Description: "Reports the progress of a task to the user.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"task_id": taskIDSchema,
+					"status": map[string]any{
+						"type": "string",
+						"enum": []ai.ProgressStatus{
+							ai.ProgressStatusStarted,
+							ai.ProgressStatusInProgress,
+							ai.ProgressStatusBlocked,
+							ai.ProgressStatusDone,
+						},
+					},
+					"message": map[string]any{"type": "string"},
+				},
+				"required": []string{"task_id", "status", "message"},
+			},
+			Handler: c.handleReportProgress,
+		},
+	}
+}
+
+func parseTaskIDArgument(arguments json.RawMessage) (string, error) {
+	var args struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.TaskID == "" {
+		return "", fmt.Errorf("task_id is required")
+	}
+	return args.TaskID, nil
+}
+
+func (c *Client) handleRecordDecision(_ context.Context, arguments json.RawMessage) (string, error) {
+	var decision ai.Decision
+	if err := json.Unmarshal(arguments, &decision); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if decision.Decision == "" {
+		return "", fmt.Errorf("decision is required")
+	}
+
+	if err := c.artifactStore.RecordDecision(decision); err != nil {
+		return "", err
+	}
+	log.Info(fmt.Sprintf("agent recorded a decision: %#v", decision))
+	return "The decision is recorded.", nil
+}
+
+func (c *Client) handleReportProgress(_ context.Context, arguments json.RawMessage) (string, error) {
+	var report ai.ProgressReport
+	if err := json.Unmarshal(arguments, &report); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	switch report.Status {
+	case ai.ProgressStatusStarted, ai.ProgressStatusInProgress, ai.ProgressStatusBlocked, ai.ProgressStatusDone:
+	default:
+		return "", fmt.Errorf("unknown status: %q", report.Status)
+	}
+
+	if err := c.artifactStore.ReportProgress(report); err != nil {
+		return "", err
+	}
+	log.Info(fmt.Sprintf("agent reported progress: %#v", report))
+
+	if c.streamCallback != nil {
+		c.streamCallback(ai.StreamMessage{
+			Role:     ai.StreamMessageRoleAssistant,
+			Type:     ai.StreamMessageTypeProgress,
+			Content:  fmt.Sprintf("%v [%v] %v", report.TaskID, report.Status, report.Message),
+			Progress: report,
+		})
+	}
+	return "The progress is reported.", nil
+}
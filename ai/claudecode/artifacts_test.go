@@ -0,0 +1,217 @@
+package claudecode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+type fakeArtifactStore struct {
+	spec      string
+	tasks     map[string]string
+	decisions []ai.Decision
+	progress  []ai.ProgressReport
+}
+
+func (s *fakeArtifactStore) ApprovedSpec() (string, error) {
+	if s.spec == "" {
+		return "", errors.New("no approved spec")
+	}
+	return s.spec, nil
+}
+
+func (s *fakeArtifactStore) Task(taskID string) (string, error) {
+	task, ok := s.tasks[taskID]
+	if !ok {
+		return "", errors.New("unknown task")
+	}
+	return task, nil
+}
+
+func (s *fakeArtifactStore) Handoff(taskID string) (string, error) {
+	return "handoff of " + taskID, nil
+}
+
+func (s *fakeArtifactStore) RecordDecision(decision ai.Decision) error {
+	s.decisions = append(s.decisions, decision)
+	return nil
+}
+
+func (s *fakeArtifactStore) ReportProgress(report ai.ProgressReport) error {
+	s.progress = append(s.progress, report)
+	return nil
+}
+
+func findArtifactTool(t *testing.T, c *Client, name string) func(context.Context, json.RawMessage) (string, error) {
+	t.Helper()
+	for _, tool := range c.artifactTools() {
+		if tool.Name == name {
+			return tool.Handler
+		}
+	}
+	t.Fatalf("artifact tool %q not found", name)
+	return nil
+}
+
+func TestArtifactTools_GetApprovedSpec(t *testing.T) {
+	c := &Client{}
+	c.SetArtifactStore(&fakeArtifactStore{spec: "# Spec"})
+
+	result, err := findArtifactTool(t, c, getApprovedSpecToolName)(context.Background(), json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "# Spec" {
+		t.Errorf("expected %q, got %q", "# Spec", result)
+	}
+}
+
+func TestArtifactTools_ReadTaskArtifacts(t *testing.T) {
+	c := &Client{taskTools: true}
+	c.SetArtifactStore(&fakeArtifactStore{
+		tasks: map[string]string{"TASK-01": "## TASK-01: Client"},
+	})
+
+	tests := []struct {
+		tool      string
+		arguments string
+		expected  string
+	}{
+		{getTaskToolName, `{"task_id":"TASK-01"}`, "## TASK-01: Client"},
+		{getHandoffToolName, `{"task_id":"TASK-00"}`, "handoff of TASK-00"},
+	}
+	for _, tt := range tests {
+		result, err := findArtifactTool(t, c, tt.tool)(context.Background(), json.RawMessage(tt.arguments))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.tool, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%v: expected %q, got %q", tt.tool, tt.expected, result)
+		}
+	}
+}
+
+func TestArtifactTools_GetTaskRequiresTaskID(t *testing.T) {
+	c := &Client{taskTools: true}
+	c.SetArtifactStore(&fakeArtifactStore{})
+
+	if _, err := findArtifactTool(t, c, getTaskToolName)(context.Background(), json.RawMessage(`{}`)); err == nil {
+		t.Error("expected error without task_id")
+	}
+}
+
+func TestArtifactTools_RecordDecision(t *testing.T) {
+	store := &fakeArtifactStore{}
+	c := &Client{taskTools: true}
+	c.SetArtifactStore(store)
+
+	_, err := findArtifactTool(t, c, recordDecisionToolName)(context.Background(), json.RawMessage(
+		`{"task_id":"TASK-01","decision":"Use a mutex","rationale":"The handler runs concurrently."}`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ai.Decision{{TaskID: "TASK-01", Decision: "Use a mutex", Rationale: "The handler runs concurrently."}}
+	if !slices.Equal(store.decisions, expected) {
+		t.Errorf("expected %+v, got %+v", expected, store.decisions)
+	}
+}
+
+func TestArtifactTools_ReportProgressNotifiesStreamCallback(t *testing.T) {
+	store := &fakeArtifactStore{}
+	var messages []ai.StreamMessage
+	c := &Client{taskTools: true}
+	c.SetArtifactStore(store)
+	c.SetStreamCallbackHandler(func(msg ai.StreamMessage) {
+		messages = append(messages, msg)
+	})
+
+	_, err := findArtifactTool(t, c, reportProgressToolName)(context.Background(), json.RawMessage(
+		`{"task_id":"TASK-01","status":"in_progress","message":"Writing tests."}`,
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := ai.ProgressReport{TaskID: "TASK-01", Status: ai.ProgressStatusInProgress, Message: "Writing tests."}
+	if !slices.Equal(store.progress, []ai.ProgressReport{expected}) {
+		t.Errorf("expected the report to be stored, got %+v", store.progress)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 stream message, got %d", len(messages))
+	}
+	if messages[0].Type != ai.StreamMessageTypeProgress || messages[0].Progress != expected {
+		t.Errorf("unexpected stream message: %+v", messages[0])
+	}
+}
+
+func TestArtifactTools_ReportProgressRejectsUnknownStatus(t *testing.T) {
+	store := &fakeArtifactStore{}
+	c := &Client{taskTools: true}
+	c.SetArtifactStore(store)
+
+	_, err := findArtifactTool(t, c, reportProgressToolName)(context.Background(), json.RawMessage(
+		`{"task_id":"TASK-01","status":"almost","message":"..."}`,
+	))
+	if err == nil {
+		t.Error("expected error for an unknown status")
+	}
+	if len(store.progress) != 0 {
+		t.Error("the report must not be stored")
+	}
+}
+
+func TestBuildCommand_ArtifactToolsArePreApproved(t *testing.T) {
+	c, err := NewCodingClient("", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetArtifactStore(&fakeArtifactStore{})
+
+	server, err := c.startMCPServer()
+	if err != nil {
+		t.Fatalf("failed to start MCP server: %v", err)
+	}
+	if server == nil {
+		t.Fatal("expected an MCP server when an artifact store is set")
+	}
+	defer server.Close()
+
+	cmd := buildCommand(c, "/tmp/prompt.md", "{}", "/tmp/mcp.json")
+
+	if got := commandArgValue(cmd.Args, "--mcp-config"); got != "/tmp/mcp.json" {
+		t.Errorf("expected the MCP config file, got %q", got)
+	}
+	if got := commandArgValue(cmd.Args, "--permission-prompt-tool"); got != "" {
+		t.Errorf("expected no permission prompt tool, got %q", got)
+	}
+	allowed := strings.Split(commandArgValue(cmd.Args, "--allowedTools"), ",")
+	for _, tool := range []string{
+		"mcp__bear__get_approved_spec", "mcp__bear__get_task", "mcp__bear__get_handoff",
+		"mcp__bear__record_decision", "mcp__bear__report_progress",
+	} {
+		if !slices.Contains(allowed, tool) {
+			t.Errorf("expected %v to be pre-approved, got %v", tool, allowed)
+		}
+	}
+}
+
+func TestArtifactTools_SpecWriterHasNoTaskTools(t *testing.T) {
+	c, err := NewClient("", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetArtifactStore(&fakeArtifactStore{})
+
+	expected := []string{"mcp__bear__get_approved_spec"}
+	if names := c.artifactToolNames(); !slices.Equal(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
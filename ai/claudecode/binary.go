@@ -2,6 +2,8 @@ package claudecode
 
 import (
 	"errors"
+	"os"
+	"os/exec"
 	"path/filepath"
 )
 
@@ -20,6 +22,11 @@ var absoluteFallbackPaths = []string{
 	"/usr/bin/claude",
 }
 
+// FindBinary returns the path of the claude binary that the client runs.
+func FindBinary() (string, error) {
+	return findClaudeBinary(exec.LookPath, os.UserHomeDir, fileExists)
+}
+
 func findClaudeBinary(
 	lookPath func(string) (string, error),
 	homeDir func() (string, error),
@@ -5,14 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/kaptinlin/jsonschema"
 	"github.com/sds-lab-dev/bear-go/ai"
 	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/spec"
 )
 
 var (
@@ -20,15 +24,60 @@ var (
 	ErrProcessExitError   = errors.New("claude process exited with error")
 )
 
-const toolList = "AskUserQuestion,Bash,TaskOutput,Edit,ExitPlanMode,Glob,Grep,KillShell,MCPSearch,Read,Skill,Task,TaskCreate,TaskGet,TaskList,TaskUpdate,WebFetch,WebSearch,Write,LSP"
+const (
+	model                           = "claude-opus-4-6"
+	toolApprovalTimeoutMilliseconds = 24 * 60 * 60 * 1000
+)
 
 type Client struct {
-	apiKey         string
-	workingDir     string
-	binaryPath     string
+	apiKey           string
+	workingDir       string
+	binaryPath       string
+	prompts          *ai.Prompts
+	permissionPolicy PermissionPolicy
+	// interactiveToolApproval makes tool calls that the permission policy
+	// neither pre-approves nor denies go to the user instead of being denied.
+	interactiveToolApproval bool
+	// specCritique makes LintSpec also ask a separate agent session to
+	// review the draft.
+	specCritique bool
+	toolApprover *toolApprover
+	// artifactStore is served to the agent through Bear's MCP server. It is
+	// nil if the agent has no access to the session artifacts.
+	artifactStore ai.ArtifactStore
+	// taskTools serves the artifact tools that take a task ID, which only a
+	// coding agent has a use for.
+	taskTools bool
+	// specTemplate gives the sections of the drafted spec and the schema of
+	// the drafting output.
+	specTemplate spec.Template
+	// sandbox confines the CLI subprocess to the workspace. It is nil if the
+	// subprocess runs unconfined.
+	sandbox        *sandbox
 	sessionID      string
 	sessionState   clientSessionState
 	streamCallback func(ai.StreamMessage)
+	// initialRequest and clarifications are given to the drafting prompt, so
+	// that the spec is written from the whole Q&A and not only from what the
+	// agent remembers of it.
+	initialRequest string
+	clarifications []clarification
+	// requestSources are the documents attached to the initial request.
+	requestSources []ai.RequestSource
+	// attachments are all files attached in the session, and
+	// pendingAttachments the ones that the agent has not been given yet.
+	attachments        []ai.Attachment
+	pendingAttachments []ai.Attachment
+	// baseDraft is the earlier draft that the user went back to. The next
+	// revision starts from it instead of from the latest draft.
+	baseDraft string
+	// approvedSpec is the spec that the plan is drafted from. The IDs of its
+	// requirements and acceptance criteria are the ones that the tasks can
+	// cover.
+	approvedSpec string
+	// maxClarificationRounds is the number of agent clarification rounds after
+	// which the agent is not asked for more questions. Zero means no limit.
+	maxClarificationRounds int
 }
 
 type clientSessionState int
@@ -39,14 +88,15 @@ const (
 	sessionStateNoClarifyingQuestions
 	sessionStateWaitUserFeedback
 	sessionStateSpecApproved
+	sessionStateWaitPlanFeedback
+	sessionStatePlanApproved
+	// A coding client starts in sessionStateCoding and implements one task.
+	sessionStateCoding
+	sessionStateTaskCoded
 )
 
 func NewClient(apiKey, workingDir string) (*Client, error) {
-	binaryPath, err := findClaudeBinary(
-		exec.LookPath,
-		os.UserHomeDir,
-		fileExists,
-	)
+	binaryPath, err := FindBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -61,11 +111,15 @@ func NewClient(apiKey, workingDir string) (*Client, error) {
 	}
 
 	return &Client{
-		apiKey:         apiKey,
-		workingDir:     workingDir,
-		binaryPath:     binaryPath,
-		sessionState:   sessionStateBegin,
-		streamCallback: nil,
+		apiKey:           apiKey,
+		workingDir:       workingDir,
+		binaryPath:       binaryPath,
+		prompts:          ai.DefaultPrompts(workingDir),
+		specTemplate:     spec.DefaultTemplate(),
+		permissionPolicy: specWriterPermissionPolicy(),
+		toolApprover:     newToolApprover(),
+		sessionState:     sessionStateBegin,
+		streamCallback:   nil,
 	}, nil
 }
 
@@ -74,81 +128,119 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
-func (c *Client) GetInitialClarifyingQuestions(
-	initialUserRequest string,
-) ([]string, error) {
-	if c.sessionState != sessionStateBegin {
-		return nil, fmt.Errorf("unexpected session state for GetInitialClarifyingQuestions: %v", c.sessionState)
-	}
-
-	log.Debug(fmt.Sprintf("getting initial clarifying questions for user request: %v", initialUserRequest))
-	type outputSchema struct {
-		Questions []string `json:"questions" jsonschema:"required,minItems=0,maxItems=5"`
-	}
-	output, err := query[outputSchema](
-		c,
-		ai.ClarificationSystemPrompt(),
-		ai.ClarificationUserPromptForInitialRequest(initialUserRequest),
-	)
-	if err != nil {
-		err = fmt.Errorf("failed to get initial clarifying questions: %w", err)
-		log.Error(err.Error())
-		return nil, err
-	}
-	log.Debug(fmt.Sprintf("received initial clarifying questions: %#v", output))
+func (c *Client) SetStreamCallbackHandler(handler func(ai.StreamMessage)) {
+	c.streamCallback = handler
+}
 
-	if len(output.Questions) == 0 {
-		c.sessionState = sessionStateNoClarifyingQuestions
-	} else {
-		c.sessionState = sessionStateWaitUserAnswers
-	}
+func (c *Client) SetToolApprovalHandler(handler func(ai.ToolApprovalRequest) ai.ToolApprovalDecision) {
+	c.toolApprover.setHandler(handler)
+}
 
-	return output.Questions, nil
+// SetSpecTemplate sets the template of the spec that DraftSpec and ReviseSpec
+// write.
+func (c *Client) SetSpecTemplate(template spec.Template) {
+	c.specTemplate = template
 }
 
-func (c *Client) GetNextClarifyingQuestions(userAnswer string) ([]string, error) {
-	if c.sessionState != sessionStateWaitUserAnswers {
-		return nil, fmt.Errorf("unexpected session state for GetNextClarifyingQuestions: %v", c.sessionState)
-	}
+// SetRequestSources sets the documents that GetInitialClarifyingQuestions
+// gives to the agent along with the request.
+func (c *Client) SetRequestSources(sources []ai.RequestSource) {
+	c.requestSources = sources
+}
 
-	// TODO
-	questions, err := []string{}, errors.New("GetNextClarifyingQuestions not implemented yet")
-	if err != nil {
-		return nil, err
-	}
+// AddAttachments attaches files to the next clarification query.
+func (c *Client) AddAttachments(attachments []ai.Attachment) {
+	c.attachments = append(c.attachments, attachments...)
+	c.pendingAttachments = append(c.pendingAttachments, attachments...)
+}
 
-	if len(questions) == 0 {
-		c.sessionState = sessionStateNoClarifyingQuestions
-	} else {
-		c.sessionState = sessionStateWaitUserAnswers
-	}
+// SetPrompts replaces the built-in prompts, for example with prompts that
+// have team overrides.
+func (c *Client) SetPrompts(prompts *ai.Prompts) {
+	c.prompts = prompts
+}
 
-	return questions, nil
+// SetMaxClarificationRounds limits the number of clarification rounds, after
+// which the spec is drafted from the answers so far. Zero means no limit.
+func (c *Client) SetMaxClarificationRounds(rounds int) {
+	c.maxClarificationRounds = rounds
 }
 
-func (c *Client) DraftSpec() (string, error) {
-	if c.sessionState != sessionStateNoClarifyingQuestions {
-		return "", fmt.Errorf("unexpected session state for DraftSpec: %v", c.sessionState)
-	}
+// EnableSpecCritique makes LintSpec ask an agent to review the draft on top of
+// the deterministic checks.
+func (c *Client) EnableSpecCritique() {
+	c.specCritique = true
+}
 
-	// TODO
-	return "", errors.New("DraftSpec not implemented yet")
+// EnableInteractiveToolApproval makes the tool calls that the permission
+// policy neither pre-approves nor denies, and every Bash command, go to the
+// tool approval handler instead of being denied.
+func (c *Client) EnableInteractiveToolApproval() {
+	c.interactiveToolApproval = true
 }
 
-func (c *Client) ReviseSpec(userFeedback string) (string, error) {
-	if c.sessionState != sessionStateWaitUserFeedback {
-		return "", fmt.Errorf("unexpected session state for ReviseSpec: %v", c.sessionState)
+// maxStructuredOutputRepairAttempts is the number of times the agent is asked
+// to correct a structured output that fails the JSON schema validation before
+// the query gives up.
+const maxStructuredOutputRepairAttempts = 2
+
+func query[T any](client *Client, systemPrompt, userPrompt string) (T, error) {
+	var zeroValue T
+
+	schema, schemaString, err := generateJSONSchema[T]()
+	if err != nil {
+		return zeroValue, err
 	}
 
-	// TODO
-	return "", errors.New("ReviseSpec not implemented yet")
+	result, err := queryWithSchema(client, systemPrompt, userPrompt, schema, schemaString)
+	if err != nil {
+		return zeroValue, err
+	}
+	var finalResult T
+	if err := json.Unmarshal(result, &finalResult); err != nil {
+		return zeroValue,
+			fmt.Errorf("failed to unmarshal processStream result into type %T: %w", zeroValue, err)
+	}
+	return finalResult, nil
 }
 
-func (c *Client) SetStreamCallbackHandler(handler func(ai.StreamMessage)) {
-	c.streamCallback = handler
+// queryWithSchema runs the query and returns the structured output once it
+// passes the schema validation.
+func queryWithSchema(
+	client *Client, systemPrompt, userPrompt string, schema *jsonschema.Schema, schemaString string,
+) (json.RawMessage, error) {
+	prompt := userPrompt
+	for attempt := 0; ; attempt++ {
+		result, err := runQuery(client, systemPrompt, prompt, schemaString)
+		if err != nil {
+			return nil, err
+		}
+
+		validator := schema.Validate(result)
+		if validator.IsValid() {
+			return result, nil
+		}
+
+		validationErrors := formatValidationErrors(validator.DetailedErrors())
+		if attempt >= maxStructuredOutputRepairAttempts {
+			return nil,
+				fmt.Errorf("JSON schema validation failed after %d repair attempts: %v",
+					attempt, validationErrors)
+		}
+
+		// The session is still usable, so resume it and ask the agent to
+		// correct its output instead of failing the whole call.
+		client.reportStructuredOutputRepair(attempt+1, validationErrors)
+		prompt, err = client.prompts.Render(ai.PromptStructuredOutputRepair, map[string]string{
+			"ValidationErrors": validationErrors,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 }
 
-func query[T any](client *Client, systemPrompt, userPrompt string) (T, error) {
+func generateJSONSchema[T any]() (*jsonschema.Schema, string, error) {
 	var zeroValue T
 
 	opts := &jsonschema.StructTagOptions{
@@ -160,44 +252,74 @@ func query[T any](client *Client, systemPrompt, userPrompt string) (T, error) {
 	}
 	schema, err := jsonschema.FromStructWithOptions[T](opts)
 	if err != nil {
-		return zeroValue, fmt.Errorf("failed to generate JSON schema for type %T: %w", zeroValue, err)
+		return nil, "", fmt.Errorf("failed to generate JSON schema for type %T: %w", zeroValue, err)
 	}
 	// Remove `$defs` from the generated schema.
 	schema.Defs = nil
 
 	schemaString, err := json.MarshalIndent(schema, "", "  ")
 	if err != nil {
-		return zeroValue, fmt.Errorf("failed to marshal JSON schema for type %T: %w", zeroValue, err)
+		return nil, "", fmt.Errorf("failed to marshal JSON schema for type %T: %w", zeroValue, err)
 	}
 	log.Debug(fmt.Sprintf("generated JSON schema for type %T: %s", zeroValue, string(schemaString)))
 
-	tmpFile, err := os.CreateTemp("", "bear-system-prompt-*.md")
+	return schema, string(schemaString), nil
+}
+
+// runQuery runs a single Claude Code CLI process and returns its raw
+// structured output.
+func runQuery(client *Client, systemPrompt, userPrompt, schemaString string) (json.RawMessage, error) {
+	systemPromptPath, err := writeTempFile("bear-system-prompt-*.md", systemPrompt)
 	if err != nil {
-		return zeroValue, fmt.Errorf("failed to create temp file for system prompt: %w", err)
+		return nil, fmt.Errorf("failed to write system prompt to temp file: %w", err)
 	}
-	defer os.Remove(tmpFile.Name())
+	defer os.Remove(systemPromptPath)
 
-	if _, err := tmpFile.WriteString(systemPrompt); err != nil {
-		tmpFile.Close()
-		return zeroValue, fmt.Errorf("failed to write system prompt to temp file: %w", err)
+	mcpServer, err := client.startMCPServer()
+	if err != nil {
+		return nil, err
+	}
+	mcpConfigPath := ""
+	if mcpServer != nil {
+		defer mcpServer.Close()
+
+		// The configuration carries the server's bearer token, so it is
+		// passed in a file only the user can read rather than on the command
+		// line, which any process can see in /proc/<pid>/cmdline.
+		mcpConfig, err := mcpServer.ConfigJSON(mcpServerName)
+		if err != nil {
+			return nil, err
+		}
+		mcpConfigPath, err = writeTempFile("bear-mcp-config-*.json", mcpConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write MCP config to temp file: %w", err)
+		}
+		defer os.Remove(mcpConfigPath)
 	}
-	tmpFile.Close()
 
-	cmd := buildCommand(client, tmpFile.Name(), string(schemaString))
+	cmd := buildCommand(client, systemPromptPath, schemaString, mcpConfigPath)
+	if client.sandbox != nil {
+		scratchDir, err := client.sandbox.newScratchDir()
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(scratchDir)
+		cmd = client.sandbox.wrap(cmd, scratchDir)
+	}
 	cmd.Stdin = strings.NewReader(userPrompt)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return zeroValue, fmt.Errorf("%w: %v", ErrProcessStartFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrProcessStartFailed, err)
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return zeroValue, fmt.Errorf("%w: %v", ErrProcessStartFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrProcessStartFailed, err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return zeroValue, fmt.Errorf("%w: %v", ErrProcessStartFailed, err)
+		return nil, fmt.Errorf("%w: %v", ErrProcessStartFailed, err)
 	}
 
 	var stderrBuf strings.Builder
@@ -206,47 +328,91 @@ func query[T any](client *Client, systemPrompt, userPrompt string) (T, error) {
 	}()
 
 	log.Debug("starting Claude Code CLI process...")
-	result, streamErr := processStream(stdout, client.streamCallback)
+	result, streamErr := processStream(stdout, client.sessionExpectation(), client.streamCallback)
 	if streamErr != nil {
-		return zeroValue, streamErr
+		return nil, streamErr
 	}
 	log.Debug(fmt.Sprintf("waiting Claude Code CLI process: result=%v, streamErr=%v", string(result), streamErr))
 	waitErr := cmd.Wait()
 	log.Debug(fmt.Sprintf("finished Claude Code CLI process: waitErr=%v", waitErr))
 	if waitErr != nil {
-		return zeroValue,
+		return nil,
 			fmt.Errorf("%w: %s", ErrProcessExitError, stderrBuf.String())
 	}
 	log.Debug(fmt.Sprintf("raw result from processStream: %v", string(result)))
 
-	if validator := schema.Validate(result); !validator.IsValid() {
-		err := validator.DetailedErrors()
-		return zeroValue,
-			fmt.Errorf("JSON schema validation failed for type %T: %v", zeroValue, err)
+	return result, nil
+}
+
+// formatValidationErrors renders the detailed validation errors as a sorted
+// list so that the agent, the log, and the tests see a stable text.
+func formatValidationErrors(detailedErrors map[string]string) string {
+	paths := slices.Sorted(maps.Keys(detailedErrors))
+	lines := make([]string, 0, len(paths))
+	for _, path := range paths {
+		lines = append(lines, fmt.Sprintf("- %v: %v", path, detailedErrors[path]))
 	}
+	return strings.Join(lines, "\n")
+}
 
-	var finalResult T
-	if err := json.Unmarshal(result, &finalResult); err != nil {
-		return zeroValue,
-			fmt.Errorf("failed to unmarshal processStream result into type %T: %w", zeroValue, err)
+func (c *Client) reportStructuredOutputRepair(attempt int, validationErrors string) {
+	log.Warning(fmt.Sprintf(
+		"structured output failed JSON schema validation; requesting repair attempt %d/%d:\n%v",
+		attempt, maxStructuredOutputRepairAttempts, validationErrors,
+	))
+	if c.streamCallback == nil {
+		return
 	}
+	c.streamCallback(ai.StreamMessage{
+		Type: ai.StreamMessageTypeWarning,
+		Content: fmt.Sprintf(
+			"The structured output did not match the expected schema; asking the agent to correct it (attempt %d of %d).",
+			attempt, maxStructuredOutputRepairAttempts,
+		),
+	})
+}
 
-	return finalResult, nil
+// writeTempFile writes content to a new temporary file, which os.CreateTemp
+// makes readable by the user only, and returns its path.
+func writeTempFile(pattern, content string) (string, error) {
+	tmpFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmpFile.WriteString(content); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
 }
 
-func buildCommand(c *Client, systemPromptPath, jsonSchema string) *exec.Cmd {
+// buildCommand builds the CLI command for a query. mcpConfigPath is the file
+// that registers the MCP server Bear serves to the CLI during the query, or
+// empty if there is none.
+func buildCommand(c *Client, systemPromptPath, jsonSchema, mcpConfigPath string) *exec.Cmd {
 	args := []string{
 		"-p",
-		"--model", "claude-opus-4-6",
+		"--model", model,
 		"--output-format", "stream-json",
 		"--verbose",
 		"--include-partial-messages",
-		"--allow-dangerously-skip-permissions",
-		"--permission-mode", "bypassPermissions",
-		"--tools", toolList,
 		"--append-system-prompt-file", systemPromptPath,
 		"--json-schema", jsonSchema,
 	}
+	promptTool := ""
+	if c.interactiveToolApproval {
+		promptTool = permissionPromptTool
+	}
+	args = append(args, c.permissionPolicy.commandArgs(promptTool, c.artifactToolNames())...)
+
+	if mcpConfigPath != "" {
+		args = append(args, "--mcp-config", mcpConfigPath)
+	}
 
 	if c.sessionID == "" {
 		c.sessionID = uuid.New().String()
@@ -270,6 +436,28 @@ func buildCommand(c *Client, systemPromptPath, jsonSchema string) *exec.Cmd {
 	if c.apiKey != "" {
 		cmd.Env = append(cmd.Env, "ANTHROPIC_API_KEY="+c.apiKey)
 	}
+	// A permission prompt waits for the user, who can take much longer than
+	// the default MCP tool timeout to decide.
+	if c.interactiveToolApproval {
+		cmd.Env = append(cmd.Env, "MCP_TOOL_TIMEOUT="+strconv.Itoa(toolApprovalTimeoutMilliseconds))
+	}
 
 	return cmd
 }
+
+// sessionExpectation returns the session settings that buildCommand requests
+// from the CLI.
+func (c *Client) sessionExpectation() sessionExpectation {
+	return sessionExpectation{
+		Model:          model,
+		Tools:          c.permissionPolicy.AllowedTools,
+		PermissionMode: c.permissionMode(),
+	}
+}
+
+func (c *Client) permissionMode() string {
+	if c.interactiveToolApproval {
+		return permissionModeDefault
+	}
+	return permissionModeDontAsk
+}
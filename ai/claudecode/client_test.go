@@ -2,10 +2,13 @@ package claudecode
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
 )
 
 func TestNewClient_BinaryNotFound(t *testing.T) {
@@ -35,7 +38,7 @@ func TestQuery_SessionIDGeneratedOnFirstCall(t *testing.T) {
 		t.Fatalf("failed to create prompt file: %v", err)
 	}
 
-	cmd := buildCommand(c, promptFile, `{"type":"object"}`)
+	cmd := buildCommand(c, promptFile, `{"type":"object"}`, "")
 
 	if c.sessionID == "" {
 		t.Fatal("sessionID should be generated after buildCommand")
@@ -65,10 +68,10 @@ func TestQuery_SessionIDReusedOnSubsequentCall(t *testing.T) {
 		t.Fatalf("failed to create prompt file: %v", err)
 	}
 
-	buildCommand(c, promptFile, `{"type":"object"}`)
+	buildCommand(c, promptFile, `{"type":"object"}`, "")
 	firstSessionID := c.sessionID
 
-	cmd2 := buildCommand(c, promptFile, `{"type":"object"}`)
+	cmd2 := buildCommand(c, promptFile, `{"type":"object"}`, "")
 
 	if c.sessionID != firstSessionID {
 		t.Errorf("sessionID changed: %q -> %q", firstSessionID, c.sessionID)
@@ -100,7 +103,7 @@ func TestQuery_EnvironmentVariablesSet(t *testing.T) {
 		t.Fatalf("failed to create prompt file: %v", err)
 	}
 
-	cmd := buildCommand(c, promptFile, `{"type":"object"}`)
+	cmd := buildCommand(c, promptFile, `{"type":"object"}`, "")
 
 	envMap := make(map[string]string)
 	for _, env := range cmd.Env {
@@ -141,7 +144,7 @@ func TestQuery_CLIArgumentsCorrect(t *testing.T) {
 		t.Fatalf("failed to create prompt file: %v", err)
 	}
 
-	cmd := buildCommand(c, promptFile, `{"type":"object"}`)
+	cmd := buildCommand(c, promptFile, `{"type":"object"}`, "")
 
 	requiredArgs := []string{
 		"-p",
@@ -151,9 +154,8 @@ func TestQuery_CLIArgumentsCorrect(t *testing.T) {
 		"stream-json",
 		"--verbose",
 		"--include-partial-messages",
-		"--allow-dangerously-skip-permissions",
 		"--permission-mode",
-		"bypassPermissions",
+		"dontAsk",
 		"--tools",
 		"--append-system-prompt-file",
 		"--json-schema",
@@ -169,6 +171,12 @@ func TestQuery_CLIArgumentsCorrect(t *testing.T) {
 	if cmd.Dir != c.workingDir {
 		t.Errorf("expected working dir %q, got %q", c.workingDir, cmd.Dir)
 	}
+
+	for _, forbidden := range []string{"--allow-dangerously-skip-permissions", "bypassPermissions"} {
+		if strings.Contains(argStr, forbidden) {
+			t.Errorf("unexpected argument %q in command args, got: %v", forbidden, cmd.Args)
+		}
+	}
 }
 
 func TestQuery_SystemPromptTempFileCleanup(t *testing.T) {
@@ -236,3 +244,170 @@ func TestQuery_StdinReceivesUserPrompt(t *testing.T) {
 		t.Errorf("expected stdin %q, got %q", userPrompt, string(captured))
 	}
 }
+
+func TestQuery_SessionExpectationMatchesCommand(t *testing.T) {
+	c := &Client{
+		apiKey:     "test-key",
+		workingDir: t.TempDir(),
+		binaryPath: "/usr/bin/claude",
+	}
+
+	cmd := buildCommand(c, "prompt.md", `{"type":"object"}`, "")
+	expectation := c.sessionExpectation()
+
+	argValue := func(name string) string {
+		for i, arg := range cmd.Args {
+			if arg == name && i+1 < len(cmd.Args) {
+				return cmd.Args[i+1]
+			}
+		}
+		return ""
+	}
+
+	if got := argValue("--model"); got != expectation.Model {
+		t.Errorf("expected model %q, got %q", expectation.Model, got)
+	}
+	if got := argValue("--permission-mode"); got != expectation.PermissionMode {
+		t.Errorf("expected permission mode %q, got %q", expectation.PermissionMode, got)
+	}
+	if got := argValue("--tools"); got != strings.Join(expectation.Tools, ",") {
+		t.Errorf("expected tools %q, got %q", strings.Join(expectation.Tools, ","), got)
+	}
+}
+
+// writeFakeClaudeScript creates a script that stands in for the claude binary.
+// The script records the stdin of every invocation to stdin_<n>.txt in dir and
+// prints outputs[n-1] as the structured output of the n-th invocation, repeating
+// the last output once the list is exhausted.
+func writeFakeClaudeScript(t *testing.T, dir string, outputs []string) string {
+	t.Helper()
+
+	var cases strings.Builder
+	for i, output := range outputs {
+		fmt.Fprintf(&cases, "%d) echo '{\"type\":\"result\",\"subtype\":\"success\",\"structured_output\":%s}' ;;\n", i+1, output)
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+n=$(cat %[1]s/count 2>/dev/null || echo 0)
+n=$((n+1))
+echo $n > %[1]s/count
+cat > %[1]s/stdin_$n.txt
+while [ $# -gt 0 ]; do
+  if [ "$1" = "--append-system-prompt-file" ]; then cp "$2" %[1]s/system_$n.txt; fi
+  shift
+done
+case $n in
+%[2]s*) echo '{"type":"result","subtype":"success","structured_output":%[3]s}' ;;
+esac
+`, dir, cases.String(), outputs[len(outputs)-1])
+
+	scriptFile := filepath.Join(dir, "fake_claude.sh")
+	if err := os.WriteFile(scriptFile, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to create script: %v", err)
+	}
+	return scriptFile
+}
+
+func readInvocationCount(t *testing.T, dir string) string {
+	t.Helper()
+	count, err := os.ReadFile(filepath.Join(dir, "count"))
+	if err != nil {
+		t.Fatalf("failed to read invocation count: %v", err)
+	}
+	return strings.TrimSpace(string(count))
+}
+
+func TestQuery_InvalidStructuredOutputIsRepaired(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":"not an array"}`,
+		`{"questions":["What is the scope?"]}`,
+	})
+
+	var warnings []ai.StreamMessage
+	c := &Client{
+		apiKey:     "test-key",
+		workingDir: tmpDir,
+		binaryPath: scriptFile,
+		prompts:    ai.DefaultPrompts(tmpDir),
+		streamCallback: func(msg ai.StreamMessage) {
+			if msg.Type == ai.StreamMessageTypeWarning {
+				warnings = append(warnings, msg)
+			}
+		},
+	}
+
+	type output struct {
+		Questions []string `json:"questions" jsonschema:"required"`
+	}
+	result, err := query[output](c, "system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Questions) != 1 || result.Questions[0] != "What is the scope?" {
+		t.Errorf("unexpected repaired result: %#v", result)
+	}
+	if got := readInvocationCount(t, tmpDir); got != "2" {
+		t.Errorf("expected 2 invocations, got %v", got)
+	}
+	repairPrompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_2.txt"))
+	if err != nil {
+		t.Fatalf("failed to read repair prompt: %v", err)
+	}
+	if !strings.Contains(string(repairPrompt), "/questions") {
+		t.Errorf("repair prompt should contain the validation errors, got %q", repairPrompt)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 repair warning, got %d", len(warnings))
+	}
+}
+
+func TestQuery_RepairAttemptsAreLimited(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":"not an array"}`,
+	})
+
+	c := &Client{
+		apiKey:     "test-key",
+		workingDir: tmpDir,
+		binaryPath: scriptFile,
+		prompts:    ai.DefaultPrompts(tmpDir),
+	}
+
+	type output struct {
+		Questions []string `json:"questions" jsonschema:"required"`
+	}
+	_, err := query[output](c, "system prompt", "user prompt")
+	if err == nil {
+		t.Fatal("expected error after exhausting repair attempts")
+	}
+
+	want := fmt.Sprint(maxStructuredOutputRepairAttempts + 1)
+	if got := readInvocationCount(t, tmpDir); got != want {
+		t.Errorf("expected %v invocations, got %v", want, got)
+	}
+}
+
+func TestWriteTempFile_ReadableByUserOnly(t *testing.T) {
+	path, err := writeTempFile("bear-test-*.json", `{"token":"secret"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat temp file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("expected mode 0600, got %o", perm)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read temp file: %v", err)
+	}
+	if string(content) != `{"token":"secret"}` {
+		t.Errorf("unexpected content: %q", content)
+	}
+}
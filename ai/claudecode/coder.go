@@ -0,0 +1,63 @@
+package claudecode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+// codingOutput is the structured output of the coding query.
+type codingOutput struct {
+	Done    bool   `json:"done" jsonschema:"required"`
+	Summary string `json:"summary" jsonschema:"required"`
+	Handoff string `json:"handoff" jsonschema:"required"`
+}
+
+// NewCodingClient creates the client of a coding agent, which implements a
+// single task of the plan in workingDir, the workspace in the task's worktree.
+// Unlike the client of NewClient, it may change the files of workingDir.
+func NewCodingClient(apiKey, workingDir string) (*Client, error) {
+	client, err := NewClient(apiKey, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	client.permissionPolicy = codingPermissionPolicy(client.workingDir)
+	client.taskTools = true
+	client.sessionState = sessionStateCoding
+	return client, nil
+}
+
+func (c *Client) CodeTask(task ai.Task) (ai.CodingResult, error) {
+	if c.sessionState != sessionStateCoding {
+		return ai.CodingResult{}, fmt.Errorf("unexpected session state for CodeTask: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("coding task %v in %v", task.ID, c.workingDir))
+	systemPrompt, err := c.prompts.Render(ai.PromptCodingSystem, nil)
+	if err != nil {
+		return ai.CodingResult{}, err
+	}
+	userPrompt, err := c.prompts.Render(ai.PromptCodingTask, map[string]string{
+		"TaskID": task.ID,
+		"Task":   task.Markdown(),
+	})
+	if err != nil {
+		return ai.CodingResult{}, err
+	}
+	output, err := query[codingOutput](c, systemPrompt, userPrompt)
+	if err != nil {
+		err = fmt.Errorf("failed to code %v: %w", task.ID, err)
+		log.Error(err.Error())
+		return ai.CodingResult{}, err
+	}
+	log.Debug(fmt.Sprintf("coded task %v: %#v", task.ID, output))
+
+	c.sessionState = sessionStateTaskCoded
+	return ai.CodingResult{
+		Done:    output.Done,
+		Summary: strings.TrimSpace(output.Summary),
+		Handoff: strings.TrimSpace(output.Handoff),
+	}, nil
+}
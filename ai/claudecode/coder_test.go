@@ -0,0 +1,54 @@
+package claudecode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+func TestCodeTask_GivesTheTaskAndReturnsTheSummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	c := &Client{
+		apiKey:           "test-key",
+		workingDir:       tmpDir,
+		binaryPath:       writeFakeClaudeScript(t, tmpDir, []string{`{"done":true,"summary":" Add the CSV writer ","handoff":"WriteCSV takes the rows."}`}),
+		prompts:          ai.DefaultPrompts(tmpDir),
+		permissionPolicy: codingPermissionPolicy(tmpDir),
+		toolApprover:     newToolApprover(),
+		sessionState:     sessionStateCoding,
+	}
+	task := ai.Task{ID: "TASK-01", Title: "Add the CSV writer", Description: "Write rows as CSV.", Covers: []string{"REQ-01"}}
+
+	result, err := c.CodeTask(task)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !result.Done || result.Summary != "Add the CSV writer" || result.Handoff != "WriteCSV takes the rows." {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	userPrompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read the coding prompt: %v", err)
+	}
+	if !strings.Contains(string(userPrompt), "## TASK-01: Add the CSV writer") ||
+		!strings.Contains(string(userPrompt), "- Covers: REQ-01") {
+		t.Errorf("the coding prompt should contain the task:\n%s", userPrompt)
+	}
+
+	systemPrompt, err := os.ReadFile(filepath.Join(tmpDir, "system_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read the system prompt: %v", err)
+	}
+	if !strings.Contains(string(systemPrompt), "TestExport_AC02") {
+		t.Errorf("the coding prompt should ask for the IDs in test names:\n%s", systemPrompt)
+	}
+
+	// A coding agent implements a single task.
+	if _, err := c.CodeTask(task); err == nil {
+		t.Error("expected an error for a second task")
+	}
+}
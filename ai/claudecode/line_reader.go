@@ -0,0 +1,75 @@
+package claudecode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// maxStreamLineBytes is the upper bound on the memory used to hold a single
+// line of the stream. It is far above the size of any realistic event; a line
+// exceeding it is skipped instead of aborting the whole query, and reported
+// with ErrStreamLineTooLong if the query ends without a result.
+const maxStreamLineBytes = 64 << 20
+
+// streamLineReader reads newline-delimited lines of any length, unlike
+// bufio.Scanner whose default token limit is 64 KiB, while never holding more
+// than maxLineBytes of a single line in memory.
+type streamLineReader struct {
+	reader       *bufio.Reader
+	maxLineBytes int
+}
+
+func newStreamLineReader(reader io.Reader, maxLineBytes int) *streamLineReader {
+	return &streamLineReader{
+		reader:       bufio.NewReader(reader),
+		maxLineBytes: maxLineBytes,
+	}
+}
+
+// ReadLine returns the next line without the trailing newline. If the line is
+// longer than maxLineBytes, the line is discarded up to its end, discarded is
+// its length, and line holds only its head, up to maxLoggedStreamLineBytes, so
+// that the skipped line can still be logged. It returns io.EOF once there are
+// no more lines.
+func (r *streamLineReader) ReadLine() (line []byte, discarded int, err error) {
+	headBytes := min(maxLoggedStreamLineBytes, r.maxLineBytes)
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		chunk = trimNewline(chunk)
+
+		if discarded == 0 && len(line)+len(chunk) > r.maxLineBytes {
+			discarded = len(line)
+			// Clone the head so that the buffer of the whole line is released.
+			line = bytes.Clone(line[:min(len(line), headBytes)])
+		}
+		if discarded > 0 {
+			discarded += len(chunk)
+			if keep := headBytes - len(line); keep > 0 {
+				line = append(line, chunk[:min(keep, len(chunk))]...)
+			}
+		} else {
+			// ReadSlice reuses its buffer, so the chunk must be copied.
+			line = append(line, chunk...)
+		}
+
+		switch {
+		case err == nil:
+			return line, discarded, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF) && (len(line) > 0 || discarded > 0):
+			return line, discarded, nil
+		default:
+			return nil, 0, err
+		}
+	}
+}
+
+func trimNewline(chunk []byte) []byte {
+	if len(chunk) > 0 && chunk[len(chunk)-1] == '\n' {
+		return chunk[:len(chunk)-1]
+	}
+	return chunk
+}
@@ -0,0 +1,74 @@
+package claudecode
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStreamLineReader_ReadsLinesLongerThanBufferSize(t *testing.T) {
+	longLine := strings.Repeat("x", 1<<20)
+	reader := newStreamLineReader(strings.NewReader(longLine+"\nshort\n"), maxStreamLineBytes)
+
+	line, discarded, err := reader.ReadLine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if discarded != 0 {
+		t.Fatal("line within the limit should not be discarded")
+	}
+	if string(line) != longLine {
+		t.Errorf("expected line of %d bytes, got %d bytes", len(longLine), len(line))
+	}
+
+	line, _, err = reader.ReadLine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(line) != "short" {
+		t.Errorf("expected 'short', got %q", line)
+	}
+
+	_, _, err = reader.ReadLine()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestStreamLineReader_LineExceedingLimitIsSkipped(t *testing.T) {
+	input := strings.Repeat("x", 10000) + "\nnext\n"
+	reader := newStreamLineReader(strings.NewReader(input), 100)
+
+	line, discarded, err := reader.ReadLine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if discarded != 10000 {
+		t.Fatalf("expected the length of the discarded line, got %d", discarded)
+	}
+	if len(line) != 100 {
+		t.Errorf("expected the head of the discarded line, got %d bytes", len(line))
+	}
+
+	line, discarded, err = reader.ReadLine()
+	if err != nil || discarded != 0 {
+		t.Fatalf("unexpected result: discarded=%v, err=%v", discarded, err)
+	}
+	if string(line) != "next" {
+		t.Errorf("expected 'next', got %q", line)
+	}
+}
+
+func TestStreamLineReader_LastLineWithoutNewline(t *testing.T) {
+	reader := newStreamLineReader(strings.NewReader("first\nlast"), maxStreamLineBytes)
+
+	_, _, _ = reader.ReadLine()
+	line, _, err := reader.ReadLine()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(line) != "last" {
+		t.Errorf("expected 'last', got %q", line)
+	}
+}
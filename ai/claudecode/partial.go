@@ -0,0 +1,101 @@
+package claudecode
+
+import (
+	"fmt"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+// partialMessageEvent is the raw Anthropic streaming event wrapped by a
+// "stream_event" line of the Claude Code CLI.
+type partialMessageEvent struct {
+	// Type can be "message_start", "content_block_start",
+	// "content_block_delta", "content_block_stop", "message_delta", or
+	// "message_stop".
+	Type string `json:"type"`
+	// Used when Type is "content_block_start", "content_block_delta", or
+	// "content_block_stop".
+	Index int `json:"index"`
+	// Used when Type is "content_block_start".
+	ContentBlock ContentBlock `json:"content_block"`
+	// Used when Type is "content_block_delta".
+	Delta partialMessageDelta `json:"delta"`
+}
+
+type partialMessageDelta struct {
+	// Type can be "text_delta", "thinking_delta", "input_json_delta", or
+	// "signature_delta".
+	Type string `json:"type"`
+	// Used when Type is "text_delta".
+	Text string `json:"text"`
+	// Used when Type is "thinking_delta".
+	Thinking string `json:"thinking"`
+	// Used when Type is "input_json_delta".
+	PartialJSON string `json:"partial_json"`
+}
+
+// partialBlockTracker remembers the content blocks started in the current
+// message by their index, because delta events only carry the block index and
+// tool input deltas need the tool call identity of the block they extend.
+type partialBlockTracker map[int]ContentBlock
+
+// toAiStreamMessage converts a partial message event into an incremental
+// ai.StreamMessage. It returns false for events that do not carry any content
+// to render, such as block boundaries and signature deltas.
+func (t partialBlockTracker) toAiStreamMessage(
+	event partialMessageEvent,
+) (ai.StreamMessage, bool) {
+	switch event.Type {
+	case "message_start":
+		// Block indices restart from zero for every message.
+		clear(t)
+		return ai.StreamMessage{}, false
+	case "content_block_start":
+		t[event.Index] = event.ContentBlock
+		return ai.StreamMessage{}, false
+	case "content_block_delta":
+		return t.deltaToAiStreamMessage(event.Index, event.Delta)
+	default:
+		return ai.StreamMessage{}, false
+	}
+}
+
+func (t partialBlockTracker) deltaToAiStreamMessage(
+	index int,
+	delta partialMessageDelta,
+) (ai.StreamMessage, bool) {
+	switch delta.Type {
+	case "text_delta":
+		return ai.StreamMessage{
+			Role:    ai.StreamMessageRoleAssistant,
+			Type:    ai.StreamMessageTypeTextDelta,
+			Content: normalizeNewlines(delta.Text),
+		}, true
+	case "thinking_delta":
+		return ai.StreamMessage{
+			Role:    ai.StreamMessageRoleAssistant,
+			Type:    ai.StreamMessageTypeThinkingDelta,
+			Content: normalizeNewlines(delta.Thinking),
+		}, true
+	case "input_json_delta":
+		block, ok := t[index]
+		if !ok {
+			log.Warning(fmt.Sprintf("input_json_delta refers to an unknown content block index: %v", index))
+		}
+		// The structured output is returned as the final result, so it is
+		// not streamed as a tool call input.
+		if block.Name == "StructuredOutput" {
+			return ai.StreamMessage{}, false
+		}
+		return ai.StreamMessage{
+			Role:       ai.StreamMessageRoleAssistant,
+			Type:       ai.StreamMessageTypeToolCallInputDelta,
+			Content:    delta.PartialJSON,
+			ToolCallID: block.ID,
+			ToolName:   block.Name,
+		}, true
+	default:
+		return ai.StreamMessage{}, false
+	}
+}
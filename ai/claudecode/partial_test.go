@@ -0,0 +1,110 @@
+package claudecode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+func TestProcessStream_PartialTextAndThinkingDeltas(t *testing.T) {
+	input := `{"type":"stream_event","event":{"type":"message_start"}}
+{"type":"stream_event","event":{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me "}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"abc"}}}
+{"type":"stream_event","event":{"type":"content_block_stop","index":0}}
+{"type":"stream_event","event":{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hello"}}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	var received []ai.StreamMessage
+	callback := func(msg ai.StreamMessage) {
+		received = append(received, msg)
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("expected 2 incremental messages, got %d: %#v", len(received), received)
+	}
+	if received[0].Type != ai.StreamMessageTypeThinkingDelta || received[0].Content != "Let me " {
+		t.Errorf("unexpected thinking delta: %#v", received[0])
+	}
+	if received[1].Type != ai.StreamMessageTypeTextDelta || received[1].Content != "Hello" {
+		t.Errorf("unexpected text delta: %#v", received[1])
+	}
+}
+
+func TestProcessStream_PartialToolInputDeltaCarriesToolIdentity(t *testing.T) {
+	input := `{"type":"stream_event","event":{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"Read","input":{}}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"file_"}}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	var received []ai.StreamMessage
+	callback := func(msg ai.StreamMessage) {
+		received = append(received, msg)
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 incremental message, got %d", len(received))
+	}
+	msg := received[0]
+	if msg.Type != ai.StreamMessageTypeToolCallInputDelta {
+		t.Errorf("expected tool call input delta, got %v", msg.Type)
+	}
+	if msg.Content != `{"file_` {
+		t.Errorf("unexpected partial JSON: %q", msg.Content)
+	}
+	if msg.ToolCallID != "toolu_1" || msg.ToolName != "Read" {
+		t.Errorf("unexpected tool identity: %#v", msg)
+	}
+}
+
+func TestProcessStream_StructuredOutputInputDeltaNotStreamed(t *testing.T) {
+	input := `{"type":"stream_event","event":{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"StructuredOutput","input":{}}}}
+{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"questions\""}}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	callCount := 0
+	callback := func(msg ai.StreamMessage) {
+		callCount++
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if callCount != 0 {
+		t.Errorf("callback should not be called for StructuredOutput input deltas, called %d times", callCount)
+	}
+}
+
+func TestPartialBlockTracker_MessageStartResetsBlocks(t *testing.T) {
+	tracker := partialBlockTracker{}
+	tracker.toAiStreamMessage(partialMessageEvent{
+		Type:         "content_block_start",
+		Index:        0,
+		ContentBlock: ContentBlock{Type: "tool_use", ID: "toolu_1", Name: "Read"},
+	})
+	tracker.toAiStreamMessage(partialMessageEvent{Type: "message_start"})
+
+	msg, ok := tracker.toAiStreamMessage(partialMessageEvent{
+		Type:  "content_block_delta",
+		Index: 0,
+		Delta: partialMessageDelta{Type: "input_json_delta", PartialJSON: "{"},
+	})
+	if !ok {
+		t.Fatal("expected an incremental message")
+	}
+	if msg.ToolCallID != "" || msg.ToolName != "" {
+		t.Errorf("expected tool identity to be reset by message_start, got %#v", msg)
+	}
+}
@@ -0,0 +1,156 @@
+package claudecode
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// permissionModeDontAsk makes the CLI deny every tool call that is not
+// pre-approved by an allow rule, instead of prompting for it or bypassing the
+// permission checks.
+const permissionModeDontAsk = "dontAsk"
+
+// fileWritingTools are the tools whose permission rules are expressed as
+// "Edit(<path glob>)"; the CLI applies an Edit rule to all of them.
+var fileWritingTools = []string{"Edit", "Write", "NotebookEdit"}
+
+// bashTool is the tool whose calls are only pre-approved for the command
+// prefixes in AllowedBashPatterns, because it can do anything.
+const bashTool = "Bash"
+
+// PermissionPolicy describes what an agent is allowed to do in its session.
+// buildCommand turns it into the CLI flags that enforce it.
+type PermissionPolicy struct {
+	// AllowedTools is the set of tools available to the agent. Every tool in
+	// this set is pre-approved, except the file writing tools, which are only
+	// approved for the paths matching WritablePathGlobs, and Bash, which is
+	// only approved for the commands matching AllowedBashPatterns.
+	AllowedTools []string
+	// AllowedBashPatterns are command prefixes that the agent may run through
+	// the Bash tool, for example "ls" or "git log". Any other command is
+	// denied, or sent to the user when tool calls are approved interactively.
+	AllowedBashPatterns []string
+	// DisallowedBashPatterns are command prefixes that the agent must never
+	// run through the Bash tool, for example "rm" or "git push". Deny rules
+	// take precedence over every allow rule.
+	DisallowedBashPatterns []string
+	// WritablePathGlobs are the absolute path globs that the file writing
+	// tools may modify. If it is empty, the agent cannot modify any file even
+	// if a file writing tool is in AllowedTools.
+	WritablePathGlobs []string
+}
+
+// specWriterPermissionPolicy is the policy for the clarification and spec
+// writing agent, whose system prompt forbids any change to the workspace. It
+// has no file writing tools, and Bash is limited to commands that only read
+// the workspace. Commands that can write through one of their options are left
+// out, since a prefix rule cannot exclude an option: find and xargs, an
+// interpreter, `tree -o`, `file -C`, `git diff/log/show --output` and
+// `date -s`.
+func specWriterPermissionPolicy() PermissionPolicy {
+	return PermissionPolicy{
+		AllowedTools: []string{bashTool, "Glob", "Grep", "LSP", "Read", "WebFetch", "WebSearch"},
+		AllowedBashPatterns: []string{
+			"ls", "pwd", "cat", "head", "tail", "wc", "stat",
+			"git status", "git blame", "git ls-files", "git rev-parse",
+			"git branch --list",
+		},
+		WritablePathGlobs: nil,
+	}
+}
+
+// codingPermissionPolicy is the policy for a coding agent, which implements a
+// task of the plan in workingDir, a worktree of its own. The file writing
+// tools are approved for the worktree only. Bash is approved for the commands
+// of the spec writer and for the usual build and test commands, which run the
+// workspace's code, so the sandbox is what keeps them in the worktree. Bear
+// commits the agent's changes on the task branch itself, so the commands that
+// commit, move the branch or throw away changes are denied even when tool
+// calls are approved interactively.
+func codingPermissionPolicy(workingDir string) PermissionPolicy {
+	return PermissionPolicy{
+		AllowedTools: []string{
+			bashTool, "Edit", "Glob", "Grep", "LSP", "NotebookEdit", "Read", "WebFetch", "WebSearch", "Write",
+		},
+		AllowedBashPatterns: append(specWriterPermissionPolicy().AllowedBashPatterns,
+			"git diff", "git log", "git show",
+			"go build", "go test", "go vet", "gofmt",
+			"cargo build", "cargo test", "npm test", "npm run build", "pytest", "make",
+		),
+		DisallowedBashPatterns: []string{
+			"sudo", "git commit", "git push", "git reset", "git checkout", "git switch",
+			"git rebase", "git merge", "git clean", "git stash", "git worktree",
+		},
+		WritablePathGlobs: []string{filepath.Join(workingDir, "**")},
+	}
+}
+
+// commandArgs returns the CLI flags that enforce the policy. If promptTool is
+// not empty, tool calls that are neither pre-approved nor denied are sent to
+// that permission prompt tool instead of being denied. mcpTools are the MCP
+// tools that are pre-approved in addition to the policy, because they are
+// served by Bear itself.
+func (p PermissionPolicy) commandArgs(promptTool string, mcpTools []string) []string {
+	permissionMode := permissionModeDontAsk
+	if promptTool != "" {
+		permissionMode = permissionModeDefault
+	}
+
+	args := []string{
+		"--permission-mode", permissionMode,
+		"--tools", strings.Join(p.AllowedTools, ","),
+	}
+	if promptTool != "" {
+		args = append(args, "--permission-prompt-tool", promptTool)
+	}
+	allowRules := append(p.allowRules(), mcpTools...)
+	if len(allowRules) > 0 {
+		args = append(args, "--allowedTools", strings.Join(allowRules, ","))
+	}
+	if denyRules := p.denyRules(); len(denyRules) > 0 {
+		args = append(args, "--disallowedTools", strings.Join(denyRules, ","))
+	}
+	return args
+}
+
+func (p PermissionPolicy) allowRules() []string {
+	var rules []string
+	allowsFileWriting := false
+	for _, tool := range p.AllowedTools {
+		if slices.Contains(fileWritingTools, tool) {
+			allowsFileWriting = true
+			continue
+		}
+		if tool == bashTool {
+			rules = append(rules, bashRules(p.AllowedBashPatterns)...)
+			continue
+		}
+		rules = append(rules, tool)
+	}
+
+	if !allowsFileWriting {
+		return rules
+	}
+	for _, glob := range p.WritablePathGlobs {
+		// A leading "//" makes the CLI treat the glob as an absolute path
+		// instead of a path relative to the settings file.
+		rules = append(rules, fmt.Sprintf("Edit(/%v)", glob))
+	}
+	return rules
+}
+
+func (p PermissionPolicy) denyRules() []string {
+	return bashRules(p.DisallowedBashPatterns)
+}
+
+// bashRules turns command prefixes into Bash permission rules such as
+// "Bash(git log:*)".
+func bashRules(patterns []string) []string {
+	rules := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		rules = append(rules, fmt.Sprintf("%v(%v:*)", bashTool, pattern))
+	}
+	return rules
+}
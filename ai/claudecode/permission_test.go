@@ -0,0 +1,145 @@
+package claudecode
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func commandArgValue(args []string, name string) string {
+	for i, arg := range args {
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func TestSpecWriterPermissionPolicy_CannotModifyWorkspace(t *testing.T) {
+	c := &Client{
+		apiKey:           "test-key",
+		workingDir:       t.TempDir(),
+		binaryPath:       "/usr/bin/claude",
+		permissionPolicy: specWriterPermissionPolicy(),
+	}
+
+	cmd := buildCommand(c, "prompt.md", `{"type":"object"}`, "")
+
+	if got := commandArgValue(cmd.Args, "--permission-mode"); got != "dontAsk" {
+		t.Errorf("expected permission mode dontAsk, got %q", got)
+	}
+
+	tools := strings.Split(commandArgValue(cmd.Args, "--tools"), ",")
+	for _, tool := range fileWritingTools {
+		if slices.Contains(tools, tool) {
+			t.Errorf("spec writer should not have the %v tool", tool)
+		}
+	}
+
+	allowed := strings.Split(commandArgValue(cmd.Args, "--allowedTools"), ",")
+	for _, rule := range allowed {
+		if strings.HasPrefix(rule, "Edit(") {
+			t.Errorf("spec writer should not be allowed to edit any path, got %q", rule)
+		}
+	}
+	// Only the read-only commands are pre-approved; any other command, such
+	// as "find -delete" or "python -c", is denied by the permission mode.
+	if slices.Contains(allowed, "Bash") {
+		t.Errorf("spec writer should not be allowed to run any command, got %v", allowed)
+	}
+	for _, rule := range []string{"Bash(ls:*)", "Bash(cat:*)", "Bash(git status:*)", "Bash(git blame:*)"} {
+		if !slices.Contains(allowed, rule) {
+			t.Errorf("expected allow rule %q, got %v", rule, allowed)
+		}
+	}
+	for _, rule := range allowed {
+		for _, command := range []string{"find", "xargs", "python", "bash", "sh", "install", "git worktree"} {
+			if strings.HasPrefix(rule, "Bash("+command) {
+				t.Errorf("spec writer should not be allowed to run %v, got %q", command, rule)
+			}
+		}
+	}
+}
+
+// Each of these commands writes a file or changes the system through one of
+// its options, which a prefix rule cannot exclude, so none of them may be
+// pre-approved in any form.
+func TestSpecWriterPermissionPolicy_DeniesCommandsWritingThroughOptions(t *testing.T) {
+	commands := []string{
+		"tree -o /workspace/out.txt",
+		"file -C -m /workspace/magic",
+		"git diff --output=/workspace/out.patch",
+		"git log --output=/workspace/out.txt",
+		"git show --output=/workspace/out.txt",
+		"date -s 2020-01-01",
+	}
+
+	allowed := specWriterPermissionPolicy().AllowedBashPatterns
+
+	for _, command := range commands {
+		for _, pattern := range allowed {
+			if command == pattern || strings.HasPrefix(command, pattern+" ") {
+				t.Errorf("command %q is pre-approved by %q", command, pattern)
+			}
+		}
+	}
+}
+
+func TestCodingPermissionPolicy_WritesInWorktreeAndNeverCommits(t *testing.T) {
+	args := codingPermissionPolicy("/repo/.git/bear/session-1/TASK-01").commandArgs("", nil)
+
+	allowed := strings.Split(commandArgValue(args, "--allowedTools"), ",")
+	if !slices.Contains(allowed, "Edit(//repo/.git/bear/session-1/TASK-01/**)") {
+		t.Errorf("expected edit rule limited to the worktree, got %v", allowed)
+	}
+	for _, rule := range []string{"Bash(go test:*)", "Bash(git diff:*)", "Bash(git status:*)"} {
+		if !slices.Contains(allowed, rule) {
+			t.Errorf("expected allow rule %q, got %v", rule, allowed)
+		}
+	}
+	// Bear commits the agent's changes on the task branch, so the agent must
+	// not commit or move the branch itself.
+	denied := strings.Split(commandArgValue(args, "--disallowedTools"), ",")
+	for _, rule := range []string{"Bash(git commit:*)", "Bash(git reset:*)", "Bash(git checkout:*)", "Bash(git push:*)"} {
+		if !slices.Contains(denied, rule) {
+			t.Errorf("expected deny rule %q, got %v", rule, denied)
+		}
+	}
+}
+
+func TestPermissionPolicy_FileWritingLimitedToGlobs(t *testing.T) {
+	policy := PermissionPolicy{
+		AllowedTools:      []string{"Read", "Edit", "Write"},
+		WritablePathGlobs: []string{"/workspace/project/**"},
+	}
+
+	allowed := strings.Split(commandArgValue(policy.commandArgs("", nil), "--allowedTools"), ",")
+
+	if !slices.Equal(allowed, []string{"Read", "Edit(//workspace/project/**)"}) {
+		t.Errorf("expected edit rule limited to the workspace, got %v", allowed)
+	}
+}
+
+func TestPermissionPolicy_FileWritingToolWithoutGlobsIsNotAllowed(t *testing.T) {
+	policy := PermissionPolicy{
+		AllowedTools: []string{"Read", "Write"},
+	}
+
+	allowed := commandArgValue(policy.commandArgs("", nil), "--allowedTools")
+
+	if allowed != "Read" {
+		t.Errorf("expected only Read to be pre-approved, got %q", allowed)
+	}
+}
+
+func TestPermissionPolicy_NoDenyRulesOmitsFlag(t *testing.T) {
+	policy := PermissionPolicy{
+		AllowedTools: []string{"Read"},
+	}
+
+	args := policy.commandArgs("", nil)
+
+	if slices.Contains(args, "--disallowedTools") {
+		t.Errorf("expected no --disallowedTools flag, got %v", args)
+	}
+}
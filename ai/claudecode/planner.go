@@ -0,0 +1,192 @@
+package claudecode
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+var ErrInvalidPlan = errors.New("plan breaks the planning rules")
+
+// planOutput is the structured output of the planning queries.
+type planOutput struct {
+	Tasks []struct {
+		Title       string   `json:"title" jsonschema:"required"`
+		Description string   `json:"description" jsonschema:"required"`
+		Covers      []string `json:"covers" jsonschema:"required"`
+		// DependsOn are the numbers of earlier tasks, counted from 1, since
+		// the tasks get their IDs only from their order.
+		DependsOn []int `json:"depends_on" jsonschema:"required"`
+	} `json:"tasks" jsonschema:"required,minItems=1,maxItems=30"`
+}
+
+func (c *Client) DraftPlan(approvedSpec string) (ai.Plan, error) {
+	if c.sessionState != sessionStateSpecApproved {
+		return ai.Plan{}, fmt.Errorf("unexpected session state for DraftPlan: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("drafting plan for approved spec of %d bytes", len(approvedSpec)))
+	userPrompt, err := c.prompts.Render(ai.PromptPlanDraft, map[string]string{
+		"Spec": approvedSpec,
+	})
+	if err != nil {
+		return ai.Plan{}, err
+	}
+	// The planner works from the approved spec alone, under a system prompt
+	// of its own, so it starts a new CLI session instead of resuming the one
+	// that wrote the spec.
+	c.sessionID = ""
+	c.approvedSpec = approvedSpec
+	plan, err := c.queryPlan(userPrompt)
+	if err != nil {
+		err = fmt.Errorf("failed to draft plan: %w", err)
+		log.Error(err.Error())
+		return ai.Plan{}, err
+	}
+
+	c.sessionState = sessionStateWaitPlanFeedback
+	return plan, nil
+}
+
+func (c *Client) RevisePlan(userFeedback string) (ai.Plan, error) {
+	if c.sessionState != sessionStateWaitPlanFeedback {
+		return ai.Plan{}, fmt.Errorf("unexpected session state for RevisePlan: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("revising plan for user feedback: %v", userFeedback))
+	userPrompt, err := c.prompts.Render(ai.PromptPlanRevision, map[string]string{
+		"Feedback": userFeedback,
+	})
+	if err != nil {
+		return ai.Plan{}, err
+	}
+	plan, err := c.queryPlan(userPrompt)
+	if err != nil {
+		err = fmt.Errorf("failed to revise plan: %w", err)
+		log.Error(err.Error())
+		return ai.Plan{}, err
+	}
+	return plan, nil
+}
+
+func (c *Client) ApprovePlan() error {
+	if c.sessionState != sessionStateWaitPlanFeedback {
+		return fmt.Errorf("unexpected session state for ApprovePlan: %v", c.sessionState)
+	}
+
+	log.Debug("approving plan")
+	c.sessionState = sessionStatePlanApproved
+	return nil
+}
+
+// queryPlan asks for a plan and checks it against the approved spec. A plan
+// that breaks the rules is sent back to the agent with the problems, like a
+// structured output that fails the schema validation.
+func (c *Client) queryPlan(userPrompt string) (ai.Plan, error) {
+	systemPrompt, err := c.prompts.Render(ai.PromptPlanSystem, nil)
+	if err != nil {
+		return ai.Plan{}, err
+	}
+
+	prompt := userPrompt
+	for attempt := 0; ; attempt++ {
+		output, err := query[planOutput](c, systemPrompt, prompt)
+		if err != nil {
+			return ai.Plan{}, err
+		}
+		plan, problems := planFromOutput(output, c.approvedSpec)
+		if len(problems) == 0 {
+			return plan, nil
+		}
+
+		formatted := "- " + strings.Join(problems, "\n- ")
+		if attempt >= maxStructuredOutputRepairAttempts {
+			return ai.Plan{}, fmt.Errorf("%w after %d repair attempts:\n%v", ErrInvalidPlan, attempt, formatted)
+		}
+
+		log.Warning(fmt.Sprintf(
+			"plan breaks the planning rules; requesting repair attempt %d/%d:\n%v",
+			attempt+1, maxStructuredOutputRepairAttempts, formatted,
+		))
+		if c.streamCallback != nil {
+			c.streamCallback(ai.StreamMessage{
+				Type: ai.StreamMessageTypeWarning,
+				Content: fmt.Sprintf(
+					"The plan breaks the planning rules; asking the agent to correct it (attempt %d of %d).",
+					attempt+1, maxStructuredOutputRepairAttempts,
+				),
+			})
+		}
+		prompt, err = c.prompts.Render(ai.PromptPlanRepair, map[string]string{
+			"Problems": formatted,
+		})
+		if err != nil {
+			return ai.Plan{}, err
+		}
+	}
+}
+
+// planFromOutput numbers the tasks of the output and returns the problems
+// that make it unusable: covered IDs that are not in the spec, IDs of the
+// spec that no task covers, and dependencies on tasks that do not come
+// earlier, which also rules out cycles.
+func planFromOutput(output planOutput, approvedSpec string) (ai.Plan, []string) {
+	var known []string
+	for _, item := range spec.ParseApprovedItems(approvedSpec) {
+		known = append(known, item.ID)
+	}
+
+	var plan ai.Plan
+	var problems []string
+	covered := map[string]bool{}
+	for i, item := range output.Tasks {
+		task := ai.Task{
+			ID:          taskID(i + 1),
+			Title:       strings.TrimSpace(item.Title),
+			Description: strings.TrimSpace(item.Description),
+		}
+		for _, cover := range item.Covers {
+			ids := spec.ExtractIDs(cover)
+			switch {
+			case len(ids) != 1:
+				problems = append(problems, fmt.Sprintf(
+					"Task %d covers %q, which is not a single requirement or criterion ID.", i+1, cover))
+			case !slices.Contains(known, ids[0]):
+				problems = append(problems, fmt.Sprintf(
+					"Task %d covers %v, which is not an ID of the spec.", i+1, ids[0]))
+			case !slices.Contains(task.Covers, ids[0]):
+				task.Covers = append(task.Covers, ids[0])
+				covered[ids[0]] = true
+			}
+		}
+		for _, number := range item.DependsOn {
+			if number < 1 || number > i {
+				problems = append(problems, fmt.Sprintf(
+					"Task %d depends on task %d, which is not listed before it.", i+1, number))
+				continue
+			}
+			if id := taskID(number); !slices.Contains(task.DependsOn, id) {
+				task.DependsOn = append(task.DependsOn, id)
+			}
+		}
+		plan.Tasks = append(plan.Tasks, task)
+	}
+
+	for _, id := range known {
+		if !covered[id] {
+			problems = append(problems, fmt.Sprintf("No task covers %v.", id))
+		}
+	}
+	return plan, problems
+}
+
+// taskID returns the ID of the task with the number, in the form that the
+// session store reads back from the plan.
+func taskID(number int) string {
+	return fmt.Sprintf("TASK-%02d", number)
+}
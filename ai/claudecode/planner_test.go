@@ -0,0 +1,161 @@
+package claudecode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+const plannedSpec = `# Report Export
+
+## Requirements
+
+- REQ-01: Reports can be exported as CSV.
+
+## Acceptance criteria
+
+- AC-01: (REQ-01) Exporting a report downloads a CSV file.
+`
+
+func newPlanningClient(t *testing.T, outputs []string) (*Client, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	return &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   writeFakeClaudeScript(t, tmpDir, outputs),
+		prompts:      ai.DefaultPrompts(tmpDir),
+		toolApprover: newToolApprover(),
+		sessionID:    "spec-session",
+		sessionState: sessionStateSpecApproved,
+	}, tmpDir
+}
+
+func TestDraftPlan_NumbersTasksInANewSession(t *testing.T) {
+	c, tmpDir := newPlanningClient(t, []string{
+		`{"tasks":[` +
+			`{"title":"Add the CSV writer","description":"Write rows as CSV.","covers":["REQ-01"],"depends_on":[]},` +
+			`{"title":"Add the export button","description":"Download the CSV.","covers":["ac1","REQ-01"],"depends_on":[1,1]}]}`,
+	})
+
+	plan, err := c.DraftPlan(plannedSpec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := ai.Plan{Tasks: []ai.Task{
+		{ID: "TASK-01", Title: "Add the CSV writer", Description: "Write rows as CSV.", Covers: []string{"REQ-01"}},
+		{ID: "TASK-02", Title: "Add the export button", Description: "Download the CSV.", Covers: []string{"AC-01", "REQ-01"}, DependsOn: []string{"TASK-01"}},
+	}}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("unexpected plan:\n%#v", plan)
+	}
+	if c.sessionID == "spec-session" {
+		t.Error("the plan should be drafted in a new session")
+	}
+	if c.sessionState != sessionStateWaitPlanFeedback {
+		t.Errorf("expected to wait for plan feedback, got state %v", c.sessionState)
+	}
+
+	userPrompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read the planning prompt: %v", err)
+	}
+	if !strings.Contains(string(userPrompt), "REQ-01: Reports can be exported as CSV.") {
+		t.Errorf("the planning prompt should contain the spec:\n%s", userPrompt)
+	}
+	systemPrompt, err := os.ReadFile(filepath.Join(tmpDir, "system_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read the system prompt: %v", err)
+	}
+	if !strings.Contains(string(systemPrompt), "Rules of a Plan") {
+		t.Errorf("expected the planner system prompt:\n%s", systemPrompt)
+	}
+}
+
+func TestDraftPlan_PlanBreakingTheRulesIsRepaired(t *testing.T) {
+	c, tmpDir := newPlanningClient(t, []string{
+		`{"tasks":[{"title":"Export","description":"d","covers":["REQ-02"],"depends_on":[1]}]}`,
+		`{"tasks":[{"title":"Export","description":"d","covers":["REQ-01","AC-01"],"depends_on":[]}]}`,
+	})
+
+	plan, err := c.DraftPlan(plannedSpec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Tasks) != 1 || len(plan.Tasks[0].Covers) != 2 {
+		t.Errorf("expected the repaired plan, got %#v", plan)
+	}
+
+	repairPrompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_2.txt"))
+	if err != nil {
+		t.Fatalf("failed to read the repair prompt: %v", err)
+	}
+	for _, want := range []string{
+		"Task 1 covers REQ-02, which is not an ID of the spec.",
+		"Task 1 depends on task 1, which is not listed before it.",
+		"No task covers REQ-01.",
+		"No task covers AC-01.",
+	} {
+		if !strings.Contains(string(repairPrompt), want) {
+			t.Errorf("the repair prompt should contain %q:\n%s", want, repairPrompt)
+		}
+	}
+}
+
+func TestDraftPlan_RepairAttemptsAreLimited(t *testing.T) {
+	c, _ := newPlanningClient(t, []string{
+		`{"tasks":[{"title":"Export","description":"d","covers":[],"depends_on":[]}]}`,
+	})
+
+	if _, err := c.DraftPlan(plannedSpec); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("expected ErrInvalidPlan, got %v", err)
+	}
+	if c.sessionState != sessionStateSpecApproved {
+		t.Errorf("the state should not change on failure, got %v", c.sessionState)
+	}
+}
+
+func TestPlanRevisionLoop(t *testing.T) {
+	c, tmpDir := newPlanningClient(t, []string{
+		`{"tasks":[{"title":"Export","description":"d","covers":["REQ-01","AC-01"],"depends_on":[]}]}`,
+		`{"tasks":[{"title":"Export as CSV","description":"d","covers":["REQ-01","AC-01"],"depends_on":[]}]}`,
+	})
+
+	if err := c.ApprovePlan(); err == nil {
+		t.Error("a plan cannot be approved before it is drafted")
+	}
+	if _, err := c.DraftPlan(plannedSpec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sessionID := c.sessionID
+	plan, err := c.RevisePlan("Name the format in the title.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Tasks[0].Title != "Export as CSV" {
+		t.Errorf("expected the revised plan, got %#v", plan)
+	}
+	if c.sessionID != sessionID {
+		t.Error("the revision should resume the planning session")
+	}
+	revisionPrompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_2.txt"))
+	if err != nil {
+		t.Fatalf("failed to read the revision prompt: %v", err)
+	}
+	if !strings.Contains(string(revisionPrompt), "Name the format in the title.") {
+		t.Errorf("the revision prompt should contain the feedback:\n%s", revisionPrompt)
+	}
+
+	if err := c.ApprovePlan(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.RevisePlan("More."); err == nil {
+		t.Error("an approved plan cannot be revised")
+	}
+}
@@ -0,0 +1,136 @@
+package claudecode
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+var (
+	ErrSandboxUnsupported = errors.New("sandboxing is not supported on this machine")
+	ErrSandboxCheckFailed = errors.New("sandbox check failed")
+	ErrSandboxNeedsAPIKey = errors.New("the sandbox requires an API key")
+)
+
+// bwrapBinary is bubblewrap, which builds the sandbox from unprivileged user
+// namespaces.
+const bwrapBinary = "bwrap"
+
+// sandbox wraps the CLI command so that the agent can only write to the
+// workspace, a scratch directory, and a configuration directory of the CLI
+// for the session. Everything else is mounted read-only.
+//
+// The network is not cut off. The CLI itself must reach the Anthropic API and
+// Bear's MCP server on the loopback interface, and a new network namespace
+// would leave it neither, so cutting the agent off would take a proxy for both
+// inside the sandbox.
+type sandbox struct {
+	bwrapPath string
+	// configDir replaces the CLI's configuration in the home directory, so
+	// that the agent cannot add hooks, settings or MCP servers that later
+	// run outside the sandbox. The sessions of the CLI are kept there, so
+	// it lives as long as the client. The login of the CLI is not there
+	// either, which is why the sandbox requires an API key.
+	configDir string
+}
+
+// EnableSandbox runs every later query of the client in a sandbox. It fails if
+// sandboxing does not work on this machine, so that the agent never runs
+// unconfined when the user asked for a sandbox.
+func (c *Client) EnableSandbox() error {
+	if c.apiKey == "" {
+		return fmt.Errorf("%w: the CLI's login in the home directory is not visible in the sandbox", ErrSandboxNeedsAPIKey)
+	}
+	bwrapPath, err := CheckSandbox()
+	if err != nil {
+		return err
+	}
+	configDir, err := os.MkdirTemp("", "bear-claude-config-*")
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox configuration directory: %w", err)
+	}
+
+	c.sandbox = &sandbox{
+		bwrapPath: bwrapPath,
+		configDir: configDir,
+	}
+	return nil
+}
+
+// Close removes the configuration directory of the sandbox, if the sandbox is
+// enabled. The CLI sessions kept there cannot be resumed afterwards.
+func (c *Client) Close() error {
+	if c.sandbox == nil {
+		return nil
+	}
+	if err := os.RemoveAll(c.sandbox.configDir); err != nil {
+		return fmt.Errorf("failed to remove sandbox configuration directory: %w", err)
+	}
+	c.sandbox = nil
+	return nil
+}
+
+// CheckSandbox reports whether sandboxing works on this machine by running a
+// trivial command in a sandbox, and returns the path of the bubblewrap binary.
+func CheckSandbox() (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("%w: requires Linux, running on %v", ErrSandboxUnsupported, runtime.GOOS)
+	}
+	bwrapPath, err := exec.LookPath(bwrapBinary)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v is not installed", ErrSandboxUnsupported, bwrapBinary)
+	}
+
+	output, err := exec.Command(bwrapPath, "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "true").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf(
+			"%w: %v (unprivileged user namespaces may be disabled): %s",
+			ErrSandboxCheckFailed, err, strings.TrimSpace(string(output)),
+		)
+	}
+	return bwrapPath, nil
+}
+
+// newScratchDir creates the directory that the sandboxed agent uses as its
+// temporary directory for a single query.
+func (s *sandbox) newScratchDir() (string, error) {
+	dir, err := os.MkdirTemp("", "bear-sandbox-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create sandbox scratch directory: %w", err)
+	}
+	return dir, nil
+}
+
+// wrap returns a command that runs cmd inside the sandbox. The returned
+// command keeps the environment, the working directory, and the standard
+// streams of cmd.
+func (s *sandbox) wrap(cmd *exec.Cmd, scratchDir string) *exec.Cmd {
+	args := []string{
+		"--die-with-parent",
+		"--unshare-pid",
+		"--unshare-ipc",
+		"--unshare-uts",
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--bind", cmd.Dir, cmd.Dir,
+		"--bind", scratchDir, scratchDir,
+		// The CLI must be able to update its sessions, for example to resume
+		// one, but not the configuration that runs unsandboxed.
+		"--bind", s.configDir, s.configDir,
+		"--setenv", "CLAUDE_CONFIG_DIR", s.configDir,
+		"--setenv", "TMPDIR", scratchDir,
+		"--chdir", cmd.Dir,
+		"--",
+	}
+	args = append(args, cmd.Args...)
+
+	wrapped := exec.Command(s.bwrapPath, args...)
+	wrapped.Dir = cmd.Dir
+	wrapped.Env = cmd.Env
+	wrapped.Stdin = cmd.Stdin
+	return wrapped
+}
@@ -0,0 +1,80 @@
+package claudecode
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSandbox_WrapMountsWorkspaceWritableAndSystemReadOnly(t *testing.T) {
+	s := &sandbox{bwrapPath: "/usr/bin/bwrap", configDir: "/tmp/bear-claude-config-1"}
+
+	cmd := exec.Command("/usr/local/bin/claude", "-p", "--model", model)
+	cmd.Dir = "/work/space"
+	cmd.Env = []string{"ANTHROPIC_API_KEY=key"}
+	wrapped := s.wrap(cmd, "/tmp/bear-sandbox-1")
+
+	if wrapped.Path != "/usr/bin/bwrap" {
+		t.Errorf("expected bwrap to run, got %q", wrapped.Path)
+	}
+	if wrapped.Dir != cmd.Dir || !slices.Equal(wrapped.Env, cmd.Env) {
+		t.Error("the wrapped command should keep the working directory and environment")
+	}
+
+	args := strings.Join(wrapped.Args, " ")
+	for _, want := range []string{
+		"--ro-bind / /",
+		"--bind /work/space /work/space",
+		"--bind /tmp/bear-sandbox-1 /tmp/bear-sandbox-1",
+		"--bind /tmp/bear-claude-config-1 /tmp/bear-claude-config-1",
+		"--setenv CLAUDE_CONFIG_DIR /tmp/bear-claude-config-1",
+		"--setenv TMPDIR /tmp/bear-sandbox-1",
+		"--chdir /work/space",
+		"-- /usr/local/bin/claude -p --model " + model,
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in args: %v", want, args)
+		}
+	}
+	// The CLI configuration in the home directory runs unsandboxed later, so
+	// the agent must not be able to write to it.
+	if strings.Contains(args, ".claude") {
+		t.Error("the CLI configuration in the home directory must not be bound")
+	}
+	if strings.Contains(args, "--unshare-net") {
+		t.Error("the CLI needs the network to reach the API")
+	}
+	// The workspace must be bound after the read-only root to take effect.
+	if strings.Index(args, "--ro-bind / /") > strings.Index(args, "--bind /work/space") {
+		t.Error("the workspace bind must come after the read-only root")
+	}
+}
+
+func TestEnableSandbox_RequiresAPIKey(t *testing.T) {
+	c := &Client{workingDir: t.TempDir()}
+
+	if err := c.EnableSandbox(); !errors.Is(err, ErrSandboxNeedsAPIKey) {
+		t.Errorf("expected ErrSandboxNeedsAPIKey, got %v", err)
+	}
+	if c.sandbox != nil {
+		t.Error("the sandbox must stay disabled")
+	}
+}
+
+func TestClient_CloseRemovesSandboxConfigDir(t *testing.T) {
+	configDir := t.TempDir()
+	c := &Client{sandbox: &sandbox{bwrapPath: "/usr/bin/bwrap", configDir: configDir}}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(configDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the configuration directory to be removed, got %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("closing twice should succeed, got %v", err)
+	}
+}
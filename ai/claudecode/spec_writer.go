@@ -0,0 +1,532 @@
+package claudecode
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kaptinlin/jsonschema"
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/spec"
+	"github.com/sds-lab-dev/bear-go/workspace"
+)
+
+// clarification is one round of clarifying questions and the user's answer.
+type clarification struct {
+	questions []ai.Question
+	answer    string
+	// focus is the topic that the user asked the round to be about. Used when
+	// the user asked for more questions.
+	focus string
+	// skipped tells that the user ended the clarification with this round,
+	// leaving some of its questions unanswered.
+	skipped bool
+	// attachments are the files attached to the answer of the last round,
+	// which the agent was not given by a clarification query.
+	attachments []ai.Attachment
+}
+
+// clarifyingQuestionsOutput is the structured output of the clarification
+// queries.
+type clarifyingQuestionsOutput struct {
+	Questions []struct {
+		Text     string   `json:"text" jsonschema:"required"`
+		Type     string   `json:"type" jsonschema:"required,enum=text single_choice multiple_choice yes_no"`
+		Options  []string `json:"options" jsonschema:"maxItems=8"`
+		Default  []string `json:"default"`
+		Coverage string   `json:"coverage" jsonschema:"required,enum=scope consumer success edge_cases errors"`
+		// Attachments are the IDs of the attachments that the question is
+		// about, if any.
+		Attachments []string `json:"attachments"`
+	} `json:"questions" jsonschema:"required,minItems=0,maxItems=5"`
+}
+
+func (c *Client) GetInitialClarifyingQuestions(
+	initialUserRequest string,
+) ([]ai.Question, error) {
+	if c.sessionState != sessionStateBegin {
+		return nil, fmt.Errorf("unexpected session state for GetInitialClarifyingQuestions: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("getting initial clarifying questions for user request: %v", initialUserRequest))
+	systemPrompt, err := c.clarificationSystemPrompt()
+	if err != nil {
+		return nil, err
+	}
+	userPrompt, err := c.prompts.Render(ai.PromptClarificationUserInitialRequest, map[string]string{
+		"Request":        initialUserRequest,
+		"ProjectContext": c.projectContext(),
+		"Sources":        formatRequestSources(c.requestSources),
+		"Attachments":    formatAttachments(c.pendingAttachments),
+	})
+	if err != nil {
+		return nil, err
+	}
+	output, err := query[clarifyingQuestionsOutput](c, systemPrompt, userPrompt)
+	if err != nil {
+		err = fmt.Errorf("failed to get initial clarifying questions: %w", err)
+		log.Error(err.Error())
+		return nil, err
+	}
+	log.Debug(fmt.Sprintf("received initial clarifying questions: %#v", output))
+
+	c.initialRequest = initialUserRequest
+	c.pendingAttachments = nil
+	questions := c.questions(output)
+	if len(questions) == 0 {
+		c.sessionState = sessionStateNoClarifyingQuestions
+	} else {
+		c.sessionState = sessionStateWaitUserAnswers
+		c.clarifications = append(c.clarifications, clarification{questions: questions})
+	}
+
+	return questions, nil
+}
+
+// questions converts the output of a clarification query into questions that
+// the user can always answer: a choice question without enough options becomes
+// a text question, defaults that are not among the options are dropped, and
+// so are references to attachments that do not exist.
+func (c *Client) questions(output clarifyingQuestionsOutput) []ai.Question {
+	questions := make([]ai.Question, 0, len(output.Questions))
+	for _, item := range output.Questions {
+		question := ai.Question{
+			Text:     strings.TrimSpace(item.Text),
+			Type:     ai.QuestionType(item.Type),
+			Coverage: ai.CoverageArea(item.Coverage),
+		}
+		for _, option := range item.Options {
+			if option = strings.TrimSpace(option); option != "" && !slices.Contains(question.Options, option) {
+				question.Options = append(question.Options, option)
+			}
+		}
+		switch question.Type {
+		case ai.QuestionTypeYesNo:
+			question.Options = []string{"Yes", "No"}
+		case ai.QuestionTypeSingleChoice, ai.QuestionTypeMultipleChoice:
+			if len(question.Options) < 2 {
+				question.Type = ai.QuestionTypeText
+				question.Options = nil
+			}
+		default:
+			question.Type = ai.QuestionTypeText
+			question.Options = nil
+		}
+		question.Default = questionDefault(question, item.Default)
+
+		for _, id := range item.Attachments {
+			known := slices.ContainsFunc(c.attachments, func(a ai.Attachment) bool { return a.ID == id })
+			if known && !slices.Contains(question.Attachments, id) {
+				question.Attachments = append(question.Attachments, id)
+			}
+		}
+		questions = append(questions, question)
+	}
+	return questions
+}
+
+// questionDefault returns the defaults that fit the question: a single text
+// for a text question, and options, spelled as in the question, for a choice
+// question.
+func questionDefault(question ai.Question, defaults []string) []string {
+	if question.Type == ai.QuestionTypeText {
+		for _, value := range defaults {
+			if value = strings.TrimSpace(value); value != "" {
+				return []string{value}
+			}
+		}
+		return nil
+	}
+
+	var chosen []string
+	for _, value := range defaults {
+		i := slices.IndexFunc(question.Options, func(option string) bool {
+			return strings.EqualFold(option, strings.TrimSpace(value))
+		})
+		if i >= 0 && !slices.Contains(chosen, question.Options[i]) {
+			chosen = append(chosen, question.Options[i])
+		}
+	}
+	if question.Type != ai.QuestionTypeMultipleChoice && len(chosen) > 1 {
+		chosen = chosen[:1]
+	}
+	return chosen
+}
+
+// formatAttachments renders the attachments for a clarification prompt. A
+// text attachment is given inline; the others are given by their path, for the
+// agent to read with its tools.
+func formatAttachments(attachments []ai.Attachment) string {
+	var b strings.Builder
+	for i, attachment := range attachments {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %v: %v (%v)\n\n", attachment.ID, attachment.Name, attachment.Kind)
+		switch attachment.Kind {
+		case ai.AttachmentKindText:
+			fmt.Fprintf(&b, "<<<\n%v\n>>>\n", attachment.Text)
+		case ai.AttachmentKindImage:
+			fmt.Fprintf(&b, "View the image by reading %v with the Read tool.\n", attachment.Path)
+		default:
+			fmt.Fprintf(&b, "Read or search the file at %v with the tools; it is too large or not text to include here.\n", attachment.Path)
+		}
+	}
+	return b.String()
+}
+
+// formatRequestSources renders the sources for the initial request prompt,
+// each under a heading that names it, with the text delimited like the
+// request itself.
+func formatRequestSources(sources []ai.RequestSource) string {
+	var b strings.Builder
+	for i, source := range sources {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %v (%v)\n\n<<<\n%v\n>>>\n", source.Name, source.Kind, source.Text)
+	}
+	return b.String()
+}
+
+// seoulTimezone is the timezone the system prompt gives the datetime in.
+// Asia/Seoul is UTC+9 all year, so a fixed zone avoids depending on the
+// timezone database of the host.
+var seoulTimezone = time.FixedZone("Asia/Seoul", 9*60*60)
+
+// clarificationSystemPrompt renders the system prompt with the current
+// datetime, which the agent cannot get from a command since it may run only
+// read-only ones.
+func (c *Client) clarificationSystemPrompt() (string, error) {
+	return c.prompts.Render(ai.PromptClarificationSystem, map[string]string{
+		"CurrentDatetime": time.Now().In(seoulTimezone).Format(time.RFC3339),
+	})
+}
+
+// projectContext summarizes the workspace so that the agent does not spend
+// tool calls, or questions, on what the workspace already tells.
+func (c *Client) projectContext() string {
+	context, err := workspace.AnalyzeProject(c.workingDir)
+	if err != nil {
+		// The agent can still explore the workspace with its tools.
+		log.Warning(fmt.Sprintf("failed to analyze project context: %v", err))
+		return "Not available; inspect the workspace with the available tools."
+	}
+	return context.Summary(workspace.MaxProjectContextBytes)
+}
+
+func (c *Client) GetNextClarifyingQuestions(userAnswer string) ([]ai.Question, error) {
+	if c.sessionState != sessionStateWaitUserAnswers {
+		return nil, fmt.Errorf("unexpected session state for GetNextClarifyingQuestions: %v", c.sessionState)
+	}
+
+	if c.maxClarificationRounds > 0 && c.agentRounds() >= c.maxClarificationRounds {
+		log.Info(fmt.Sprintf("reached the limit of %d clarification rounds", c.maxClarificationRounds))
+		c.endClarification(userAnswer, false)
+		if c.streamCallback != nil {
+			c.streamCallback(ai.StreamMessage{
+				Type: ai.StreamMessageTypeWarning,
+				Content: fmt.Sprintf(
+					"Reached the limit of %d clarification rounds, so the spec is drafted without asking more questions.",
+					c.maxClarificationRounds,
+				),
+			})
+		}
+		return nil, nil
+	}
+	return c.nextClarifyingQuestions(userAnswer, "")
+}
+
+// agentRounds is the number of clarification rounds that the agent started.
+// The rounds that the user asked for with AskMoreAbout do not count toward
+// the limit.
+func (c *Client) agentRounds() int {
+	rounds := 0
+	for _, round := range c.clarifications {
+		if round.focus == "" {
+			rounds++
+		}
+	}
+	return rounds
+}
+
+func (c *Client) AskMoreAbout(userAnswer, topic string) ([]ai.Question, error) {
+	if c.sessionState != sessionStateWaitUserAnswers {
+		return nil, fmt.Errorf("unexpected session state for AskMoreAbout: %v", c.sessionState)
+	}
+	return c.nextClarifyingQuestions(userAnswer, topic)
+}
+
+// nextClarifyingQuestions asks the agent for the next round of clarifying
+// questions, focused on the topic if it is not empty.
+func (c *Client) nextClarifyingQuestions(userAnswer, topic string) ([]ai.Question, error) {
+	log.Debug(fmt.Sprintf("getting next clarifying questions for user answer: %v (focus: %v)", userAnswer, topic))
+	systemPrompt, err := c.clarificationSystemPrompt()
+	if err != nil {
+		return nil, err
+	}
+	userPrompt, err := c.prompts.Render(ai.PromptClarificationUserAnswers, map[string]string{
+		"Answers":     userAnswer,
+		"Focus":       topic,
+		"Attachments": formatAttachments(c.pendingAttachments),
+	})
+	if err != nil {
+		return nil, err
+	}
+	output, err := query[clarifyingQuestionsOutput](c, systemPrompt, userPrompt)
+	if err != nil {
+		err = fmt.Errorf("failed to get next clarifying questions: %w", err)
+		log.Error(err.Error())
+		return nil, err
+	}
+	log.Debug(fmt.Sprintf("received next clarifying questions: %#v", output))
+
+	c.clarifications[len(c.clarifications)-1].answer = userAnswer
+	c.pendingAttachments = nil
+	questions := c.questions(output)
+	if len(questions) == 0 {
+		c.sessionState = sessionStateNoClarifyingQuestions
+	} else {
+		c.sessionState = sessionStateWaitUserAnswers
+		c.clarifications = append(c.clarifications, clarification{questions: questions, focus: topic})
+	}
+
+	return questions, nil
+}
+
+func (c *Client) SkipClarifyingQuestions(userAnswer string) error {
+	if c.sessionState != sessionStateWaitUserAnswers {
+		return fmt.Errorf("unexpected session state for SkipClarifyingQuestions: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("skipping clarifying questions with user answer: %v", userAnswer))
+	c.endClarification(userAnswer, true)
+	return nil
+}
+
+// endClarification records the answer to the last round without asking the
+// agent for more questions. The attachments of the answer go to the drafting
+// prompt with the Q&A instead.
+func (c *Client) endClarification(userAnswer string, skipped bool) {
+	round := &c.clarifications[len(c.clarifications)-1]
+	round.answer = userAnswer
+	round.skipped = skipped
+	round.attachments = c.pendingAttachments
+	c.pendingAttachments = nil
+	c.sessionState = sessionStateNoClarifyingQuestions
+}
+
+func (c *Client) DraftSpec() (string, error) {
+	if c.sessionState != sessionStateNoClarifyingQuestions {
+		return "", fmt.Errorf("unexpected session state for DraftSpec: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("drafting spec with template %v", c.specTemplate.Name))
+	userPrompt, err := c.prompts.Render(ai.PromptSpecDraft, map[string]string{
+		"SpecTemplate": c.specTemplate.Guidance(),
+		"Request":      c.initialRequest,
+		"QAHistory":    c.qaHistory(),
+	})
+	if err != nil {
+		return "", err
+	}
+	draft, err := c.querySpec(userPrompt)
+	if err != nil {
+		err = fmt.Errorf("failed to draft spec: %w", err)
+		log.Error(err.Error())
+		return "", err
+	}
+
+	c.sessionState = sessionStateWaitUserFeedback
+	return draft, nil
+}
+
+func (c *Client) ReviseSpec(userFeedback string) (string, error) {
+	if c.sessionState != sessionStateWaitUserFeedback {
+		return "", fmt.Errorf("unexpected session state for ReviseSpec: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("revising spec for user feedback: %v", userFeedback))
+	userPrompt, err := c.prompts.Render(ai.PromptSpecRevision, map[string]string{
+		"SpecTemplate": c.specTemplate.Guidance(),
+		"BaseDraft":    c.baseDraft,
+		"Feedback":     userFeedback,
+	})
+	if err != nil {
+		return "", err
+	}
+	draft, err := c.querySpec(userPrompt)
+	if err != nil {
+		err = fmt.Errorf("failed to revise spec: %w", err)
+		log.Error(err.Error())
+		return "", err
+	}
+
+	c.baseDraft = ""
+	return draft, nil
+}
+
+func (c *Client) RestoreSpecDraft(draft string) error {
+	if c.sessionState != sessionStateWaitUserFeedback {
+		return fmt.Errorf("unexpected session state for RestoreSpecDraft: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("restoring spec draft of %d bytes", len(draft)))
+	c.baseDraft = draft
+	return nil
+}
+
+func (c *Client) ApproveSpec(draft string) error {
+	if c.sessionState != sessionStateWaitUserFeedback {
+		return fmt.Errorf("unexpected session state for ApproveSpec: %v", c.sessionState)
+	}
+
+	log.Debug(fmt.Sprintf("approving spec draft of %d bytes", len(draft)))
+	c.baseDraft = ""
+	c.sessionState = sessionStateSpecApproved
+	return nil
+}
+
+func (c *Client) LintSpec(draft string) []spec.Finding {
+	findings := spec.Lint(c.specTemplate, draft)
+	if !c.specCritique {
+		return findings
+	}
+
+	critique, err := c.critiqueSpec(draft)
+	if err != nil {
+		// The deterministic findings are still worth showing.
+		log.Warning(fmt.Sprintf("failed to critique spec: %v", err))
+		if c.streamCallback != nil {
+			c.streamCallback(ai.StreamMessage{
+				Type:    ai.StreamMessageTypeWarning,
+				Content: fmt.Sprintf("The spec critique failed, so only the deterministic checks were run: %v", err),
+			})
+		}
+		return findings
+	}
+	return append(findings, critique...)
+}
+
+// critiqueSpec asks an agent to review the draft against the spec rules. The
+// review runs in a client of its own; see newCriticClient.
+func (c *Client) critiqueSpec(draft string) ([]spec.Finding, error) {
+	type outputSchema struct {
+		Findings []struct {
+			Section string `json:"section" jsonschema:"required"`
+			Message string `json:"message" jsonschema:"required"`
+		} `json:"findings" jsonschema:"required,maxItems=20"`
+	}
+	systemPrompt, err := c.prompts.Render(ai.PromptSpecCritiqueSystem, nil)
+	if err != nil {
+		return nil, err
+	}
+	userPrompt, err := c.prompts.Render(ai.PromptSpecCritique, map[string]string{
+		"SpecTemplate": c.specTemplate.Guidance(),
+		"Spec":         draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := query[outputSchema](c.newCriticClient(), systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]spec.Finding, 0, len(output.Findings))
+	for _, finding := range output.Findings {
+		findings = append(findings, spec.Finding{
+			Rule:     spec.RuleCritique,
+			Severity: spec.SeverityWarning,
+			Section:  finding.Section,
+			Message:  finding.Message,
+		})
+	}
+	return findings, nil
+}
+
+// newCriticClient returns a client for a spec critique, which shares only the
+// settings of the CLI with c. It runs in a new session, so that the review is
+// not biased by the conversation that wrote the draft and does not become a
+// part of it. Its stream is not shown, since the draft view is about the
+// draft, and it gets neither the artifact tools nor interactive tool
+// approval, since it only reads the workspace.
+func (c *Client) newCriticClient() *Client {
+	return &Client{
+		apiKey:           c.apiKey,
+		workingDir:       c.workingDir,
+		binaryPath:       c.binaryPath,
+		prompts:          c.prompts,
+		permissionPolicy: c.permissionPolicy,
+		toolApprover:     newToolApprover(),
+		specTemplate:     c.specTemplate,
+		sandbox:          c.sandbox,
+		sessionState:     sessionStateBegin,
+	}
+}
+
+// querySpec asks for a spec in the shape of the spec template and renders the
+// output as Markdown.
+func (c *Client) querySpec(userPrompt string) (string, error) {
+	schemaString, err := c.specTemplate.OutputSchema()
+	if err != nil {
+		return "", err
+	}
+	schema, err := jsonschema.NewCompiler().Compile([]byte(schemaString))
+	if err != nil {
+		return "", fmt.Errorf("failed to compile output schema of spec template %v: %w", c.specTemplate.Name, err)
+	}
+	systemPrompt, err := c.clarificationSystemPrompt()
+	if err != nil {
+		return "", err
+	}
+
+	result, err := queryWithSchema(c, systemPrompt, userPrompt, schema, schemaString)
+	if err != nil {
+		return "", err
+	}
+	var output map[string]string
+	if err := json.Unmarshal(result, &output); err != nil {
+		return "", fmt.Errorf("failed to unmarshal spec output: %w", err)
+	}
+	return c.specTemplate.Render(output), nil
+}
+
+// qaHistory renders the clarifying questions and answers as Markdown.
+func (c *Client) qaHistory() string {
+	if len(c.clarifications) == 0 {
+		return "No clarifying questions were asked."
+	}
+	var b strings.Builder
+	for i, round := range c.clarifications {
+		fmt.Fprintf(&b, "## Round %d\n\n", i+1)
+		if round.focus != "" {
+			fmt.Fprintf(&b, "The user asked for more questions about: %v\n\n", round.focus)
+		}
+		b.WriteString("### Questions\n\n")
+		for j, question := range round.questions {
+			fmt.Fprintf(&b, "%d. %v", j+1, question.Text)
+			if len(question.Attachments) > 0 {
+				fmt.Fprintf(&b, " (about %v)", strings.Join(question.Attachments, ", "))
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "\n### Answer\n\n%v\n\n", strings.TrimSpace(round.answer))
+		for _, attachment := range round.attachments {
+			fmt.Fprintf(&b, "- Attached %v: %v (%v), at %v\n", attachment.ID, attachment.Name, attachment.Kind, attachment.Path)
+		}
+		if len(round.attachments) > 0 {
+			b.WriteString("\n")
+		}
+		if round.skipped {
+			b.WriteString("The user skipped the rest of the clarification to get the draft now. " +
+				"Record every question above that the answer does not settle under the assumptions, " +
+				"with the answer you assumed, or under the open questions.\n\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,568 @@
+package claudecode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+func TestDraftSpec_FollowsSpecTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":[{"text":"Which formats are supported?","type":"text","coverage":"scope"}]}`,
+		`{"questions":[]}`,
+		`{"title":"CSV export","summary":"Export reports as CSV.","acceptance_criteria":"- A report downloads as CSV."}`,
+	})
+
+	template := spec.Template{
+		Name:  "tiny",
+		Title: "Tiny",
+		Sections: []spec.Section{
+			{ID: "summary", Title: "Summary", Guidance: "One paragraph.", Required: true},
+			{ID: "notes", Title: "Notes"},
+			{ID: spec.AcceptanceCriteriaSectionID, Title: "Acceptance criteria", Required: true},
+		},
+	}
+	c := &Client{
+		apiKey:     "test-key",
+		workingDir: tmpDir,
+		binaryPath: scriptFile,
+		prompts:    ai.DefaultPrompts(tmpDir),
+	}
+	c.SetSpecTemplate(template)
+
+	if _, err := c.GetInitialClarifyingQuestions("Export reports"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetNextClarifyingQuestions("Only CSV"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	draft, err := c.DraftSpec()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# CSV export\n\n## Summary\n\nExport reports as CSV.\n\n## Acceptance criteria\n\n- A report downloads as CSV.\n"
+	if draft != want {
+		t.Errorf("unexpected draft:\n%v", draft)
+	}
+	if c.sessionState != sessionStateWaitUserFeedback {
+		t.Errorf("expected to wait for user feedback, got state %v", c.sessionState)
+	}
+
+	draftPrompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_3.txt"))
+	if err != nil {
+		t.Fatalf("failed to read drafting prompt: %v", err)
+	}
+	for _, want := range []string{"Export reports", "Which formats are supported?", "Only CSV", "## Summary (`summary`, required)", "One paragraph."} {
+		if !strings.Contains(string(draftPrompt), want) {
+			t.Errorf("drafting prompt should contain %q", want)
+		}
+	}
+}
+
+func TestDraftSpec_OutputMissingRequiredSectionIsRepaired(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"title":"Incomplete"}`,
+		`{"title":"Complete","overview":"o","scope":"s","requirements":"r","errors":"e","acceptance_criteria":"a","assumptions":"x","open_questions":"q"}`,
+	})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+		sessionState: sessionStateNoClarifyingQuestions,
+	}
+	draft, err := c.DraftSpec()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(draft, "# Complete\n") {
+		t.Errorf("expected the repaired draft, got:\n%v", draft)
+	}
+	if got := readInvocationCount(t, tmpDir); got != "2" {
+		t.Errorf("expected 2 invocations, got %v", got)
+	}
+}
+
+func TestReviseSpec_StartsFromRestoredDraft(t *testing.T) {
+	tmpDir := t.TempDir()
+	output := `{"title":"T","overview":"o","scope":"s","requirements":"r","errors":"e","acceptance_criteria":"a","assumptions":"x","open_questions":"q"}`
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{output, output})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+		sessionState: sessionStateWaitUserFeedback,
+	}
+	if err := c.RestoreSpecDraft("# Earlier draft"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.ReviseSpec("shorter"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.ReviseSpec("even shorter"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, err := os.ReadFile(filepath.Join(tmpDir, "stdin_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read revision prompt: %v", err)
+	}
+	if !strings.Contains(string(first), "# Draft To Revise") || !strings.Contains(string(first), "# Earlier draft") {
+		t.Errorf("revision prompt should contain the restored draft:\n%s", first)
+	}
+	second, err := os.ReadFile(filepath.Join(tmpDir, "stdin_2.txt"))
+	if err != nil {
+		t.Fatalf("failed to read revision prompt: %v", err)
+	}
+	if strings.Contains(string(second), "# Draft To Revise") {
+		t.Error("the restored draft should be used for one revision only")
+	}
+}
+
+func TestRestoreSpecDraft_RequiresDraft(t *testing.T) {
+	c := &Client{sessionState: sessionStateNoClarifyingQuestions}
+	if err := c.RestoreSpecDraft("# Draft"); err == nil {
+		t.Error("expected an error before the first draft")
+	}
+}
+
+func TestApproveSpec_EndsRevisionLoop(t *testing.T) {
+	c := &Client{sessionState: sessionStateWaitUserFeedback, baseDraft: "# Draft 1"}
+	if err := c.ApproveSpec("# Draft 1"); err != nil {
+		t.Fatalf("ApproveSpec() error = %v", err)
+	}
+	if c.sessionState != sessionStateSpecApproved {
+		t.Errorf("state = %v, want approved", c.sessionState)
+	}
+	if _, err := c.ReviseSpec("shorter"); err == nil {
+		t.Error("expected an error when revising an approved spec")
+	}
+	if err := c.ApproveSpec("# Draft 1"); err == nil {
+		t.Error("expected an error when approving twice")
+	}
+}
+
+func TestLintSpec_CritiqueRunsInNewSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"findings":[{"section":"overview","message":"Names an internal cache."}]}`,
+	})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+		sessionID:    "drafting-session",
+	}
+	c.EnableSpecCritique()
+
+	findings := c.LintSpec("# Empty spec\n")
+
+	last := findings[len(findings)-1]
+	if last.Rule != spec.RuleCritique || last.Section != "overview" || last.Message != "Names an internal cache." {
+		t.Errorf("expected the critique finding last, got %#v", last)
+	}
+	if findings[0].Rule != spec.RuleMissingSection {
+		t.Errorf("expected the deterministic findings first, got %#v", findings[0])
+	}
+	if c.sessionID != "drafting-session" {
+		t.Errorf("expected the drafting session to be kept, got %q", c.sessionID)
+	}
+	prompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read critique prompt: %v", err)
+	}
+	if !strings.Contains(string(prompt), "# Empty spec") {
+		t.Errorf("critique prompt should contain the draft, got %q", prompt)
+	}
+	systemPrompt, err := os.ReadFile(filepath.Join(tmpDir, "system_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read critique system prompt: %v", err)
+	}
+	if !strings.Contains(string(systemPrompt), "strict reviewer") || strings.Contains(string(systemPrompt), "Clarification Questions") {
+		t.Errorf("expected the critique system prompt, got %q", systemPrompt)
+	}
+}
+
+func TestNewCriticClient_SharesOnlyTheCLISettings(t *testing.T) {
+	c := &Client{
+		apiKey:                  "test-key",
+		workingDir:              "/work/project",
+		binaryPath:              "/usr/local/bin/claude",
+		prompts:                 ai.DefaultPrompts("/work/project"),
+		permissionPolicy:        specWriterPermissionPolicy(),
+		interactiveToolApproval: true,
+		toolApprover:            newToolApprover(),
+		artifactStore:           &fakeArtifactStore{},
+		specTemplate:            spec.DefaultTemplate(),
+		sessionID:               "drafting-session",
+		sessionState:            sessionStateWaitUserFeedback,
+		streamCallback:          func(ai.StreamMessage) {},
+		clarifications:          []clarification{{answer: "A"}},
+	}
+
+	critic := c.newCriticClient()
+
+	if critic.apiKey != c.apiKey || critic.binaryPath != c.binaryPath || critic.workingDir != c.workingDir {
+		t.Error("the critic should run the same CLI in the same workspace")
+	}
+	if critic.sessionID != "" || critic.streamCallback != nil || critic.clarifications != nil {
+		t.Errorf("the critic should start a session of its own, got %+v", critic)
+	}
+	if critic.artifactStore != nil || critic.interactiveToolApproval || critic.toolApprover == c.toolApprover {
+		t.Error("the critic should get neither the artifact tools nor the tool approval of the client")
+	}
+}
+
+func TestLintSpec_CritiqueFailureKeepsDeterministicFindings(t *testing.T) {
+	tmpDir := t.TempDir()
+	var warnings []ai.StreamMessage
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   filepath.Join(tmpDir, "missing-claude"),
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+		streamCallback: func(msg ai.StreamMessage) {
+			if msg.Type == ai.StreamMessageTypeWarning {
+				warnings = append(warnings, msg)
+			}
+		},
+	}
+	c.EnableSpecCritique()
+
+	findings := c.LintSpec("# Empty spec\n")
+
+	if len(findings) == 0 || findings[0].Rule != spec.RuleMissingSection {
+		t.Errorf("expected the deterministic findings, got %#v", findings)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 warning about the failed critique, got %d", len(warnings))
+	}
+}
+
+func TestGetInitialClarifyingQuestions_IncludesRequestSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{`{"questions":[]}`})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+	}
+	c.SetRequestSources([]ai.RequestSource{
+		{Kind: ai.RequestSourceKindIssue, Name: "issue-42.json", Text: "# #42: Export reports as CSV"},
+		{Kind: ai.RequestSourceKindSpec, Name: "spec:20260101-abc", Text: "# Report Export"},
+	})
+
+	if _, err := c.GetInitialClarifyingQuestions("Add Excel export too."); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read prompt: %v", err)
+	}
+	for _, expected := range []string{
+		"# Attached Sources",
+		"## issue-42.json (issue)\n\n<<<\n# #42: Export reports as CSV\n>>>",
+		"## spec:20260101-abc (spec)",
+	} {
+		if !strings.Contains(string(prompt), expected) {
+			t.Errorf("prompt should contain %q, got %q", expected, prompt)
+		}
+	}
+}
+
+func TestGetInitialClarifyingQuestions_OmitsSourcesSectionWithoutSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{`{"questions":[]}`})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+	}
+	if _, err := c.GetInitialClarifyingQuestions("Add Excel export."); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read prompt: %v", err)
+	}
+	if strings.Contains(string(prompt), "Attached Sources") {
+		t.Errorf("prompt should not have a sources section, got %q", prompt)
+	}
+}
+
+func TestClarifyingQuestions_ReferToAttachments(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":[{"text":"Should the chart in ATT-1 be exported too?","type":"yes_no","coverage":"scope","attachments":["ATT-1","ATT-9"]},{"text":"Which formats?","type":"text","coverage":"scope"}]}`,
+		`{"questions":[]}`,
+	})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+	}
+	c.AddAttachments([]ai.Attachment{
+		{ID: "ATT-1", Kind: ai.AttachmentKindImage, Name: "mockup.png", Path: "/work/.bear/attachments/ATT-1-mockup.png"},
+	})
+
+	questions, err := c.GetInitialClarifyingQuestions("Export reports")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ai.Question{
+		{Text: "Should the chart in ATT-1 be exported too?", Type: ai.QuestionTypeYesNo, Options: []string{"Yes", "No"}, Coverage: ai.CoverageScope, Attachments: []string{"ATT-1"}},
+		{Text: "Which formats?", Type: ai.QuestionTypeText, Coverage: ai.CoverageScope},
+	}
+	if !reflect.DeepEqual(questions, expected) {
+		t.Errorf("expected %#v, got %#v", expected, questions)
+	}
+
+	c.AddAttachments([]ai.Attachment{
+		{ID: "ATT-2", Kind: ai.AttachmentKindText, Name: "error.log", Path: "/work/.bear/attachments/ATT-2-error.log", Text: "panic: nil map"},
+	})
+	if _, err := c.GetNextClarifyingQuestions("Only CSV; see the log."); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, err := os.ReadFile(filepath.Join(tmpDir, "stdin_1.txt"))
+	if err != nil {
+		t.Fatalf("failed to read prompt: %v", err)
+	}
+	if !strings.Contains(string(first), "## ATT-1: mockup.png (image)\n\nView the image by reading /work/.bear/attachments/ATT-1-mockup.png") {
+		t.Errorf("initial prompt should reference the image, got %q", first)
+	}
+	second, err := os.ReadFile(filepath.Join(tmpDir, "stdin_2.txt"))
+	if err != nil {
+		t.Fatalf("failed to read prompt: %v", err)
+	}
+	if !strings.Contains(string(second), "## ATT-2: error.log (text)\n\n<<<\npanic: nil map\n>>>") {
+		t.Errorf("answers prompt should inline the text attachment, got %q", second)
+	}
+	if strings.Contains(string(second), "ATT-1: mockup.png") {
+		t.Error("answers prompt should not repeat the attachments given before")
+	}
+	if history := c.qaHistory(); !strings.Contains(history, "1. Should the chart in ATT-1 be exported too? (about ATT-1)") {
+		t.Errorf("Q&A history should keep the attachment references, got %q", history)
+	}
+}
+
+func TestClarifyingQuestions_AreTyped(t *testing.T) {
+	tmpDir := t.TempDir()
+	output := `{"questions":[` +
+		`{"text":"Which format?","type":"single_choice","options":["CSV"," Excel ","CSV",""],"default":["excel","CSV"],"coverage":"scope"},` +
+		`{"text":"Who exports?","type":"multiple_choice","options":["Admins","Analysts"],"default":["Analysts","Admins","Guests"],"coverage":"consumer"},` +
+		`{"text":"Which encoding?","type":"single_choice","options":["UTF-8"],"default":["UTF-8"],"coverage":"edge_cases"},` +
+		`{"text":"Keep empty rows?","type":"yes_no","options":["Sure"],"default":["no"],"coverage":"edge_cases"},` +
+		`{"text":"What happens on failure?","type":"text","options":["Retry"],"default":["", "Show an error"],"coverage":"errors"}` +
+		`]}`
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{output})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+	}
+	questions, err := c.GetInitialClarifyingQuestions("Export reports")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ai.Question{
+		{Text: "Which format?", Type: ai.QuestionTypeSingleChoice, Options: []string{"CSV", "Excel"}, Default: []string{"Excel"}, Coverage: ai.CoverageScope},
+		{Text: "Who exports?", Type: ai.QuestionTypeMultipleChoice, Options: []string{"Admins", "Analysts"}, Default: []string{"Analysts", "Admins"}, Coverage: ai.CoverageConsumer},
+		{Text: "Which encoding?", Type: ai.QuestionTypeText, Default: []string{"UTF-8"}, Coverage: ai.CoverageEdgeCases},
+		{Text: "Keep empty rows?", Type: ai.QuestionTypeYesNo, Options: []string{"Yes", "No"}, Default: []string{"No"}, Coverage: ai.CoverageEdgeCases},
+		{Text: "What happens on failure?", Type: ai.QuestionTypeText, Default: []string{"Show an error"}, Coverage: ai.CoverageErrors},
+	}
+	if !reflect.DeepEqual(questions, expected) {
+		t.Errorf("expected %#v, got %#v", expected, questions)
+	}
+}
+
+func TestSkipClarifyingQuestions_DraftRecordsAssumptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":[{"text":"Which formats?","type":"text","coverage":"scope"},{"text":"Who exports?","type":"text","coverage":"consumer"}]}`,
+		`{"title":"CSV export","overview":"Export reports as CSV."}`,
+	})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+	}
+	if _, err := c.GetInitialClarifyingQuestions("Export reports"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.AddAttachments([]ai.Attachment{
+		{ID: "ATT-1", Kind: ai.AttachmentKindImage, Name: "mockup.png", Path: "/work/.bear/attachments/ATT-1-mockup.png"},
+	})
+	if err := c.SkipClarifyingQuestions("1. Which formats?\nAnswer: CSV\n\n2. Who exports?\nAnswer: (not answered)"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.sessionState != sessionStateNoClarifyingQuestions {
+		t.Fatalf("expected the clarification to end, got state %v", c.sessionState)
+	}
+	if err := c.SkipClarifyingQuestions("again"); err == nil {
+		t.Error("expected an error for skipping after the clarification ended")
+	}
+
+	// The draft may fail the schema of the default template; only the
+	// prompt matters here.
+	_, _ = c.DraftSpec()
+	draftPrompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_2.txt"))
+	if err != nil {
+		t.Fatalf("failed to read drafting prompt: %v", err)
+	}
+	for _, want := range []string{
+		"Answer: (not answered)",
+		"- Attached ATT-1: mockup.png (image), at /work/.bear/attachments/ATT-1-mockup.png",
+		"The user skipped the rest of the clarification",
+	} {
+		if !strings.Contains(string(draftPrompt), want) {
+			t.Errorf("drafting prompt should contain %q", want)
+		}
+	}
+}
+
+func TestGetNextClarifyingQuestions_StopsAtRoundLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":[{"text":"Which formats?","type":"text","coverage":"scope"}]}`,
+		`{"questions":[{"text":"Which encoding?","type":"text","coverage":"edge_cases"}]}`,
+	})
+
+	var warnings []string
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+		streamCallback: func(msg ai.StreamMessage) {
+			if msg.Type == ai.StreamMessageTypeWarning {
+				warnings = append(warnings, msg.Content)
+			}
+		},
+	}
+	c.SetMaxClarificationRounds(2)
+
+	if _, err := c.GetInitialClarifyingQuestions("Export reports"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if questions, err := c.GetNextClarifyingQuestions("CSV"); err != nil || len(questions) != 1 {
+		t.Fatalf("expected the second round, got %v, %v", questions, err)
+	}
+	questions, err := c.GetNextClarifyingQuestions("UTF-8")
+	if err != nil || len(questions) != 0 {
+		t.Fatalf("expected no questions after the limit, got %v, %v", questions, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "stdin_3.txt")); err == nil {
+		t.Error("the agent should not be asked after the limit")
+	}
+	if c.sessionState != sessionStateNoClarifyingQuestions || c.clarifications[1].answer != "UTF-8" {
+		t.Errorf("the last answer should be kept for the draft, got state %v", c.sessionState)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "limit of 2 clarification rounds") {
+		t.Errorf("expected a warning about the limit, got %v", warnings)
+	}
+}
+
+func TestGetNextClarifyingQuestions_RequestedRoundsDoNotCountTowardLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":[{"text":"Which formats?","type":"text","coverage":"scope"}]}`,
+		`{"questions":[{"text":"What if the export times out?","type":"text","coverage":"errors"}]}`,
+		`{"questions":[{"text":"Which encoding?","type":"text","coverage":"edge_cases"}]}`,
+	})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+	}
+	c.SetMaxClarificationRounds(2)
+
+	if _, err := c.GetInitialClarifyingQuestions("Export reports"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.AskMoreAbout("CSV", "the error handling"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	questions, err := c.GetNextClarifyingQuestions("Retry once")
+	if err != nil || len(questions) != 1 {
+		t.Fatalf("expected the agent's second round, got %v, %v", questions, err)
+	}
+	if c.sessionState != sessionStateWaitUserAnswers {
+		t.Errorf("expected to wait for answers, got state %v", c.sessionState)
+	}
+}
+
+func TestAskMoreAbout_FocusesNextRound(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptFile := writeFakeClaudeScript(t, tmpDir, []string{
+		`{"questions":[{"text":"Which formats?","type":"text","coverage":"scope"}]}`,
+		`{"questions":[{"text":"What if the export times out?","type":"text","coverage":"errors"}]}`,
+	})
+
+	c := &Client{
+		apiKey:       "test-key",
+		workingDir:   tmpDir,
+		binaryPath:   scriptFile,
+		prompts:      ai.DefaultPrompts(tmpDir),
+		specTemplate: spec.DefaultTemplate(),
+	}
+	// The limit does not apply to the rounds that the user asks for.
+	c.SetMaxClarificationRounds(1)
+
+	if _, err := c.GetInitialClarifyingQuestions("Export reports"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	questions, err := c.AskMoreAbout("CSV", "the error handling")
+	if err != nil || len(questions) != 1 {
+		t.Fatalf("expected a follow-up round, got %v, %v", questions, err)
+	}
+
+	prompt, err := os.ReadFile(filepath.Join(tmpDir, "stdin_2.txt"))
+	if err != nil {
+		t.Fatalf("failed to read prompt: %v", err)
+	}
+	if !strings.Contains(string(prompt), "# Focus") || !strings.Contains(string(prompt), "<<<\nthe error handling\n>>>") {
+		t.Errorf("answers prompt should give the topic, got %q", prompt)
+	}
+	if history := c.qaHistory(); !strings.Contains(history, "## Round 2\n\nThe user asked for more questions about: the error handling") {
+		t.Errorf("Q&A history should record the topic, got %q", history)
+	}
+}
@@ -1,7 +1,6 @@
 package claudecode
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -9,6 +8,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 
@@ -22,10 +22,14 @@ const (
 	streamEventTypeAssistant streamEventType = "assistant"
 	streamEventTypeUser      streamEventType = "user"
 	streamEventTypeResult    streamEventType = "result"
+	streamEventTypeSystem    streamEventType = "system"
+	// Partial message events emitted when the CLI runs with
+	// `--include-partial-messages`.
+	streamEventTypeStreamEvent streamEventType = "stream_event"
 )
 
 type StreamMessage struct {
-	// Type can be "assistant", "user", or "result".
+	// Type can be "assistant", "user", "result", or "stream_event".
 	Type streamEventType `json:"type"`
 	// Used when Type is "result".
 	Subtype string `json:"subtype"`
@@ -37,17 +41,17 @@ type StreamMessage struct {
 	Message Message `json:"message"`
 	// Used when Type is "result".
 	StructuredOutput json.RawMessage `json:"structured_output"`
+	// Used when Type is "stream_event".
+	Event partialMessageEvent `json:"event"`
 	// Used when Claude Code CLI does not log in.
 	Error string `json:"error"`
-	// Internal field to hold the original JSON line for debugging purposes.
-	RawJSON string `json:"-"`
 }
 
-func (msg StreamMessage) toAiStreamMessage() ai.StreamMessage {
+func (msg StreamMessage) toAiStreamMessages() []ai.StreamMessage {
 	if msg.Type != streamEventTypeAssistant && msg.Type != streamEventTypeUser {
-		panic(fmt.Sprintf("unexpected StreamMessage type for toAiStreamMessage: %v", msg.Type))
+		panic(fmt.Sprintf("unexpected StreamMessage type for toAiStreamMessages: %v", msg.Type))
 	}
-	return msg.Message.toAiStreamMessage()
+	return msg.Message.toAiStreamMessages()
 }
 
 type Message struct {
@@ -59,45 +63,63 @@ type Message struct {
 	Content []ContentBlock `json:"content"`
 }
 
-func (msg Message) toAiStreamMessage() ai.StreamMessage {
+// toAiStreamMessages converts every content block of the message into its own
+// ai.StreamMessage, keeping the order of the blocks, so that a single assistant
+// turn with thinking, text, and several tool calls is reported in full.
+func (msg Message) toAiStreamMessages() []ai.StreamMessage {
 	if msg.Role != string(streamEventTypeUser) && msg.Role != string(streamEventTypeAssistant) {
 		log.Warning(fmt.Sprintf("unexpected stream message role: %v", msg.Role))
 		// Fallback
 		jsonBytes, err := json.Marshal(msg)
 		if err != nil {
 			log.Error(fmt.Sprintf("failed to marshal stream message: %v", err))
-			return ai.StreamMessage{}
+			return []ai.StreamMessage{{}}
 		}
-		return ai.StreamMessage{
+		return []ai.StreamMessage{{
 			Type:    ai.StreamMessageTypeText,
 			Content: string(jsonBytes),
-		}
+		}}
 	}
 
 	if len(msg.Content) == 0 {
 		log.Warning("stream message has no content blocks")
 		// Fallback
-		return ai.StreamMessage{
+		return []ai.StreamMessage{{
 			Type:    ai.StreamMessageTypeText,
 			Content: "empty stream message",
-		}
+		}}
 	}
 
-	// For simplicity, we only convert the first content block to an
-	// ai.StreamMessage.
-	content := msg.Content[0]
+	messages := make([]ai.StreamMessage, 0, len(msg.Content))
+	for _, content := range msg.Content {
+		messages = append(messages, content.toAiStreamMessage())
+	}
+	return messages
+}
+
+func (content ContentBlock) toAiStreamMessage() ai.StreamMessage {
 	// Special handling for StructuredOutput tool call.
 	if content.Type == "tool_use" && content.Name == "StructuredOutput" {
 		return ai.StreamMessage{
-			Type:    ai.StreamMessageTypeToolCallStructuredOutput,
-			Content: "",
+			Type:       ai.StreamMessageTypeToolCallStructuredOutput,
+			Content:    "",
+			ToolCallID: content.ID,
+			ToolName:   content.Name,
 		}
 	}
 
-	return ai.StreamMessage{
+	message := ai.StreamMessage{
 		Type:    content.StreamMessageType(),
 		Content: normalizeNewlines(content.StreamMessageContent()),
 	}
+	switch content.Type {
+	case "tool_use":
+		message.ToolCallID = content.ID
+		message.ToolName = content.Name
+	case "tool_result":
+		message.ToolCallID = content.ToolUseID
+	}
+	return message
 }
 
 func normalizeNewlines(s string) string {
@@ -114,9 +136,14 @@ type ContentBlock struct {
 	// Used when Type is "text".
 	Text string `json:"text"`
 	// Used when Type is "tool_use".
+	ID string `json:"id"`
+	// Used when Type is "tool_use".
 	Name string `json:"name"`
 	// Used when Type is "tool_use".
 	Input json.RawMessage `json:"input"`
+	// Used when Type is "tool_result". It refers to the ID of the "tool_use"
+	// block that this result answers.
+	ToolUseID string `json:"tool_use_id"`
 	// Used when Type is "tool_result". This field can be both string and array
 	// of JSON objects, so we use json.RawMessage.
 	Content json.RawMessage `json:"content"`
@@ -141,6 +168,17 @@ func (content ContentBlock) StreamMessageType() ai.StreamMessageType {
 }
 
 func convertJSONToYAML(raw []byte) (string, error) {
+	return convertJSONToYAMLWith(raw, func(v any) any { return v })
+}
+
+// convertToolResultToYAML is convertJSONToYAML that truncates every long string
+// of a tool result before the conversion, so that a large file read is not
+// converted in full only to be cut afterwards.
+func convertToolResultToYAML(raw []byte) (string, error) {
+	return convertJSONToYAMLWith(raw, truncateJSONStrings)
+}
+
+func convertJSONToYAMLWith(raw []byte, transform func(any) any) (string, error) {
 	if len(raw) == 0 {
 		return "", nil
 	}
@@ -153,7 +191,7 @@ func convertJSONToYAML(raw []byte) (string, error) {
 		return "", err
 	}
 
-	out, err := yaml.Marshal(v)
+	out, err := yaml.Marshal(transform(v))
 	if err != nil {
 		return "", err
 	}
@@ -161,6 +199,22 @@ func convertJSONToYAML(raw []byte) (string, error) {
 	return string(out), nil
 }
 
+func truncateJSONStrings(v any) any {
+	switch v := v.(type) {
+	case string:
+		return truncateToolResultContent(v)
+	case []any:
+		for i, elem := range v {
+			v[i] = truncateJSONStrings(elem)
+		}
+	case map[string]any:
+		for key, elem := range v {
+			v[key] = truncateJSONStrings(elem)
+		}
+	}
+	return v
+}
+
 // PrettyPrintQuotedEscaped takes a string that includes the surrounding quotes
 // (") and contains escape sequences like \n, \t, \".
 //
@@ -218,7 +272,7 @@ func (content ContentBlock) StreamMessageContent() string {
 		// readability, and if that fails, we pretty print the original content
 		// as a quoted and escaped string. If that also fails, use the original
 		// content as a fallback string.
-		result, err := convertJSONToYAML(content.Content)
+		result, err := convertToolResultToYAML(content.Content)
 		if err != nil {
 			log.Debug("failed to process tool_result content: failed to convert to YAML from JSON")
 			log.Debug("trying to pretty print tool_result content as quoted and escaped string...")
@@ -232,7 +286,7 @@ func (content ContentBlock) StreamMessageContent() string {
 			log.Warning("tool_result content block has empty content")
 			return "empty tool_result content"
 		}
-		return result
+		return truncateToolResultContent(result)
 	case "thinking":
 		if content.Thinking == "" {
 			log.Warning("thinking content block has empty thinking text")
@@ -250,30 +304,69 @@ func (content ContentBlock) StreamMessageContent() string {
 	}
 }
 
+// maxToolResultContentBytes limits the size of a tool result passed to the
+// stream callback. Tool results such as large file reads can be many megabytes,
+// which the UI neither needs nor can render. The head of the payload is still
+// written to the log as part of the raw stream line.
+const maxToolResultContentBytes = 16 << 10
+
+func truncateToolResultContent(content string) string {
+	if len(content) <= maxToolResultContentBytes {
+		return content
+	}
+
+	// Cut at a rune boundary so that the truncated content stays valid UTF-8.
+	cut := maxToolResultContentBytes
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%v\n... (%d bytes truncated)", content[:cut], len(content)-cut)
+}
+
 var (
 	ErrStreamParseFailed = errors.New("failed to parse stream JSON line")
 	ErrResultError       = errors.New("result returned an error")
 	ErrNoResultReceived  = errors.New("stream ended without a result message")
+	ErrStreamLineTooLong = errors.New("stream ended without a result message after skipping a line over the size limit")
 )
 
 func processStream(
 	reader io.Reader,
+	expectation sessionExpectation,
 	streamCallback func(ai.StreamMessage),
 ) (json.RawMessage, error) {
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+	toolCalls := toolCallRegistry{}
+	partialBlocks := partialBlockTracker{}
+	lineReader := newStreamLineReader(reader, maxStreamLineBytes)
+	// largestSkippedLine is the size of the largest line skipped for being
+	// over the limit, which may have been the result.
+	largestSkippedLine := 0
+	for {
+		rawLine, discarded, err := lineReader.ReadLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stream read error: %w", err)
+		}
+		if discarded > 0 {
+			log.Warning(fmt.Sprintf("skipping stream line of %d bytes, longer than the limit of %d bytes: %v", discarded, maxStreamLineBytes, truncateForLog(rawLine)))
+			largestSkippedLine = max(largestSkippedLine, discarded)
+			continue
+		}
+
+		// The line is parsed in place; it is the only copy of the event held
+		// besides the decoded message.
+		line := bytes.TrimSpace(rawLine)
+		if len(line) == 0 {
 			continue
 		}
-		log.Debug(fmt.Sprintf("received raw stream line: %v", line))
+		log.Debug(fmt.Sprintf("received raw stream line: %v", truncateForLog(line)))
 
 		var msg StreamMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			return nil, fmt.Errorf("%w: %s", ErrStreamParseFailed, line)
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStreamParseFailed, truncateForLog(line))
 		}
-		msg.RawJSON = line
-		log.Debug(fmt.Sprintf("parsed stream message: %#v", msg))
 
 		if len(msg.Error) > 0 {
 			if msg.Error == "authentication_failed" {
@@ -287,7 +380,31 @@ func processStream(
 			if streamCallback == nil {
 				continue
 			}
-			streamCallback(msg.toAiStreamMessage())
+			for _, aiMsg := range msg.toAiStreamMessages() {
+				streamCallback(toolCalls.link(aiMsg))
+			}
+		case streamEventTypeSystem:
+			// A system event that Bear cannot parse, such as one of a newer
+			// CLI, tells nothing about the query's result.
+			event, err := parseSystemEvent(line)
+			if err != nil {
+				log.Warning(fmt.Sprintf("skipping system event: %v", err))
+				continue
+			}
+			warnings := handleSystemEvent(event, expectation)
+			if streamCallback == nil {
+				continue
+			}
+			for _, warning := range warnings {
+				streamCallback(warning)
+			}
+		case streamEventTypeStreamEvent:
+			if streamCallback == nil {
+				continue
+			}
+			if aiMsg, ok := partialBlocks.toAiStreamMessage(msg.Event); ok {
+				streamCallback(aiMsg)
+			}
 		case streamEventTypeResult:
 			// IsError can be true even if Subtype is "success", so we check the
 			// Subtype and StructuredOutput to determine if this is a successful
@@ -296,12 +413,48 @@ func processStream(
 				return msg.StructuredOutput, nil
 			}
 			return nil, fmt.Errorf("%w: %v", ErrResultError, msg.Result)
+		default:
+			log.Debug(fmt.Sprintf("ignoring unrecognized stream event type: %v", msg.Type))
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("stream read error: %w", err)
+	if largestSkippedLine > 0 {
+		return nil, fmt.Errorf("%w: line of %d bytes, limit of %d bytes", ErrStreamLineTooLong, largestSkippedLine, maxStreamLineBytes)
 	}
-
 	return nil, ErrNoResultReceived
 }
+
+// maxLoggedStreamLineBytes limits how much of a stream line is written to the
+// log or an error. Lines carrying a large file read or tool output would
+// otherwise be formatted into the log in full.
+const maxLoggedStreamLineBytes = 64 << 10
+
+func truncateForLog(line []byte) string {
+	if len(line) <= maxLoggedStreamLineBytes {
+		return string(line)
+	}
+	return fmt.Sprintf("%s... (%d bytes truncated)", line[:maxLoggedStreamLineBytes], len(line)-maxLoggedStreamLineBytes)
+}
+
+// toolCallRegistry remembers the tool name of every tool call observed in the
+// stream, so that the matching tool result, which carries only the tool call
+// ID, can be reported together with the name of the tool that produced it.
+type toolCallRegistry map[string]string
+
+func (r toolCallRegistry) link(msg ai.StreamMessage) ai.StreamMessage {
+	if msg.ToolCallID == "" {
+		return msg
+	}
+
+	switch msg.Type {
+	case ai.StreamMessageTypeToolCall, ai.StreamMessageTypeToolCallStructuredOutput:
+		r[msg.ToolCallID] = msg.ToolName
+	case ai.StreamMessageTypeToolCallResult:
+		name, ok := r[msg.ToolCallID]
+		if !ok {
+			log.Warning(fmt.Sprintf("tool_result refers to an unknown tool_use ID: %v", msg.ToolCallID))
+		}
+		msg.ToolName = name
+	}
+	return msg
+}
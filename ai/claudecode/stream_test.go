@@ -3,8 +3,10 @@ package claudecode
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/sds-lab-dev/bear-go/ai"
 )
@@ -18,7 +20,7 @@ func TestProcessStream_AssistantMessageCallsCallback(t *testing.T) {
 		called = true
 	}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -37,7 +39,7 @@ func TestProcessStream_UserMessageCallsCallback(t *testing.T) {
 		called = true
 	}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -54,7 +56,7 @@ func TestProcessStream_ResultMessageNotPassedToCallback(t *testing.T) {
 		callCount++
 	}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -72,7 +74,7 @@ func TestProcessStream_SystemMessageIgnored(t *testing.T) {
 		callCount++
 	}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -90,7 +92,7 @@ func TestProcessStream_StreamEventIgnored(t *testing.T) {
 		callCount++
 	}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -104,7 +106,7 @@ func TestProcessStream_SuccessResultReturnsStructuredOutput(t *testing.T) {
 `
 	callback := func(msg ai.StreamMessage) {}
 
-	result, err := processStream(strings.NewReader(input), callback)
+	result, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -128,7 +130,7 @@ func TestProcessStream_ErrorResultReturnsError(t *testing.T) {
 `
 	callback := func(msg ai.StreamMessage) {}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if !errors.Is(err, ErrResultError) {
 		t.Fatalf("expected ErrResultError, got: %v", err)
 	}
@@ -139,7 +141,7 @@ func TestProcessStream_InvalidJSONReturnsError(t *testing.T) {
 `
 	callback := func(msg ai.StreamMessage) {}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if !errors.Is(err, ErrStreamParseFailed) {
 		t.Fatalf("expected ErrStreamParseFailed, got: %v", err)
 	}
@@ -150,12 +152,26 @@ func TestProcessStream_NoResultReturnsError(t *testing.T) {
 `
 	callback := func(msg ai.StreamMessage) {}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if !errors.Is(err, ErrNoResultReceived) {
 		t.Fatalf("expected ErrNoResultReceived, got: %v", err)
 	}
 }
 
+func TestProcessStream_SkippedLongLineReturnsError(t *testing.T) {
+	longResult := `{"type":"result","subtype":"success","result":"` + strings.Repeat("x", maxStreamLineBytes) + `"}`
+	input := `{"type":"assistant","content":[{"type":"text","text":"thinking..."}]}
+` + longResult + "\n"
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, nil)
+	if !errors.Is(err, ErrStreamLineTooLong) {
+		t.Fatalf("expected ErrStreamLineTooLong, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("line of %d bytes", len(longResult))) {
+		t.Errorf("expected the size of the skipped line, got: %v", err)
+	}
+}
+
 func TestProcessStream_EmptyLinesSkipped(t *testing.T) {
 	input := `
 {"type":"assistant","content":[{"type":"text","text":"hello"}]}
@@ -168,7 +184,7 @@ func TestProcessStream_EmptyLinesSkipped(t *testing.T) {
 		callCount++
 	}
 
-	_, err := processStream(strings.NewReader(input), callback)
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -176,3 +192,154 @@ func TestProcessStream_EmptyLinesSkipped(t *testing.T) {
 		t.Errorf("expected callback called once for assistant message, called %d times", callCount)
 	}
 }
+
+func TestProcessStream_AllContentBlocksConvertedInOrder(t *testing.T) {
+	input := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"thinking","thinking":"let me look"},{"type":"text","text":"reading files"},{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"a.go"}},{"type":"tool_use","id":"toolu_2","name":"Grep","input":{"pattern":"foo"}}]}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	var received []ai.StreamMessage
+	callback := func(msg ai.StreamMessage) {
+		received = append(received, msg)
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedTypes := []ai.StreamMessageType{
+		ai.StreamMessageTypeThinking,
+		ai.StreamMessageTypeText,
+		ai.StreamMessageTypeToolCall,
+		ai.StreamMessageTypeToolCall,
+	}
+	if len(received) != len(expectedTypes) {
+		t.Fatalf("expected %d messages, got %d: %#v", len(expectedTypes), len(received), received)
+	}
+	for i, want := range expectedTypes {
+		if received[i].Type != want {
+			t.Errorf("message %d: expected type %v, got %v", i, want, received[i].Type)
+		}
+	}
+	if received[2].ToolCallID != "toolu_1" || received[2].ToolName != "Read" {
+		t.Errorf("unexpected first tool call identity: %#v", received[2])
+	}
+	if received[3].ToolCallID != "toolu_2" || received[3].ToolName != "Grep" {
+		t.Errorf("unexpected second tool call identity: %#v", received[3])
+	}
+}
+
+func TestProcessStream_ToolResultLinkedToToolUse(t *testing.T) {
+	input := `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{}},{"type":"tool_use","id":"toolu_2","name":"Glob","input":{}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"b.go"},{"type":"tool_result","tool_use_id":"toolu_1","content":"package a"}]}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	var results []ai.StreamMessage
+	callback := func(msg ai.StreamMessage) {
+		if msg.Type == ai.StreamMessageTypeToolCallResult {
+			results = append(results, msg)
+		}
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 tool results, got %d", len(results))
+	}
+	if results[0].ToolCallID != "toolu_2" || results[0].ToolName != "Glob" {
+		t.Errorf("first result should be linked to Glob call, got %#v", results[0])
+	}
+	if results[1].ToolCallID != "toolu_1" || results[1].ToolName != "Read" {
+		t.Errorf("second result should be linked to Read call, got %#v", results[1])
+	}
+}
+
+func TestProcessStream_UnknownToolResultHasEmptyToolName(t *testing.T) {
+	input := `{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_9","content":"orphan"}]}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	var received []ai.StreamMessage
+	callback := func(msg ai.StreamMessage) {
+		received = append(received, msg)
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(received))
+	}
+	if received[0].ToolCallID != "toolu_9" {
+		t.Errorf("expected tool call ID toolu_9, got %q", received[0].ToolCallID)
+	}
+	if received[0].ToolName != "" {
+		t.Errorf("expected empty tool name, got %q", received[0].ToolName)
+	}
+}
+
+func TestProcessStream_LargeToolResultTruncatedForCallback(t *testing.T) {
+	payload := strings.Repeat("a", 1<<20)
+	input := `{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"` + payload + `"}]}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	var received []ai.StreamMessage
+	callback := func(msg ai.StreamMessage) {
+		received = append(received, msg)
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(received))
+	}
+	if len(received[0].Content) > maxToolResultContentBytes+100 {
+		t.Errorf("expected truncated content, got %d bytes", len(received[0].Content))
+	}
+	if !strings.Contains(received[0].Content, "bytes truncated") {
+		t.Error("expected truncation indicator in content")
+	}
+}
+
+func TestTruncateToolResultContent_KeepsValidUTF8(t *testing.T) {
+	content := strings.Repeat("한", maxToolResultContentBytes)
+
+	result := truncateToolResultContent(content)
+
+	if !utf8.ValidString(result) {
+		t.Error("truncated content should be valid UTF-8")
+	}
+}
+
+func TestConvertToolResultToYAML_TruncatesStringsBeforeConversion(t *testing.T) {
+	payload := strings.Repeat("a", 1<<20)
+	raw := []byte(`[{"type":"text","text":"` + payload + `"}]`)
+
+	result, err := convertToolResultToYAML(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) > maxToolResultContentBytes+100 {
+		t.Errorf("expected truncated content, got %d bytes", len(result))
+	}
+	if !strings.Contains(result, "bytes truncated") {
+		t.Error("expected truncation indicator in content")
+	}
+}
+
+func TestTruncateForLog_LimitsLongLines(t *testing.T) {
+	line := []byte(strings.Repeat("x", maxLoggedStreamLineBytes+10))
+
+	logged := truncateForLog(line)
+
+	if !strings.HasSuffix(logged, "... (10 bytes truncated)") {
+		t.Errorf("expected truncation indicator, got suffix %q", logged[len(logged)-30:])
+	}
+}
@@ -0,0 +1,172 @@
+package claudecode
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+// sessionExpectation describes the session that buildCommand asked the CLI
+// for. It is checked against the "system" init event to detect settings that
+// the CLI did not apply, for example a requested tool that is not available.
+// Empty fields are not checked.
+type sessionExpectation struct {
+	Model          string
+	Tools          []string
+	PermissionMode string
+}
+
+type systemEventSubtype string
+
+const (
+	systemEventSubtypeInit            systemEventSubtype = "init"
+	systemEventSubtypeCompactBoundary systemEventSubtype = "compact_boundary"
+	systemEventSubtypeHookStarted     systemEventSubtype = "hook_started"
+	systemEventSubtypeHookResponse    systemEventSubtype = "hook_response"
+)
+
+// systemEvent is a "system" line of the stream. Only the fields of the
+// subtypes that Bear cares about are declared.
+type systemEvent struct {
+	Subtype systemEventSubtype `json:"subtype"`
+
+	// Used when Subtype is "init".
+	SessionID string `json:"session_id"`
+	// Used when Subtype is "init".
+	Model string `json:"model"`
+	// Used when Subtype is "init".
+	Tools []string `json:"tools"`
+	// Used when Subtype is "init".
+	MCPServers []mcpServerStatus `json:"mcp_servers"`
+	// Used when Subtype is "init".
+	PermissionMode string `json:"permissionMode"`
+
+	// Used when Subtype is "compact_boundary".
+	CompactMetadata compactMetadata `json:"compact_metadata"`
+
+	// Used when Subtype is "hook_started" or "hook_response".
+	HookName string `json:"hook_name"`
+	// Used when Subtype is "hook_started" or "hook_response".
+	HookEvent string `json:"hook_event"`
+	// Used when Subtype is "hook_response".
+	ExitCode int `json:"exit_code"`
+	// Used when Subtype is "hook_response".
+	Stderr string `json:"stderr"`
+}
+
+type mcpServerStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type compactMetadata struct {
+	// Trigger can be "manual" or "auto".
+	Trigger   string `json:"trigger"`
+	PreTokens int    `json:"pre_tokens"`
+}
+
+func parseSystemEvent(line []byte) (systemEvent, error) {
+	var event systemEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return systemEvent{}, fmt.Errorf("%w: %v", ErrStreamParseFailed, truncateForLog(line))
+	}
+	return event, nil
+}
+
+// handleSystemEvent logs the system event and returns the warnings that should
+// be surfaced to the user.
+func handleSystemEvent(event systemEvent, expectation sessionExpectation) []ai.StreamMessage {
+	switch event.Subtype {
+	case systemEventSubtypeInit:
+		log.Info(fmt.Sprintf(
+			"claude session initialized: sessionID=%v, model=%v, permissionMode=%v, tools=%v, mcpServers=%v",
+			event.SessionID, event.Model, event.PermissionMode, event.Tools, event.MCPServers,
+		))
+		return toWarningStreamMessages(checkSessionExpectation(event, expectation))
+	case systemEventSubtypeCompactBoundary:
+		log.Info(fmt.Sprintf(
+			"claude session context compacted: trigger=%v, preTokens=%v",
+			event.CompactMetadata.Trigger, event.CompactMetadata.PreTokens,
+		))
+		return nil
+	case systemEventSubtypeHookStarted:
+		log.Info(fmt.Sprintf("claude hook started: name=%v, event=%v", event.HookName, event.HookEvent))
+		return nil
+	case systemEventSubtypeHookResponse:
+		log.Info(fmt.Sprintf(
+			"claude hook finished: name=%v, event=%v, exitCode=%v, stderr=%v",
+			event.HookName, event.HookEvent, event.ExitCode, event.Stderr,
+		))
+		if event.ExitCode != 0 {
+			return toWarningStreamMessages([]string{
+				fmt.Sprintf("Hook %v failed with exit code %v: %v", event.HookName, event.ExitCode, event.Stderr),
+			})
+		}
+		return nil
+	default:
+		log.Debug(fmt.Sprintf("ignoring unrecognized system event subtype: %v", event.Subtype))
+		return nil
+	}
+}
+
+// checkSessionExpectation compares the effective session settings reported by
+// the init event with the settings requested by buildCommand, and returns a
+// human readable warning for each mismatch.
+func checkSessionExpectation(event systemEvent, expectation sessionExpectation) []string {
+	var warnings []string
+
+	if expectation.Model != "" && event.Model != expectation.Model {
+		warnings = append(warnings, fmt.Sprintf(
+			"The session runs on model %q instead of the requested model %q.",
+			event.Model, expectation.Model,
+		))
+	}
+
+	if expectation.PermissionMode != "" && event.PermissionMode != expectation.PermissionMode {
+		warnings = append(warnings, fmt.Sprintf(
+			"The session runs in permission mode %q instead of the requested mode %q.",
+			event.PermissionMode, expectation.PermissionMode,
+		))
+	}
+
+	var missingTools []string
+	for _, tool := range expectation.Tools {
+		if !slices.Contains(event.Tools, tool) {
+			missingTools = append(missingTools, tool)
+		}
+	}
+	if len(missingTools) > 0 {
+		warnings = append(warnings, fmt.Sprintf(
+			"The following requested tools are not available in the session: %v.",
+			strings.Join(missingTools, ", "),
+		))
+	}
+
+	for _, server := range event.MCPServers {
+		if server.Status != "connected" {
+			warnings = append(warnings, fmt.Sprintf(
+				"MCP server %q is not connected (status: %v).", server.Name, server.Status,
+			))
+		}
+	}
+
+	for _, warning := range warnings {
+		log.Warning(warning)
+	}
+	return warnings
+}
+
+func toWarningStreamMessages(warnings []string) []ai.StreamMessage {
+	messages := make([]ai.StreamMessage, 0, len(warnings))
+	for _, warning := range warnings {
+		messages = append(messages, ai.StreamMessage{
+			Type:    ai.StreamMessageTypeWarning,
+			Content: warning,
+		})
+	}
+	return messages
+}
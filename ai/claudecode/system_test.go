@@ -0,0 +1,125 @@
+package claudecode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+func TestProcessStream_SystemInitMatchingExpectationHasNoWarnings(t *testing.T) {
+	input := `{"type":"system","subtype":"init","session_id":"s1","model":"claude-opus-4-6","permissionMode":"bypassPermissions","tools":["Read","Grep","StructuredOutput"],"mcp_servers":[]}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	expectation := sessionExpectation{
+		Model:          "claude-opus-4-6",
+		Tools:          []string{"Read", "Grep"},
+		PermissionMode: "bypassPermissions",
+	}
+	callCount := 0
+	callback := func(msg ai.StreamMessage) {
+		callCount++
+	}
+
+	_, err := processStream(strings.NewReader(input), expectation, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if callCount != 0 {
+		t.Errorf("expected no warnings, callback called %d times", callCount)
+	}
+}
+
+func TestProcessStream_SystemInitMismatchSurfacesWarnings(t *testing.T) {
+	input := `{"type":"system","subtype":"init","model":"claude-sonnet-4-5","permissionMode":"default","tools":["Read"],"mcp_servers":[{"name":"bear","status":"failed"}]}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	expectation := sessionExpectation{
+		Model:          "claude-opus-4-6",
+		Tools:          []string{"Read", "WebSearch"},
+		PermissionMode: "bypassPermissions",
+	}
+	var warnings []string
+	callback := func(msg ai.StreamMessage) {
+		if msg.Type != ai.StreamMessageTypeWarning {
+			t.Errorf("expected warning message, got type %v", msg.Type)
+		}
+		warnings = append(warnings, msg.Content)
+	}
+
+	_, err := processStream(strings.NewReader(input), expectation, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	joined := strings.Join(warnings, "\n")
+	for _, want := range []string{"claude-sonnet-4-5", "default", "WebSearch", "bear"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected a warning mentioning %q, got %q", want, joined)
+		}
+	}
+	if strings.Contains(joined, "Read") {
+		t.Errorf("available tool should not be reported as missing, got %q", joined)
+	}
+}
+
+func TestProcessStream_FailedHookSurfacesWarning(t *testing.T) {
+	input := `{"type":"system","subtype":"hook_response","hook_name":"SessionStart:startup","hook_event":"SessionStart","exit_code":2,"stderr":"boom"}
+{"type":"system","subtype":"compact_boundary","compact_metadata":{"trigger":"auto","pre_tokens":1000}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	var warnings []string
+	callback := func(msg ai.StreamMessage) {
+		warnings = append(warnings, msg.Content)
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[0], "boom") {
+		t.Errorf("expected warning to contain hook stderr, got %q", warnings[0])
+	}
+}
+
+func TestProcessStream_UnparsableSystemEventSkipped(t *testing.T) {
+	input := `{"type":"system","subtype":"status","tools":"not a list"}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	callCount := 0
+	callback := func(msg ai.StreamMessage) {
+		callCount++
+	}
+
+	output, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(output) != `{"questions":[]}` {
+		t.Errorf("expected the result after the skipped event, got %s", output)
+	}
+	if callCount != 0 {
+		t.Errorf("expected no callback for the skipped event, got %d", callCount)
+	}
+}
+
+func TestProcessStream_UnrecognizedEventTypeIgnored(t *testing.T) {
+	input := `{"type":"rate_limit_event","info":{}}
+{"type":"result","subtype":"success","structured_output":{"questions":[]}}
+`
+	callCount := 0
+	callback := func(msg ai.StreamMessage) {
+		callCount++
+	}
+
+	_, err := processStream(strings.NewReader(input), sessionExpectation{}, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if callCount != 0 {
+		t.Errorf("callback should not be called for unrecognized events, called %d times", callCount)
+	}
+}
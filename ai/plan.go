@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan breaks an approved spec down into tasks that coding agents work on one
+// at a time, each on a branch of its own.
+type Plan struct {
+	Tasks []Task
+}
+
+// Task is a unit of work of the plan that one coding agent can finish and
+// commit on its own.
+type Task struct {
+	// ID is "TASK-01", "TASK-02" and so on, in the order of the plan.
+	ID          string
+	Title       string
+	Description string
+	// Covers are the IDs of the requirements and acceptance criteria of the
+	// spec that the task implements, such as "REQ-01" and "AC-02". The
+	// coding agent refers to them in its commits and test names.
+	Covers []string
+	// DependsOn are the IDs of the earlier tasks whose work the task builds
+	// on, which are merged before it starts.
+	DependsOn []string
+}
+
+// Task returns the task with the ID.
+func (p Plan) Task(id string) (Task, bool) {
+	for _, task := range p.Tasks {
+		if task.ID == id {
+			return task, true
+		}
+	}
+	return Task{}, false
+}
+
+// Markdown renders the plan with a "## TASK-01: Title" section for each task,
+// which is how the session store finds the tasks in the saved plan.
+func (p Plan) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Plan\n")
+	for _, task := range p.Tasks {
+		fmt.Fprintf(&b, "\n%v\n", task.Markdown())
+	}
+	return b.String()
+}
+
+// Markdown renders the section of the task in the plan.
+func (t Task) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %v: %v\n\n", t.ID, t.Title)
+	fmt.Fprintf(&b, "- Covers: %v\n", joinOrNone(t.Covers))
+	fmt.Fprintf(&b, "- Depends on: %v\n", joinOrNone(t.DependsOn))
+	fmt.Fprintf(&b, "\n%v\n", strings.TrimSpace(t.Description))
+	return b.String()
+}
+
+func joinOrNone(ids []string) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	return strings.Join(ids, ", ")
+}
@@ -1,5 +1,11 @@
 package ai
 
+// The ports take the spec template and return the lint findings as the types
+// of the spec package, because specs are the domain that the agents work on.
+// The dependency points from the ports to the domain only: the spec package
+// imports no other package of Bear, which its tests enforce.
+import "github.com/sds-lab-dev/bear-go/spec"
+
 type Ports interface {
 	// NewSession creates a new AI session for the given working directory.
 	//
@@ -13,23 +19,38 @@ type Ports interface {
 	// workingDir is empty, the AI session can use the current working directory
 	// as the default.
 	NewSession(workingDir string) (Session, error)
+
+	// NewCodingAgent creates an agent that implements a single task of the
+	// approved plan in workingDir, which is the workspace in the task's
+	// worktree. Every task gets an agent of its own, so that the agents of
+	// the tasks that run in parallel share nothing.
+	NewCodingAgent(workingDir string) (CodingAgent, error)
 }
 
 type Session interface {
 	SpecWriter
+	PlanWriter
+	SpecTemplateHandler
+	RequestSourceHandler
+	ArtifactStoreHandler
+
+	// Close releases what the session keeps across interactions, such as
+	// temporary directories. The session must not be used afterwards.
+	Close() error
 }
 
 // SpecWriter is the interface that defines the methods for generating a
 // specification based on a user request.
 type SpecWriter interface {
 	StreamCallbackHandler
+	ToolApprovalHandler
 
 	// GetInitialClarifyingQuestions takes the initial user request to generate
 	// clarifying questions.
 	//
 	// This function returns a list of clarifying questions, and the list can be
 	// empty if no clarifying questions are needed.
-	GetInitialClarifyingQuestions(initialUserRequest string) ([]string, error)
+	GetInitialClarifyingQuestions(initialUserRequest string) ([]Question, error)
 
 	// GetNextClarifyingQuestions takes the user's answer to the previous
 	// clarifying questions to generate the next set of clarifying questions.
@@ -43,7 +64,23 @@ type SpecWriter interface {
 	//
 	// This function returns a list of clarifying questions, and the list can be
 	// empty if no clarifying questions are needed.
-	GetNextClarifyingQuestions(userAnswer string) ([]string, error)
+	GetNextClarifyingQuestions(userAnswer string) ([]Question, error)
+
+	// AskMoreAbout is GetNextClarifyingQuestions for when the user asks for
+	// a follow-up round focused on the topic. It is not limited by the cap on
+	// the clarification rounds, since the user asked for it.
+	AskMoreAbout(userAnswer, topic string) ([]Question, error)
+
+	// SkipClarifyingQuestions ends the clarification with the user's answer to
+	// some of the previous clarifying questions, so that the caller can draft
+	// the spec right away. The draft records the unanswered questions as
+	// assumptions or open questions.
+	SkipClarifyingQuestions(userAnswer string) error
+
+	// AddAttachments attaches files, such as screenshots or logs, to the next
+	// request or answer given to the agent. Each attachment must have an ID
+	// that is unique in the session, by which the questions refer to it.
+	AddAttachments(attachments []Attachment)
 
 	// DraftSpec generates a draft specification based on the initial user
 	// request and the clarifying Q&As between the user and the AI agent.
@@ -52,6 +89,178 @@ type SpecWriter interface {
 	// ReviseSpec takes the user's feedback on the previous drafted spec and
 	// generates a revised spec.
 	ReviseSpec(userFeedback string) (string, error)
+
+	// RestoreSpecDraft makes an earlier draft the one that the next call to
+	// ReviseSpec revises, so that the user can go back to it and continue
+	// revising from there. The drafts after it are discarded by the agent.
+	RestoreSpecDraft(draft string) error
+
+	// ApproveSpec ends the revision loop with the draft that the user
+	// approved, which can be an earlier draft than the latest one. The spec
+	// writer takes no more feedback afterwards.
+	ApproveSpec(draft string) error
+
+	// LintSpec checks a drafted or revised spec against the rules that every
+	// spec must follow, so that the user sees the findings before giving
+	// feedback. A failure of an optional check is reported as a warning
+	// through the stream callback instead of failing the whole lint.
+	LintSpec(draft string) []spec.Finding
+}
+
+// PlanWriter breaks the spec that the user approved down into tasks for the
+// coding agents. It takes over the session once SpecWriter.ApproveSpec
+// returns.
+type PlanWriter interface {
+	StreamCallbackHandler
+	ToolApprovalHandler
+
+	// DraftPlan drafts the plan of the approved spec. Every task covers
+	// requirements or acceptance criteria of the spec by their IDs, and
+	// depends only on earlier tasks.
+	DraftPlan(approvedSpec string) (Plan, error)
+
+	// RevisePlan takes the user's feedback on the latest plan draft and
+	// generates a revised plan.
+	RevisePlan(userFeedback string) (Plan, error)
+
+	// ApprovePlan ends the revision loop with the latest plan draft. The plan
+	// writer takes no more feedback afterwards.
+	ApprovePlan() error
+}
+
+// CodingAgent implements a task of the approved plan. It changes the files of
+// its working directory but does not commit them: Bear commits the changes on
+// the task's branch once CodeTask returns.
+type CodingAgent interface {
+	StreamCallbackHandler
+	ToolApprovalHandler
+	ArtifactStoreHandler
+
+	// CodeTask implements the task and its tests, and returns the summary of
+	// the change. The agent reads the approved spec through the artifact
+	// store.
+	CodeTask(task Task) (CodingResult, error)
+
+	// Close releases what the agent keeps, such as temporary directories. The
+	// agent must not be used afterwards.
+	Close() error
+}
+
+type CodingResult struct {
+	// Done tells whether the agent finished the task. An unfinished task is
+	// still committed on its branch, so that its work can be inspected, but
+	// it is not merged.
+	Done bool
+	// Summary describes the change as a commit message: a subject line,
+	// followed by a body that tells why the change was made, or, if the task
+	// is not done, what stopped the agent.
+	Summary string
+	// Handoff is the report that the agent leaves for the agents of the tasks
+	// that depend on the task, such as the interfaces it added and what it
+	// left for them to do.
+	Handoff string
+}
+
+// QuestionType is the kind of answer that a clarifying question takes.
+type QuestionType string
+
+const (
+	QuestionTypeText           QuestionType = "text"
+	QuestionTypeSingleChoice   QuestionType = "single_choice"
+	QuestionTypeMultipleChoice QuestionType = "multiple_choice"
+	QuestionTypeYesNo          QuestionType = "yes_no"
+)
+
+// CoverageArea is the area that a clarifying question covers. The questions
+// of the clarification must cover all of them together.
+type CoverageArea string
+
+const (
+	CoverageScope     CoverageArea = "scope"
+	CoverageConsumer  CoverageArea = "consumer"
+	CoverageSuccess   CoverageArea = "success"
+	CoverageEdgeCases CoverageArea = "edge_cases"
+	CoverageErrors    CoverageArea = "errors"
+)
+
+// Question is a clarifying question of the agent.
+type Question struct {
+	Text string
+	Type QuestionType
+	// Options are the suggested answers of a choice question. A yes/no
+	// question has the options "Yes" and "No".
+	Options []string
+	// Default is the answer that the agent recommends: the text of a text
+	// question, or the options it would choose.
+	Default  []string
+	Coverage CoverageArea
+	// Attachments are the IDs of the attachments that the question is about.
+	Attachments []string
+}
+
+type AttachmentKind string
+
+const (
+	// AttachmentKindText is a small text file whose content is given to the
+	// agent inline.
+	AttachmentKindText AttachmentKind = "text"
+	// AttachmentKindImage is an image that the agent views by reading Path.
+	AttachmentKindImage AttachmentKind = "image"
+	// AttachmentKindFile is any other file, such as a large log, that the
+	// agent reads or searches at Path.
+	AttachmentKindFile AttachmentKind = "file"
+)
+
+// Attachment is a file that the user attached to the request or an answer.
+// It is staged where the agent can read it.
+type Attachment struct {
+	// ID refers to the attachment in questions and answers, such as
+	// "ATT-1".
+	ID   string
+	Kind AttachmentKind
+	// Name is the name of the file as the user attached it.
+	Name string
+	// Path is the absolute path of the staged copy.
+	Path string
+	// Text is the content of a text attachment.
+	Text string
+}
+
+// A spec template handler sets the template of the spec that the spec writer
+// drafts, which gives the sections of the spec and what the acceptance
+// criteria must cover.
+//
+// If no template is set, the built-in default template is used.
+type SpecTemplateHandler interface {
+	SetSpecTemplate(spec.Template)
+}
+
+// A request source handler sets the documents that the user's request is based
+// on, such as a ticket or an earlier spec. They are given to the agent with the
+// initial request, so they must be set before GetInitialClarifyingQuestions.
+type RequestSourceHandler interface {
+	SetRequestSources([]RequestSource)
+}
+
+type RequestSourceKind string
+
+const (
+	RequestSourceKindText  RequestSourceKind = "text"
+	RequestSourceKindPDF   RequestSourceKind = "pdf"
+	RequestSourceKindIssue RequestSourceKind = "issue"
+	// RequestSourceKindSpec is a spec that the user approved in an earlier
+	// session, which the request extends.
+	RequestSourceKindSpec RequestSourceKind = "spec"
+)
+
+// RequestSource is a document attached to the user's request, with its text
+// already extracted and normalized.
+type RequestSource struct {
+	Kind RequestSourceKind
+	// Name tells the user and the agent where the source came from, such as
+	// the path of the file.
+	Name string
+	Text string
 }
 
 // A stream callback handler function is used to send intermediate messages back
@@ -68,10 +277,103 @@ type StreamCallbackHandler interface {
 	SetStreamCallbackHandler(func(StreamMessage))
 }
 
+// A tool approval handler function is called when the agent wants to run a
+// tool call that needs a human decision, and it blocks the agent until it
+// returns the decision.
+//
+// The handler can be called from a goroutine other than the one that started
+// the current request. If no handler is set, tool calls that are not
+// pre-approved by the agent's permission policy are denied.
+type ToolApprovalHandler interface {
+	SetToolApprovalHandler(func(ToolApprovalRequest) ToolApprovalDecision)
+}
+
+// An artifact store gives the agent the session artifacts, such as the approved
+// spec and the plan, through tools that it calls when it needs them, instead of
+// having them pasted into its prompts. The agent also records its decisions
+// and reports its progress through the store.
+//
+// If no store is set, the agent has no access to the session artifacts.
+type ArtifactStoreHandler interface {
+	SetArtifactStore(ArtifactStore)
+}
+
+type ArtifactStore interface {
+	// ApprovedSpec returns the spec that the user approved.
+	ApprovedSpec() (string, error)
+	// Task returns the plan section of the task with the given ID, for
+	// example "TASK-01".
+	Task(taskID string) (string, error)
+	// Handoff returns the report that the agent of the given task left for
+	// the tasks that depend on it.
+	Handoff(taskID string) (string, error)
+	RecordDecision(Decision) error
+	ReportProgress(ProgressReport) error
+}
+
+// Decision is a design decision that an agent made while working on a task,
+// kept so that later tasks and reviewers know why the code looks the way it
+// does.
+type Decision struct {
+	TaskID    string `json:"task_id"`
+	Decision  string `json:"decision"`
+	Rationale string `json:"rationale"`
+}
+
+type ProgressReport struct {
+	TaskID  string         `json:"task_id"`
+	Status  ProgressStatus `json:"status"`
+	Message string         `json:"message"`
+}
+
+type ProgressStatus string
+
+const (
+	ProgressStatusStarted    ProgressStatus = "started"
+	ProgressStatusInProgress ProgressStatus = "in_progress"
+	ProgressStatusBlocked    ProgressStatus = "blocked"
+	ProgressStatusDone       ProgressStatus = "done"
+)
+
+type ToolApprovalRequest struct {
+	ToolName string
+	// Input is the tool input rendered as YAML for display.
+	Input string
+	// AlwaysAllowScope describes the calls that ToolApprovalDecisionAlwaysAllow
+	// approves, for example "Read", or "Bash(go test ./...)" for a tool that
+	// is only always allowed for the same command.
+	AlwaysAllowScope string
+	// Expired is closed when the agent stops waiting for the decision, for
+	// example because the query was cancelled. The handler should then stop
+	// asking the user and return; its decision is ignored.
+	Expired <-chan struct{}
+}
+
+type ToolApprovalDecision int
+
+const (
+	ToolApprovalDecisionDeny ToolApprovalDecision = iota
+	ToolApprovalDecisionApprove
+	// ToolApprovalDecisionAlwaysAllow approves the tool call and every later
+	// call within the request's AlwaysAllowScope for the rest of the session.
+	ToolApprovalDecisionAlwaysAllow
+)
+
 type StreamMessage struct {
 	Role    StreamMessageRole
 	Type    StreamMessageType
 	Content string
+	// ToolCallID identifies the tool call that a StreamMessageTypeToolCall
+	// message starts and a StreamMessageTypeToolCallResult message answers, so
+	// that the caller can pair each result with its originating call. It is
+	// empty for all other message types.
+	ToolCallID string
+	// ToolName is the name of the tool for StreamMessageTypeToolCall and
+	// StreamMessageTypeToolCallResult messages. It can be empty for a result
+	// whose originating call was not observed.
+	ToolName string
+	// Used when Type is StreamMessageTypeProgress.
+	Progress ProgressReport
 }
 
 type StreamMessageRole int
@@ -89,4 +391,36 @@ const (
 	StreamMessageTypeToolCallStructuredOutput
 	StreamMessageTypeToolCallResult
 	StreamMessageTypeText
+
+	// The following three types are incremental: the content is a fragment of a
+	// block that is still being generated, and it should be appended to the
+	// fragments received before it. The complete block is sent again as a
+	// non-incremental message once it is finished.
+
+	StreamMessageTypeTextDelta
+	StreamMessageTypeThinkingDelta
+	StreamMessageTypeToolCallInputDelta
+
+	// StreamMessageTypeWarning reports a problem with the session that does
+	// not stop the agent but that the user should know about, for example a
+	// requested tool that is not available.
+	StreamMessageTypeWarning
+
+	// StreamMessageTypeProgress carries a structured progress report of a
+	// task, sent by its agent through the artifact store or by the coding
+	// stage. Content is its one-line summary.
+	StreamMessageTypeProgress
 )
+
+// IsIncremental reports whether the message type carries a fragment of a block
+// that is still being generated.
+func (t StreamMessageType) IsIncremental() bool {
+	switch t {
+	case StreamMessageTypeTextDelta,
+		StreamMessageTypeThinkingDelta,
+		StreamMessageTypeToolCallInputDelta:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+var (
+	ErrUnknownPrompt  = errors.New("unknown prompt")
+	ErrInvalidPrompt  = errors.New("invalid prompt template")
+	ErrPromptVariable = errors.New("prompt rendered with an undeclared variable")
+)
+
+//go:embed prompts/*.md
+var builtinPrompts embed.FS
+
+type PromptName string
+
+const (
+	PromptClarificationSystem             PromptName = "clarification_system"
+	PromptClarificationUserInitialRequest PromptName = "clarification_user_initial_request"
+	PromptClarificationUserAnswers        PromptName = "clarification_user_answers"
+	PromptSpecDraft                       PromptName = "spec_draft"
+	PromptSpecRevision                    PromptName = "spec_revision"
+	PromptSpecCritique                    PromptName = "spec_critique"
+	PromptSpecCritiqueSystem              PromptName = "spec_critique_system"
+	PromptStructuredOutputRepair          PromptName = "structured_output_repair"
+	PromptPlanSystem                      PromptName = "plan_system"
+	PromptPlanDraft                       PromptName = "plan_draft"
+	PromptPlanRevision                    PromptName = "plan_revision"
+	PromptPlanRepair                      PromptName = "plan_repair"
+	PromptCodingSystem                    PromptName = "coding_system"
+	PromptCodingTask                      PromptName = "coding_task"
+	// PromptLanguageRules is rendered first and given to the other prompts as
+	// the LanguageRules variable, so that it can be overridden on its own.
+	PromptLanguageRules PromptName = "language_rules"
+)
+
+// commonPromptVariables are available to every prompt except
+// PromptLanguageRules, which has only WorkspaceDir.
+var commonPromptVariables = []string{"WorkspaceDir", "LanguageRules"}
+
+// promptVariables are the variables that each prompt is rendered with, on top
+// of commonPromptVariables. A template that refers to any other variable is
+// rejected when the prompts are loaded, not when the agent is about to run.
+var promptVariables = map[PromptName][]string{
+	PromptLanguageRules:                   nil,
+	PromptClarificationSystem:             {"CurrentDatetime"},
+	PromptClarificationUserInitialRequest: {"Request", "ProjectContext", "Sources", "Attachments"},
+	PromptClarificationUserAnswers:        {"Answers", "Focus", "Attachments"},
+	PromptSpecDraft:                       {"SpecTemplate", "Request", "QAHistory"},
+	PromptSpecRevision:                    {"SpecTemplate", "BaseDraft", "Feedback"},
+	PromptSpecCritique:                    {"SpecTemplate", "Spec"},
+	PromptSpecCritiqueSystem:              nil,
+	PromptStructuredOutputRepair:          {"ValidationErrors"},
+	PromptPlanSystem:                      nil,
+	PromptPlanDraft:                       {"Spec"},
+	PromptPlanRevision:                    {"Feedback"},
+	PromptPlanRepair:                      {"Problems"},
+	PromptCodingSystem:                    nil,
+	PromptCodingTask:                      {"TaskID", "Task"},
+}
+
+// PromptNames returns the names of all prompts in a stable order.
+func PromptNames() []PromptName {
+	return slices.Sorted(maps.Keys(promptVariables))
+}
+
+// PromptVariables returns the variables that the prompt can use.
+func PromptVariables(name PromptName) []string {
+	if name == PromptLanguageRules {
+		return []string{"WorkspaceDir"}
+	}
+	return append(slices.Clone(commonPromptVariables), promptVariables[name]...)
+}
+
+type prompt struct {
+	// source is the override file the template was read from, or "built-in".
+	source   string
+	text     string
+	template *template.Template
+}
+
+// Prompts renders the prompt templates of a workspace. A template is the
+// built-in one unless an override directory has a "<name>.md" file.
+type Prompts struct {
+	workspaceDir  string
+	languageRules string
+	prompts       map[PromptName]prompt
+}
+
+// LoadPrompts loads the built-in templates and replaces them with the ones in
+// overrideDirs, where a later directory wins over an earlier one. Missing
+// directories are skipped. Every template is checked for syntax errors and
+// undeclared variables.
+func LoadPrompts(workspaceDir string, overrideDirs ...string) (*Prompts, error) {
+	p := &Prompts{
+		workspaceDir: workspaceDir,
+		prompts:      map[PromptName]prompt{},
+	}
+	for _, name := range PromptNames() {
+		text, err := builtinPrompts.ReadFile("prompts/" + string(name) + ".md")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in prompt %v: %w", name, err)
+		}
+		p.prompts[name] = prompt{source: "built-in", text: string(text)}
+	}
+
+	for _, dir := range overrideDirs {
+		if err := p.loadOverrides(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range PromptNames() {
+		if err := p.parse(name); err != nil {
+			return nil, err
+		}
+	}
+
+	languageRules, err := p.execute(PromptLanguageRules, map[string]string{"WorkspaceDir": workspaceDir})
+	if err != nil {
+		return nil, err
+	}
+	p.languageRules = languageRules
+	return p, nil
+}
+
+// DefaultPrompts returns the built-in prompts, which are checked by the tests
+// and therefore cannot fail to load.
+func DefaultPrompts(workspaceDir string) *Prompts {
+	p, err := LoadPrompts(workspaceDir)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Prompts) loadOverrides(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return fmt.Errorf("failed to list prompt overrides in %v: %w", dir, err)
+	}
+	for _, path := range paths {
+		name := PromptName(strings.TrimSuffix(filepath.Base(path), ".md"))
+		// A misspelled file name would otherwise be ignored silently.
+		if _, ok := promptVariables[name]; !ok {
+			return fmt.Errorf("%w: %v does not override any of %v", ErrUnknownPrompt, path, PromptNames())
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt override: %w", err)
+		}
+		p.prompts[name] = prompt{source: path, text: string(text)}
+	}
+	return nil
+}
+
+// parse parses the template and renders it once with every declared variable,
+// which fails if the template refers to a variable that is not declared.
+func (p *Prompts) parse(name PromptName) error {
+	prompt := p.prompts[name]
+	tmpl, err := template.New(string(name)).Option("missingkey=error").Parse(prompt.text)
+	if err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrInvalidPrompt, prompt.source, err)
+	}
+
+	data := map[string]string{}
+	for _, variable := range PromptVariables(name) {
+		data[variable] = ""
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, data); err != nil {
+		return fmt.Errorf(
+			"%w: %v: %v (available variables: %v)",
+			ErrInvalidPrompt, prompt.source, err, strings.Join(PromptVariables(name), ", "),
+		)
+	}
+
+	prompt.template = tmpl
+	p.prompts[name] = prompt
+	return nil
+}
+
+// Render renders the prompt with its variables. The common variables are
+// filled in by Prompts.
+func (p *Prompts) Render(name PromptName, variables map[string]string) (string, error) {
+	if _, ok := promptVariables[name]; !ok {
+		return "", fmt.Errorf("%w: %v", ErrUnknownPrompt, name)
+	}
+
+	data := map[string]string{
+		"WorkspaceDir":  p.workspaceDir,
+		"LanguageRules": p.languageRules,
+	}
+	for variable, value := range variables {
+		if !slices.Contains(promptVariables[name], variable) {
+			return "", fmt.Errorf("%w: %v in %v", ErrPromptVariable, variable, name)
+		}
+		data[variable] = value
+	}
+	return p.execute(name, data)
+}
+
+func (p *Prompts) execute(name PromptName, data map[string]string) (string, error) {
+	var b bytes.Buffer
+	if err := p.prompts[name].template.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %v: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// Source returns the path of the override file of the prompt, or "built-in".
+func (p *Prompts) Source(name PromptName) string {
+	return p.prompts[name].source
+}
+
+// Text returns the template text of the prompt.
+func (p *Prompts) Text(name PromptName) string {
+	return p.prompts[name].text
+}
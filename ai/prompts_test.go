@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePromptOverride(t *testing.T, dir string, name PromptName, text string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create override directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, string(name)+".md"), []byte(text), 0o644); err != nil {
+		t.Fatalf("failed to write override: %v", err)
+	}
+}
+
+func TestLoadPrompts_BuiltinPromptsRender(t *testing.T) {
+	prompts, err := LoadPrompts("/work/project")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	system, err := prompts.Render(PromptClarificationSystem, map[string]string{
+		"CurrentDatetime": "2026-01-02T03:04:05+09:00",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(system, "Keep code identifiers") {
+		t.Error("system prompt should contain the language rules")
+	}
+	if !strings.Contains(system, "2026-01-02T03:04:05+09:00") {
+		t.Error("system prompt should contain the current datetime")
+	}
+
+	user, err := prompts.Render(PromptClarificationUserInitialRequest, map[string]string{
+		"Request":        "Add {{.ProjectContext}} support",
+		"ProjectContext": "## Languages",
+		"Sources":        "",
+		"Attachments":    "",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(user, "Add {{.ProjectContext}} support") || !strings.Contains(user, "## Languages") {
+		t.Errorf("variables should be inserted verbatim:\n%v", user)
+	}
+	if prompts.Source(PromptClarificationSystem) != "built-in" {
+		t.Errorf("expected a built-in source, got %v", prompts.Source(PromptClarificationSystem))
+	}
+}
+
+func TestLoadPrompts_LaterOverrideWins(t *testing.T) {
+	userDir := filepath.Join(t.TempDir(), "user")
+	workspaceDir := filepath.Join(t.TempDir(), "workspace")
+	writePromptOverride(t, userDir, PromptLanguageRules, "Write in Korean.")
+	writePromptOverride(t, userDir, PromptClarificationSystem, "user system")
+	writePromptOverride(t, workspaceDir, PromptClarificationSystem, "team system for {{.WorkspaceDir}}: {{.LanguageRules}}")
+
+	prompts, err := LoadPrompts("/work/project", userDir, workspaceDir, filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	system, err := prompts.Render(PromptClarificationSystem, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if system != "team system for /work/project: Write in Korean." {
+		t.Errorf("unexpected system prompt: %q", system)
+	}
+	if source := prompts.Source(PromptClarificationSystem); source != filepath.Join(workspaceDir, "clarification_system.md") {
+		t.Errorf("unexpected source: %v", source)
+	}
+}
+
+func TestLoadPrompts_RejectsInvalidOverrides(t *testing.T) {
+	tests := map[string]struct {
+		name PromptName
+		text string
+		err  error
+	}{
+		"undeclared variable": {PromptStructuredOutputRepair, "{{.Request}}", ErrInvalidPrompt},
+		"syntax error":        {PromptClarificationSystem, "{{.LanguageRules", ErrInvalidPrompt},
+		"unknown prompt":      {"clarification_sytem", "text", ErrUnknownPrompt},
+	}
+	for description, tt := range tests {
+		dir := t.TempDir()
+		writePromptOverride(t, dir, tt.name, tt.text)
+
+		if _, err := LoadPrompts("", dir); !errors.Is(err, tt.err) {
+			t.Errorf("%v: expected %v, got %v", description, tt.err, err)
+		}
+	}
+}
+
+func TestPrompts_RenderChecksVariables(t *testing.T) {
+	prompts := DefaultPrompts("")
+
+	if _, err := prompts.Render(PromptStructuredOutputRepair, map[string]string{"Request": "x"}); !errors.Is(err, ErrPromptVariable) {
+		t.Errorf("expected ErrPromptVariable, got %v", err)
+	}
+	if _, err := prompts.Render(PromptStructuredOutputRepair, nil); err == nil {
+		t.Error("expected an error for a missing variable")
+	}
+	if _, err := prompts.Render("missing", nil); !errors.Is(err, ErrUnknownPrompt) {
+		t.Errorf("expected ErrUnknownPrompt, got %v", err)
+	}
+}
@@ -1,14 +1,23 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/coding"
 	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/snapshot"
+	"github.com/sds-lab-dev/bear-go/source"
+	"github.com/sds-lab-dev/bear-go/spec"
 	"github.com/sds-lab-dev/bear-go/ui"
+	"github.com/sds-lab-dev/bear-go/workspace"
+	"github.com/sds-lab-dev/bear-go/worktree"
 )
 
 type mainModelState int
@@ -16,9 +25,13 @@ type mainModelState int
 const (
 	mainStateWorkspaceDir mainModelState = iota
 	mainStateUserRequest
+	mainStateSpecTemplate
 	mainStateSpecDrafting
+	mainStatePlanning
+	mainStateCoding
 	mainStateDone
 	mainStateSwitching
+	mainStateSnapshotting
 )
 
 type stateSwitchMsg struct {
@@ -26,6 +39,19 @@ type stateSwitchMsg struct {
 	newModel tea.Model
 }
 
+// snapshotTakenMsg tells that the workspace snapshot before the coding is
+// taken.
+type snapshotTakenMsg struct {
+	err error
+}
+
+// rollbackDoneMsg tells that the workspace is put back to the snapshot of the
+// stage.
+type rollbackDoneMsg struct {
+	stage string
+	err   error
+}
+
 type mainModel struct {
 	sessionID     string
 	state         mainModelState
@@ -33,10 +59,25 @@ type mainModel struct {
 	mainHeaderCmd tea.Cmd
 	workspacePath string
 	aiPorts       ai.Ports
-	err           error
+	stateDir      string
+	// specTemplates loads the spec templates that apply to a workspace.
+	specTemplates func(workspaceDir string) ([]spec.Template, error)
+	// aiSession and userRequest are kept while the user chooses the spec
+	// template.
+	aiSession   ai.Session
+	userRequest string
+	// plan is kept while the workspace snapshot before the coding is taken.
+	plan ai.Plan
+	// store keeps the session artifacts in the workspace. It is nil until the
+	// workspace is chosen.
+	store *session.Store
+	// snapshots captures the workspace before each stage that can change
+	// files, so that the user can roll it back.
+	snapshots *snapshot.Manager
+	err       error
 }
 
-func newMainModel(sessionID string, aiPorts ai.Ports) (mainModel, error) {
+func newMainModel(cfg Config) (mainModel, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return mainModel{}, fmt.Errorf("failed to get current working directory: %w", err)
@@ -47,15 +88,23 @@ func newMainModel(sessionID string, aiPorts ai.Ports) (mainModel, error) {
 		return mainModel{}, fmt.Errorf("failed to build main header: %w", err)
 	}
 
+	recent, err := workspace.LoadRecent(cfg.StateDir)
+	if err != nil {
+		log.Warning(fmt.Sprintf("failed to load recent workspaces: %v", err))
+	}
+
 	return mainModel{
-		sessionID: sessionID,
+		sessionID: cfg.SessionID,
 		state:     mainStateWorkspaceDir,
 		currentModel: ui.NewWorkspacePromptModel(
 			cwd,
-			validateWorkspacePath,
+			recent,
+			inspectWorkspacePath,
 		),
 		mainHeaderCmd: mainHeaderCmd,
-		aiPorts:       aiPorts,
+		stateDir:      cfg.StateDir,
+		aiPorts:       cfg.AIPorts,
+		specTemplates: cfg.SpecTemplates,
 		err:           nil,
 	}, nil
 }
@@ -102,6 +151,9 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Type == tea.KeyCtrlC {
 			return m, tea.Quit
 		}
+		if m.state == mainStateSnapshotting {
+			return m, nil
+		}
 	case stateSwitchMsg:
 		// This internal message is used to switch between sub-models to ensure
 		// that the mainModel clears the terminal and re-renders the new sub-model
@@ -115,19 +167,84 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.currentModel.Init()
 	case ui.WorkspacePromptResult:
 		m.workspacePath = msg.Path
-		return m.switchModel(mainStateUserRequest, ui.NewUserRequestPromptModel(), nil)
+		if err := workspace.AddRecent(m.stateDir, msg.Path); err != nil {
+			log.Warning(fmt.Sprintf("failed to save recent workspace: %v", err))
+		}
+		// The store is created before the request, because the files attached
+		// to the request are staged in the session directory.
+		m.store = session.NewStore(m.workspacePath, m.sessionID, time.Now())
+		return m.switchModel(
+			mainStateUserRequest,
+			ui.NewUserRequestPromptModel(m.workspacePath, m.sourceLoader(), m.attachmentLoader()),
+			nil,
+		)
 	case ui.UserRequestPromptResult:
-		session, err := m.aiPorts.NewSession(m.workspacePath)
+		aiSession, err := m.aiPorts.NewSession(m.workspacePath)
 		if err != nil {
 			m.err = fmt.Errorf("failed to create AI session: %w", err)
 			return m, tea.Quit
 		}
-		return m.switchModel(mainStateSpecDrafting, ui.NewSpecPromptModel(msg.Text, session), nil)
+		if err := m.store.SaveUserRequest(msg.Text); err != nil {
+			m.err = fmt.Errorf("failed to save user request: %w", err)
+			return m, tea.Quit
+		}
+		if len(msg.Sources) > 0 {
+			if err := m.store.SaveRequestSources(msg.Sources); err != nil {
+				m.err = fmt.Errorf("failed to save request sources: %w", err)
+				return m, tea.Quit
+			}
+		}
+		aiSession.SetRequestSources(msg.Sources)
+		aiSession.AddAttachments(msg.Attachments)
+		aiSession.SetArtifactStore(m.store)
+		m.aiSession = aiSession
+		m.userRequest = msg.Text
+		return m.chooseSpecTemplate()
+	case snapshotTakenMsg:
+		if msg.err != nil {
+			m.err = fmt.Errorf("failed to snapshot the workspace: %w", msg.err)
+			return m, tea.Quit
+		}
+		return m.codePlan()
+	case ui.SpecTemplatePromptResult:
+		return m.startSpecDrafting(msg.Template)
+	case ui.RollbackRequestMsg:
+		return m, m.rollback()
+	case rollbackDoneMsg:
+		return m.switchModel(mainStateDone, nil, tea.Sequence(
+			tea.Println(ui.RenderRollbackResult(msg.stage, msg.err)),
+			tea.Quit,
+		))
 	case ui.SpecPromptResult:
 		if msg.Err != nil {
 			m.err = fmt.Errorf("spec prompt failed: %w", msg.Err)
 			return m, tea.Quit
 		}
+		if msg.ApprovedSpec == "" {
+			return m.switchModel(mainStateDone, nil, tea.Quit)
+		}
+		if err := m.store.SaveApprovedSpec(msg.ApprovedSpec); err != nil {
+			m.err = fmt.Errorf("failed to save approved spec: %w", err)
+			return m, tea.Quit
+		}
+		return m.switchModel(mainStatePlanning, ui.NewPlanPromptModel(msg.ApprovedSpec, m.aiSession), nil)
+	case ui.PlanPromptResult:
+		if msg.Err != nil {
+			m.err = fmt.Errorf("plan prompt failed: %w", msg.Err)
+			return m, tea.Quit
+		}
+		// The trace matrix reads the tasks, and what they cover, from the
+		// saved plan.
+		if err := m.store.SavePlan(msg.ApprovedPlan.Markdown()); err != nil {
+			m.err = fmt.Errorf("failed to save approved plan: %w", err)
+			return m, tea.Quit
+		}
+		return m.startCoding(msg.ApprovedPlan)
+	case ui.CodingProgressResult:
+		if msg.Err != nil {
+			m.err = fmt.Errorf("coding failed: %w", msg.Err)
+			return m, tea.Quit
+		}
 		return m.switchModel(mainStateDone, nil, tea.Quit)
 	}
 
@@ -145,6 +262,97 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// chooseSpecTemplate lets the user choose the spec template, unless there is
+// only one to choose from.
+func (m mainModel) chooseSpecTemplate() (tea.Model, tea.Cmd) {
+	templates, err := m.specTemplates(m.workspacePath)
+	if err != nil {
+		m.err = fmt.Errorf("failed to load spec templates: %w", err)
+		return m, tea.Quit
+	}
+	if len(templates) == 1 {
+		return m.startSpecDrafting(templates[0])
+	}
+	suggested := spec.Suggest(templates, m.userRequest)
+	return m.switchModel(mainStateSpecTemplate, ui.NewSpecTemplatePromptModel(templates, suggested), nil)
+}
+
+func (m mainModel) startSpecDrafting(template spec.Template) (tea.Model, tea.Cmd) {
+	m.aiSession.SetSpecTemplate(template)
+	model := ui.NewSpecPromptModel(m.userRequest, m.aiSession, m.workspacePath, m.attachmentLoader())
+	return m.switchModel(mainStateSpecDrafting, model, nil)
+}
+
+// startCoding takes the workspace snapshot that the user can roll back to
+// once the coding is done, and then codes the approved plan. The coding needs
+// the workspace to be in a git repository; outside one, the session ends with
+// the saved plan.
+func (m mainModel) startCoding(plan ai.Plan) (tea.Model, tea.Cmd) {
+	if _, err := worktree.RepositoryRoot(m.workspacePath); errors.Is(err, worktree.ErrNotGitRepository) {
+		message := fmt.Sprintf(
+			"The plan is saved in %v. The workspace is not in a git repository, so the tasks cannot be coded in worktrees of their own.",
+			m.store.Dir(),
+		)
+		return m.switchModel(mainStateDone, nil, tea.Sequence(tea.Println(message), tea.Quit))
+	}
+
+	m.plan = plan
+	m.snapshots = snapshot.NewManager(m.workspacePath, m.store.Dir(), m.sessionID)
+	// Capturing a large workspace takes a while, so the snapshot is taken off
+	// the UI thread and the keys are ignored until it is done.
+	m.state = mainStateSnapshotting
+	return m, m.takeSnapshot(codingStage)
+}
+
+// codePlan has the coding agents implement the approved plan, each task in a
+// worktree of its own, so that the user's checkout is never touched.
+func (m mainModel) codePlan() (tea.Model, tea.Cmd) {
+	manager, err := worktree.NewManager(m.workspacePath, m.sessionID)
+	if err != nil {
+		m.err = fmt.Errorf("failed to create the worktrees of the session: %w", err)
+		return m, tea.Quit
+	}
+
+	runner := coding.NewRunner(m.sessionID, m.aiPorts, manager, m.store)
+	return m.switchModel(mainStateCoding, ui.NewCodingProgressModel(m.plan, runner), nil)
+}
+
+func (m mainModel) sourceLoader() func(ref string) (ai.RequestSource, error) {
+	workspacePath := m.workspacePath
+	return func(ref string) (ai.RequestSource, error) {
+		return source.Load(workspacePath, ref)
+	}
+}
+
+func (m mainModel) attachmentLoader() func(ref string) (ai.Attachment, error) {
+	store, workspacePath := m.store, m.workspacePath
+	return func(ref string) (ai.Attachment, error) {
+		return source.Attach(store, workspacePath, ref)
+	}
+}
+
+// codingStage is the snapshot stage taken before the coding agents run.
+const codingStage = "coding"
+
+func (m mainModel) takeSnapshot(stage string) tea.Cmd {
+	snapshots := m.snapshots
+	return func() tea.Msg {
+		_, err := snapshots.Take(stage)
+		return snapshotTakenMsg{err: err}
+	}
+}
+
+func (m mainModel) rollback() tea.Cmd {
+	if m.snapshots == nil {
+		return tea.Println(ui.RenderRollbackResult("", snapshot.ErrSnapshotNotFound))
+	}
+	snapshots := m.snapshots
+	return func() tea.Msg {
+		restored, err := snapshots.Rollback("")
+		return rollbackDoneMsg{stage: restored.Stage, err: err}
+	}
+}
+
 func (m mainModel) View() string {
 	log.Debug(fmt.Sprintf("main model rendering view in state %v with current sub-model of type %T", m.state, m.currentModel))
 
@@ -157,8 +365,8 @@ func (m mainModel) View() string {
 	return m.currentModel.View()
 }
 
-func appMain(sessionID string, aiPorts ai.Ports) error {
-	model, err := newMainModel(sessionID, aiPorts)
+func appMain(cfg Config) error {
+	model, err := newMainModel(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to initialize main model: %v", err)
 	}
@@ -169,7 +377,13 @@ func appMain(sessionID string, aiPorts ai.Ports) error {
 		return fmt.Errorf("application main loop failed: %v", err)
 	}
 
-	if model, ok := finalModel.(mainModel); ok && model.err != nil {
+	model, ok := finalModel.(mainModel)
+	if ok && model.aiSession != nil {
+		if err := model.aiSession.Close(); err != nil {
+			log.Warning(fmt.Sprintf("failed to close the AI session: %v", err))
+		}
+	}
+	if ok && model.err != nil {
 		return fmt.Errorf("failed to run main model: %v", model.err)
 	}
 
@@ -177,10 +391,15 @@ func appMain(sessionID string, aiPorts ai.Ports) error {
 }
 
 type Config struct {
-	LogDir       string
+	LogDir string
+	// StateDir keeps the state that outlives a session, such as the recently
+	// used workspaces.
+	StateDir     string
 	BuildVersion string
 	SessionID    string
 	AIPorts      ai.Ports
+	// SpecTemplates loads the spec templates that apply to a workspace.
+	SpecTemplates func(workspaceDir string) ([]spec.Template, error)
 }
 
 func Run(cfg Config) {
@@ -193,7 +412,7 @@ func Run(cfg Config) {
 	fmt.Printf("Log file initialized at %s\n", log.GetLogPath())
 	log.Info(fmt.Sprintf("Starting application: sessionID=%v, buildVersion=%v", cfg.SessionID, cfg.BuildVersion))
 
-	if err := appMain(cfg.SessionID, cfg.AIPorts); err != nil {
+	if err := appMain(cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		log.Fatal(err.Error())
 	}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/source"
+	"github.com/sds-lab-dev/bear-go/ui"
+)
+
+// fakeSession is an AI session that drafts the plan it is given. Any other
+// call panics on the nil Session.
+type fakeSession struct {
+	ai.Session
+	plan ai.Plan
+}
+
+func (s *fakeSession) SetStreamCallbackHandler(func(ai.StreamMessage)) {}
+
+func (s *fakeSession) SetToolApprovalHandler(func(ai.ToolApprovalRequest) ai.ToolApprovalDecision) {}
+
+func (s *fakeSession) DraftPlan(string) (ai.Plan, error) {
+	return s.plan, nil
+}
+
+func TestMainModel_ApprovedSpecIsSavedForLaterSessions(t *testing.T) {
+	workspaceDir := t.TempDir()
+	m := mainModel{
+		sessionID:     "session-1",
+		state:         mainStateSpecDrafting,
+		workspacePath: workspaceDir,
+		aiSession:     &fakeSession{},
+		store:         session.NewStore(workspaceDir, "session-1", time.Now()),
+	}
+
+	updated, _ := m.Update(ui.SpecPromptResult{ApprovedSpec: "# Report Export"})
+	if err := updated.(mainModel).err; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The spec tools, export and trace commands read the spec from the store,
+	// and a later session can start from it.
+	store, err := session.OpenStore(workspaceDir, "session-1")
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	if approved, err := store.ApprovedSpec(); err != nil || approved != "# Report Export" {
+		t.Errorf("ApprovedSpec() = %q, %v, want the approved spec", approved, err)
+	}
+	loaded, err := source.Load(workspaceDir, source.SpecRefPrefix+"session-1")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Text != "# Report Export" {
+		t.Errorf("loaded spec = %q, want the approved spec", loaded.Text)
+	}
+}
+
+func TestMainModel_ApprovedSpecIsPlannedAndThePlanSaved(t *testing.T) {
+	workspaceDir := t.TempDir()
+	plan := ai.Plan{Tasks: []ai.Task{{ID: "TASK-01", Title: "Export", Covers: []string{"REQ-01"}}}}
+	var m tea.Model = mainModel{
+		sessionID:     "session-1",
+		state:         mainStateSpecDrafting,
+		workspacePath: workspaceDir,
+		aiSession:     &fakeSession{plan: plan},
+		store:         session.NewStore(workspaceDir, "session-1", time.Now()),
+	}
+
+	m, cmd := m.Update(ui.SpecPromptResult{ApprovedSpec: "# Report Export"})
+	m, _ = m.Update(cmd().(stateSwitchMsg))
+	if m.(mainModel).state != mainStatePlanning {
+		t.Fatalf("expected the planning stage, got state %v", m.(mainModel).state)
+	}
+
+	m, _ = m.Update(ui.PlanPromptResult{ApprovedPlan: plan})
+	if err := m.(mainModel).err; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tasks, err := m.(mainModel).store.Tasks()
+	if err != nil {
+		t.Fatalf("Tasks() error = %v", err)
+	}
+	if !strings.Contains(tasks["TASK-01"], "Covers: REQ-01") {
+		t.Errorf("the saved plan should have the task, got %#v", tasks)
+	}
+}
+
+// fakePorts creates coding agents that fail right away.
+type fakePorts struct {
+	ai.Ports
+}
+
+func (fakePorts) NewCodingAgent(string) (ai.CodingAgent, error) {
+	return nil, errors.New("no agent in tests")
+}
+
+func TestMainModel_ApprovedPlanIsCodedInWorktrees(t *testing.T) {
+	workspaceDir := testutil.NewRepository(t, "README.md")
+	plan := ai.Plan{Tasks: []ai.Task{{ID: "TASK-01", Title: "Export", Covers: []string{"REQ-01"}}}}
+	store := session.NewStore(workspaceDir, "session-1", time.Now())
+	if err := store.SaveApprovedSpec("# Report Export"); err != nil {
+		t.Fatalf("failed to save the spec: %v", err)
+	}
+	var m tea.Model = mainModel{
+		sessionID:     "session-1",
+		state:         mainStatePlanning,
+		workspacePath: workspaceDir,
+		aiPorts:       fakePorts{},
+		store:         store,
+	}
+
+	m, cmd := m.Update(ui.PlanPromptResult{ApprovedPlan: plan})
+	if m.(mainModel).state != mainStateSnapshotting {
+		t.Fatalf("expected the snapshot before the coding, got state %v", m.(mainModel).state)
+	}
+	m, cmd = m.Update(cmd())
+	if err := m.(mainModel).err; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.(mainModel).snapshots.Load(codingStage); err != nil {
+		t.Errorf("expected the snapshot of the coding stage: %v", err)
+	}
+	m, _ = m.Update(cmd().(stateSwitchMsg))
+	if m.(mainModel).state != mainStateCoding {
+		t.Fatalf("expected the coding stage, got state %v", m.(mainModel).state)
+	}
+
+	// The runner removes the worktrees of the session once it is done.
+	worktreesDir := filepath.Join(workspaceDir, ".git", "bear", "session-1")
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, err := os.Stat(worktreesDir); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the coding run did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -4,31 +4,29 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+
+	"github.com/sds-lab-dev/bear-go/workspace"
 )
 
 var (
-	ErrRelativePath = errors.New("path must be absolute; relative paths are not allowed")
 	ErrPathNotExist = errors.New("path does not exist")
 	ErrNotDirectory = errors.New("path is not a directory; please enter a directory path")
 )
 
-func validateWorkspacePath(path string) error {
-	if !filepath.IsAbs(path) {
-		return ErrRelativePath
-	}
-
+// inspectWorkspacePath checks the path that the workspace prompt resolved
+// against the current directory, so it is always absolute.
+func inspectWorkspacePath(path string) (workspace.Info, error) {
 	info, err := os.Stat(path)
 	if errors.Is(err, os.ErrNotExist) {
-		return ErrPathNotExist
+		return workspace.Info{}, ErrPathNotExist
 	}
 	if err != nil {
-		return fmt.Errorf("failed to stat path: %w", err)
+		return workspace.Info{}, fmt.Errorf("failed to stat path: %w", err)
 	}
 
 	if !info.IsDir() {
-		return ErrNotDirectory
+		return workspace.Info{}, ErrNotDirectory
 	}
 
-	return nil
+	return workspace.Inspect(path)
 }
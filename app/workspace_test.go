@@ -5,32 +5,61 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
 )
 
-func TestValidateWorkspacePath_AbsoluteDirectoryPath(t *testing.T) {
-	dir := t.TempDir()
+func TestInspectWorkspacePath_AbsoluteDirectoryPath(t *testing.T) {
+	dir := testutil.NewRepository(t)
 
-	err := validateWorkspacePath(dir)
+	_, err := inspectWorkspacePath(dir)
 	if err != nil {
 		t.Fatalf("expected nil error for valid absolute directory, got: %v", err)
 	}
 }
 
-func TestValidateWorkspacePath_RelativePath(t *testing.T) {
-	err := validateWorkspacePath("./some/path")
-	if !errors.Is(err, ErrRelativePath) {
-		t.Fatalf("expected ErrRelativePath, got: %v", err)
+func TestInspectWorkspacePath_SubdirectoryOfRepository(t *testing.T) {
+	dir := testutil.NewRepository(t)
+	subdir := filepath.Join(dir, "sub")
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatalf("failed to create subdirectory: %v", err)
+	}
+
+	info, err := inspectWorkspacePath(subdir)
+	if err != nil {
+		t.Fatalf("expected nil error for a subdirectory of a repository, got: %v", err)
+	}
+	if !info.InSubdirectory() {
+		t.Error("expected the workspace to be reported as a subdirectory")
+	}
+}
+
+func TestInspectWorkspacePath_NotGitRepository(t *testing.T) {
+	info, err := inspectWorkspacePath(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected nil error for a directory outside a repository, got: %v", err)
+	}
+	if info.RepositoryRoot != "" {
+		t.Errorf("expected no repository root, got %q", info.RepositoryRoot)
+	}
+}
+
+func TestInspectWorkspacePath_RepositoryWithoutCommits(t *testing.T) {
+	dir := testutil.InitRepository(t)
+
+	if _, err := inspectWorkspacePath(dir); err != nil {
+		t.Fatalf("expected nil error for a repository without commits, got: %v", err)
 	}
 }
 
-func TestValidateWorkspacePath_NonExistentPath(t *testing.T) {
-	err := validateWorkspacePath("/nonexistent/path/abc123")
+func TestInspectWorkspacePath_NonExistentPath(t *testing.T) {
+	_, err := inspectWorkspacePath("/nonexistent/path/abc123")
 	if !errors.Is(err, ErrPathNotExist) {
 		t.Fatalf("expected ErrPathNotExist, got: %v", err)
 	}
 }
 
-func TestValidateWorkspacePath_FilePath(t *testing.T) {
+func TestInspectWorkspacePath_FilePath(t *testing.T) {
 	dir := t.TempDir()
 	filePath := filepath.Join(dir, "testfile.txt")
 
@@ -40,15 +69,15 @@ func TestValidateWorkspacePath_FilePath(t *testing.T) {
 	}
 	f.Close()
 
-	err = validateWorkspacePath(filePath)
+	_, err = inspectWorkspacePath(filePath)
 	if !errors.Is(err, ErrNotDirectory) {
 		t.Fatalf("expected ErrNotDirectory, got: %v", err)
 	}
 }
 
-func TestValidateWorkspacePath_EmptyPath(t *testing.T) {
-	err := validateWorkspacePath("")
-	if !errors.Is(err, ErrRelativePath) {
-		t.Fatalf("expected ErrRelativePath for empty path, got: %v", err)
+func TestInspectWorkspacePath_EmptyPath(t *testing.T) {
+	_, err := inspectWorkspacePath("")
+	if !errors.Is(err, ErrPathNotExist) {
+		t.Fatalf("expected ErrPathNotExist for empty path, got: %v", err)
 	}
 }
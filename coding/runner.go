@@ -0,0 +1,282 @@
+// Package coding implements the tasks of an approved plan. Each task is coded
+// by an agent of its own in a worktree of its own, committed on the task's
+// branch, and merged into the session branch in dependency order.
+//
+// There is no review stage: a task is done when its agent says so, and the
+// user reviews the merged session branch. The coding agents are therefore the
+// only agents that refer to the spec IDs in commits and test names.
+package coding
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+var ErrTaskNotDone = errors.New("coding agent did not finish the task")
+
+// TaskFailure is a task whose agent failed or did not finish. Its work, if
+// any, is committed on its branch but not merged.
+type TaskFailure struct {
+	TaskID string
+	Err    error
+}
+
+type Result struct {
+	// Merge is the outcome of merging the task branches into the session
+	// branch. The tasks that failed are not in it.
+	Merge  worktree.MergeResult
+	Failed []TaskFailure
+}
+
+// ArtifactStore is the store of the session artifacts that the agents read
+// and write, and that the runner reports the progress of the tasks to.
+type ArtifactStore interface {
+	ai.ArtifactStore
+	// SpecPath returns the path of the approved spec in the user's checkout.
+	SpecPath() string
+	// SaveHandoff saves the report that the agent of the task left for the
+	// tasks that depend on it, which they read through ai.ArtifactStore.
+	SaveHandoff(taskID, report string) error
+}
+
+// Runner runs the tasks of the plan in waves: the tasks whose dependencies are
+// merged run in parallel, and their branches are merged before the next wave
+// starts from the session branch. A task that depends on a task that failed
+// or conflicted is skipped.
+type Runner struct {
+	sessionID           string
+	ports               ai.Ports
+	manager             *worktree.Manager
+	store               ArtifactStore
+	streamMu            sync.Mutex
+	streamCallback      func(ai.StreamMessage)
+	toolApprovalHandler func(ai.ToolApprovalRequest) ai.ToolApprovalDecision
+}
+
+// NewRunner creates a runner that codes the tasks in the worktrees of the
+// manager, which it closes once the run is over.
+func NewRunner(sessionID string, ports ai.Ports, manager *worktree.Manager, store ArtifactStore) *Runner {
+	return &Runner{
+		sessionID: sessionID,
+		ports:     ports,
+		manager:   manager,
+		store:     store,
+	}
+}
+
+// SetStreamCallbackHandler sets the handler that gets the complete blocks of
+// every agent, each prefixed with its task ID, and the progress of the tasks.
+// The agents run in parallel, so the fragments of their blocks would
+// interleave and are not passed on. The handler is never called concurrently.
+func (r *Runner) SetStreamCallbackHandler(handler func(ai.StreamMessage)) {
+	r.streamCallback = handler
+}
+
+// SetToolApprovalHandler sets the tool approval handler of every agent. It is
+// called concurrently when the agents of a wave need a decision at once.
+func (r *Runner) SetToolApprovalHandler(handler func(ai.ToolApprovalRequest) ai.ToolApprovalDecision) {
+	r.toolApprovalHandler = handler
+}
+
+// SessionBranch returns the branch that the tasks are merged into.
+func (r *Runner) SessionBranch() string {
+	return r.manager.IntegrationBranch()
+}
+
+// Run codes and merges the tasks of the plan. It returns an error only if the
+// run cannot go on, for example because a merge failed for a reason other than
+// a conflict; the tasks that fail are reported in the result instead.
+func (r *Runner) Run(plan ai.Plan) (result Result, err error) {
+	defer func() {
+		if closeErr := r.manager.Close(); closeErr != nil {
+			log.Warning(fmt.Sprintf("failed to remove the worktrees of the session: %v", closeErr))
+		}
+	}()
+
+	if _, err := worktree.SortTasks(worktreeTasks(plan.Tasks)); err != nil {
+		return Result{}, err
+	}
+	specPath, err := r.manager.RepositoryPath(r.store.SpecPath())
+	if err != nil {
+		return Result{}, err
+	}
+
+	pending := plan.Tasks
+	merged := map[string]bool{}
+	notMerged := map[string]bool{}
+	for len(pending) > 0 {
+		var wave, rest []ai.Task
+		for _, task := range pending {
+			switch {
+			case slices.ContainsFunc(task.DependsOn, func(id string) bool { return notMerged[id] }):
+				notMerged[task.ID] = true
+				result.Merge.Skipped = append(result.Merge.Skipped, task.ID)
+			case !slices.ContainsFunc(task.DependsOn, func(id string) bool { return !merged[id] }):
+				wave = append(wave, task)
+			default:
+				rest = append(rest, task)
+			}
+		}
+		pending = rest
+
+		failures := r.runWave(wave, specPath)
+		var coded []worktree.Task
+		for _, task := range wave {
+			if failed := slices.ContainsFunc(failures, func(f TaskFailure) bool { return f.TaskID == task.ID }); failed {
+				notMerged[task.ID] = true
+				continue
+			}
+			// The dependencies are merged already, so the tasks of a wave are
+			// merged in plan order.
+			coded = append(coded, worktree.Task{ID: task.ID})
+		}
+		result.Failed = append(result.Failed, failures...)
+
+		mergeResult, err := r.manager.MergeTasks(coded)
+		if err != nil {
+			return result, err
+		}
+		for _, id := range mergeResult.Merged {
+			merged[id] = true
+		}
+		for _, conflict := range mergeResult.Conflicts {
+			notMerged[conflict.TaskID] = true
+		}
+		for _, id := range mergeResult.Skipped {
+			notMerged[id] = true
+		}
+		result.Merge.Merged = append(result.Merge.Merged, mergeResult.Merged...)
+		result.Merge.Conflicts = append(result.Merge.Conflicts, mergeResult.Conflicts...)
+		result.Merge.Skipped = append(result.Merge.Skipped, mergeResult.Skipped...)
+	}
+
+	log.Info(fmt.Sprintf("coded the plan: %#v", result))
+	return result, nil
+}
+
+func worktreeTasks(tasks []ai.Task) []worktree.Task {
+	converted := make([]worktree.Task, 0, len(tasks))
+	for _, task := range tasks {
+		converted = append(converted, worktree.Task{ID: task.ID, Dependencies: task.DependsOn})
+	}
+	return converted
+}
+
+// runWave codes the tasks in parallel and returns the ones that failed, in
+// plan order.
+func (r *Runner) runWave(tasks []ai.Task, specPath string) []TaskFailure {
+	errs := make([]error, len(tasks))
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Go(func() {
+			errs[i] = r.runTask(task, specPath)
+		})
+	}
+	wg.Wait()
+
+	var failures []TaskFailure
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		log.Error(fmt.Sprintf("failed to code %v: %v", tasks[i].ID, err))
+		r.reportProgress(ai.ProgressReport{TaskID: tasks[i].ID, Status: ai.ProgressStatusBlocked, Message: err.Error()})
+		failures = append(failures, TaskFailure{TaskID: tasks[i].ID, Err: err})
+	}
+	return failures
+}
+
+// runTask codes the task in its worktree and commits the changes of the agent
+// on the task's branch.
+func (r *Runner) runTask(task ai.Task, specPath string) error {
+	taskWorktree, err := r.manager.CreateTaskWorktree(task.ID)
+	if err != nil {
+		return err
+	}
+	agent, err := r.ports.NewCodingAgent(taskWorktree.WorkspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to create coding agent: %w", err)
+	}
+	defer func() {
+		if err := agent.Close(); err != nil {
+			log.Warning(fmt.Sprintf("failed to close the coding agent of %v: %v", task.ID, err))
+		}
+	}()
+	agent.SetStreamCallbackHandler(r.agentStreamCallback(task.ID))
+	agent.SetToolApprovalHandler(r.toolApprovalHandler)
+	agent.SetArtifactStore(r.store)
+
+	r.reportProgress(ai.ProgressReport{TaskID: task.ID, Status: ai.ProgressStatusStarted, Message: task.Title})
+	coded, err := agent.CodeTask(task)
+	if err != nil {
+		return err
+	}
+
+	_, err = worktree.CommitTask(taskWorktree.Path, taskWorktree.Branch, worktree.CommitMessage{
+		SessionID: r.sessionID,
+		TaskID:    task.ID,
+		Round:     1,
+		Summary:   coded.Summary,
+		SpecPath:  specPath,
+		Covers:    task.Covers,
+	})
+	if errors.Is(err, worktree.ErrNothingToCommit) {
+		log.Warning(fmt.Sprintf("the agent of %v changed nothing", task.ID))
+	} else if err != nil {
+		return err
+	}
+
+	if !coded.Done {
+		return fmt.Errorf("%w: %v", ErrTaskNotDone, coded.Summary)
+	}
+	if err := r.store.SaveHandoff(task.ID, coded.Handoff); err != nil {
+		return fmt.Errorf("failed to save the handoff of %v: %w", task.ID, err)
+	}
+	subject, _, _ := strings.Cut(coded.Summary, "\n")
+	r.reportProgress(ai.ProgressReport{TaskID: task.ID, Status: ai.ProgressStatusDone, Message: subject})
+	return nil
+}
+
+// agentStreamCallback passes the complete blocks of the agent of the task on
+// to the stream callback handler.
+func (r *Runner) agentStreamCallback(taskID string) func(ai.StreamMessage) {
+	return func(msg ai.StreamMessage) {
+		if msg.Type.IsIncremental() {
+			return
+		}
+		if msg.Type != ai.StreamMessageTypeProgress {
+			msg.Content = fmt.Sprintf("[%v] %v", taskID, msg.Content)
+		}
+		r.stream(msg)
+	}
+}
+
+// reportProgress records the progress of a task that the runner, rather than
+// the agent, knows about, and shows it to the user.
+func (r *Runner) reportProgress(report ai.ProgressReport) {
+	if err := r.store.ReportProgress(report); err != nil {
+		log.Warning(fmt.Sprintf("failed to record the progress of %v: %v", report.TaskID, err))
+	}
+	r.stream(ai.StreamMessage{
+		Role:     ai.StreamMessageRoleAssistant,
+		Type:     ai.StreamMessageTypeProgress,
+		Content:  fmt.Sprintf("%v [%v] %v", report.TaskID, report.Status, report.Message),
+		Progress: report,
+	})
+}
+
+func (r *Runner) stream(msg ai.StreamMessage) {
+	if r.streamCallback == nil {
+		return
+	}
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+	r.streamCallback(msg)
+}
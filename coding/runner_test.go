@@ -0,0 +1,176 @@
+package coding
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+// fakePorts creates coding agents that run code instead of an agent.
+type fakePorts struct {
+	code func(workingDir string, task ai.Task) (ai.CodingResult, error)
+}
+
+func (p fakePorts) NewSession(string) (ai.Session, error) {
+	return nil, errors.New("not used by the runner")
+}
+
+func (p fakePorts) NewCodingAgent(workingDir string) (ai.CodingAgent, error) {
+	return &fakeAgent{workingDir: workingDir, code: p.code}, nil
+}
+
+type fakeAgent struct {
+	workingDir string
+	code       func(workingDir string, task ai.Task) (ai.CodingResult, error)
+}
+
+func (a *fakeAgent) SetStreamCallbackHandler(func(ai.StreamMessage)) {}
+
+func (a *fakeAgent) SetToolApprovalHandler(func(ai.ToolApprovalRequest) ai.ToolApprovalDecision) {}
+
+func (a *fakeAgent) SetArtifactStore(ai.ArtifactStore) {}
+
+func (a *fakeAgent) CodeTask(task ai.Task) (ai.CodingResult, error) {
+	return a.code(a.workingDir, task)
+}
+
+func (a *fakeAgent) Close() error {
+	return nil
+}
+
+func newTestRunner(t *testing.T, code func(string, ai.Task) (ai.CodingResult, error)) (*Runner, *session.Store, string) {
+	t.Helper()
+	repo := testutil.NewRepository(t, "README.md")
+	store := session.NewStore(repo, "session-1", time.Now())
+	if err := store.SaveApprovedSpec("# Report Export\n"); err != nil {
+		t.Fatalf("failed to save the spec: %v", err)
+	}
+	manager, err := worktree.NewManager(repo, "session-1")
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	return NewRunner("session-1", fakePorts{code: code}, manager, store), store, repo
+}
+
+func TestRunner_CodesWavesAndMergesInDependencyOrder(t *testing.T) {
+	var mu sync.Mutex
+	sawBase := false
+	runner, store, repo := newTestRunner(t, func(dir string, task ai.Task) (ai.CodingResult, error) {
+		if task.ID == "TASK-02" {
+			_, err := os.Stat(filepath.Join(dir, "TASK-01.txt"))
+			mu.Lock()
+			sawBase = err == nil
+			mu.Unlock()
+		}
+		testutil.WriteFile(t, dir, task.ID+".txt", task.ID+"\n")
+		return ai.CodingResult{Done: true, Summary: "Add " + task.ID + "\n\nIt is needed.", Handoff: task.ID + " is done."}, nil
+	})
+	var messages []ai.StreamMessage
+	runner.SetStreamCallbackHandler(func(msg ai.StreamMessage) {
+		messages = append(messages, msg)
+	})
+
+	result, err := runner.Run(ai.Plan{Tasks: []ai.Task{
+		{ID: "TASK-01", Title: "Base", Covers: []string{"REQ-01"}},
+		{ID: "TASK-02", Title: "Dependent", Covers: []string{"AC-01"}, DependsOn: []string{"TASK-01"}},
+		{ID: "TASK-03", Title: "Independent"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(result.Merge.Merged, []string{"TASK-01", "TASK-03", "TASK-02"}) {
+		t.Errorf("unexpected merge order: %v", result.Merge.Merged)
+	}
+	if !sawBase {
+		t.Error("a task should start from the merged work of its dependencies")
+	}
+	files := testutil.Git(t, repo, "ls-tree", "--name-only", runner.SessionBranch())
+	for _, name := range []string{"TASK-01.txt", "TASK-02.txt", "TASK-03.txt"} {
+		if !strings.Contains(files, name) {
+			t.Errorf("expected %v on the session branch, got %v", name, files)
+		}
+	}
+	message := testutil.Git(t, repo, "log", "-1", "--format=%B", "bear/task/session-1/TASK-02")
+	if !strings.HasPrefix(message, "TASK-02: Add TASK-02") || !strings.Contains(message, "Bear-Covers: AC-01") ||
+		!strings.Contains(message, "Bear-Spec: .bear/") {
+		t.Errorf("unexpected commit message of TASK-02:\n%v", message)
+	}
+	if !slices.ContainsFunc(messages, func(msg ai.StreamMessage) bool {
+		return msg.Type == ai.StreamMessageTypeProgress && msg.Progress.TaskID == "TASK-02" &&
+			msg.Progress.Status == ai.ProgressStatusDone && msg.Progress.Message == "Add TASK-02"
+	}) {
+		t.Errorf("expected TASK-02 to be reported done, got %v", messages)
+	}
+	// The agents of the dependent tasks read the handoff through the store.
+	if handoff, err := store.Handoff("TASK-01"); err != nil || handoff != "TASK-01 is done." {
+		t.Errorf("expected the handoff of TASK-01, got %q, %v", handoff, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(repo, ".git", "bear")); len(entries) != 0 {
+		t.Errorf("expected the worktrees to be removed, got %v", entries)
+	}
+	if branch := testutil.Git(t, repo, "branch", "--show-current"); branch != "main" {
+		t.Errorf("the user's checkout must stay on main, got %v", branch)
+	}
+}
+
+func TestRunner_FailedTaskIsKeptOnItsBranchAndDependentsSkipped(t *testing.T) {
+	runner, _, repo := newTestRunner(t, func(dir string, task ai.Task) (ai.CodingResult, error) {
+		switch task.ID {
+		case "TASK-01":
+			testutil.WriteFile(t, dir, "partial.txt", "partial\n")
+			return ai.CodingResult{Done: false, Summary: "Start the export\n\nThe spec does not say which columns."}, nil
+		case "TASK-03":
+			return ai.CodingResult{}, errors.New("agent crashed")
+		}
+		testutil.WriteFile(t, dir, task.ID+".txt", task.ID+"\n")
+		return ai.CodingResult{Done: true, Summary: "Add " + task.ID}, nil
+	})
+
+	result, err := runner.Run(ai.Plan{Tasks: []ai.Task{
+		{ID: "TASK-01"},
+		{ID: "TASK-02", DependsOn: []string{"TASK-01"}},
+		{ID: "TASK-03"},
+		{ID: "TASK-04"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(result.Merge.Merged, []string{"TASK-04"}) {
+		t.Errorf("unexpected merged tasks: %v", result.Merge.Merged)
+	}
+	if !slices.Equal(result.Merge.Skipped, []string{"TASK-02"}) {
+		t.Errorf("unexpected skipped tasks: %v", result.Merge.Skipped)
+	}
+	if len(result.Failed) != 2 || result.Failed[0].TaskID != "TASK-01" || !errors.Is(result.Failed[0].Err, ErrTaskNotDone) ||
+		result.Failed[1].TaskID != "TASK-03" {
+		t.Errorf("unexpected failures: %+v", result.Failed)
+	}
+	// The work of the unfinished task can still be inspected.
+	if files := testutil.Git(t, repo, "ls-tree", "--name-only", "bear/task/session-1/TASK-01"); !strings.Contains(files, "partial.txt") {
+		t.Errorf("expected the unfinished work on the task branch, got %v", files)
+	}
+}
+
+func TestRunner_RejectsPlanWithUnknownDependency(t *testing.T) {
+	runner, _, _ := newTestRunner(t, func(string, ai.Task) (ai.CodingResult, error) {
+		t.Fatal("no task should be coded")
+		return ai.CodingResult{}, nil
+	})
+
+	_, err := runner.Run(ai.Plan{Tasks: []ai.Task{{ID: "TASK-01", DependsOn: []string{"TASK-09"}}}})
+	if !errors.Is(err, worktree.ErrUnknownDependency) {
+		t.Errorf("expected ErrUnknownDependency, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// subcommand is a command that runs instead of the interactive session when
+// its name is the first argument, for example `bear doctor`.
+type subcommand struct {
+	name        string
+	description string
+	// run returns the exit status of the process.
+	run func(cfg config, args []string, stdout, stderr io.Writer) int
+}
+
+func subcommands() []subcommand {
+	return []subcommand{
+		{
+			name:        "doctor",
+			description: "Check whether this machine can run Bear and its optional features.",
+			run:         runDoctor,
+		},
+		{
+			name:        "squash",
+			description: "Squash the commits of a session branch into one commit.",
+			run:         runSquash,
+		},
+		{
+			name:        "export-patches",
+			description: "Export the commits of a session branch as a patch series.",
+			run:         runExportPatches,
+		},
+		{
+			name:        "rollback",
+			description: "Put the workspace back to a snapshot taken during a session.",
+			run:         runRollback,
+		},
+		{
+			name:        "prompts",
+			description: "Print the effective prompt templates, with team overrides applied.",
+			run:         runPrompts,
+		},
+		{
+			name:        "trace",
+			description: "Show which plan tasks, commits and tests cover each spec requirement.",
+			run:         runTrace,
+		},
+		{
+			name:        "spec",
+			description: "Export an approved spec to HTML, JSON, YAML or an issue tracker.",
+			run:         runSpec,
+		},
+	}
+}
+
+// runSubcommand runs the subcommand named by args[0] and returns the exit
+// status of the process.
+func runSubcommand(cfg config, args []string, stdout, stderr io.Writer) int {
+	commands := subcommands()
+	index := slices.IndexFunc(commands, func(c subcommand) bool { return c.name == args[0] })
+	if index < 0 {
+		fmt.Fprintf(stderr, "unknown command: %v\n\n%v", args[0], usage(commands))
+		return 2
+	}
+	return commands[index].run(cfg, args[1:], stdout, stderr)
+}
+
+func usage(commands []subcommand) string {
+	var b strings.Builder
+	b.WriteString("Usage:\n  bear                 Start an interactive session.\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "  bear %-15v %v\n", c.name, c.description)
+	}
+	return b.String()
+}
+
+func exitWithSubcommand(cfg config) {
+	if len(os.Args) < 2 {
+		return
+	}
+	os.Exit(runSubcommand(cfg, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// newFlagSet returns a flag set that reports errors to stderr instead of
+// exiting, so that the subcommand can return its own exit status.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("bear "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
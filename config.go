@@ -1,10 +1,42 @@
 package main
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
 
 const (
 	ANTHROPIC_API_KEY_ENV_VAR = "BEAR_ANTHROPIC_API_KEY"
 	LOG_DIR_ENV_VAR           = "BEAR_LOG_DIR"
+	// Directory for the state that outlives a session, such as the recently
+	// used workspaces. Defaults to $XDG_STATE_HOME/bear or
+	// ~/.local/state/bear.
+	STATE_DIR_ENV_VAR = "BEAR_STATE_DIR"
+	// Directory for the user's configuration, such as prompt overrides.
+	// Defaults to $XDG_CONFIG_HOME/bear or ~/.config/bear.
+	CONFIG_DIR_ENV_VAR = "BEAR_CONFIG_DIR"
+	// If true, risky tool calls are sent to the user for approval instead of
+	// being denied by the agent's permission policy.
+	INTERACTIVE_TOOL_APPROVAL_ENV_VAR = "BEAR_INTERACTIVE_TOOL_APPROVAL"
+	// If true, the agent runs in a sandbox that can only write to the
+	// workspace. Bear refuses to start a session if sandboxing does not work
+	// or BEAR_ANTHROPIC_API_KEY is not set, since the sandboxed CLI cannot
+	// see its login. The network stays reachable, because the CLI talks to
+	// the API over it.
+	SANDBOX_ENV_VAR = "BEAR_SANDBOX"
+	// If true, every spec draft is also reviewed by a separate agent session
+	// on top of the deterministic lint checks.
+	SPEC_CRITIQUE_ENV_VAR = "BEAR_SPEC_CRITIQUE"
+	// Number of clarification rounds after which the spec is drafted without
+	// asking the agent for more questions. 0 means no limit.
+	MAX_CLARIFICATION_ROUNDS_ENV_VAR = "BEAR_MAX_CLARIFICATION_ROUNDS"
+	// Endpoint that `bear spec export -post` sends issues to, such as the
+	// issues API of a GitHub repository or a GitLab project.
+	ISSUE_URL_ENV_VAR = "BEAR_ISSUE_URL"
+	// Token for the issue endpoint. It is kept out of the command line so
+	// that it does not end up in the shell history.
+	ISSUE_TOKEN_ENV_VAR = "BEAR_ISSUE_TOKEN"
 )
 
 type config struct{}
@@ -24,3 +56,71 @@ func (c config) AnthropicAPIKey() string {
 func (c config) LogDir() string {
 	return loadEnvironmentVariable(LOG_DIR_ENV_VAR, "/tmp/bear_logs")
 }
+
+func (c config) StateDir() string {
+	if dir := loadEnvironmentVariable(STATE_DIR_ENV_VAR, ""); dir != "" {
+		return dir
+	}
+	if dir := loadEnvironmentVariable("XDG_STATE_HOME", ""); dir != "" {
+		return filepath.Join(dir, "bear")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "/tmp/bear_state"
+	}
+	return filepath.Join(homeDir, ".local", "state", "bear")
+}
+
+func (c config) ConfigDir() string {
+	if dir := loadEnvironmentVariable(CONFIG_DIR_ENV_VAR, ""); dir != "" {
+		return dir
+	}
+	if dir := loadEnvironmentVariable("XDG_CONFIG_HOME", ""); dir != "" {
+		return filepath.Join(dir, "bear")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "/tmp/bear_config"
+	}
+	return filepath.Join(homeDir, ".config", "bear")
+}
+
+func loadBoolEnvironmentVariable(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(loadEnvironmentVariable(key, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func loadIntEnvironmentVariable(key string, defaultValue int) int {
+	value, err := strconv.Atoi(loadEnvironmentVariable(key, strconv.Itoa(defaultValue)))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (c config) InteractiveToolApproval() bool {
+	return loadBoolEnvironmentVariable(INTERACTIVE_TOOL_APPROVAL_ENV_VAR, false)
+}
+
+func (c config) Sandbox() bool {
+	return loadBoolEnvironmentVariable(SANDBOX_ENV_VAR, false)
+}
+
+func (c config) SpecCritique() bool {
+	return loadBoolEnvironmentVariable(SPEC_CRITIQUE_ENV_VAR, false)
+}
+
+func (c config) MaxClarificationRounds() int {
+	return loadIntEnvironmentVariable(MAX_CLARIFICATION_ROUNDS_ENV_VAR, 5)
+}
+
+func (c config) IssueURL() string {
+	return loadEnvironmentVariable(ISSUE_URL_ENV_VAR, "")
+}
+
+func (c config) IssueToken() string {
+	return loadEnvironmentVariable(ISSUE_TOKEN_ENV_VAR, "")
+}
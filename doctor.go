@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/ai/claudecode"
+	"github.com/sds-lab-dev/bear-go/source"
+)
+
+// doctorCheck is a single check of `bear doctor`. A failed check that is not
+// required only disables an optional feature.
+type doctorCheck struct {
+	name     string
+	required bool
+	run      func(cfg config) (string, error)
+}
+
+func doctorChecks() []doctorCheck {
+	return []doctorCheck{
+		{
+			name:     "Claude Code CLI",
+			required: true,
+			run: func(config) (string, error) {
+				return claudecode.FindBinary()
+			},
+		},
+		{
+			name:     "Sandbox",
+			required: false,
+			run:      checkSandbox,
+		},
+		{
+			name:     "PDF sources",
+			required: false,
+			run: func(config) (string, error) {
+				return source.FindPDFToText()
+			},
+		},
+	}
+}
+
+func checkSandbox(cfg config) (string, error) {
+	bwrapPath, err := claudecode.CheckSandbox()
+	if err != nil {
+		if cfg.Sandbox() {
+			return "", fmt.Errorf("%v; %v is set, so sessions will fail to start", err, SANDBOX_ENV_VAR)
+		}
+		return "", err
+	}
+
+	status := "disabled"
+	if cfg.Sandbox() {
+		if cfg.AnthropicAPIKey() == "" {
+			return "", fmt.Errorf(
+				"%w: %v is set, but %v is not, and the sandboxed CLI cannot see its login",
+				claudecode.ErrSandboxNeedsAPIKey, SANDBOX_ENV_VAR, ANTHROPIC_API_KEY_ENV_VAR,
+			)
+		}
+		status = "enabled"
+	}
+	return fmt.Sprintf("works with %v (%v; set %v to change)", bwrapPath, status, SANDBOX_ENV_VAR), nil
+}
+
+func runDoctor(cfg config, _ []string, stdout, _ io.Writer) int {
+	exitCode := 0
+	for _, check := range doctorChecks() {
+		detail, err := check.run(cfg)
+		switch {
+		case err == nil:
+			fmt.Fprintf(stdout, "[ok]   %v: %v\n", check.name, detail)
+		case check.required:
+			fmt.Fprintf(stdout, "[fail] %v: %v\n", check.name, err)
+			exitCode = 1
+		default:
+			fmt.Fprintf(stdout, "[warn] %v: %v\n", check.name, err)
+		}
+	}
+	if userNamespaces, ok := unprivilegedUserNamespaces(); ok {
+		fmt.Fprintf(stdout, "       unprivileged user namespaces: %v\n", userNamespaces)
+	}
+	return exitCode
+}
+
+// unprivilegedUserNamespaces reports the kernel setting that decides whether
+// bubblewrap can run without setuid. ok is false if the setting is not exposed
+// by the kernel.
+func unprivilegedUserNamespaces() (string, bool) {
+	content, err := os.ReadFile("/proc/sys/kernel/unprivileged_userns_clone")
+	if err != nil {
+		return "", false
+	}
+	if strings.TrimSpace(string(content)) == "1" {
+		return "enabled", true
+	}
+	return "disabled", true
+}
@@ -0,0 +1,83 @@
+// Package export turns an approved spec into the formats that people outside
+// of Bear read: HTML pages, PDF-ready HTML, structured documents and issues in
+// an issue tracker.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// HTML renders the Markdown spec as a standalone HTML page.
+func HTML(title, markdown string) string {
+	return htmlDocument(title, markdown, screenStyle)
+}
+
+// PrintHTML renders the Markdown spec as a standalone HTML page laid out for
+// printing, so that a browser's "Save as PDF" gives a clean document.
+func PrintHTML(title, markdown string) string {
+	return htmlDocument(title, markdown, screenStyle+printStyle)
+}
+
+const screenStyle = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #1f2328; }
+main { max-width: 52rem; margin: 2rem auto; padding: 0 1rem; }
+h1, h2, h3 { line-height: 1.25; }
+h2 { border-bottom: 1px solid #d1d9e0; padding-bottom: 0.3em; }
+code { font-family: ui-monospace, Menlo, Consolas, monospace; background: #f6f8fa; padding: 0.1em 0.3em; border-radius: 4px; }
+pre { background: #f6f8fa; padding: 1em; overflow-x: auto; border-radius: 6px; }
+pre code { padding: 0; background: none; }
+blockquote { margin: 0; padding: 0 1em; color: #59636e; border-left: 0.25em solid #d1d9e0; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d1d9e0; padding: 0.3em 0.8em; }
+`
+
+const printStyle = `@page { size: A4; margin: 20mm 18mm; }
+@media print {
+  body { font-size: 11pt; color: #000; }
+  main { max-width: none; margin: 0; padding: 0; }
+  h1, h2, h3 { break-after: avoid; }
+  pre, blockquote, table, li { break-inside: avoid; }
+  pre { white-space: pre-wrap; }
+  a { color: inherit; }
+  a[href^="http"]::after { content: " (" attr(href) ")"; font-size: 90%; }
+}
+`
+
+func htmlDocument(title, markdown, style string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%v</title>
+<style>
+%v</style>
+</head>
+<body>
+<main>
+%v</main>
+</body>
+</html>
+`, html.EscapeString(title), style, renderMarkdown(markdown))
+}
+
+// markdownRenderer renders the Markdown of specs as CommonMark with the
+// GitHub tables, strikethrough and autolinks. Raw HTML is left out and links
+// with unsafe schemes such as javascript: are not linked.
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+func renderMarkdown(markdown string) string {
+	var b bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(markdown), &b); err != nil {
+		// Rendering into memory cannot fail; keep the text readable anyway.
+		return "<pre>" + html.EscapeString(markdown) + "</pre>\n"
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	markdown := "## Requirements & scope\n\n" +
+		"Intro with `code <b>` and **bold**, *italic* and snake_case_name.\n" +
+		"Second line of the paragraph.\n\n" +
+		"- REQ-01: first\n" +
+		"  continued\n" +
+		"  - nested\n" +
+		"- REQ-02: [docs](https://example.com) and [bad](javascript:alert)\n\n" +
+		"1. one\n2. two\n\n" +
+		"> quoted\n\n" +
+		"| Field | Type |\n| --- | --- |\n| a \\| b | `int` |\n\n" +
+		"```go\nif a < b {}\n```\n"
+
+	got := renderMarkdown(markdown + "<script>alert(1)</script>\n")
+	for _, want := range []string{
+		`<h2 id="requirements--scope">Requirements &amp; scope</h2>`,
+		"<p>Intro with <code>code &lt;b&gt;</code> and <strong>bold</strong>, <em>italic</em> and snake_case_name.\nSecond line of the paragraph.</p>",
+		"<ul>\n<li>REQ-01: first\ncontinued\n<ul>\n<li>nested</li>\n</ul>\n</li>\n",
+		`<a href="https://example.com">docs</a>`,
+		"<ol>\n<li>one</li>\n<li>two</li>\n</ol>",
+		"<blockquote>\n<p>quoted</p>\n</blockquote>",
+		"<th>Field</th>",
+		"<td>a | b</td>\n<td><code>int</code></td>",
+		"<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%v", want, got)
+		}
+	}
+	for _, unsafe := range []string{"javascript:", "<script>"} {
+		if strings.Contains(got, unsafe) {
+			t.Errorf("expected %q to be left out of:\n%v", unsafe, got)
+		}
+	}
+}
+
+func TestPrintHTML(t *testing.T) {
+	got := PrintHTML("A <title>", "# A\n")
+	for _, want := range []string{"<title>A &lt;title&gt;</title>", "@page", "@media print", `<h1 id="a">A</h1>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%v", want, got)
+		}
+	}
+	if strings.Contains(HTML("A", "# A\n"), "@page") {
+		t.Error("the screen HTML should not have the print layout")
+	}
+}
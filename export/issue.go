@@ -0,0 +1,149 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+var (
+	ErrUnknownTracker = errors.New("unknown issue tracker")
+	ErrIssueRejected  = errors.New("issue tracker rejected the issue")
+)
+
+type Tracker string
+
+const (
+	TrackerGitHub Tracker = "github"
+	TrackerGitLab Tracker = "gitlab"
+)
+
+func ParseTracker(name string) (Tracker, error) {
+	switch tracker := Tracker(strings.ToLower(name)); tracker {
+	case TrackerGitHub, TrackerGitLab:
+		return tracker, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownTracker, name)
+}
+
+// Issue is an approved spec as an issue, for the people who follow the work
+// in an issue tracker rather than in the session directory.
+type Issue struct {
+	Title  string
+	Body   string
+	Labels []string
+}
+
+// NewIssue makes an issue of the approved spec. The title of the spec becomes
+// the title of the issue, and the rest of the spec its body, followed by a
+// note on where it came from.
+func NewIssue(markdown, sessionID string, labels []string) Issue {
+	document := spec.ParseDocument(markdown, nil)
+	title := document.Title
+	if title == "" {
+		title = "Spec of Bear session " + sessionID
+	}
+
+	var body []string
+	titleDropped := false
+	for line := range strings.Lines(markdown) {
+		if !titleDropped && strings.HasPrefix(line, "# ") {
+			titleDropped = true
+			continue
+		}
+		body = append(body, line)
+	}
+	text := strings.TrimSpace(strings.Join(body, ""))
+	text += fmt.Sprintf("\n\n---\n\n_Exported from the approved spec of Bear session `%v`._\n", sessionID)
+	return Issue{Title: title, Body: text, Labels: labels}
+}
+
+// Payload returns the request body that creates the issue through the
+// tracker's REST API, or through a service that accepts the same body.
+func (i Issue) Payload(tracker Tracker) ([]byte, error) {
+	labels := i.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	var payload any
+	switch tracker {
+	case TrackerGitHub:
+		payload = struct {
+			Title  string   `json:"title"`
+			Body   string   `json:"body"`
+			Labels []string `json:"labels"`
+		}{i.Title, i.Body, labels}
+	case TrackerGitLab:
+		payload = struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Labels      string `json:"labels"`
+		}{i.Title, i.Body, strings.Join(labels, ",")}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTracker, tracker)
+	}
+
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal issue: %w", err)
+	}
+	return data, nil
+}
+
+// Post creates the issue by sending its payload to the endpoint, for example
+// https://api.github.com/repos/OWNER/REPO/issues or
+// https://gitlab.example.com/api/v4/projects/ID/issues. The token is sent the
+// way the tracker expects, if it is not empty. It returns the web URL of the
+// created issue, or an empty string if the response does not have one.
+func Post(client *http.Client, endpoint, token string, tracker Tracker, issue Issue) (string, error) {
+	payload, err := issue.Payload(tracker)
+	if err != nil {
+		return "", err
+	}
+	request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create issue request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	if token != "" {
+		switch tracker {
+		case TrackerGitHub:
+			request.Header.Set("Authorization", "Bearer "+token)
+		case TrackerGitLab:
+			request.Header.Set("PRIVATE-TOKEN", token)
+		}
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to send issue to %v: %w", endpoint, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response of %v: %w", endpoint, err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return "", fmt.Errorf("%w: %v: %v", ErrIssueRejected, response.Status, strings.TrimSpace(string(body)))
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+		WebURL  string `json:"web_url"`
+	}
+	// A stub or a proxy may answer with anything; the issue exists anyway.
+	if json.Unmarshal(body, &created) != nil {
+		return "", nil
+	}
+	if created.HTMLURL != "" {
+		return created.HTMLURL, nil
+	}
+	return created.WebURL, nil
+}
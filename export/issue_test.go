@@ -0,0 +1,89 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewIssue(t *testing.T) {
+	issue := NewIssue("# CSV export\n\n## Overview\n\nExport reports.\n", "session-1", []string{"spec"})
+
+	if issue.Title != "CSV export" {
+		t.Errorf("title = %q, want the spec title", issue.Title)
+	}
+	if !strings.HasPrefix(issue.Body, "## Overview\n\nExport reports.") || strings.Contains(issue.Body, "# CSV export") {
+		t.Errorf("body = %q, want the spec without its title", issue.Body)
+	}
+	if !strings.Contains(issue.Body, "Bear session `session-1`") {
+		t.Errorf("body = %q, want a note on the session", issue.Body)
+	}
+}
+
+func TestPost(t *testing.T) {
+	tests := []struct {
+		tracker    Tracker
+		authHeader string
+		authValue  string
+		bodyField  string
+		response   string
+		wantURL    string
+	}{
+		{TrackerGitHub, "Authorization", "Bearer secret", "body", `{"html_url":"https://github.test/1"}`, "https://github.test/1"},
+		{TrackerGitLab, "PRIVATE-TOKEN", "secret", "description", `{"web_url":"https://gitlab.test/1"}`, "https://gitlab.test/1"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.tracker), func(t *testing.T) {
+			var received map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get(tt.authHeader); got != tt.authValue {
+					t.Errorf("%v = %q, want %q", tt.authHeader, got, tt.authValue)
+				}
+				data, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(data, &received); err != nil {
+					t.Errorf("invalid payload: %v", err)
+				}
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			issue := Issue{Title: "CSV export", Body: "Export reports.", Labels: []string{"spec", "bear"}}
+			url, err := Post(server.Client(), server.URL, "secret", tt.tracker, issue)
+			if err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			if url != tt.wantURL {
+				t.Errorf("url = %q, want %q", url, tt.wantURL)
+			}
+			if received["title"] != "CSV export" || received[tt.bodyField] != "Export reports." {
+				t.Errorf("payload = %v, want the title and the %v", received, tt.bodyField)
+			}
+		})
+	}
+}
+
+func TestPost_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad credentials", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := Post(server.Client(), server.URL, "", TrackerGitHub, Issue{Title: "t"})
+	if !errors.Is(err, ErrIssueRejected) || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("Post() error = %v, want ErrIssueRejected with the response", err)
+	}
+}
+
+func TestParseTracker(t *testing.T) {
+	if tracker, err := ParseTracker("GitLab"); err != nil || tracker != TrackerGitLab {
+		t.Errorf("ParseTracker(GitLab) = %v, %v", tracker, err)
+	}
+	if _, err := ParseTracker("jira"); !errors.Is(err, ErrUnknownTracker) {
+		t.Errorf("ParseTracker(jira) error = %v, want ErrUnknownTracker", err)
+	}
+}
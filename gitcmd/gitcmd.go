@@ -0,0 +1,65 @@
+// Package gitcmd runs the git command line for the packages that read or
+// change the workspace repository, so that they report git failures alike.
+package gitcmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrNotFound      = errors.New("git is not installed")
+	ErrCommandFailed = errors.New("git command failed")
+)
+
+// Error is a git command that exited with a non-zero status. It matches
+// ErrCommandFailed.
+type Error struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"%v: git %v: exit status %d: %v",
+		ErrCommandFailed, strings.Join(e.Args, " "), e.ExitCode, e.Stderr,
+	)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrCommandFailed
+}
+
+// Run runs git in dir and returns its trimmed standard output.
+func Run(dir string, args ...string) (string, error) {
+	return RunWithEnv(dir, nil, args...)
+}
+
+// RunWithEnv is Run with extra environment variables, for example to point
+// git at another index file.
+func RunWithEnv(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			if errors.Is(err, exec.ErrNotFound) {
+				return "", ErrNotFound
+			}
+			return "", fmt.Errorf("%w: git %v: %v", ErrCommandFailed, strings.Join(args, " "), err)
+		}
+		return "", &Error{Args: args, ExitCode: exitErr.ExitCode(), Stderr: strings.TrimSpace(stderr.String())}
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
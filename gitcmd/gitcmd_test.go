@@ -0,0 +1,56 @@
+package gitcmd
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+
+	if _, err := Run(dir, "init", "--quiet"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output, err := Run(dir, "rev-parse", "--is-inside-work-tree")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "true" {
+		t.Errorf("expected trimmed output %q, got %q", "true", output)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	_, err := Run(t.TempDir(), "rev-parse", "--verify", "--quiet", "HEAD")
+
+	if !errors.Is(err, ErrCommandFailed) {
+		t.Fatalf("expected ErrCommandFailed, got %v", err)
+	}
+	var gitErr *Error
+	if !errors.As(err, &gitErr) || gitErr.ExitCode == 0 {
+		t.Errorf("expected the exit status, got %#v", err)
+	}
+}
+
+func TestRunWithEnv(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	output, err := RunWithEnv(t.TempDir(), []string{"GIT_AUTHOR_NAME=bear", "GIT_AUTHOR_EMAIL=bear@example.com"}, "var", "GIT_AUTHOR_IDENT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(output, "bear ") {
+		t.Errorf("expected the author from the environment, got %q", output)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+func runSquash(_ config, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("squash", stderr)
+	message := flags.String("m", "", "message of the squashed commit (default: a summary of the session)")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bear squash [-m message] <session-id>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	sessionID := flags.Arg(0)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to get current working directory: %v\n", err)
+		return 1
+	}
+	if *message == "" {
+		commits, err := worktree.SessionCommits(cwd, sessionID)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to read the session commits: %v\n", err)
+			return 1
+		}
+		*message = worktree.SquashMessage(sessionID, commits)
+	}
+	commit, err := worktree.Squash(cwd, sessionID, *message)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to squash session: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Squashed %v into %v\n", worktree.SessionBranch(sessionID), commit)
+	return 0
+}
+
+func runExportPatches(_ config, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("export-patches", stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bear export-patches <session-id> <output-dir>")
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to get current working directory: %v\n", err)
+		return 1
+	}
+	patches, err := worktree.ExportPatches(cwd, flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to export patches: %v\n", err)
+		return 1
+	}
+
+	for _, patch := range patches {
+		fmt.Fprintln(stdout, patch)
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+// Package testutil holds the helpers that the tests of several packages share,
+// such as creating a git repository to run against. It is imported by tests
+// only.
+package testutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+)
+
+// Git runs git in the directory and returns its output, failing the test if
+// git fails.
+func Git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := gitcmd.Run(dir, args...)
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+	return output
+}
+
+// WriteFile writes the file at the path relative to the directory, creating
+// the directories on the way.
+func WriteFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create directory for %v: %v", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %v: %v", name, err)
+	}
+}
+
+// WriteFiles writes the files for the tests that only need them to exist.
+// Each file holds its own name.
+func WriteFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		WriteFile(t, dir, name, name+"\n")
+	}
+}
+
+// InitRepository creates a repository without commits on the main branch. The
+// test is skipped if git is not installed, and the commits that it makes are
+// authored by a test identity.
+func InitRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := t.TempDir()
+	Git(t, dir, "init", "--quiet", "--initial-branch=main")
+	return dir
+}
+
+// NewRepository creates a repository with the files, written as WriteFiles
+// does, committed on the main branch.
+func NewRepository(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := InitRepository(t)
+	WriteFiles(t, dir, names...)
+	Git(t, dir, "add", "--all")
+	Git(t, dir, "commit", "--quiet", "--allow-empty", "-m", "initial")
+	return dir
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/google/uuid"
 	"github.com/sds-lab-dev/bear-go/ai"
 	"github.com/sds-lab-dev/bear-go/ai/claudecode"
 	"github.com/sds-lab-dev/bear-go/app"
+	"github.com/sds-lab-dev/bear-go/spec"
 )
 
 var (
@@ -17,6 +19,20 @@ var (
 
 func main() {
 	config := config{}
+	exitWithSubcommand(config)
+
+	// Broken team prompts and spec templates must be found before the user
+	// types a request. The workspace overrides are checked when the session
+	// starts.
+	if _, err := ai.LoadPrompts("", promptOverrideDirs(config, "")...); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load prompts (run `bear prompts dump` to inspect them): %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := spec.LoadTemplates(specTemplateDirs(config, "")...); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load spec templates: %v\n", err)
+		os.Exit(1)
+	}
+
 	if config.AnthropicAPIKey() == "" {
 		fmt.Printf(
 			"- WARNING:\n%v environment variable is not set or empty; trying to use a subscription plan, but this may fail if the key is required for authentication.\n\n",
@@ -28,16 +44,64 @@ func main() {
 		BuildVersion: buildVersion,
 		SessionID:    uuid.New().String(),
 		AIPorts: aiSession{
-			apiKey: config.AnthropicAPIKey(),
+			apiKey:                  config.AnthropicAPIKey(),
+			interactiveToolApproval: config.InteractiveToolApproval(),
+			sandbox:                 config.Sandbox(),
+			config:                  config,
 		},
-		LogDir: config.LogDir(),
+		SpecTemplates: loadSpecTemplates(config),
+		LogDir:        config.LogDir(),
+		StateDir:      config.StateDir(),
 	})
 }
 
 type aiSession struct {
-	apiKey string
+	apiKey                  string
+	interactiveToolApproval bool
+	sandbox                 bool
+	config                  config
 }
 
 func (r aiSession) NewSession(workingDir string) (ai.Session, error) {
-	return claudecode.NewClient(r.apiKey, workingDir)
+	client, err := claudecode.NewClient(r.apiKey, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.configure(client, workingDir); err != nil {
+		return nil, err
+	}
+	client.SetMaxClarificationRounds(r.config.MaxClarificationRounds())
+	if r.config.SpecCritique() {
+		client.EnableSpecCritique()
+	}
+	return client, nil
+}
+
+func (r aiSession) NewCodingAgent(workingDir string) (ai.CodingAgent, error) {
+	client, err := claudecode.NewCodingClient(r.apiKey, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.configure(client, workingDir); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// configure applies the settings that every agent of the session shares.
+func (r aiSession) configure(client *claudecode.Client, workingDir string) error {
+	prompts, err := ai.LoadPrompts(workingDir, promptOverrideDirs(r.config, workingDir)...)
+	if err != nil {
+		return fmt.Errorf("failed to load prompts: %w", err)
+	}
+	client.SetPrompts(prompts)
+	if r.interactiveToolApproval {
+		client.EnableInteractiveToolApproval()
+	}
+	if r.sandbox {
+		if err := client.EnableSandbox(); err != nil {
+			return fmt.Errorf("failed to enable sandbox (run `bear doctor` for details): %w", err)
+		}
+	}
+	return nil
 }
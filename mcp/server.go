@@ -0,0 +1,259 @@
+// Package mcp implements a minimal Model Context Protocol server that Bear
+// serves to the Claude Code CLI over the Streamable HTTP transport. It only
+// supports what Bear needs: tool listing and tool calls with JSON responses.
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+const (
+	protocolVersion = "2025-06-18"
+	serverName      = "bear"
+	endpointPath    = "/mcp"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	errorCodeParseError     = -32700
+	errorCodeMethodNotFound = -32601
+	errorCodeInvalidParams  = -32602
+)
+
+// Tool is a tool that the server exposes to the agent.
+type Tool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON schema of the tool arguments.
+	InputSchema map[string]any
+	// Handler runs the tool with its raw JSON arguments and returns the text
+	// content of the result. An error is reported to the agent as a tool
+	// result with isError set, not as a protocol error. The context is
+	// cancelled when the CLI abandons the request.
+	Handler func(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// Server is an MCP server listening on a loopback address. Any local process
+// can connect to the address, so every request must carry the bearer token
+// that only the CLI is given through ConfigJSON.
+type Server struct {
+	mu         sync.Mutex
+	tools      []Tool
+	token      string
+	listener   net.Listener
+	httpServer *http.Server
+}
+
+func NewServer(tools []Tool) *Server {
+	return &Server{tools: tools, token: rand.Text()}
+}
+
+// Start starts listening on a random loopback port.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen for MCP server: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(endpointPath, s.handleHTTP)
+
+	s.mu.Lock()
+	s.listener = listener
+	s.httpServer = &http.Server{Handler: mux}
+	s.mu.Unlock()
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(fmt.Sprintf("MCP server stopped unexpectedly: %v", err))
+		}
+	}()
+	log.Info(fmt.Sprintf("MCP server listening on %v", s.URL()))
+
+	return nil
+}
+
+// URL returns the endpoint URL of the server. It must be called after Start.
+func (s *Server) URL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("http://%v%v", s.listener.Addr().String(), endpointPath)
+}
+
+// Close stops the server and cancels the requests in progress.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Close()
+}
+
+// ConfigJSON returns the content of the `--mcp-config` file that registers the
+// server under the given name. It carries the bearer token of the server, so it
+// must not be passed on a command line.
+func (s *Server) ConfigJSON(name string) (string, error) {
+	config := map[string]any{
+		"mcpServers": map[string]any{
+			name: map[string]any{
+				"type": "http",
+				"url":  s.URL(),
+				"headers": map[string]string{
+					"Authorization": s.authorization(),
+				},
+			},
+		},
+	}
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal MCP config: %w", err)
+	}
+	return string(configBytes), nil
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *Server) authorization() string {
+	return "Bearer " + s.token
+}
+
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(s.authorization())) != 1 {
+		log.Warning(fmt.Sprintf("MCP server rejected a request without the token from %v", r.RemoteAddr))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	// The server never initiates messages, so the optional SSE stream opened
+	// with GET is not supported.
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeResponse(w, rpcResponse{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: errorCodeParseError, Message: err.Error()},
+		})
+		return
+	}
+	log.Debug(fmt.Sprintf("MCP server received request: method=%v, params=%s", request.Method, request.Params))
+
+	// Notifications have no ID and expect no response.
+	if len(request.ID) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	result, rpcErr := s.dispatch(r.Context(), request)
+	writeResponse(w, rpcResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Result:  result,
+		Error:   rpcErr,
+	})
+}
+
+func writeResponse(w http.ResponseWriter, response rpcResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error(fmt.Sprintf("failed to write MCP response: %v", err))
+	}
+}
+
+func (s *Server) dispatch(ctx context.Context, request rpcRequest) (any, *rpcError) {
+	switch request.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": serverName, "version": "1.0.0"},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.listTools()}, nil
+	case "tools/call":
+		return s.callTool(ctx, request.Params)
+	default:
+		return nil, &rpcError{
+			Code:    errorCodeMethodNotFound,
+			Message: fmt.Sprintf("method not found: %v", request.Method),
+		}
+	}
+}
+
+func (s *Server) listTools() []map[string]any {
+	tools := make([]map[string]any, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, map[string]any{
+			"name":        tool.Name,
+			"description": tool.Description,
+			"inputSchema": tool.InputSchema,
+		})
+	}
+	return tools
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, *rpcError) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: errorCodeInvalidParams, Message: err.Error()}
+	}
+
+	for _, tool := range s.tools {
+		if tool.Name != call.Name {
+			continue
+		}
+		text, err := tool.Handler(ctx, call.Arguments)
+		if err != nil {
+			log.Warning(fmt.Sprintf("MCP tool %v failed: %v", call.Name, err))
+			return toolResult(err.Error(), true), nil
+		}
+		return toolResult(text, false), nil
+	}
+
+	return nil, &rpcError{
+		Code:    errorCodeInvalidParams,
+		Message: fmt.Sprintf("unknown tool: %v", call.Name),
+	}
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
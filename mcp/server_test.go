@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func startTestServer(t *testing.T, tools []Tool) *Server {
+	t.Helper()
+	server := NewServer(tools)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func postRPC(t *testing.T, server *Server, body string) (int, map[string]any) {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, server.URL(), bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", server.authorization())
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("failed to post request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	var decoded map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode, decoded
+}
+
+func echoTool() Tool {
+	return Tool{
+		Name:        "echo",
+		Description: "Echoes the message.",
+		InputSchema: map[string]any{"type": "object"},
+		Handler: func(_ context.Context, arguments json.RawMessage) (string, error) {
+			var args struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(arguments, &args); err != nil {
+				return "", err
+			}
+			if args.Message == "" {
+				return "", errors.New("empty message")
+			}
+			return args.Message, nil
+		},
+	}
+}
+
+func TestServer_Initialize(t *testing.T) {
+	server := startTestServer(t, nil)
+
+	_, resp := postRPC(t, server, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+
+	result, ok := resp["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected result object, got %v", resp)
+	}
+	if result["protocolVersion"] != protocolVersion {
+		t.Errorf("unexpected protocol version: %v", result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]any)["tools"]; !ok {
+		t.Error("expected tools capability")
+	}
+}
+
+func TestServer_NotificationIsAccepted(t *testing.T) {
+	server := startTestServer(t, nil)
+
+	status, _ := postRPC(t, server, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	if status != http.StatusAccepted {
+		t.Errorf("expected status %v, got %v", http.StatusAccepted, status)
+	}
+}
+
+func TestServer_GetIsNotAllowed(t *testing.T) {
+	server := startTestServer(t, nil)
+
+	request, err := http.NewRequest(http.MethodGet, server.URL(), nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	request.Header.Set("Authorization", server.authorization())
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %v, got %v", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestServer_RequestWithoutTokenIsRejected(t *testing.T) {
+	server := startTestServer(t, []Tool{echoTool()})
+	other := NewServer(nil)
+
+	for _, authorization := range []string{"", other.authorization(), "Bearer "} {
+		request, err := http.NewRequest(http.MethodPost, server.URL(), strings.NewReader(
+			`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`,
+		))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("failed to post request: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("authorization %q: expected status %v, got %v", authorization, http.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_ListTools(t *testing.T) {
+	server := startTestServer(t, []Tool{echoTool()})
+
+	_, resp := postRPC(t, server, `{"jsonrpc":"2.0","id":"a","method":"tools/list"}`)
+
+	if resp["id"] != "a" {
+		t.Errorf("expected the request ID to be echoed, got %v", resp["id"])
+	}
+	tools := resp["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("expected 1 tool, got %d", len(tools))
+	}
+	tool := tools[0].(map[string]any)
+	if tool["name"] != "echo" || tool["description"] != "Echoes the message." {
+		t.Errorf("unexpected tool: %v", tool)
+	}
+}
+
+func TestServer_CallTool(t *testing.T) {
+	server := startTestServer(t, []Tool{echoTool()})
+
+	_, resp := postRPC(t, server, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hello"}}}`)
+
+	result := resp["result"].(map[string]any)
+	if result["isError"] != false {
+		t.Errorf("expected isError false, got %v", result["isError"])
+	}
+	content := result["content"].([]any)[0].(map[string]any)
+	if content["type"] != "text" || content["text"] != "hello" {
+		t.Errorf("unexpected content: %v", content)
+	}
+}
+
+func TestServer_CallToolErrorIsToolResult(t *testing.T) {
+	server := startTestServer(t, []Tool{echoTool()})
+
+	_, resp := postRPC(t, server, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{}}}`)
+
+	if _, ok := resp["error"]; ok {
+		t.Fatalf("tool failures must not be protocol errors: %v", resp)
+	}
+	result := resp["result"].(map[string]any)
+	if result["isError"] != true {
+		t.Errorf("expected isError true, got %v", result["isError"])
+	}
+	content := result["content"].([]any)[0].(map[string]any)
+	if content["text"] != "empty message" {
+		t.Errorf("unexpected content: %v", content)
+	}
+}
+
+func TestServer_UnknownToolAndMethod(t *testing.T) {
+	server := startTestServer(t, []Tool{echoTool()})
+
+	tests := []struct {
+		name string
+		body string
+		code float64
+	}{
+		{"unknown tool", `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"nope"}}`, errorCodeInvalidParams},
+		{"unknown method", `{"jsonrpc":"2.0","id":5,"method":"resources/list"}`, errorCodeMethodNotFound},
+	}
+	for _, tt := range tests {
+		_, resp := postRPC(t, server, tt.body)
+		rpcErr, ok := resp["error"].(map[string]any)
+		if !ok {
+			t.Errorf("%v: expected error, got %v", tt.name, resp)
+			continue
+		}
+		if rpcErr["code"] != tt.code {
+			t.Errorf("%v: expected code %v, got %v", tt.name, tt.code, rpcErr["code"])
+		}
+	}
+}
+
+func TestServer_ConfigJSON(t *testing.T) {
+	server := startTestServer(t, nil)
+
+	config, err := server.ConfigJSON("bear")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		MCPServers map[string]struct {
+			Type    string            `json:"type"`
+			URL     string            `json:"url"`
+			Headers map[string]string `json:"headers"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal([]byte(config), &decoded); err != nil {
+		t.Fatalf("invalid config JSON: %v", err)
+	}
+	entry, ok := decoded.MCPServers["bear"]
+	if !ok {
+		t.Fatalf("expected bear server entry, got %v", config)
+	}
+	if entry.Type != "http" || entry.URL != server.URL() || entry.Headers["Authorization"] != "Bearer "+server.token {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if !strings.HasPrefix(entry.URL, "http://127.0.0.1:") {
+		t.Errorf("expected a loopback URL, got %v", entry.URL)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/session"
+)
+
+// promptOverrideDirs returns the directories whose templates override the
+// built-in prompts, the user's own first so that the workspace wins. The
+// workspace directory is left out if workspaceDir is empty.
+func promptOverrideDirs(cfg config, workspaceDir string) []string {
+	dirs := []string{filepath.Join(cfg.ConfigDir(), "prompts")}
+	if workspaceDir != "" {
+		dirs = append(dirs, filepath.Join(workspaceDir, session.DirName, "prompts"))
+	}
+	return dirs
+}
+
+func runPrompts(cfg config, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("prompts", stderr)
+	workspaceDir := flags.String("workspace", "", "workspace whose overrides apply (default: the current directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bear prompts dump [-workspace dir] [prompt...]")
+		fmt.Fprintf(stderr, "Prompts: %v\n", ai.PromptNames())
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "dump" {
+		flags.Usage()
+		return 2
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		flags.Usage()
+		return 2
+	}
+
+	if *workspaceDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to get current working directory: %v\n", err)
+			return 1
+		}
+		*workspaceDir = cwd
+	}
+
+	names := ai.PromptNames()
+	if flags.NArg() > 0 {
+		names = nil
+		for _, arg := range flags.Args() {
+			name := ai.PromptName(arg)
+			if !slices.Contains(ai.PromptNames(), name) {
+				fmt.Fprintf(stderr, "unknown prompt: %v\n", arg)
+				flags.Usage()
+				return 2
+			}
+			names = append(names, name)
+		}
+	}
+
+	prompts, err := ai.LoadPrompts(*workspaceDir, promptOverrideDirs(cfg, *workspaceDir)...)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load prompts: %v\n", err)
+		return 1
+	}
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "==> %v (%v) <==\n", name, prompts.Source(name))
+		fmt.Fprintf(stdout, "Variables: %v\n\n", ai.PromptVariables(name))
+		fmt.Fprint(stdout, prompts.Text(name))
+	}
+	return 0
+}
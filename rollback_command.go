@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/snapshot"
+)
+
+func runRollback(_ config, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("rollback", stderr)
+	list := flags.Bool("list", false, "list the snapshots of the session instead of rolling back")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bear rollback [-list] <session-id> [stage]")
+		fmt.Fprintln(stderr, "Run it in the workspace of the session. Without a stage, the latest snapshot is restored.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 2
+	}
+	sessionID, stage := flags.Arg(0), flags.Arg(1)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to get current working directory: %v\n", err)
+		return 1
+	}
+	sessionDir, err := session.FindSessionDir(cwd, sessionID)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to find session: %v\n", err)
+		return 1
+	}
+	snapshots := snapshot.NewManager(cwd, sessionDir, sessionID)
+
+	if *list {
+		all, err := snapshots.List()
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to list snapshots: %v\n", err)
+			return 1
+		}
+		for _, s := range all {
+			fmt.Fprintf(stdout, "%v  %-8v %v\n", s.CreatedAt.Format("2006-01-02 15:04:05"), s.Kind, s.Stage)
+		}
+		return 0
+	}
+
+	restored, err := snapshots.Rollback(stage)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to roll back: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Rolled back %v to its state before %v.\n", restored.WorkspaceDir, restored.Stage)
+	fmt.Fprintf(stdout, "To undo, run: bear rollback %v %v\n", sessionID, snapshot.PreRollbackStage)
+	return 0
+}
@@ -0,0 +1,354 @@
+// Package session stores the artifacts of a Bear session on disk, under
+// ".bear/<yyyymmdd>/<session-id>/" in the workspace, so that they survive the
+// process and can be served to agents on demand.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+var (
+	ErrArtifactNotFound = errors.New("artifact not found")
+	ErrInvalidTaskID    = errors.New("invalid task ID")
+	ErrInvalidSessionID = errors.New("invalid session ID")
+)
+
+const (
+	// DirName is the directory in the workspace that holds the artifacts of
+	// every session.
+	DirName = ".bear"
+
+	userRequestFileName = "user-request.md"
+	sourcesFileName     = "sources.md"
+	attachmentsDirName  = "attachments"
+	specFileName        = "spec.md"
+	planFileName        = "plan.md"
+	decisionsFileName   = "decisions.jsonl"
+	progressFileName    = "progress.jsonl"
+)
+
+// Store reads and writes the artifacts of a single session. It implements
+// ai.ArtifactStore and is safe for concurrent use.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+var _ ai.ArtifactStore = (*Store)(nil)
+
+// NewStore returns the store of the session, whose directory is named after
+// the date the session started. The directory is created on the first write.
+func NewStore(workspaceDir, sessionID string, startedAt time.Time) *Store {
+	return &Store{
+		dir: filepath.Join(workspaceDir, DirName, startedAt.Format("20060102"), sessionID),
+	}
+}
+
+// Dir returns the directory that holds the session artifacts.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) SaveUserRequest(text string) error {
+	return s.writeFile(userRequestFileName, text)
+}
+
+// SaveRequestSources saves the text of the documents attached to the user
+// request, as the agent got them, next to the request.
+func (s *Store) SaveRequestSources(sources []ai.RequestSource) error {
+	var b strings.Builder
+	for i, source := range sources {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %v (%v)\n\n%v\n", source.Name, source.Kind, source.Text)
+	}
+	return s.writeFile(sourcesFileName, b.String())
+}
+
+// StageAttachment copies a file that the user attached into the session
+// directory, where the agent can read it, under the next attachment ID such as
+// "ATT-1". It returns the ID and the absolute path of the copy.
+func (s *Store) StageAttachment(name string, data []byte) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, attachmentsDirName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list attachments: %w", err)
+	}
+	id := fmt.Sprintf("ATT-%d", len(entries)+1)
+	path, err := filepath.Abs(filepath.Join(dir, id+"-"+filepath.Base(name)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve attachment path: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to stage attachment %v: %w", name, err)
+	}
+	return id, path, nil
+}
+
+func (s *Store) SaveApprovedSpec(spec string) error {
+	return s.writeFile(specFileName, spec)
+}
+
+func (s *Store) SavePlan(plan string) error {
+	return s.writeFile(planFileName, plan)
+}
+
+// SaveHandoff saves the report that the agent of the task left for the tasks
+// that depend on it.
+func (s *Store) SaveHandoff(taskID, report string) error {
+	fileName, err := handoffFileName(taskID)
+	if err != nil {
+		return err
+	}
+	return s.writeFile(fileName, report)
+}
+
+// SpecPath returns the path of the approved spec, which the commits of the
+// session refer to.
+func (s *Store) SpecPath() string {
+	return filepath.Join(s.dir, specFileName)
+}
+
+func (s *Store) ApprovedSpec() (string, error) {
+	return s.readFile(specFileName)
+}
+
+// Task returns the section of the plan that describes the task, from its
+// heading up to the next heading of the same or a higher level.
+func (s *Store) Task(taskID string) (string, error) {
+	number, err := parseTaskNumber(taskID)
+	if err != nil {
+		return "", err
+	}
+	plan, err := s.readFile(planFileName)
+	if err != nil {
+		return "", err
+	}
+
+	section, ok := findTaskSection(plan, number)
+	if !ok {
+		return "", fmt.Errorf("%w: %v is not in the plan", ErrArtifactNotFound, taskID)
+	}
+	return section, nil
+}
+
+// Tasks returns the plan section of every task, keyed by task ID, for example
+// "TASK-01".
+func (s *Store) Tasks() (map[string]string, error) {
+	plan, err := s.readFile(planFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := map[string]string{}
+	for _, line := range strings.Split(plan, "\n") {
+		match := taskHeadingPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		number, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+		if section, ok := findTaskSection(plan, number); ok {
+			tasks[fmt.Sprintf("TASK-%02d", number)] = section
+		}
+	}
+	return tasks, nil
+}
+
+func (s *Store) Handoff(taskID string) (string, error) {
+	fileName, err := handoffFileName(taskID)
+	if err != nil {
+		return "", err
+	}
+	return s.readFile(fileName)
+}
+
+func (s *Store) RecordDecision(decision ai.Decision) error {
+	return s.appendRecord(decisionsFileName, decision)
+}
+
+func (s *Store) ReportProgress(report ai.ProgressReport) error {
+	return s.appendRecord(progressFileName, report)
+}
+
+func (s *Store) writeFile(fileName, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, fileName), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write %v: %w", fileName, err)
+	}
+	return nil
+}
+
+func (s *Store) readFile(fileName string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := os.ReadFile(filepath.Join(s.dir, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %v", ErrArtifactNotFound, fileName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %v: %w", fileName, err)
+	}
+	return string(content), nil
+}
+
+// timestampedRecord is a line of a JSONL log, with the time it was appended.
+type timestampedRecord struct {
+	Time   time.Time `json:"time"`
+	Record any       `json:"record"`
+}
+
+func (s *Store) appendRecord(fileName string, record any) error {
+	line, err := json.Marshal(timestampedRecord{Time: time.Now(), Record: record})
+	if err != nil {
+		return fmt.Errorf("failed to marshal record for %v: %w", fileName, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(s.dir, fileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", fileName, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to %v: %w", fileName, err)
+	}
+	return nil
+}
+
+var taskIDPattern = regexp.MustCompile(`^(?i:TASK-)?(\d+)$`)
+
+// parseTaskNumber accepts "TASK-01", "TASK-1", "task-01" and "1" alike,
+// because agents are not consistent about the zero padding.
+func parseTaskNumber(taskID string) (int, error) {
+	match := taskIDPattern.FindStringSubmatch(strings.TrimSpace(taskID))
+	if match == nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTaskID, taskID)
+	}
+	number, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTaskID, taskID)
+	}
+	return number, nil
+}
+
+func handoffFileName(taskID string) (string, error) {
+	number, err := parseTaskNumber(taskID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("TASK-%02d.md", number), nil
+}
+
+var taskHeadingPattern = regexp.MustCompile(`^(#+)\s+TASK-(\d+)\b`)
+
+func findTaskSection(plan string, number int) (string, bool) {
+	lines := strings.Split(plan, "\n")
+
+	start, level := -1, 0
+	for i, line := range lines {
+		match := taskHeadingPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if n, err := strconv.Atoi(match[2]); err == nil && n == number {
+			start, level = i, len(match[1])
+			break
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+
+	end := len(lines)
+	inCodeBlock := false
+	for i := start + 1; i < len(lines); i++ {
+		// A shell comment in a code block looks like a heading.
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if !inCodeBlock && headingLevel(lines[i]) > 0 && headingLevel(lines[i]) <= level {
+			end = i
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines[start:end], "\n")), true
+}
+
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level == len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+// FindSessionDir returns the directory of the session in the workspace,
+// whatever the date it started on.
+func FindSessionDir(workspaceDir, sessionID string) (string, error) {
+	if err := validateSessionID(sessionID); err != nil {
+		return "", err
+	}
+	matches, err := filepath.Glob(filepath.Join(workspaceDir, DirName, "*", sessionID))
+	if err != nil {
+		return "", fmt.Errorf("failed to search session directory: %w", err)
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("%w: session %v in %v", ErrArtifactNotFound, sessionID, workspaceDir)
+	}
+	return matches[0], nil
+}
+
+// OpenStore returns the store of an existing session in the workspace.
+func OpenStore(workspaceDir, sessionID string) (*Store, error) {
+	dir, err := FindSessionDir(workspaceDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// validateSessionID rejects session IDs that would escape the .bear directory
+// or be interpreted as a glob.
+func validateSessionID(sessionID string) error {
+	if sessionID == "" || strings.ContainsAny(sessionID, `/\*?[`) || sessionID == "." || sessionID == ".." {
+		return fmt.Errorf("%w: %q", ErrInvalidSessionID, sessionID)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+const testPlan = `# Plan
+
+## Tasks
+
+### TASK-00: Install dependencies
+
+Add the modules.
+
+` + "```bash" + `
+# not a heading
+go get ./...
+` + "```" + `
+
+#### Acceptance
+
+- It builds.
+
+### TASK-01: Implement the client
+
+**Dependencies**: TASK-00
+
+## Risks
+
+None.
+`
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	startedAt := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	return NewStore(t.TempDir(), "session-1", startedAt)
+}
+
+func TestNewStore_Dir(t *testing.T) {
+	startedAt := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	store := NewStore("/workspace", "session-1", startedAt)
+
+	expected := filepath.Join("/workspace", ".bear", "20260218", "session-1")
+	if store.Dir() != expected {
+		t.Errorf("expected %q, got %q", expected, store.Dir())
+	}
+}
+
+func TestStore_SaveAndReadApprovedSpec(t *testing.T) {
+	store := newTestStore(t)
+
+	if _, err := store.ApprovedSpec(); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("expected ErrArtifactNotFound before the spec is saved, got %v", err)
+	}
+
+	if err := store.SaveApprovedSpec("# Spec\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spec, err := store.ApprovedSpec()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spec != "# Spec\n" {
+		t.Errorf("unexpected spec: %q", spec)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir(), "spec.md")); err != nil {
+		t.Errorf("expected spec.md in the session directory: %v", err)
+	}
+}
+
+func TestStore_SaveRequestSources(t *testing.T) {
+	store := newTestStore(t)
+
+	err := store.SaveRequestSources([]ai.RequestSource{
+		{Kind: ai.RequestSourceKindIssue, Name: "issue.json", Text: "# #42: Export"},
+		{Kind: ai.RequestSourceKindText, Name: "notes.md", Text: "Only CSV."},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(store.Dir(), "sources.md"))
+	if err != nil {
+		t.Fatalf("failed to read sources.md: %v", err)
+	}
+	expected := "# issue.json (issue)\n\n# #42: Export\n\n# notes.md (text)\n\nOnly CSV.\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
+
+func TestStore_Task(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.SavePlan(testPlan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		taskID       string
+		wantPrefix   string
+		wantContains string
+		wantMissing  string
+	}{
+		{"TASK-00", "### TASK-00: Install dependencies", "It builds.", "TASK-01"},
+		{"task-0", "### TASK-00: Install dependencies", "# not a heading", "Implement"},
+		{"1", "### TASK-01: Implement the client", "**Dependencies**: TASK-00", "Risks"},
+	}
+	for _, tt := range tests {
+		section, err := store.Task(tt.taskID)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.taskID, err)
+			continue
+		}
+		if !strings.HasPrefix(section, tt.wantPrefix) {
+			t.Errorf("%v: expected section to start with %q, got %q", tt.taskID, tt.wantPrefix, section)
+		}
+		if !strings.Contains(section, tt.wantContains) {
+			t.Errorf("%v: expected section to contain %q, got %q", tt.taskID, tt.wantContains, section)
+		}
+		if strings.Contains(section, tt.wantMissing) {
+			t.Errorf("%v: section should not contain %q, got %q", tt.taskID, tt.wantMissing, section)
+		}
+	}
+}
+
+func TestStore_Tasks(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.SavePlan(testPlan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tasks, err := store.Tasks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %v", tasks)
+	}
+	if !strings.Contains(tasks["TASK-00"], "It builds.") || !strings.HasPrefix(tasks["TASK-01"], "### TASK-01") {
+		t.Errorf("unexpected task sections: %#v", tasks)
+	}
+}
+
+func TestStore_TasksOfRenderedPlan(t *testing.T) {
+	store := newTestStore(t)
+	plan := ai.Plan{Tasks: []ai.Task{
+		{ID: "TASK-01", Title: "Add the CSV writer", Description: "Write rows as CSV.", Covers: []string{"REQ-01"}},
+		{ID: "TASK-02", Title: "Add the export button", Description: "Download the CSV.", Covers: []string{"AC-01"}, DependsOn: []string{"TASK-01"}},
+	}}
+	if err := store.SavePlan(plan.Markdown()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tasks, err := store.Tasks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, task := range plan.Tasks {
+		if tasks[task.ID] != strings.TrimSpace(task.Markdown()) {
+			t.Errorf("expected the section of %v, got %q", task.ID, tasks[task.ID])
+		}
+	}
+}
+
+func TestStore_TaskErrors(t *testing.T) {
+	store := newTestStore(t)
+
+	if _, err := store.Task("TASK-00"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("expected ErrArtifactNotFound without a plan, got %v", err)
+	}
+	if err := store.SavePlan(testPlan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Task("TASK-09"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("expected ErrArtifactNotFound for an unknown task, got %v", err)
+	}
+	if _, err := store.Task("../spec"); !errors.Is(err, ErrInvalidTaskID) {
+		t.Errorf("expected ErrInvalidTaskID, got %v", err)
+	}
+}
+
+func TestStore_Handoff(t *testing.T) {
+	store := newTestStore(t)
+
+	if err := store.SaveHandoff("TASK-1", "# Report\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir(), "TASK-01.md")); err != nil {
+		t.Errorf("expected the handoff to be saved as TASK-01.md: %v", err)
+	}
+
+	report, err := store.Handoff("TASK-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report != "# Report\n" {
+		t.Errorf("unexpected report: %q", report)
+	}
+	if _, err := store.Handoff("TASK-02"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("expected ErrArtifactNotFound, got %v", err)
+	}
+}
+
+func TestStore_RecordDecisionAndReportProgressAppendJSONL(t *testing.T) {
+	store := newTestStore(t)
+
+	decisions := []ai.Decision{
+		{TaskID: "TASK-00", Decision: "Use bubbletea v1", Rationale: "It is stable."},
+		{TaskID: "TASK-01", Decision: "Inject lookupEnv", Rationale: "It is testable."},
+	}
+	for _, decision := range decisions {
+		if err := store.RecordDecision(decision); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := store.ReportProgress(ai.ProgressReport{
+		TaskID: "TASK-00", Status: ai.ProgressStatusDone, Message: "All tests pass.",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(store.Dir(), "decisions.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to read decisions: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != len(decisions) {
+		t.Fatalf("expected %d lines, got %d", len(decisions), len(lines))
+	}
+	for i, line := range lines {
+		var record struct {
+			Time   time.Time   `json:"time"`
+			Record ai.Decision `json:"record"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if record.Record != decisions[i] || record.Time.IsZero() {
+			t.Errorf("line %d: unexpected record %+v", i, record)
+		}
+	}
+
+	progress, err := os.ReadFile(filepath.Join(store.Dir(), "progress.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to read progress: %v", err)
+	}
+	if !strings.Contains(string(progress), `"status":"done"`) {
+		t.Errorf("unexpected progress log: %s", progress)
+	}
+}
+
+func TestFindSessionDir(t *testing.T) {
+	workspaceDir := t.TempDir()
+	store := NewStore(workspaceDir, "session-1", time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC))
+	if err := store.SaveUserRequest("request"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir, err := FindSessionDir(workspaceDir, "session-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir != store.Dir() {
+		t.Errorf("expected %q, got %q", store.Dir(), dir)
+	}
+
+	if _, err := FindSessionDir(workspaceDir, "session-2"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("expected ErrArtifactNotFound, got %v", err)
+	}
+	for _, invalid := range []string{"", "..", "a/b", "*"} {
+		if _, err := FindSessionDir(workspaceDir, invalid); !errors.Is(err, ErrInvalidSessionID) {
+			t.Errorf("%q: expected ErrInvalidSessionID, got %v", invalid, err)
+		}
+	}
+}
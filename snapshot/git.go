@@ -0,0 +1,197 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/session"
+)
+
+func gitRepositoryRoot(dir string) (string, bool) {
+	root, err := gitcmd.Run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", false
+	}
+	// A repository without commits has no HEAD to restore the index from.
+	if _, err := gitcmd.Run(root, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return "", false
+	}
+	return root, true
+}
+
+// excludeSessionArtifacts is the pathspec that keeps the session artifacts out
+// of the snapshot, wherever the .bear directory is in the repository.
+var excludeSessionArtifacts = fmt.Sprintf(":(glob,exclude)**/%v/**", session.DirName)
+
+// workspacePathspec returns the pathspec of the workspace of the snapshot,
+// relative to the root of the repository. Snapshots that have no path were
+// taken of the whole repository.
+func workspacePathspec(snapshot Snapshot) string {
+	if snapshot.Path == "" {
+		return "."
+	}
+	return snapshot.Path
+}
+
+func (m *Manager) takeGit(repoRoot, stage string) (Snapshot, error) {
+	// The workspace may be a subdirectory of the repository, and only the
+	// workspace is captured, so that a rollback leaves the rest alone.
+	prefix, err := gitcmd.Run(m.workspaceDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	path := strings.TrimSuffix(prefix, "/")
+
+	head, err := gitcmd.Run(repoRoot, "rev-parse", "HEAD")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	indexTree, err := gitcmd.Run(repoRoot, "write-tree")
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to record the staged changes: %w", err)
+	}
+
+	// Stage the working tree of the workspace into a copy of the index,
+	// leaving the user's index as it is.
+	indexPath, err := gitcmd.Run(repoRoot, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	tmpIndex, err := copyToTempFile(indexPath, "bear-snapshot-index-*")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer os.Remove(tmpIndex)
+
+	snapshot := Snapshot{
+		Stage:        stage,
+		Kind:         KindGit,
+		WorkspaceDir: repoRoot,
+		Path:         path,
+		Head:         head,
+		IndexTree:    indexTree,
+	}
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := gitcmd.RunWithEnv(repoRoot, env, "add", "--all", "--", workspacePathspec(snapshot), excludeSessionArtifacts); err != nil {
+		return Snapshot{}, err
+	}
+	tree, err := gitcmd.RunWithEnv(repoRoot, env, "write-tree")
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	message := fmt.Sprintf("Bear snapshot of session %v before %v", m.sessionID, stage)
+	commit, err := gitcmd.Run(repoRoot, "commit-tree", tree, "-p", head, "-m", message)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	// The ref keeps the commit from being garbage collected.
+	ref := fmt.Sprintf("refs/bear/snapshots/%v/%v", m.sessionID, stage)
+	if _, err := gitcmd.Run(repoRoot, "update-ref", ref, commit); err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot.CreatedAt = time.Now()
+	snapshot.Commit = commit
+	return snapshot, nil
+}
+
+func (m *Manager) restoreGit(snapshot Snapshot) error {
+	dir := snapshot.WorkspaceDir
+	pathspec := workspacePathspec(snapshot)
+
+	// Check the workspace out of the snapshot into a temporary index that
+	// holds the snapshot, so that the untracked files are those that did not
+	// exist then, and delete them. Only the workspace is touched; ignored
+	// files and the session artifacts are kept.
+	indexPath, err := gitcmd.Run(dir, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return err
+	}
+	tmpIndex, err := copyToTempFile(indexPath, "bear-rollback-index-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpIndex)
+
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := gitcmd.RunWithEnv(dir, env, "read-tree", snapshot.Commit+"^{tree}"); err != nil {
+		return err
+	}
+	// git checkout fails on a pathspec that matches nothing, as it does when
+	// the workspace had no files then.
+	if hasPath(dir, snapshot.Commit, snapshot.Path) {
+		if _, err := gitcmd.RunWithEnv(dir, env, "checkout", snapshot.Commit, "--", pathspec); err != nil {
+			return err
+		}
+	}
+	if _, err := gitcmd.RunWithEnv(dir, env, "clean", "--force", "-d", "--exclude", session.DirName, "--", pathspec); err != nil {
+		return err
+	}
+
+	// Bear never moves the user's branch, but the user may have; only go
+	// back to the old staged tree if the old HEAD is still checked out.
+	head, err := gitcmd.Run(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	indexTree := "HEAD"
+	if head == snapshot.Head {
+		indexTree = snapshot.IndexTree
+	}
+	return resetIndex(dir, indexTree, snapshot.Path)
+}
+
+// hasPath reports whether the tree-ish has the path, where an empty path is
+// the root of the tree.
+func hasPath(dir, treeish, path string) bool {
+	_, err := gitcmd.Run(dir, "rev-parse", "--verify", "--quiet", treeish+":"+path)
+	return err == nil
+}
+
+// resetIndex makes the entries of the user's index under the path match the
+// tree-ish, leaving the entries outside the path as they are.
+func resetIndex(dir, treeish, path string) error {
+	if path == "" {
+		_, err := gitcmd.Run(dir, "read-tree", treeish)
+		return err
+	}
+	if _, err := gitcmd.Run(dir, "rm", "--cached", "-r", "--force", "--quiet", "--ignore-unmatch", "--", path); err != nil {
+		return err
+	}
+	if !hasPath(dir, treeish, path) {
+		return nil
+	}
+	_, err := gitcmd.Run(dir, "read-tree", "--prefix="+path+"/", treeish+":"+path)
+	return err
+}
+
+func copyToTempFile(path, pattern string) (string, error) {
+	tmp, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tmp.Close()
+
+	source, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// A fresh repository may not have an index yet.
+		return tmp.Name(), nil
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to open %v: %w", filepath.Base(path), err)
+	}
+	defer source.Close()
+
+	if _, err := io.Copy(tmp, source); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to copy %v: %w", filepath.Base(path), err)
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,234 @@
+// Package snapshot captures the state of the workspace before a stage that can
+// change files, and puts the workspace back to a captured state on rollback.
+//
+// A git workspace is captured as a commit object built from a temporary index,
+// so that neither the user's index nor the working tree is touched. Any other
+// directory is captured as a tarball.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/session"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrInvalidStage     = errors.New("invalid stage name")
+)
+
+// snapshotsDirName is the directory in the session directory that holds the
+// snapshot metadata and tarballs.
+const snapshotsDirName = "snapshots"
+
+// PreRollbackStage is the stage of the snapshot that Rollback takes before it
+// changes anything, so that a rollback can itself be undone.
+const PreRollbackStage = "pre-rollback"
+
+type Kind string
+
+const (
+	KindGit     Kind = "git"
+	KindTarball Kind = "tarball"
+)
+
+type Snapshot struct {
+	Stage     string    `json:"stage"`
+	Kind      Kind      `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+	// WorkspaceDir is the directory that was captured. For a git workspace it
+	// is the root of the repository, and Path is the workspace relative to
+	// it, which is empty when the workspace is the root. Only the workspace
+	// is captured and restored.
+	WorkspaceDir string `json:"workspace_dir"`
+	Path         string `json:"path,omitempty"`
+
+	// Used when Kind is KindGit. Commit holds the working tree, including the
+	// untracked files that are not ignored. Head and IndexTree are the HEAD
+	// commit and the staged tree at the time of the snapshot.
+	Commit    string `json:"commit,omitempty"`
+	Head      string `json:"head,omitempty"`
+	IndexTree string `json:"index_tree,omitempty"`
+
+	// Used when Kind is KindTarball.
+	Archive string `json:"archive,omitempty"`
+}
+
+// Manager takes and restores the snapshots of a session. The metadata is kept
+// in the session directory, which is never captured nor restored.
+type Manager struct {
+	workspaceDir string
+	sessionDir   string
+	sessionID    string
+}
+
+func NewManager(workspaceDir, sessionDir, sessionID string) *Manager {
+	return &Manager{
+		workspaceDir: workspaceDir,
+		sessionDir:   sessionDir,
+		sessionID:    sessionID,
+	}
+}
+
+var stagePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Take captures the workspace before the stage. Taking a snapshot of a stage
+// again replaces the previous one.
+func (m *Manager) Take(stage string) (Snapshot, error) {
+	if !stagePattern.MatchString(stage) {
+		return Snapshot{}, fmt.Errorf("%w: %q", ErrInvalidStage, stage)
+	}
+
+	var snapshot Snapshot
+	var err error
+	if repoRoot, ok := gitRepositoryRoot(m.workspaceDir); ok {
+		snapshot, err = m.takeGit(repoRoot, stage)
+	} else {
+		snapshot, err = m.takeTarball(stage)
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to take snapshot before %v: %w", stage, err)
+	}
+
+	if err := m.save(snapshot); err != nil {
+		return Snapshot{}, err
+	}
+	log.Info(fmt.Sprintf("took workspace snapshot: %#v", snapshot))
+	return snapshot, nil
+}
+
+// Rollback puts the workspace back to the snapshot of the stage, or to the
+// latest snapshot if stage is empty. Files that are ignored by git are left
+// alone. It first takes a PreRollbackStage snapshot so that the rollback can
+// be undone by rolling back to PreRollbackStage.
+func (m *Manager) Rollback(stage string) (Snapshot, error) {
+	var snapshot Snapshot
+	var err error
+	if stage == "" {
+		snapshot, err = m.Latest()
+	} else {
+		snapshot, err = m.Load(stage)
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	// Undoing a rollback must not overwrite the snapshot it restores.
+	if snapshot.Stage != PreRollbackStage {
+		if _, err := m.Take(PreRollbackStage); err != nil {
+			return Snapshot{}, fmt.Errorf("refusing to roll back without a safety snapshot: %w", err)
+		}
+	}
+
+	switch snapshot.Kind {
+	case KindGit:
+		err = m.restoreGit(snapshot)
+	case KindTarball:
+		err = m.restoreTarball(snapshot)
+	default:
+		err = fmt.Errorf("unknown snapshot kind: %q", snapshot.Kind)
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to roll back to %v: %w", snapshot.Stage, err)
+	}
+
+	log.Info(fmt.Sprintf("rolled back workspace to snapshot: %#v", snapshot))
+	return snapshot, nil
+}
+
+// List returns the snapshots of the session, oldest first.
+func (m *Manager) List() ([]Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(m.snapshotsDir(), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(paths))
+	for _, path := range paths {
+		snapshot, err := readSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Latest returns the most recent snapshot that is not a PreRollbackStage
+// snapshot, so that rolling back twice does not undo the first rollback.
+func (m *Manager) Latest() (Snapshot, error) {
+	snapshots, err := m.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if snapshots[i].Stage != PreRollbackStage {
+			return snapshots[i], nil
+		}
+	}
+	return Snapshot{}, fmt.Errorf("%w: session %v has no snapshots", ErrSnapshotNotFound, m.sessionID)
+}
+
+func (m *Manager) Load(stage string) (Snapshot, error) {
+	if !stagePattern.MatchString(stage) {
+		return Snapshot{}, fmt.Errorf("%w: %q", ErrInvalidStage, stage)
+	}
+	snapshot, err := readSnapshot(m.metadataPath(stage))
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, fmt.Errorf("%w: stage %v of session %v", ErrSnapshotNotFound, stage, m.sessionID)
+	}
+	return snapshot, err
+}
+
+func (m *Manager) snapshotsDir() string {
+	return filepath.Join(m.sessionDir, snapshotsDirName)
+}
+
+func (m *Manager) metadataPath(stage string) string {
+	return filepath.Join(m.snapshotsDir(), stage+".json")
+}
+
+func (m *Manager) save(snapshot Snapshot) error {
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := os.MkdirAll(m.snapshotsDir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(m.metadataPath(snapshot.Stage), content, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot metadata: %w", err)
+	}
+	return nil
+}
+
+func readSnapshot(path string) (Snapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot metadata %v: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// isSessionArtifact reports whether the path, relative to the workspace,
+// belongs to the session artifacts, which must survive a rollback.
+func isSessionArtifact(relativePath string) bool {
+	first, _, _ := strings.Cut(filepath.ToSlash(relativePath), "/")
+	return first == session.DirName
+}
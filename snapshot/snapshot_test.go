@@ -0,0 +1,217 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+)
+
+func assertFile(t *testing.T, dir, name, expected string) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Errorf("expected %v to exist: %v", name, err)
+		return
+	}
+	if string(content) != expected {
+		t.Errorf("%v: expected %q, got %q", name, expected, content)
+	}
+}
+
+func assertNoFile(t *testing.T, dir, name string) {
+	t.Helper()
+	if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %v not to exist", name)
+	}
+}
+
+// workspaceStatus returns the status of the workspace without the session
+// artifacts, which the snapshots themselves add.
+func workspaceStatus(t *testing.T, dir string) string {
+	t.Helper()
+	return testutil.Git(t, dir, "status", "--porcelain", "--", ".", ":(exclude).bear")
+}
+
+func newGitWorkspace(t *testing.T) string {
+	t.Helper()
+	dir := testutil.InitRepository(t)
+	testutil.WriteFile(t, dir, "tracked.txt", "original\n")
+	testutil.WriteFile(t, dir, "deleted.txt", "keep me\n")
+	testutil.WriteFile(t, dir, ".gitignore", "ignored.txt\n")
+	testutil.Git(t, dir, "add", ".")
+	testutil.Git(t, dir, "commit", "--quiet", "-m", "initial")
+	return dir
+}
+
+func newTestManager(dir string) *Manager {
+	return NewManager(dir, filepath.Join(dir, ".bear", "20260218", "session-1"), "session-1")
+}
+
+func TestManager_GitRollback(t *testing.T) {
+	dir := newGitWorkspace(t)
+	// State before the stage: a staged change and an untracked file.
+	testutil.WriteFile(t, dir, "tracked.txt", "staged\n")
+	testutil.Git(t, dir, "add", "tracked.txt")
+	testutil.WriteFile(t, dir, "untracked.txt", "mine\n")
+	statusBefore := workspaceStatus(t, dir)
+
+	m := newTestManager(dir)
+	snapshot, err := m.Take("coding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snapshot.Kind != KindGit {
+		t.Fatalf("expected a git snapshot, got %v", snapshot.Kind)
+	}
+	if status := workspaceStatus(t, dir); status != statusBefore {
+		t.Errorf("taking a snapshot must not touch the index: %q", status)
+	}
+
+	// The agent's changes.
+	testutil.WriteFile(t, dir, "tracked.txt", "agent\n")
+	testutil.WriteFile(t, dir, "untracked.txt", "agent\n")
+	os.Remove(filepath.Join(dir, "deleted.txt"))
+	testutil.WriteFile(t, dir, "new/agent.txt", "agent\n")
+	testutil.WriteFile(t, dir, "ignored.txt", "build output\n")
+	testutil.WriteFile(t, dir, ".bear/20260218/session-1/spec.md", "spec\n")
+
+	restored, err := m.Rollback("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if restored.Stage != "coding" {
+		t.Errorf("expected the latest snapshot, got %v", restored.Stage)
+	}
+	assertFile(t, dir, "tracked.txt", "staged\n")
+	assertFile(t, dir, "untracked.txt", "mine\n")
+	assertFile(t, dir, "deleted.txt", "keep me\n")
+	assertNoFile(t, dir, "new/agent.txt")
+	assertFile(t, dir, "ignored.txt", "build output\n")
+	assertFile(t, dir, ".bear/20260218/session-1/spec.md", "spec\n")
+	if status := workspaceStatus(t, dir); status != statusBefore {
+		t.Errorf("expected the index to be restored:\nwant %q\ngot  %q", statusBefore, status)
+	}
+}
+
+func TestManager_GitRollbackKeepsTheRestOfTheRepository(t *testing.T) {
+	root := newGitWorkspace(t)
+	testutil.WriteFile(t, root, "service/main.go", "package main\n")
+	testutil.WriteFile(t, root, "service/old.go", "package main\n")
+	testutil.Git(t, root, "add", ".")
+	testutil.Git(t, root, "commit", "--quiet", "-m", "service")
+	dir := filepath.Join(root, "service")
+
+	m := newTestManager(dir)
+	snapshot, err := m.Take("coding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snapshot.WorkspaceDir != root || snapshot.Path != "service" {
+		t.Errorf("expected the workspace service in %v, got %v in %v", root, snapshot.Path, snapshot.WorkspaceDir)
+	}
+
+	// The agent's changes in the workspace.
+	testutil.WriteFile(t, dir, "main.go", "package agent\n")
+	testutil.WriteFile(t, dir, "agent.go", "package agent\n")
+	testutil.Git(t, dir, "add", "agent.go")
+	os.Remove(filepath.Join(dir, "old.go"))
+	// The user's changes outside of it, made after the snapshot.
+	testutil.WriteFile(t, root, "tracked.txt", "staged later\n")
+	testutil.Git(t, root, "add", "tracked.txt")
+	testutil.WriteFile(t, root, "notes.txt", "later\n")
+	statusOutside := testutil.Git(t, root, "status", "--porcelain", "--", ".", ":(exclude)service")
+
+	if _, err := m.Rollback(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertFile(t, dir, "main.go", "package main\n")
+	assertFile(t, dir, "old.go", "package main\n")
+	assertNoFile(t, dir, "agent.go")
+	if status := workspaceStatus(t, dir); status != "" {
+		t.Errorf("expected the workspace to be clean, got %q", status)
+	}
+	assertFile(t, root, "tracked.txt", "staged later\n")
+	assertFile(t, root, "notes.txt", "later\n")
+	if status := testutil.Git(t, root, "status", "--porcelain", "--", ".", ":(exclude)service"); status != statusOutside {
+		t.Errorf("expected the rest of the repository to be untouched:\nwant %q\ngot  %q", statusOutside, status)
+	}
+}
+
+func TestManager_RollbackCanBeUndone(t *testing.T) {
+	dir := newGitWorkspace(t)
+	m := newTestManager(dir)
+	if _, err := m.Take("coding"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testutil.WriteFile(t, dir, "tracked.txt", "agent\n")
+
+	if _, err := m.Rollback("coding"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFile(t, dir, "tracked.txt", "original\n")
+
+	if _, err := m.Rollback(PreRollbackStage); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFile(t, dir, "tracked.txt", "agent\n")
+
+	latest, err := m.Latest()
+	if err != nil || latest.Stage != "coding" {
+		t.Errorf("the safety snapshot must not be the latest snapshot, got %v, %v", latest.Stage, err)
+	}
+}
+
+func TestManager_TarballRollback(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFile(t, dir, "main.go", "package main\n")
+	testutil.WriteFile(t, dir, "sub/data.txt", "data\n")
+	if err := os.Symlink("main.go", filepath.Join(dir, "link.go")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	m := newTestManager(dir)
+	snapshot, err := m.Take("coding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snapshot.Kind != KindTarball {
+		t.Fatalf("expected a tarball snapshot, got %v", snapshot.Kind)
+	}
+
+	testutil.WriteFile(t, dir, "main.go", "package broken\n")
+	os.RemoveAll(filepath.Join(dir, "sub"))
+	testutil.WriteFile(t, dir, "agent.txt", "agent\n")
+
+	if _, err := m.Rollback("coding"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertFile(t, dir, "main.go", "package main\n")
+	assertFile(t, dir, "sub/data.txt", "data\n")
+	assertNoFile(t, dir, "agent.txt")
+	if target, err := os.Readlink(filepath.Join(dir, "link.go")); err != nil || target != "main.go" {
+		t.Errorf("expected the symlink to be restored, got %q, %v", target, err)
+	}
+	if _, err := m.Load(PreRollbackStage); err != nil {
+		t.Errorf("expected a safety snapshot: %v", err)
+	}
+}
+
+func TestManager_Errors(t *testing.T) {
+	m := newTestManager(t.TempDir())
+
+	if _, err := m.Rollback(""); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+	if _, err := m.Rollback("coding"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+	if _, err := m.Take("../escape"); !errors.Is(err, ErrInvalidStage) {
+		t.Errorf("expected ErrInvalidStage, got %v", err)
+	}
+}
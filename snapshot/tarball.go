@@ -0,0 +1,201 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func (m *Manager) takeTarball(stage string) (Snapshot, error) {
+	if err := os.MkdirAll(m.snapshotsDir(), 0o755); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	// Write to a temporary file first so that a failure does not destroy the
+	// previous snapshot of the stage.
+	archive := filepath.Join(m.snapshotsDir(), stage+".tar.gz")
+	tmpArchive := archive + ".tmp"
+	if err := writeTarball(m.workspaceDir, tmpArchive); err != nil {
+		os.Remove(tmpArchive)
+		return Snapshot{}, err
+	}
+	if err := os.Rename(tmpArchive, archive); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to save snapshot archive: %w", err)
+	}
+
+	return Snapshot{
+		Stage:        stage,
+		Kind:         KindTarball,
+		CreatedAt:    time.Now(),
+		WorkspaceDir: m.workspaceDir,
+		Archive:      archive,
+	}, nil
+}
+
+func writeTarball(workspaceDir, archive string) (err error) {
+	file, err := os.Create(archive)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot archive: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close snapshot archive: %w", closeErr)
+		}
+	}()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	walkErr := filepath.WalkDir(workspaceDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(workspaceDir, path)
+		if err != nil || relativePath == "." {
+			return err
+		}
+		if isSessionArtifact(relativePath) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return addToTarball(tarWriter, path, relativePath, entry)
+	})
+	if walkErr != nil {
+		return fmt.Errorf("failed to archive workspace: %w", walkErr)
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	return nil
+}
+
+func addToTarball(tarWriter *tar.Writer, path, relativePath string, entry fs.DirEntry) error {
+	info, err := entry.Info()
+	if err != nil {
+		return err
+	}
+
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	} else if !info.IsDir() && !info.Mode().IsRegular() {
+		// Sockets, pipes and devices cannot be restored meaningfully.
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(relativePath)
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(tarWriter, file)
+	return err
+}
+
+func (m *Manager) restoreTarball(snapshot Snapshot) error {
+	archive, err := os.Open(snapshot.Archive)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot archive: %w", err)
+	}
+	defer archive.Close()
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot archive: %w", err)
+	}
+	defer gzipReader.Close()
+
+	if err := clearWorkspace(snapshot.WorkspaceDir); err != nil {
+		return err
+	}
+	return extractTarball(tar.NewReader(gzipReader), snapshot.WorkspaceDir)
+}
+
+// clearWorkspace removes everything in the workspace except the session
+// artifacts.
+func clearWorkspace(workspaceDir string) error {
+	entries, err := os.ReadDir(workspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to list workspace: %w", err)
+	}
+	for _, entry := range entries {
+		if isSessionArtifact(entry.Name()) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(workspaceDir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to clear workspace: %w", err)
+		}
+	}
+	return nil
+}
+
+func extractTarball(tarReader *tar.Reader, workspaceDir string) error {
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid snapshot archive: %w", err)
+		}
+
+		path := filepath.Join(workspaceDir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(path, filepath.Clean(workspaceDir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid snapshot archive: %q is outside the workspace", header.Name)
+		}
+
+		mode := fs.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, mode)
+		case tar.TypeSymlink:
+			err = os.Symlink(header.Linkname, path)
+		case tar.TypeReg:
+			err = extractFile(tarReader, path, mode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore %v: %w", header.Name, err)
+		}
+	}
+}
+
+func extractFile(reader io.Reader, path string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
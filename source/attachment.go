@@ -0,0 +1,79 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/workspace"
+)
+
+var ErrAttachmentTooLarge = errors.New("attachment is too large")
+
+const (
+	// MaxAttachmentBytes bounds the size of an attached file, because every
+	// attachment is copied into the session directory.
+	MaxAttachmentBytes = 20 << 20
+	// MaxInlineTextBytes is the size up to which a text file is given to the
+	// agent inline. A larger one, such as a long log, is left for the agent to
+	// search.
+	MaxInlineTextBytes = 16 * 1024
+)
+
+// imageTypes are the image formats that the agent can view.
+var imageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Attach stages the file that ref refers to, a path relative to the workspace
+// or starting with "~", in the session directory and returns it as an
+// attachment.
+func Attach(store *session.Store, workspaceDir, ref string) (ai.Attachment, error) {
+	ref = strings.TrimSpace(ref)
+	path, err := workspace.ResolvePath(workspaceDir, ref)
+	if err != nil {
+		return ai.Attachment{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ai.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if info.IsDir() {
+		return ai.Attachment{}, fmt.Errorf("%w: %v is a directory", ErrUnsupportedSource, ref)
+	}
+	if info.Size() > MaxAttachmentBytes {
+		return ai.Attachment{}, fmt.Errorf("%w: %v has %d bytes, more than %d", ErrAttachmentTooLarge, ref, info.Size(), MaxAttachmentBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ai.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	attachment := ai.Attachment{Kind: attachmentKind(data), Name: filepath.Base(path)}
+	if attachment.Kind == ai.AttachmentKindText {
+		attachment.Text = Normalize(string(data))
+	}
+	attachment.ID, attachment.Path, err = store.StageAttachment(attachment.Name, data)
+	if err != nil {
+		return ai.Attachment{}, err
+	}
+	return attachment, nil
+}
+
+// attachmentKind tells the kind of a file by its content, because screenshots
+// are often saved without an extension or with the wrong one.
+func attachmentKind(data []byte) ai.AttachmentKind {
+	if slices.Contains(imageTypes, http.DetectContentType(data)) {
+		return ai.AttachmentKindImage
+	}
+	if len(data) <= MaxInlineTextBytes && utf8.Valid(data) && !bytes.ContainsRune(data, 0) {
+		return ai.AttachmentKindText
+	}
+	return ai.AttachmentKindFile
+}
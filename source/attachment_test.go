@@ -0,0 +1,61 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+	"github.com/sds-lab-dev/bear-go/session"
+)
+
+func TestAttach(t *testing.T) {
+	dir := t.TempDir()
+	store := session.NewStore(dir, "session-1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	testutil.WriteFile(t, dir, "shots/mockup", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	testutil.WriteFile(t, dir, "error.log", "panic: nil map\r\n")
+	testutil.WriteFile(t, dir, "huge.log", strings.Repeat("line\n", MaxInlineTextBytes))
+
+	tests := []struct {
+		ref      string
+		expected ai.Attachment
+	}{
+		{"shots/mockup", ai.Attachment{ID: "ATT-1", Kind: ai.AttachmentKindImage, Name: "mockup"}},
+		{"error.log", ai.Attachment{ID: "ATT-2", Kind: ai.AttachmentKindText, Name: "error.log", Text: "panic: nil map"}},
+		{"huge.log", ai.Attachment{ID: "ATT-3", Kind: ai.AttachmentKindFile, Name: "huge.log"}},
+	}
+	for _, tt := range tests {
+		attachment, err := Attach(store, dir, tt.ref)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.ref, err)
+		}
+		expectedPath := filepath.Join(store.Dir(), "attachments", tt.expected.ID+"-"+tt.expected.Name)
+		if attachment.Path != expectedPath {
+			t.Errorf("%v: expected path %v, got %v", tt.ref, expectedPath, attachment.Path)
+		}
+		if _, err := os.Stat(attachment.Path); err != nil {
+			t.Errorf("%v: expected a staged copy: %v", tt.ref, err)
+		}
+		attachment.Path = ""
+		if attachment != tt.expected {
+			t.Errorf("%v: expected %#v, got %#v", tt.ref, tt.expected, attachment)
+		}
+	}
+}
+
+func TestAttach_Errors(t *testing.T) {
+	dir := t.TempDir()
+	store := session.NewStore(dir, "session-1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	testutil.WriteFile(t, dir, "docs/a.md", "a")
+
+	if _, err := Attach(store, dir, "docs"); !errors.Is(err, ErrUnsupportedSource) {
+		t.Errorf("expected a directory to be rejected, got %v", err)
+	}
+	if _, err := Attach(store, dir, "missing.png"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file to be rejected, got %v", err)
+	}
+}
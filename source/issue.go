@@ -0,0 +1,132 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNotAnIssue = errors.New("JSON is not an issue export")
+
+// issueExport has the fields of the issue exports of GitHub (the REST API and
+// `gh issue view --json`), GitLab and Jira that matter for a request. Labels
+// are names in some exports and objects with a name in others.
+type issueExport struct {
+	Number      int               `json:"number"`
+	IID         int               `json:"iid"`
+	Key         string            `json:"key"`
+	Title       string            `json:"title"`
+	Body        string            `json:"body"`
+	Description string            `json:"description"`
+	State       string            `json:"state"`
+	URL         string            `json:"url"`
+	HTMLURL     string            `json:"html_url"`
+	WebURL      string            `json:"web_url"`
+	Labels      []json.RawMessage `json:"labels"`
+	Comments    json.RawMessage   `json:"comments"`
+	Fields      *struct {
+		Summary string `json:"summary"`
+		// Description is a string in Jira's API v2, and a rich text
+		// document in v3, which is left out.
+		Description json.RawMessage `json:"description"`
+	} `json:"fields"`
+}
+
+type issueComment struct {
+	Body   string `json:"body"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// parseIssue turns an issue export into Markdown with the title, the facts
+// that help to find the issue again, the description and the comments, which
+// often hold decisions made after the issue was written.
+func parseIssue(data []byte) (string, error) {
+	var issue issueExport
+	if err := json.Unmarshal(data, &issue); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNotAnIssue, err)
+	}
+	if issue.Fields != nil {
+		issue.Title = firstNonEmpty(issue.Title, issue.Fields.Summary)
+		var description string
+		if json.Unmarshal(issue.Fields.Description, &description) == nil {
+			issue.Description = firstNonEmpty(issue.Description, description)
+		}
+	}
+	body := firstNonEmpty(issue.Body, issue.Description)
+	if issue.Title == "" && body == "" {
+		return "", fmt.Errorf("%w: it has no title or description", ErrNotAnIssue)
+	}
+
+	var b strings.Builder
+	title := issue.Title
+	switch {
+	case issue.Key != "":
+		title = issue.Key + ": " + title
+	case issue.Number != 0:
+		title = fmt.Sprintf("#%d: %v", issue.Number, title)
+	case issue.IID != 0:
+		title = fmt.Sprintf("#%d: %v", issue.IID, title)
+	}
+	fmt.Fprintf(&b, "# %v\n", strings.TrimSpace(title))
+
+	var facts []string
+	if url := firstNonEmpty(issue.HTMLURL, issue.WebURL, issue.URL); url != "" {
+		facts = append(facts, "URL: "+url)
+	}
+	if issue.State != "" {
+		facts = append(facts, "State: "+issue.State)
+	}
+	if labels := issueLabels(issue.Labels); len(labels) > 0 {
+		facts = append(facts, "Labels: "+strings.Join(labels, ", "))
+	}
+	if len(facts) > 0 {
+		fmt.Fprintf(&b, "\n%v\n", strings.Join(facts, "\n"))
+	}
+	if body != "" {
+		fmt.Fprintf(&b, "\n%v\n", strings.TrimSpace(body))
+	}
+
+	var comments []issueComment
+	// Only `gh issue view` embeds the comments; the REST APIs count them.
+	if json.Unmarshal(issue.Comments, &comments) == nil && len(comments) > 0 {
+		b.WriteString("\n## Comments\n")
+		for _, comment := range comments {
+			author := firstNonEmpty(comment.Author.Login, comment.User.Login, "unknown")
+			fmt.Fprintf(&b, "\n### %v\n\n%v\n", author, strings.TrimSpace(comment.Body))
+		}
+	}
+	return b.String(), nil
+}
+
+func issueLabels(raw []json.RawMessage) []string {
+	var labels []string
+	for _, label := range raw {
+		var name string
+		if json.Unmarshal(label, &name) == nil {
+			labels = append(labels, name)
+			continue
+		}
+		var object struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(label, &object) == nil && object.Name != "" {
+			labels = append(labels, object.Name)
+		}
+	}
+	return labels
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
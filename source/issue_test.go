@@ -0,0 +1,51 @@
+package source
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseIssue(t *testing.T) {
+	tests := map[string]struct {
+		data     string
+		expected string
+	}{
+		"gh issue view": {
+			data: `{"number": 42, "title": "Export reports as CSV", "body": "Users need CSV.\r\n",
+				"state": "OPEN", "url": "https://github.com/o/r/issues/42",
+				"labels": [{"name": "feature"}, {"name": "reports"}],
+				"comments": [{"author": {"login": "pm"}, "body": "Only the monthly report."}]}`,
+			expected: "# #42: Export reports as CSV\n\nURL: https://github.com/o/r/issues/42\nState: OPEN\nLabels: feature, reports\n\nUsers need CSV.\n\n## Comments\n\n### pm\n\nOnly the monthly report.\n",
+		},
+		"GitLab": {
+			data:     `{"iid": 7, "title": "Dark mode", "description": "Follow the OS theme.", "web_url": "https://gitlab.example.com/g/p/-/issues/7", "labels": ["ui"], "comments": 3}`,
+			expected: "# #7: Dark mode\n\nURL: https://gitlab.example.com/g/p/-/issues/7\nLabels: ui\n\nFollow the OS theme.\n",
+		},
+		"Jira": {
+			data:     `{"key": "APP-12", "fields": {"summary": "Audit log", "description": "Log every admin action."}}`,
+			expected: "# APP-12: Audit log\n\nLog every admin action.\n",
+		},
+		"Jira rich text": {
+			data:     `{"key": "APP-13", "fields": {"summary": "Audit export", "description": {"type": "doc"}}}`,
+			expected: "# APP-13: Audit export\n",
+		},
+	}
+	for name, tt := range tests {
+		got, err := parseIssue([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", name, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%v: expected %q, got %q", name, tt.expected, got)
+		}
+	}
+}
+
+func TestParseIssue_RejectsOtherJSON(t *testing.T) {
+	for _, data := range []string{`{"name": "app"}`, `[1, 2]`, `not json`} {
+		if _, err := parseIssue([]byte(data)); !errors.Is(err, ErrNotAnIssue) {
+			t.Errorf("%v: expected ErrNotAnIssue, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var ErrNoPDFText = errors.New("no text could be read from the PDF")
+
+// pdfToTextBinary is pdftotext from Poppler, which handles every font
+// encoding of a PDF.
+const pdfToTextBinary = "pdftotext"
+
+// FindPDFToText returns the path of pdftotext, which PDF sources need.
+func FindPDFToText() (string, error) {
+	path, err := exec.LookPath(pdfToTextBinary)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v from Poppler is not installed", ErrNoPDFText, pdfToTextBinary)
+	}
+	return path, nil
+}
+
+// extractPDFText returns the text of the PDF with pdftotext.
+func extractPDFText(path string) (string, error) {
+	binaryPath, err := FindPDFToText()
+	if err != nil {
+		return "", err
+	}
+
+	output, err := exec.Command(binaryPath, "-layout", "-enc", "UTF-8", path, "-").Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v failed: %v", ErrNoPDFText, pdfToTextBinary, err)
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return "", fmt.Errorf("%w: the PDF has no text layer; it may be scanned", ErrNoPDFText)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,25 @@
+package source
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestExtractPDFText_WithoutPDFToText(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if _, err := extractPDFText("/nonexistent.pdf"); !errors.Is(err, ErrNoPDFText) {
+		t.Errorf("expected ErrNoPDFText, got %v", err)
+	}
+}
+
+func TestExtractPDFText_UnreadableFile(t *testing.T) {
+	if _, err := exec.LookPath(pdfToTextBinary); err != nil {
+		t.Skip("pdftotext is not installed")
+	}
+
+	if _, err := extractPDFText("/nonexistent.pdf"); !errors.Is(err, ErrNoPDFText) {
+		t.Errorf("expected ErrNoPDFText, got %v", err)
+	}
+}
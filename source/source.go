@@ -0,0 +1,148 @@
+// Package source reads the documents that a request can start from, such as a
+// ticket exported from an issue tracker, a Markdown or PDF document, or a spec
+// approved in an earlier session, into text for the agent.
+package source
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/workspace"
+)
+
+var (
+	ErrUnsupportedSource = errors.New("unsupported source")
+	ErrEmptySource       = errors.New("source has no text")
+)
+
+// MaxTextBytes bounds the text of a source, so that a large document does not
+// crowd the request out of the agent's context.
+const MaxTextBytes = 64 * 1024
+
+// SpecRefPrefix refers to the approved spec of an earlier session instead of a
+// file, as in "spec:<session-id>".
+const SpecRefPrefix = "spec:"
+
+// textExtensions are read as they are. Files without an extension are read as
+// text too, if they are valid UTF-8.
+var textExtensions = []string{".md", ".markdown", ".txt", ".text", ".rst", ".adoc", ""}
+
+// Load reads the source that ref refers to: a file path, relative to the
+// workspace or starting with "~", or "spec:<session-id>". The kind of a file
+// is told by its extension and content; a JSON file must be an issue.
+func Load(workspaceDir, ref string) (ai.RequestSource, error) {
+	ref = strings.TrimSpace(ref)
+	if sessionID, ok := strings.CutPrefix(ref, SpecRefPrefix); ok {
+		return loadApprovedSpec(workspaceDir, sessionID)
+	}
+
+	path, err := workspace.ResolvePath(workspaceDir, ref)
+	if err != nil {
+		return ai.RequestSource{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ai.RequestSource{}, fmt.Errorf("failed to read source: %w", err)
+	}
+
+	source := ai.RequestSource{Name: ref}
+	extension := strings.ToLower(filepath.Ext(path))
+	switch {
+	case extension == ".pdf" || strings.HasPrefix(string(data), "%PDF-"):
+		source.Kind = ai.RequestSourceKindPDF
+		source.Text, err = extractPDFText(path)
+	case extension == ".json":
+		source.Kind = ai.RequestSourceKindIssue
+		source.Text, err = parseIssue(data)
+	case isApprovedSpecFile(path):
+		source.Kind = ai.RequestSourceKindSpec
+		source.Text = string(data)
+	case slices.Contains(textExtensions, extension) && utf8.Valid(data):
+		source.Kind = ai.RequestSourceKindText
+		source.Text = string(data)
+	default:
+		return ai.RequestSource{}, fmt.Errorf("%w: %v is not Markdown, text, PDF or issue JSON", ErrUnsupportedSource, ref)
+	}
+	if err != nil {
+		return ai.RequestSource{}, fmt.Errorf("failed to read %v: %w", ref, err)
+	}
+	return finish(source)
+}
+
+func loadApprovedSpec(workspaceDir, sessionID string) (ai.RequestSource, error) {
+	store, err := session.OpenStore(workspaceDir, sessionID)
+	if err != nil {
+		return ai.RequestSource{}, err
+	}
+	approvedSpec, err := store.ApprovedSpec()
+	if err != nil {
+		return ai.RequestSource{}, fmt.Errorf("failed to read the approved spec of session %v: %w", sessionID, err)
+	}
+	return finish(ai.RequestSource{
+		Kind: ai.RequestSourceKindSpec,
+		Name: SpecRefPrefix + sessionID,
+		Text: approvedSpec,
+	})
+}
+
+// isApprovedSpecFile reports whether the path is the spec of a session, which
+// is found in the .bear directory of a workspace.
+func isApprovedSpecFile(path string) bool {
+	return filepath.Base(path) == "spec.md" &&
+		strings.Contains(filepath.ToSlash(path), "/"+session.DirName+"/")
+}
+
+func finish(source ai.RequestSource) (ai.RequestSource, error) {
+	source.Text = Normalize(source.Text)
+	if source.Text == "" {
+		return ai.RequestSource{}, fmt.Errorf("%w: %v", ErrEmptySource, source.Name)
+	}
+	return source, nil
+}
+
+var blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+
+// Normalize cleans up text extracted from a document: it drops the byte order
+// mark and control characters, unifies line endings, trims trailing spaces,
+// collapses runs of blank lines and truncates the text to MaxTextBytes.
+func Normalize(text string) string {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError:
+			return -1
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	text = strings.TrimSpace(text)
+
+	if len(text) > MaxTextBytes {
+		cut := MaxTextBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "\n\n[... truncated by Bear]"
+	}
+	return text
+}
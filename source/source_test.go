@@ -0,0 +1,98 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+	"github.com/sds-lab-dev/bear-go/session"
+)
+
+func TestNormalize(t *testing.T) {
+	input := "\uFEFF# Title  \r\n\r\n\r\n\r\nBody\x00 text\t\r\nNext\x1b line\n\n"
+	expected := "# Title\n\nBody text\nNext line"
+	if got := Normalize(input); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestNormalize_Truncates(t *testing.T) {
+	got := Normalize(strings.Repeat("가", MaxTextBytes))
+	if !strings.HasSuffix(got, "[... truncated by Bear]") {
+		t.Fatal("expected a truncation marker")
+	}
+	text := strings.TrimSuffix(got, "\n\n[... truncated by Bear]")
+	if len(text) > MaxTextBytes || strings.ContainsRune(text, '�') {
+		t.Errorf("expected the text to be cut at a rune boundary within %d bytes, got %d bytes", MaxTextBytes, len(text))
+	}
+}
+
+func TestLoad_Text(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFile(t, dir, "docs/request.md", "# Export\r\n\r\nExport reports as CSV.\r\n")
+
+	source, err := Load(dir, "docs/request.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := ai.RequestSource{Kind: ai.RequestSourceKindText, Name: "docs/request.md", Text: "# Export\n\nExport reports as CSV."}
+	if source != expected {
+		t.Errorf("expected %#v, got %#v", expected, source)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFile(t, dir, "logo.png", "\x89PNG\r\n\x1a\n")
+	testutil.WriteFile(t, dir, "empty.txt", " \n\n")
+	testutil.WriteFile(t, dir, "package.json", `{"name": "app"}`)
+
+	tests := map[string]error{
+		"logo.png":     ErrUnsupportedSource,
+		"empty.txt":    ErrEmptySource,
+		"package.json": ErrNotAnIssue,
+		"missing.md":   os.ErrNotExist,
+	}
+	for ref, expected := range tests {
+		if _, err := Load(dir, ref); !errors.Is(err, expected) {
+			t.Errorf("%v: expected %v, got %v", ref, expected, err)
+		}
+	}
+}
+
+func TestLoad_ApprovedSpec(t *testing.T) {
+	dir := t.TempDir()
+	store := session.NewStore(dir, "20260101-abc", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := store.SaveApprovedSpec("# Report Export\n\n## Overview\n"); err != nil {
+		t.Fatalf("failed to save spec: %v", err)
+	}
+
+	source, err := Load(dir, "spec:20260101-abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.Kind != ai.RequestSourceKindSpec || source.Name != "spec:20260101-abc" || source.Text != "# Report Export\n\n## Overview" {
+		t.Errorf("unexpected source: %#v", source)
+	}
+
+	path, err := filepath.Rel(dir, filepath.Join(store.Dir(), "spec.md"))
+	if err != nil {
+		t.Fatalf("failed to get relative path: %v", err)
+	}
+	source, err = Load(dir, path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.Kind != ai.RequestSourceKindSpec {
+		t.Errorf("expected the spec file to be a spec source, got %v", source.Kind)
+	}
+
+	if _, err := Load(dir, "spec:20260101-missing"); err == nil {
+		t.Error("expected an error for a session without a spec")
+	}
+}
@@ -0,0 +1,154 @@
+package spec
+
+import (
+	"slices"
+	"strings"
+)
+
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// SectionDiff is the line diff of a section of two spec drafts. A section
+// that only one of the drafts has is all inserted or all deleted lines.
+type SectionDiff struct {
+	// Heading is the "## " heading line of the section, or empty for the
+	// lines before the first one, such as the title.
+	Heading string
+	// Added and Removed are set for a section that only the new or only the
+	// old draft has.
+	Added   bool
+	Removed bool
+	Lines   []DiffLine
+}
+
+// Changed reports whether the section differs between the drafts.
+func (d SectionDiff) Changed() bool {
+	return d.Added || d.Removed || slices.ContainsFunc(d.Lines, func(line DiffLine) bool {
+		return line.Op != DiffEqual
+	})
+}
+
+// DiffSections compares two Markdown spec drafts section by section, so that
+// a section that moved or was rewritten does not smear into its neighbours.
+// The sections are in the order of the new draft, followed by the sections
+// that only the old draft has.
+func DiffSections(oldDraft, newDraft string) []SectionDiff {
+	oldSections := splitSections(oldDraft)
+	newSections := splitSections(newDraft)
+
+	var diffs []SectionDiff
+	for _, section := range newSections {
+		index := slices.IndexFunc(oldSections, func(old markdownSection) bool {
+			return strings.EqualFold(old.heading, section.heading)
+		})
+		diff := SectionDiff{Heading: section.heading, Added: index < 0}
+		var oldLines []string
+		if index >= 0 {
+			oldLines = oldSections[index].lines
+			oldSections = slices.Delete(oldSections, index, index+1)
+		}
+		diff.Lines = diffLines(oldLines, section.lines)
+		diffs = append(diffs, diff)
+	}
+	for _, section := range oldSections {
+		diffs = append(diffs, SectionDiff{Heading: section.heading, Removed: true, Lines: diffLines(section.lines, nil)})
+	}
+	return diffs
+}
+
+type markdownSection struct {
+	heading string
+	lines   []string
+}
+
+// splitSections splits a Markdown document at its "## " headings outside code
+// blocks. The blank lines around a section are dropped.
+func splitSections(markdown string) []markdownSection {
+	sections := []markdownSection{{}}
+	inCodeBlock := false
+	for line := range strings.Lines(markdown) {
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeBlock = !inCodeBlock
+		}
+		if !inCodeBlock && strings.HasPrefix(line, "## ") {
+			sections = append(sections, markdownSection{heading: strings.TrimSpace(line)})
+			continue
+		}
+		last := &sections[len(sections)-1]
+		last.lines = append(last.lines, line)
+	}
+
+	var result []markdownSection
+	for _, section := range sections {
+		start := slices.IndexFunc(section.lines, func(line string) bool { return strings.TrimSpace(line) != "" })
+		if start < 0 {
+			section.lines = nil
+		} else {
+			end := len(section.lines)
+			for strings.TrimSpace(section.lines[end-1]) == "" {
+				end--
+			}
+			section.lines = section.lines[start:end]
+		}
+		if section.heading != "" || len(section.lines) > 0 {
+			result = append(result, section)
+		}
+	}
+	return result
+}
+
+// diffLines returns the shortest edit script from a to b, computed from their
+// longest common subsequence. Drafts are short enough for the quadratic
+// table.
+func diffLines(a, b []string) []DiffLine {
+	// common[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return lines
+}
@@ -0,0 +1,47 @@
+package spec
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffSections(t *testing.T) {
+	oldDraft := "# Spec\n\n## Overview\n\nExport reports.\n\n## Requirements\n\n- REQ-01: CSV\n- REQ-02: empty\n\n## Notes\n\nOld note.\n"
+	newDraft := "# Spec\n\n## Requirements\n\n- REQ-01: CSV\n- REQ-02: header only\n\n```\n## not a heading\n```\n\n## Overview\n\nExport reports.\n"
+
+	diffs := DiffSections(oldDraft, newDraft)
+
+	var headings []string
+	for _, diff := range diffs {
+		headings = append(headings, diff.Heading)
+	}
+	if want := []string{"", "## Requirements", "## Overview", "## Notes"}; !slices.Equal(headings, want) {
+		t.Fatalf("headings = %q, want %q", headings, want)
+	}
+	if diffs[0].Changed() || diffs[2].Changed() {
+		t.Errorf("the title and the moved overview should be unchanged: %+v", diffs)
+	}
+	if !diffs[3].Removed || !diffs[3].Changed() {
+		t.Errorf("notes should be removed: %+v", diffs[3])
+	}
+
+	want := []DiffLine{
+		{DiffEqual, "- REQ-01: CSV"},
+		{DiffDelete, "- REQ-02: empty"},
+		{DiffInsert, "- REQ-02: header only"},
+		{DiffInsert, ""},
+		{DiffInsert, "```"},
+		{DiffInsert, "## not a heading"},
+		{DiffInsert, "```"},
+	}
+	if !slices.Equal(diffs[1].Lines, want) {
+		t.Errorf("requirements diff = %+v, want %+v", diffs[1].Lines, want)
+	}
+}
+
+func TestDiffSections_AddedSection(t *testing.T) {
+	diffs := DiffSections("# Spec\n", "# Spec\n\n## Notes\n\nNew.\n")
+	if len(diffs) != 2 || !diffs[1].Added || !slices.Equal(diffs[1].Lines, []DiffLine{{DiffInsert, "New."}}) {
+		t.Errorf("diffs = %+v, want an added notes section", diffs)
+	}
+}
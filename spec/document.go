@@ -0,0 +1,120 @@
+package spec
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+)
+
+//go:embed document.schema.json
+var documentSchema string
+
+// DocumentSchema returns the JSON Schema of a Document, for the tools that
+// read exported specs.
+func DocumentSchema() string {
+	return documentSchema
+}
+
+// Document is an approved spec in a structured form, for the tools that do
+// not read Markdown.
+type Document struct {
+	Title string `json:"title" yaml:"title"`
+	// Template is the name of the template that the sections match, or empty
+	// if none does.
+	Template string            `json:"template,omitempty" yaml:"template,omitempty"`
+	Sections []DocumentSection `json:"sections" yaml:"sections"`
+	Items    []Item            `json:"items" yaml:"items"`
+}
+
+type DocumentSection struct {
+	ID      string `json:"id" yaml:"id"`
+	Title   string `json:"title" yaml:"title"`
+	Content string `json:"content" yaml:"content"`
+}
+
+// ParseDocument reads an approved Markdown spec into a Document. The approved
+// spec does not record its template, so the section IDs are taken from the
+// template whose section titles match the most headings. A heading that the
+// template does not know gets an ID made from its title.
+func ParseDocument(markdown string, templates []Template) Document {
+	sections := splitSections(markdown)
+	template, ok := matchTemplate(templates, sections)
+
+	document := Document{Sections: []DocumentSection{}, Items: ParseApprovedItems(markdown)}
+	if document.Items == nil {
+		document.Items = []Item{}
+	}
+	if ok {
+		document.Template = template.Name
+	}
+	for _, section := range sections {
+		content := strings.Join(section.lines, "\n")
+		if section.heading == "" {
+			// The lines before the first section hold the title, and rarely an
+			// introduction that is kept as a section of its own.
+			var rest []string
+			for _, line := range section.lines {
+				if title, found := strings.CutPrefix(line, "# "); found && document.Title == "" {
+					document.Title = strings.TrimSpace(title)
+					continue
+				}
+				rest = append(rest, line)
+			}
+			if content = strings.TrimSpace(strings.Join(rest, "\n")); content != "" {
+				document.Sections = append(document.Sections, DocumentSection{ID: "introduction", Content: content})
+			}
+			continue
+		}
+
+		title := strings.TrimSpace(strings.TrimPrefix(section.heading, "## "))
+		document.Sections = append(document.Sections, DocumentSection{
+			ID:      template.sectionID(title),
+			Title:   title,
+			Content: content,
+		})
+	}
+	return document
+}
+
+func matchTemplate(templates []Template, sections []markdownSection) (Template, bool) {
+	best, bestMatches := Template{}, 0
+	for _, template := range templates {
+		matches := 0
+		for _, section := range sections {
+			title := strings.TrimSpace(strings.TrimPrefix(section.heading, "## "))
+			if section.heading != "" && template.knowsTitle(title) {
+				matches++
+			}
+		}
+		if matches > bestMatches {
+			best, bestMatches = template, matches
+		}
+	}
+	return best, bestMatches > 0
+}
+
+func (t Template) knowsTitle(title string) bool {
+	for _, section := range t.Sections {
+		if strings.EqualFold(section.Title, title) {
+			return true
+		}
+	}
+	return false
+}
+
+var nonIDCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sectionID returns the ID of the template section with the title, or an ID
+// made from the title if the template has no such section.
+func (t Template) sectionID(title string) string {
+	for _, section := range t.Sections {
+		if strings.EqualFold(section.Title, title) {
+			return section.ID
+		}
+	}
+	id := strings.Trim(nonIDCharacters.ReplaceAllString(strings.ToLower(title), "_"), "_")
+	if id == "" || id[0] < 'a' || id[0] > 'z' {
+		id = "section_" + id
+	}
+	return strings.TrimSuffix(id, "_")
+}
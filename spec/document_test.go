@@ -0,0 +1,73 @@
+package spec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kaptinlin/jsonschema"
+)
+
+const approvedTestSpec = `# CSV export
+
+Reports are exported from the dashboard.
+
+## Overview
+
+Export reports as CSV.
+
+## Functional requirements
+
+- REQ-01: Exporting a report produces a CSV download.
+
+## Acceptance criteria
+
+- AC-01: (REQ-01) the download has one row per entry.
+
+## Rollout Plan (v2)
+
+Behind a flag.
+`
+
+func TestParseDocument(t *testing.T) {
+	other := Template{Name: "other", Sections: []Section{{ID: "summary", Title: "Overview"}}}
+	document := ParseDocument(approvedTestSpec, []Template{other, DefaultTemplate()})
+
+	if document.Title != "CSV export" || document.Template != DefaultTemplateName {
+		t.Errorf("title = %q, template = %q, want the default template to match best", document.Title, document.Template)
+	}
+	want := []DocumentSection{
+		{ID: "introduction", Content: "Reports are exported from the dashboard."},
+		{ID: "overview", Title: "Overview", Content: "Export reports as CSV."},
+		{ID: RequirementsSectionID, Title: "Functional requirements", Content: "- REQ-01: Exporting a report produces a CSV download."},
+		{ID: AcceptanceCriteriaSectionID, Title: "Acceptance criteria", Content: "- AC-01: (REQ-01) the download has one row per entry."},
+		{ID: "rollout_plan_v2", Title: "Rollout Plan (v2)", Content: "Behind a flag."},
+	}
+	if len(document.Sections) != len(want) {
+		t.Fatalf("sections = %+v, want %+v", document.Sections, want)
+	}
+	for i := range want {
+		if document.Sections[i] != want[i] {
+			t.Errorf("section %d = %+v, want %+v", i, document.Sections[i], want[i])
+		}
+	}
+	if len(document.Items) != 2 || document.Items[1].ID != "AC-01" {
+		t.Errorf("items = %+v, want REQ-01 and AC-01", document.Items)
+	}
+}
+
+func TestParseDocument_FollowsSchema(t *testing.T) {
+	schema, err := jsonschema.NewCompiler().Compile([]byte(DocumentSchema()))
+	if err != nil {
+		t.Fatalf("failed to compile document schema: %v", err)
+	}
+
+	for _, markdown := range []string{approvedTestSpec, "", "## 1. Scope\n\nAll reports.\n"} {
+		data, err := json.Marshal(ParseDocument(markdown, []Template{DefaultTemplate()}))
+		if err != nil {
+			t.Fatalf("failed to marshal document: %v", err)
+		}
+		if result := schema.Validate(data); !result.IsValid() {
+			t.Errorf("document of %q does not follow the schema: %v\n%s", markdown, result.DetailedErrors(), data)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package spec
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestImports_NoOtherBearPackage keeps the spec package at the bottom of the
+// dependency graph, since the AI ports and the UI depend on its types.
+func TestImports_NoOtherBearPackage(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("failed to list the package files: %v", err)
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatalf("failed to parse %v: %v", file, err)
+		}
+		for _, imported := range parsed.Imports {
+			path, _ := strconv.Unquote(imported.Path.Value)
+			if strings.HasPrefix(path, "github.com/sds-lab-dev/bear-go/") {
+				t.Errorf("%v imports %v; the spec package must not depend on other Bear packages", file, path)
+			}
+		}
+	}
+}
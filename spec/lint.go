@@ -0,0 +1,388 @@
+package spec
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RequirementsSectionID is the section whose list items are checked for
+// acceptance criteria. A template without it skips the check.
+const RequirementsSectionID = "requirements"
+
+type Severity string
+
+const (
+	// SeverityError is a violation of a rule that the spec must follow.
+	SeverityError Severity = "error"
+	// SeverityWarning is a likely violation that the user should look at.
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rules, named so that the findings of a rule can be told apart.
+const (
+	RuleMissingSection       = "missing-section"
+	RuleUncoveredRequirement = "uncovered-requirement"
+	RuleMissingID            = "missing-id"
+	RuleDuplicateID          = "duplicate-id"
+	RuleUnknownReference     = "unknown-reference"
+	RuleUntestableCriterion  = "untestable-criterion"
+	RuleFilePath             = "file-path"
+	RuleForbiddenTerm        = "forbidden-term"
+	RuleCritique             = "critique"
+)
+
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Section is the ID of the section that the finding is about, or empty if
+	// it is about the whole spec.
+	Section string `json:"section"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Section == "" {
+		return fmt.Sprintf("%v [%v] %v", f.Severity, f.Rule, f.Message)
+	}
+	return fmt.Sprintf("%v [%v] %v: %v", f.Severity, f.Rule, f.Section, f.Message)
+}
+
+// vagueTerms make an acceptance criterion a matter of opinion instead of a
+// check that a test can run.
+var vagueTerms = []string{
+	"appropriate", "appropriately", "as expected", "fast", "quickly", "user-friendly",
+	"intuitive", "robust", "reasonable", "reasonably", "properly", "etc",
+}
+
+// sourceExtensions are the file extensions that make a word a reference to
+// the implementation even without a directory.
+var sourceExtensions = []string{
+	"c", "cc", "cpp", "cs", "go", "h", "hpp", "java", "js", "jsx", "kt", "php",
+	"py", "rb", "rs", "sh", "sql", "swift", "ts", "tsx",
+}
+
+// Lint checks a Markdown spec that was rendered from the template against the
+// rules that every spec must follow. The findings are ordered by the sections
+// of the template.
+func Lint(t Template, draft string) []Finding {
+	sections := t.ParseSections(draft)
+	items := t.ParseItems(draft)
+
+	var findings []Finding
+	for _, section := range t.Sections {
+		content := sections[section.ID]
+		if section.Required && content == "" {
+			findings = append(findings, Finding{
+				Rule:     RuleMissingSection,
+				Severity: SeverityError,
+				Section:  section.ID,
+				Message:  fmt.Sprintf("the required section %q is missing or empty", section.Title),
+			})
+			continue
+		}
+
+		switch section.ID {
+		case RequirementsSectionID:
+			findings = append(findings, lintIDs(items, ItemKindRequirement, section.ID)...)
+			findings = append(findings, lintRequirementCoverage(items, sections[AcceptanceCriteriaSectionID])...)
+		case AcceptanceCriteriaSectionID:
+			findings = append(findings, lintIDs(items, ItemKindCriterion, section.ID)...)
+			findings = append(findings, lintCriteria(items)...)
+		}
+		findings = append(findings, lintImplementationDetails(t, section.ID, content)...)
+	}
+	return findings
+}
+
+// ParseSections splits a Markdown spec that was rendered from the template
+// into the content of its sections, keyed by section ID. Headings inside code
+// blocks and sections that the template does not know are ignored.
+func (t Template) ParseSections(draft string) map[string]string {
+	ids := map[string]string{}
+	for _, section := range t.Sections {
+		ids[strings.ToLower(section.Title)] = section.ID
+	}
+
+	contents := map[string][]string{}
+	current := ""
+	inCodeBlock := false
+	for line := range strings.Lines(draft) {
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeBlock = !inCodeBlock
+		}
+		if !inCodeBlock && strings.HasPrefix(line, "## ") {
+			current = ids[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "## ")))]
+			continue
+		}
+		if !inCodeBlock && strings.HasPrefix(line, "# ") {
+			current = ""
+			continue
+		}
+		if current != "" {
+			contents[current] = append(contents[current], line)
+		}
+	}
+
+	sections := map[string]string{}
+	for id, lines := range contents {
+		sections[id] = strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	return sections
+}
+
+// listItems returns the top-level list items of the Markdown, with their
+// continuation lines joined.
+func listItems(markdown string) []string {
+	var items []string
+	for line := range strings.Lines(markdown) {
+		line = strings.TrimRight(line, "\r\n")
+		if item, ok := strings.CutPrefix(line, "- "); ok {
+			items = append(items, item)
+		} else if item, ok := strings.CutPrefix(line, "* "); ok {
+			items = append(items, item)
+		} else if match := orderedItemPattern.FindStringSubmatch(line); match != nil {
+			items = append(items, match[1])
+		} else if len(items) > 0 && strings.TrimSpace(line) != "" && strings.HasPrefix(line, " ") {
+			items[len(items)-1] += " " + strings.TrimSpace(line)
+		}
+	}
+	return items
+}
+
+var (
+	orderedItemPattern = regexp.MustCompile(`^\d+[.)] (.*)$`)
+	wordPattern        = regexp.MustCompile(`[a-z0-9][a-z0-9_-]*`)
+	extensionPattern   = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+)
+
+// stopWords are too common in requirements to tell whether a criterion covers
+// a requirement.
+var stopWords = []string{
+	"about", "after", "also", "been", "before", "being", "both", "does", "each",
+	"every", "from", "have", "into", "must", "only", "other", "same", "shall",
+	"should", "than", "that", "their", "them", "then", "there", "these", "they",
+	"this", "those", "when", "where", "which", "while", "will", "with", "without",
+	"would", "user", "users", "system",
+}
+
+// significantWords are the words of the text that say what it is about.
+func significantWords(text string) []string {
+	var words []string
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		if len(word) >= 4 && !slices.Contains(stopWords, word) && !slices.Contains(words, word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// lintIDs reports the items of the section that have no ID or the ID of
+// another item.
+func lintIDs(items []Item, kind ItemKind, sectionID string) []Finding {
+	var findings []Finding
+	seen := map[string]bool{}
+	number := 0
+	for _, item := range items {
+		if item.Kind != kind {
+			continue
+		}
+		number++
+		switch {
+		case item.ID == "":
+			findings = append(findings, Finding{
+				Rule:     RuleMissingID,
+				Severity: SeverityWarning,
+				Section:  sectionID,
+				Message:  fmt.Sprintf("%v %d has no ID, so that plans, commits and tests cannot refer to it", kind, number),
+			})
+		case seen[item.ID]:
+			findings = append(findings, Finding{
+				Rule:     RuleDuplicateID,
+				Severity: SeverityError,
+				Section:  sectionID,
+				Message:  fmt.Sprintf("%v is the ID of more than one %v", item.ID, kind),
+			})
+		}
+		seen[item.ID] = true
+	}
+	return findings
+}
+
+// lintRequirementCoverage reports the requirements that no acceptance
+// criterion covers. A requirement with an ID is covered if a criterion refers
+// to the ID. A requirement without one counts as covered if the criteria
+// mention at least two of its significant words, or all of them if it has
+// fewer.
+func lintRequirementCoverage(items []Item, criteria string) []Finding {
+	criteriaWords := significantWords(criteria)
+	referenced := map[string]bool{}
+	for _, item := range items {
+		for _, id := range item.Covers {
+			referenced[id] = true
+		}
+	}
+
+	var findings []Finding
+	number := 0
+	for _, item := range items {
+		if item.Kind != ItemKindRequirement {
+			continue
+		}
+		number++
+		if item.ID != "" {
+			if !referenced[item.ID] {
+				// A duplicate ID is reported once.
+				referenced[item.ID] = true
+				findings = append(findings, Finding{
+					Rule:     RuleUncoveredRequirement,
+					Severity: SeverityWarning,
+					Section:  RequirementsSectionID,
+					Message:  fmt.Sprintf("no acceptance criterion refers to %v", item.ID),
+				})
+			}
+			continue
+		}
+
+		words := significantWords(item.Text)
+		if len(words) == 0 {
+			continue
+		}
+		covered := 0
+		for _, word := range words {
+			if slices.Contains(criteriaWords, word) {
+				covered++
+			}
+		}
+		if covered >= min(2, len(words)) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:     RuleUncoveredRequirement,
+			Severity: SeverityWarning,
+			Section:  RequirementsSectionID,
+			Message:  fmt.Sprintf("requirement %d seems to have no acceptance criterion: %q", number, item.Text),
+		})
+	}
+	return findings
+}
+
+func lintCriteria(items []Item) []Finding {
+	requirements := map[string]bool{}
+	for _, item := range items {
+		if item.Kind == ItemKindRequirement && item.ID != "" {
+			requirements[item.ID] = true
+		}
+	}
+
+	var findings []Finding
+	number := 0
+	for _, item := range items {
+		if item.Kind != ItemKindCriterion {
+			continue
+		}
+		number++
+		name := item.ID
+		if name == "" {
+			name = fmt.Sprintf("criterion %d", number)
+		}
+
+		for _, id := range item.Covers {
+			if !requirements[id] {
+				findings = append(findings, Finding{
+					Rule:     RuleUnknownReference,
+					Severity: SeverityWarning,
+					Section:  AcceptanceCriteriaSectionID,
+					Message:  fmt.Sprintf("%v refers to %v, which is not a requirement of the spec", name, id),
+				})
+			}
+		}
+
+		lower := " " + strings.Join(wordPattern.FindAllString(strings.ToLower(item.Text), -1), " ") + " "
+		for _, term := range vagueTerms {
+			if strings.Contains(lower, " "+term+" ") {
+				findings = append(findings, Finding{
+					Rule:     RuleUntestableCriterion,
+					Severity: SeverityWarning,
+					Section:  AcceptanceCriteriaSectionID,
+					Message:  fmt.Sprintf("%v uses %q, which a test cannot check", name, term),
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// lintImplementationDetails reports file paths and the template's forbidden
+// terms, which tie the spec to an implementation.
+func lintImplementationDetails(t Template, sectionID, content string) []Finding {
+	var findings []Finding
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(content) {
+		word = strings.Trim(word, "`'\"()[]{},;:.!?*")
+		if !seen[word] && isFilePath(word) {
+			seen[word] = true
+			findings = append(findings, Finding{
+				Rule:     RuleFilePath,
+				Severity: SeverityWarning,
+				Section:  sectionID,
+				Message:  fmt.Sprintf("%q looks like a file path, which is an implementation detail", word),
+			})
+		}
+	}
+
+	lower := strings.ToLower(content)
+	for _, term := range t.ForbiddenTerms {
+		if containsWord(lower, strings.ToLower(term)) {
+			findings = append(findings, Finding{
+				Rule:     RuleForbiddenTerm,
+				Severity: SeverityWarning,
+				Section:  sectionID,
+				Message:  fmt.Sprintf("%q is an implementation detail that the spec should not prescribe", term),
+			})
+		}
+	}
+	return findings
+}
+
+func isFilePath(word string) bool {
+	if word == "" || strings.Contains(word, "://") {
+		return false
+	}
+	extension := strings.TrimPrefix(path.Ext(word), ".")
+	if extension == "" || extension == word[1:] {
+		return false
+	}
+	if slices.Contains(sourceExtensions, strings.ToLower(extension)) {
+		return true
+	}
+	// A directory and an extension make a path even for other files, such as
+	// "config/app.yaml".
+	return strings.Contains(word, "/") && len(extension) <= 5 && extensionPattern.MatchString(extension)
+}
+
+// containsWord reports whether term appears in text with no letter or digit
+// right before or after it.
+func containsWord(text, term string) bool {
+	if term == "" {
+		return false
+	}
+	isWordByte := func(b byte) bool {
+		return 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || b == '_'
+	}
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(term)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
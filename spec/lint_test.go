@@ -0,0 +1,144 @@
+package spec
+
+import (
+	"strings"
+	"testing"
+)
+
+func lintTestTemplate() Template {
+	return Template{
+		Name:  "lint",
+		Title: "Lint",
+		Sections: []Section{
+			{ID: "overview", Title: "Overview", Required: true},
+			{ID: RequirementsSectionID, Title: "Functional requirements", Required: true},
+			{ID: AcceptanceCriteriaSectionID, Title: "Acceptance criteria", Required: true},
+			{ID: "assumptions", Title: "Assumptions", Required: true},
+			{ID: "notes", Title: "Notes"},
+		},
+		ForbiddenTerms: []string{"Redis"},
+	}
+}
+
+func findingRules(findings []Finding) []string {
+	rules := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		rules = append(rules, finding.Section+":"+finding.Rule)
+	}
+	return rules
+}
+
+func TestLint_CleanSpec(t *testing.T) {
+	draft := `# Export
+
+## Overview
+
+Reports can be exported.
+
+## Functional requirements
+
+- REQ-01: Exporting a report produces a CSV download.
+- **REQ-02**: Exporting an empty report produces a header row only.
+
+## Acceptance criteria
+
+1. AC-01: (REQ-01) Exporting a report with two rows downloads a CSV file with three lines.
+2. AC-02: (REQ-02) Exporting an empty report downloads a CSV file with the header row only.
+
+## Assumptions
+
+- None.
+`
+	if findings := Lint(lintTestTemplate(), draft); len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findingRules(findings))
+	}
+}
+
+func TestLint_Findings(t *testing.T) {
+	draft := "# Export\n\n" +
+		"## Functional requirements\n\n" +
+		"- Exporting a report produces a CSV download.\n" +
+		"- Deleted accounts are purged nightly\n  from the archive.\n\n" +
+		"## Acceptance criteria\n\n" +
+		"- Exporting a report downloads a CSV file quickly.\n\n" +
+		"## Assumptions\n\n" +
+		"- The cache in internal/cache/store.go and Redis stay as they are; main.go is unchanged.\n" +
+		"- See https://example.com/docs/guide.html and version 1.2.3.\n\n" +
+		"## Notes\n\n" +
+		"```\n## Overview\n```\n"
+
+	findings := Lint(lintTestTemplate(), draft)
+
+	want := []string{
+		"overview:" + RuleMissingSection,
+		RequirementsSectionID + ":" + RuleMissingID,
+		RequirementsSectionID + ":" + RuleMissingID,
+		RequirementsSectionID + ":" + RuleUncoveredRequirement,
+		AcceptanceCriteriaSectionID + ":" + RuleMissingID,
+		AcceptanceCriteriaSectionID + ":" + RuleUntestableCriterion,
+		"assumptions:" + RuleFilePath,
+		"assumptions:" + RuleFilePath,
+		"assumptions:" + RuleForbiddenTerm,
+	}
+	if got := strings.Join(findingRules(findings), ","); got != strings.Join(want, ",") {
+		t.Fatalf("unexpected findings:\n%v\nwant:\n%v", got, strings.Join(want, ","))
+	}
+	if findings[0].Severity != SeverityError {
+		t.Errorf("expected a missing section to be an error, got %v", findings[0].Severity)
+	}
+	if !strings.Contains(findings[3].Message, "requirement 2") || !strings.Contains(findings[3].Message, "from the archive") {
+		t.Errorf("expected the second requirement with its continuation line, got %q", findings[3].Message)
+	}
+	if !strings.Contains(findings[6].Message, "internal/cache/store.go") || !strings.Contains(findings[7].Message, "main.go") {
+		t.Errorf("unexpected file path findings: %q, %q", findings[6].Message, findings[7].Message)
+	}
+}
+
+func TestLint_IDs(t *testing.T) {
+	draft := "# Export\n\n" +
+		"## Overview\n\nReports can be exported.\n\n" +
+		"## Functional requirements\n\n" +
+		"- REQ-01: Exporting a report produces a CSV download.\n" +
+		"- REQ-02: Deleted accounts are purged nightly.\n" +
+		"- REQ-02: Exports are logged.\n\n" +
+		"## Acceptance criteria\n\n" +
+		"- AC-01: (REQ-01, REQ-07) A report downloads as CSV.\n\n" +
+		"## Assumptions\n\n- None.\n"
+
+	findings := Lint(lintTestTemplate(), draft)
+
+	want := []string{
+		RequirementsSectionID + ":" + RuleDuplicateID,
+		RequirementsSectionID + ":" + RuleUncoveredRequirement,
+		AcceptanceCriteriaSectionID + ":" + RuleUnknownReference,
+	}
+	if got := strings.Join(findingRules(findings), ","); got != strings.Join(want, ",") {
+		t.Fatalf("unexpected findings:\n%v\nwant:\n%v", got, strings.Join(want, ","))
+	}
+	if !strings.Contains(findings[1].Message, "REQ-02") || !strings.Contains(findings[2].Message, "REQ-07") {
+		t.Errorf("unexpected messages: %q, %q", findings[1].Message, findings[2].Message)
+	}
+}
+
+func TestTemplate_ParseSections(t *testing.T) {
+	template := lintTestTemplate()
+	output := map[string]string{
+		"title":                     "Export",
+		"overview":                  "Reports can be exported.",
+		"notes":                     "```md\n## Acceptance criteria\n```",
+		AcceptanceCriteriaSectionID: "- It works.",
+	}
+
+	sections := template.ParseSections(template.Render(output))
+
+	for id, want := range map[string]string{
+		"overview":                  "Reports can be exported.",
+		"notes":                     "```md\n## Acceptance criteria\n```",
+		AcceptanceCriteriaSectionID: "- It works.",
+		RequirementsSectionID:       "",
+	} {
+		if sections[id] != want {
+			t.Errorf("section %v: got %q, want %q", id, sections[id], want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Package spec defines the shape of the specs that Bear drafts. A spec
+// template lists the sections of a spec for one kind of project, and the
+// drafting prompt and output schema are generated from it.
+package spec
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidTemplate = errors.New("invalid spec template")
+
+//go:embed templates/*.yaml
+var builtinTemplates embed.FS
+
+// DefaultTemplateName is the template that is used when no other template
+// matches the request.
+const DefaultTemplateName = "general"
+
+// AcceptanceCriteriaSectionID is the section that every template must have,
+// because a spec must be testable.
+const AcceptanceCriteriaSectionID = "acceptance_criteria"
+
+// titleProperty is the output property that holds the title of the spec. It
+// cannot be used as a section ID.
+const titleProperty = "title"
+
+type Section struct {
+	// ID is the property of the section in the drafting output.
+	ID       string `yaml:"id"`
+	Title    string `yaml:"title"`
+	Guidance string `yaml:"guidance"`
+	Required bool   `yaml:"required"`
+}
+
+type Template struct {
+	Name        string `yaml:"name"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	// Keywords are the words in a request that suggest the template.
+	Keywords []string  `yaml:"keywords"`
+	Sections []Section `yaml:"sections"`
+	// AcceptanceChecklist are the items that the acceptance criteria must
+	// address on top of the spec's own requirements.
+	AcceptanceChecklist []string `yaml:"acceptance_checklist"`
+	// ForbiddenTerms are the names, such as libraries or frameworks, that the
+	// spec must not prescribe. The linter reports them.
+	ForbiddenTerms []string `yaml:"forbidden_terms"`
+
+	// Source is the file the template was read from, or "built-in".
+	Source string `yaml:"-"`
+}
+
+var (
+	namePattern      = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	sectionIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+func (t Template) validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits and dashes", t.Name)
+	}
+	if t.Title == "" {
+		return errors.New("title is required")
+	}
+	if len(t.Sections) == 0 {
+		return errors.New("at least one section is required")
+	}
+
+	seen := map[string]bool{}
+	for _, section := range t.Sections {
+		if !sectionIDPattern.MatchString(section.ID) || section.ID == titleProperty {
+			return fmt.Errorf("section ID %q must be lowercase letters, digits and underscores, and not %q", section.ID, titleProperty)
+		}
+		if seen[section.ID] {
+			return fmt.Errorf("duplicate section ID %q", section.ID)
+		}
+		seen[section.ID] = true
+		if section.Title == "" {
+			return fmt.Errorf("section %q has no title", section.ID)
+		}
+	}
+	if !seen[AcceptanceCriteriaSectionID] {
+		return fmt.Errorf("a section with ID %q is required", AcceptanceCriteriaSectionID)
+	}
+	return nil
+}
+
+// LoadTemplates loads the built-in templates and the ones in overrideDirs,
+// where a template replaces any earlier template of the same name. Missing
+// directories are skipped. The default template comes first, then the others
+// by name.
+func LoadTemplates(overrideDirs ...string) ([]Template, error) {
+	templates := map[string]Template{}
+
+	entries, err := builtinTemplates.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list built-in spec templates: %w", err)
+	}
+	for _, entry := range entries {
+		content, err := builtinTemplates.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in spec template: %w", err)
+		}
+		template, err := parseTemplate(content, "built-in")
+		if err != nil {
+			return nil, err
+		}
+		templates[template.Name] = template
+	}
+
+	for _, dir := range overrideDirs {
+		var paths []string
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern))
+			if err != nil {
+				return nil, fmt.Errorf("failed to list spec templates in %v: %w", dir, err)
+			}
+			paths = append(paths, matches...)
+		}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read spec template: %w", err)
+			}
+			template, err := parseTemplate(content, path)
+			if err != nil {
+				return nil, err
+			}
+			templates[template.Name] = template
+		}
+	}
+
+	sorted := make([]Template, 0, len(templates))
+	for _, template := range templates {
+		sorted = append(sorted, template)
+	}
+	slices.SortFunc(sorted, func(a, b Template) int {
+		switch {
+		case a.Name == DefaultTemplateName:
+			return -1
+		case b.Name == DefaultTemplateName:
+			return 1
+		default:
+			return strings.Compare(a.Name, b.Name)
+		}
+	})
+	return sorted, nil
+}
+
+func parseTemplate(content []byte, source string) (Template, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	// A misspelled field would otherwise be dropped silently.
+	decoder.KnownFields(true)
+
+	var template Template
+	if err := decoder.Decode(&template); err != nil {
+		return Template{}, fmt.Errorf("%w: %v: %v", ErrInvalidTemplate, source, err)
+	}
+	if err := template.validate(); err != nil {
+		return Template{}, fmt.Errorf("%w: %v: %v", ErrInvalidTemplate, source, err)
+	}
+	template.Source = source
+	return template, nil
+}
+
+// DefaultTemplate returns the built-in default template.
+func DefaultTemplate() Template {
+	content, err := builtinTemplates.ReadFile("templates/" + DefaultTemplateName + ".yaml")
+	if err != nil {
+		panic(err)
+	}
+	template, err := parseTemplate(content, "built-in")
+	if err != nil {
+		panic(err)
+	}
+	return template
+}
+
+// Suggest returns the template whose keywords appear most often in the
+// request, or the default template if none appears.
+func Suggest(templates []Template, request string) Template {
+	words := strings.FieldsFunc(strings.ToLower(request), func(r rune) bool {
+		return !(r == '-' || r == '_' || 'a' <= r && r <= 'z' || '0' <= r && r <= '9' || r > 127)
+	})
+
+	best := -1
+	bestScore := 0
+	for i, template := range templates {
+		score := 0
+		for _, keyword := range template.Keywords {
+			score += countPhrase(words, strings.Fields(strings.ToLower(keyword)))
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best >= 0 {
+		return templates[best]
+	}
+
+	for _, template := range templates {
+		if template.Name == DefaultTemplateName {
+			return template
+		}
+	}
+	return DefaultTemplate()
+}
+
+func countPhrase(words, phrase []string) int {
+	if len(phrase) == 0 {
+		return 0
+	}
+	count := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(phrase)], phrase) {
+			count++
+		}
+	}
+	return count
+}
+
+// Guidance describes the template to the drafting agent.
+func (t Template) Guidance() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Spec template: %v (%v)\n", t.Title, t.Name)
+	if t.Description != "" {
+		fmt.Fprintf(&b, "\n%v\n", strings.TrimSpace(t.Description))
+	}
+
+	b.WriteString("\nWrite the spec with the following sections, in this order. Each section is a property of the structured output; leave an optional section empty if it does not apply.\n")
+	for _, section := range t.Sections {
+		requirement := "optional"
+		if section.Required {
+			requirement = "required"
+		}
+		fmt.Fprintf(&b, "\n## %v (`%v`, %v)\n", section.Title, section.ID, requirement)
+		if section.Guidance != "" {
+			fmt.Fprintf(&b, "\n%v\n", strings.TrimSpace(section.Guidance))
+		}
+	}
+
+	b.WriteString(t.idGuidance())
+
+	if len(t.ForbiddenTerms) > 0 {
+		b.WriteString("\n## Forbidden terms\n\nThe spec MUST NOT prescribe any of the following, unless the user explicitly asked for it:\n\n")
+		for _, term := range t.ForbiddenTerms {
+			fmt.Fprintf(&b, "- %v\n", term)
+		}
+	}
+
+	if len(t.AcceptanceChecklist) > 0 {
+		fmt.Fprintf(&b, "\n## Acceptance criteria checklist\n\nThe `%v` section MUST also address each of the following:\n\n", AcceptanceCriteriaSectionID)
+		for _, item := range t.AcceptanceChecklist {
+			fmt.Fprintf(&b, "- %v\n", item)
+		}
+	}
+	return b.String()
+}
+
+// OutputSchema returns the JSON schema of the drafting output: the title of
+// the spec and one Markdown string per section.
+func (t Template) OutputSchema() (string, error) {
+	properties := map[string]any{
+		titleProperty: map[string]any{
+			"type":        "string",
+			"description": "Short title of the spec.",
+		},
+	}
+	required := []string{titleProperty}
+	for _, section := range t.Sections {
+		properties[section.ID] = map[string]any{
+			"type":        "string",
+			"description": fmt.Sprintf("Markdown content of the %q section, without its heading.", section.Title),
+		}
+		if section.Required {
+			required = append(required, section.ID)
+		}
+	}
+
+	schema, err := json.MarshalIndent(map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal output schema of spec template %v: %w", t.Name, err)
+	}
+	return string(schema), nil
+}
+
+// Render turns the drafting output into the Markdown spec. Empty sections are
+// left out.
+func (t Template) Render(output map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %v\n", strings.TrimSpace(output[titleProperty]))
+	for _, section := range t.Sections {
+		content := strings.TrimSpace(output[section.ID])
+		if content == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %v\n\n%v\n", section.Title, content)
+	}
+	return b.String()
+}
@@ -0,0 +1,178 @@
+package spec
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create template directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+}
+
+func templateNames(templates []Template) []string {
+	names := make([]string, 0, len(templates))
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+	return names
+}
+
+func TestLoadTemplates_BuiltIn(t *testing.T) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "general,cli-feature,library-api,service-endpoint"
+	if got := strings.Join(templateNames(templates), ","); got != want {
+		t.Errorf("expected templates %v, got %v", want, got)
+	}
+	for _, template := range templates {
+		if template.Source != "built-in" {
+			t.Errorf("expected built-in source for %v, got %v", template.Name, template.Source)
+		}
+	}
+	if DefaultTemplate().Name != DefaultTemplateName {
+		t.Errorf("unexpected default template: %v", DefaultTemplate().Name)
+	}
+}
+
+func TestLoadTemplates_LaterDirectoryWins(t *testing.T) {
+	userDir := filepath.Join(t.TempDir(), "user")
+	workspaceDir := filepath.Join(t.TempDir(), "workspace")
+	writeTemplate(t, userDir, "team.yaml", "name: team\ntitle: User team\nsections:\n  - {id: acceptance_criteria, title: AC}\n")
+	writeTemplate(t, workspaceDir, "team.yml", "name: team\ntitle: Workspace team\nsections:\n  - {id: acceptance_criteria, title: AC}\n")
+	writeTemplate(t, workspaceDir, "general.yaml", "name: general\ntitle: Our general\nsections:\n  - {id: acceptance_criteria, title: AC}\n")
+
+	templates, err := LoadTemplates(userDir, workspaceDir, filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "general,cli-feature,library-api,service-endpoint,team"
+	if got := strings.Join(templateNames(templates), ","); got != want {
+		t.Errorf("expected templates %v, got %v", want, got)
+	}
+	if templates[0].Title != "Our general" {
+		t.Errorf("expected the workspace to replace the default template, got %q", templates[0].Title)
+	}
+	team := templates[len(templates)-1]
+	if team.Title != "Workspace team" || team.Source != filepath.Join(workspaceDir, "team.yml") {
+		t.Errorf("expected the workspace template to win, got %q from %v", team.Title, team.Source)
+	}
+}
+
+func TestLoadTemplates_InvalidTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown field", "name: x\ntitle: X\nsection: []\n", "field section not found"},
+		{"bad name", "name: Bad Name\ntitle: X\nsections:\n  - {id: acceptance_criteria, title: AC}\n", "name"},
+		{"no acceptance criteria", "name: x\ntitle: X\nsections:\n  - {id: overview, title: Overview}\n", AcceptanceCriteriaSectionID},
+		{"duplicate section", "name: x\ntitle: X\nsections:\n  - {id: acceptance_criteria, title: AC}\n  - {id: acceptance_criteria, title: AC}\n", "duplicate"},
+		{"title section", "name: x\ntitle: X\nsections:\n  - {id: title, title: Title}\n  - {id: acceptance_criteria, title: AC}\n", "section ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplate(t, dir, "broken.yaml", tt.content)
+
+			_, err := LoadTemplates(dir)
+			if !errors.Is(err, ErrInvalidTemplate) {
+				t.Fatalf("expected ErrInvalidTemplate, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "broken.yaml") {
+				t.Errorf("expected the error to name the file and %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		request string
+		want    string
+	}{
+		{"Add a --verbose flag to the sync subcommand", "cli-feature"},
+		{"Add a REST endpoint that returns the user's orders", "service-endpoint"},
+		{"Make the parser a public API of the library", "library-api"},
+		{"Improve things", DefaultTemplateName},
+	}
+	for _, tt := range tests {
+		if got := Suggest(templates, tt.request).Name; got != tt.want {
+			t.Errorf("Suggest(%q) = %v, want %v", tt.request, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate_OutputSchema(t *testing.T) {
+	template := Template{
+		Name: "tiny",
+		Sections: []Section{
+			{ID: "summary", Title: "Summary", Required: true},
+			{ID: "notes", Title: "Notes"},
+		},
+	}
+
+	schemaString, err := template.OutputSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var schema struct {
+		Properties           map[string]any `json:"properties"`
+		Required             []string       `json:"required"`
+		AdditionalProperties bool           `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal([]byte(schemaString), &schema); err != nil {
+		t.Fatalf("invalid schema JSON: %v", err)
+	}
+
+	if len(schema.Properties) != 3 {
+		t.Errorf("expected title and two section properties, got %v", schema.Properties)
+	}
+	if strings.Join(schema.Required, ",") != "title,summary" {
+		t.Errorf("unexpected required properties: %v", schema.Required)
+	}
+	if schema.AdditionalProperties {
+		t.Error("expected additional properties to be rejected")
+	}
+}
+
+func TestTemplate_GuidanceAndRender(t *testing.T) {
+	template := Template{
+		Name:                "tiny",
+		Title:               "Tiny",
+		Sections:            []Section{{ID: "summary", Title: "Summary", Guidance: "Say it briefly.", Required: true}, {ID: "notes", Title: "Notes"}},
+		AcceptanceChecklist: []string{"Exit codes are covered."},
+	}
+
+	guidance := template.Guidance()
+	for _, want := range []string{"## Summary (`summary`, required)", "Say it briefly.", "## Notes (`notes`, optional)", "- Exit codes are covered."} {
+		if !strings.Contains(guidance, want) {
+			t.Errorf("guidance should contain %q, got:\n%v", want, guidance)
+		}
+	}
+
+	got := template.Render(map[string]string{"title": "Tiny spec", "summary": " Short. \n", "notes": "  "})
+	want := "# Tiny spec\n\n## Summary\n\nShort.\n"
+	if got != want {
+		t.Errorf("unexpected rendering:\n%q\nwant:\n%q", got, want)
+	}
+}
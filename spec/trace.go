@@ -0,0 +1,155 @@
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The prefixes of the stable IDs of requirements and acceptance criteria, for
+// example "REQ-01" and "AC-03".
+const (
+	RequirementIDPrefix = "REQ"
+	CriterionIDPrefix   = "AC"
+)
+
+type ItemKind string
+
+const (
+	ItemKindRequirement ItemKind = "requirement"
+	ItemKindCriterion   ItemKind = "criterion"
+)
+
+// Item is a requirement or an acceptance criterion of a spec.
+type Item struct {
+	ID   string   `json:"id" yaml:"id"`
+	Kind ItemKind `json:"kind" yaml:"kind"`
+	Text string   `json:"text" yaml:"text"`
+	// Covers are the requirements that a criterion verifies.
+	Covers []string `json:"covers,omitempty" yaml:"covers,omitempty"`
+}
+
+var idPattern = regexp.MustCompile(`(?i)(req|ac)[-_]?(\d{1,3})`)
+
+// ExtractIDs returns the requirement and criterion IDs that the text refers
+// to, normalized and in the order of their first appearance. The IDs are
+// found in any spelling that agents use in prose, commit messages and test
+// names, such as "REQ-1", "req_01" and "TestExport_AC01".
+func ExtractIDs(text string) []string {
+	var ids []string
+	for _, match := range idPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		if !isIDBoundary(text, start, end) {
+			continue
+		}
+		id := normalizeID(text[match[2]:match[3]], text[match[4]:match[5]])
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// isIDBoundary reports whether the match is a whole ID and not a part of a
+// word such as "BACK-1", "vac01" or the hex digits "ac1f". An upper-case ID
+// may follow a lower-case letter, as in "TestExportAC01", and be followed by
+// an upper-case one, as in "TestAC01Empty".
+func isIDBoundary(text string, start, end int) bool {
+	if end < len(text) && (unicode.IsDigit(rune(text[end])) || unicode.IsLower(rune(text[end]))) {
+		return false
+	}
+	if start == 0 {
+		return true
+	}
+	previous := rune(text[start-1])
+	if !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+		return true
+	}
+	return unicode.IsLower(previous) && unicode.IsUpper(rune(text[start]))
+}
+
+func normalizeID(prefix, number string) string {
+	n, _ := strconv.Atoi(number)
+	return fmt.Sprintf("%v-%02d", strings.ToUpper(prefix), n)
+}
+
+// ParseItems returns the requirements and acceptance criteria of a Markdown
+// spec that was rendered from the template. An item is a top-level list item
+// of the requirements or the acceptance criteria section that starts with its
+// ID. The items without an ID are returned with an empty ID.
+func (t Template) ParseItems(draft string) []Item {
+	sections := t.ParseSections(draft)
+
+	var items []Item
+	for _, text := range listItems(sections[RequirementsSectionID]) {
+		items = append(items, parseItem(ItemKindRequirement, RequirementIDPrefix, text))
+	}
+	for _, text := range listItems(sections[AcceptanceCriteriaSectionID]) {
+		items = append(items, parseItem(ItemKindCriterion, CriterionIDPrefix, text))
+	}
+	return items
+}
+
+// ParseApprovedItems returns the items of an approved spec, whose template is
+// not known any more. Every list item that starts with an ID counts.
+func ParseApprovedItems(markdown string) []Item {
+	var items []Item
+	for _, text := range listItems(markdown) {
+		requirement := parseItem(ItemKindRequirement, RequirementIDPrefix, text)
+		criterion := parseItem(ItemKindCriterion, CriterionIDPrefix, text)
+		switch {
+		case requirement.ID != "":
+			items = append(items, requirement)
+		case criterion.ID != "":
+			items = append(items, criterion)
+		}
+	}
+	return items
+}
+
+var leadingIDPattern = regexp.MustCompile(`^\**\s*([A-Za-z]+)-(\d{1,3})\s*\**\s*[:.)-]?\s*\**\s*`)
+
+func parseItem(kind ItemKind, prefix, text string) Item {
+	item := Item{Kind: kind, Text: strings.TrimSpace(text)}
+	match := leadingIDPattern.FindStringSubmatch(text)
+	if match == nil || !strings.EqualFold(match[1], prefix) {
+		return item
+	}
+	item.ID = normalizeID(match[1], match[2])
+	item.Text = strings.TrimSpace(text[len(match[0]):])
+	if kind == ItemKindCriterion {
+		for _, id := range ExtractIDs(item.Text) {
+			if strings.HasPrefix(id, RequirementIDPrefix+"-") {
+				item.Covers = append(item.Covers, id)
+			}
+		}
+	}
+	return item
+}
+
+// idGuidance tells the drafting agent how to number the requirements and
+// criteria, so that plans, commits and tests can refer to them.
+func (t Template) idGuidance() string {
+	var b strings.Builder
+	b.WriteString("\n## Requirement and criterion IDs\n\n")
+	if t.hasSection(RequirementsSectionID) {
+		fmt.Fprintf(&b,
+			"Start every list item of the `%v` section with a stable ID such as `%v-01:`, and every list item of the `%v` section with an ID such as `%v-01:`, followed by the IDs of the requirements that the criterion verifies, for example `%v-03: (%v-01, %v-02)`.\n",
+			RequirementsSectionID, RequirementIDPrefix, AcceptanceCriteriaSectionID, CriterionIDPrefix,
+			CriterionIDPrefix, RequirementIDPrefix, RequirementIDPrefix)
+	} else {
+		fmt.Fprintf(&b, "Start every list item of the `%v` section with a stable ID such as `%v-01:`.\n",
+			AcceptanceCriteriaSectionID, CriterionIDPrefix)
+	}
+	b.WriteString("\nIDs are permanent: when revising the spec, keep the ID of every item that stays, give new items the next unused number, and never renumber or reuse the ID of a removed item.\n")
+	return b.String()
+}
+
+func (t Template) hasSection(id string) bool {
+	return slices.ContainsFunc(t.Sections, func(section Section) bool {
+		return section.ID == id
+	})
+}
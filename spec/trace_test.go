@@ -0,0 +1,97 @@
+package spec
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractIDs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"implements REQ-1 and req_02", []string{"REQ-01", "REQ-02"}},
+		{"func TestExport_AC01(t *testing.T)", []string{"AC-01"}},
+		{"func TestExportAC3Empty(t *testing.T)", []string{"AC-03"}},
+		{"it('AC-12: exports (REQ-04)')", []string{"AC-12", "REQ-04"}},
+		{"REQ-01, REQ-1 again", []string{"REQ-01"}},
+		{"BACK-1 vac01 REQ-1234 space0", nil},
+		{"session 3f2a-ac1f-4e", nil},
+	}
+	for _, tt := range tests {
+		if got := ExtractIDs(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("ExtractIDs(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate_ParseItems(t *testing.T) {
+	draft := `## Functional requirements
+
+- REQ-01: Exporting a report produces a CSV download.
+- **REQ-2.** Empty reports export the header row.
+- A requirement without an ID.
+
+## Acceptance criteria
+
+1. AC-01 (REQ-01, REQ-02): the download has one row per entry.
+2. ac-2: an empty report has only the header.
+`
+	items := lintTestTemplate().ParseItems(draft)
+
+	want := []Item{
+		{ID: "REQ-01", Kind: ItemKindRequirement, Text: "Exporting a report produces a CSV download."},
+		{ID: "REQ-02", Kind: ItemKindRequirement, Text: "Empty reports export the header row."},
+		{ID: "", Kind: ItemKindRequirement, Text: "A requirement without an ID."},
+		{ID: "AC-01", Kind: ItemKindCriterion, Text: "(REQ-01, REQ-02): the download has one row per entry.", Covers: []string{"REQ-01", "REQ-02"}},
+		{ID: "AC-02", Kind: ItemKindCriterion, Text: "an empty report has only the header."},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(items), len(want), items)
+	}
+	for i := range want {
+		got := items[i]
+		if got.ID != want[i].ID || got.Kind != want[i].Kind || got.Text != want[i].Text || !slices.Equal(got.Covers, want[i].Covers) {
+			t.Errorf("item %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestParseApprovedItems(t *testing.T) {
+	markdown := `# Export
+
+## Overview
+
+- Reports are exported from the dashboard.
+
+## Requirements
+
+- REQ-01: Exporting produces a CSV download.
+
+## Acceptance Criteria
+
+- AC-01: (REQ-01) the download has one row per entry.
+`
+	items := ParseApprovedItems(markdown)
+
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if !slices.Equal(ids, []string{"REQ-01", "AC-01"}) {
+		t.Fatalf("ids = %v, want [REQ-01 AC-01]", ids)
+	}
+	if items[1].Kind != ItemKindCriterion || !slices.Equal(items[1].Covers, []string{"REQ-01"}) {
+		t.Errorf("criterion = %+v, want a criterion covering REQ-01", items[1])
+	}
+}
+
+func TestTemplate_GuidanceDescribesIDs(t *testing.T) {
+	guidance := lintTestTemplate().Guidance()
+	for _, want := range []string{"REQ-01:", "AC-01:", "never renumber"} {
+		if !strings.Contains(guidance, want) {
+			t.Errorf("guidance does not contain %q:\n%v", want, guidance)
+		}
+	}
+}
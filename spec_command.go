@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sds-lab-dev/bear-go/export"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+var specExportFormats = []string{"markdown", "html", "pdf-html", "json", "yaml", "issue"}
+
+// issuePostTimeout bounds the request that creates an issue, so that an
+// unreachable tracker does not hang the command.
+const issuePostTimeout = 30 * time.Second
+
+func runSpec(cfg config, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("spec", stderr)
+	format := flags.String("format", "markdown", "output format: "+strings.Join(specExportFormats, ", "))
+	output := flags.String("o", "", "file to write to (default: standard output)")
+	tracker := flags.String("tracker", string(export.TrackerGitHub), "issue tracker of the issue format: github or gitlab")
+	labels := flags.String("labels", "", "comma-separated labels of the issue")
+	post := flags.Bool("post", false, "create the issue by sending it to the issue endpoint instead of printing it")
+	issueURL := flags.String("issue-url", cfg.IssueURL(), fmt.Sprintf("issue endpoint of -post (default: $%v)", ISSUE_URL_ENV_VAR))
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bear spec export [-format format] [-o file] [-tracker github|gitlab] [-labels a,b] [-post] <session-id>")
+		fmt.Fprintln(stderr, "Run it in the workspace of the session. The json and yaml formats follow `bear spec schema`.")
+		fmt.Fprintf(stderr, "The issue endpoint gets the token in $%v.\n", ISSUE_TOKEN_ENV_VAR)
+		flags.PrintDefaults()
+	}
+	if len(args) == 1 && args[0] == "schema" {
+		fmt.Fprint(stdout, spec.DocumentSchema())
+		return 0
+	}
+	if len(args) == 0 || args[0] != "export" {
+		flags.Usage()
+		return 2
+	}
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 || !slices.Contains(specExportFormats, *format) {
+		flags.Usage()
+		return 2
+	}
+	if *post && (*format != "issue" || *issueURL == "") {
+		fmt.Fprintln(stderr, "-post needs -format issue and an issue endpoint")
+		return 2
+	}
+	issueTracker, err := export.ParseTracker(*tracker)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	sessionID := flags.Arg(0)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to get current working directory: %v\n", err)
+		return 1
+	}
+	store, err := session.OpenStore(cwd, sessionID)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to find session: %v\n", err)
+		return 1
+	}
+	approvedSpec, err := store.ApprovedSpec()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read the approved spec: %v\n", err)
+		return 1
+	}
+
+	var issue export.Issue
+	if *format == "issue" {
+		issue = export.NewIssue(approvedSpec, sessionID, parseLabels(*labels))
+	}
+	if *post {
+		return postIssue(cfg, *issueURL, issueTracker, issue, stdout, stderr)
+	}
+
+	content, err := renderSpecExport(cfg, cwd, *format, approvedSpec, issue, issueTracker)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *output == "" {
+		fmt.Fprint(stdout, content)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(content), 0o644); err != nil {
+		fmt.Fprintf(stderr, "failed to write %v: %v\n", *output, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Exported the spec to %v.\n", *output)
+	return 0
+}
+
+// renderSpecExport renders the approved spec in one of specExportFormats. The
+// issue is used by the issue format only.
+func renderSpecExport(
+	cfg config,
+	workspaceDir, format, approvedSpec string,
+	issue export.Issue,
+	tracker export.Tracker,
+) (string, error) {
+	switch format {
+	case "html", "pdf-html":
+		title := spec.ParseDocument(approvedSpec, nil).Title
+		if format == "html" {
+			return export.HTML(title, approvedSpec), nil
+		}
+		return export.PrintHTML(title, approvedSpec), nil
+	case "json", "yaml":
+		templates, err := spec.LoadTemplates(specTemplateDirs(cfg, workspaceDir)...)
+		if err != nil {
+			return "", fmt.Errorf("failed to load spec templates: %w", err)
+		}
+		return marshalDocument(spec.ParseDocument(approvedSpec, templates), format)
+	case "issue":
+		payload, err := issue.Payload(tracker)
+		if err != nil {
+			return "", err
+		}
+		return string(payload) + "\n", nil
+	default:
+		return approvedSpec, nil
+	}
+}
+
+// postIssue creates the issue by sending it to the issue endpoint.
+func postIssue(cfg config, endpoint string, tracker export.Tracker, issue export.Issue, stdout, stderr io.Writer) int {
+	client := &http.Client{Timeout: issuePostTimeout}
+	url, err := export.Post(client, endpoint, cfg.IssueToken(), tracker, issue)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create issue: %v\n", err)
+		return 1
+	}
+	if url == "" {
+		url = "(the endpoint did not return its URL)"
+	}
+	fmt.Fprintf(stdout, "Created issue: %v\n", url)
+	return 0
+}
+
+// parseLabels splits the comma-separated labels of the -labels flag.
+func parseLabels(labels string) []string {
+	var parsed []string
+	for label := range strings.SplitSeq(labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			parsed = append(parsed, label)
+		}
+	}
+	return parsed
+}
+
+func marshalDocument(document spec.Document, format string) (string, error) {
+	if format == "yaml" {
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal spec: %w", err)
+		}
+		return string(data), nil
+	}
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal spec: %w", err)
+	}
+	return string(data) + "\n", nil
+}
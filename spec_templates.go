@@ -0,0 +1,25 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+// specTemplateDirs returns the directories whose spec templates are added to
+// the built-in ones, the user's own first so that the workspace wins. The
+// workspace directory is left out if workspaceDir is empty.
+func specTemplateDirs(cfg config, workspaceDir string) []string {
+	dirs := []string{filepath.Join(cfg.ConfigDir(), "spec-templates")}
+	if workspaceDir != "" {
+		dirs = append(dirs, filepath.Join(workspaceDir, session.DirName, "spec-templates"))
+	}
+	return dirs
+}
+
+func loadSpecTemplates(cfg config) func(workspaceDir string) ([]spec.Template, error) {
+	return func(workspaceDir string) ([]spec.Template, error) {
+		return spec.LoadTemplates(specTemplateDirs(cfg, workspaceDir)...)
+	}
+}
@@ -0,0 +1,52 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+// Collect reads the sources of the matrix of a session in the workspace: the
+// approved spec, the plan tasks, the commits of the session branch and the
+// tests on it. Without a session branch, the tests are read from the working
+// tree.
+func Collect(workspaceDir, sessionID string) (Sources, error) {
+	store, err := session.OpenStore(workspaceDir, sessionID)
+	if err != nil {
+		return Sources{}, err
+	}
+	approvedSpec, err := store.ApprovedSpec()
+	if err != nil {
+		return Sources{}, fmt.Errorf("failed to read the approved spec: %w", err)
+	}
+	tasks, err := store.Tasks()
+	if err != nil && !errors.Is(err, session.ErrArtifactNotFound) {
+		return Sources{}, fmt.Errorf("failed to read the plan: %w", err)
+	}
+
+	repoDir, err := worktree.RepositoryRoot(workspaceDir)
+	if err != nil {
+		return Sources{}, err
+	}
+	sources := Sources{Spec: approvedSpec, Tasks: tasks}
+
+	rev := ""
+	branch := worktree.SessionBranch(sessionID)
+	if _, err := gitcmd.Run(repoDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		rev = branch
+		commits, err := worktree.SessionCommits(repoDir, sessionID)
+		if err != nil && !errors.Is(err, worktree.ErrNoSessionCommit) {
+			return Sources{}, err
+		}
+		sources.Commits = commits
+	}
+
+	sources.Tests, err = FindTests(repoDir, rev)
+	if err != nil {
+		return Sources{}, fmt.Errorf("failed to search the tests: %w", err)
+	}
+	return sources, nil
+}
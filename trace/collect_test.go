@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+const testSessionID = "session-1"
+
+func TestCollect(t *testing.T) {
+	dir := newTestRepository(t)
+	store := session.NewStore(dir, testSessionID, time.Now())
+	if err := store.SaveApprovedSpec(testSpec); err != nil {
+		t.Fatalf("SaveApprovedSpec() error = %v", err)
+	}
+	if err := store.SavePlan("# Plan\n\n## TASK-01\n\nImplement the export (REQ-01).\n"); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+
+	testutil.Git(t, dir, "switch", "--quiet", "-c", worktree.SessionBranch(testSessionID))
+	testutil.WriteFile(t, dir, "export_test.go", "func TestExport_AC01(t *testing.T) {}\n")
+	testutil.Git(t, dir, "add", "export_test.go")
+	testutil.Git(t, dir, "commit", "--quiet", "-m", "TASK-01: export reports (REQ-01)")
+	testutil.Git(t, dir, "switch", "--quiet", "main")
+
+	sources, err := Collect(dir, testSessionID)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if sources.Spec != testSpec {
+		t.Errorf("spec = %q, want the approved spec", sources.Spec)
+	}
+	if _, ok := sources.Tasks["TASK-01"]; !ok {
+		t.Errorf("tasks = %v, want TASK-01", sources.Tasks)
+	}
+	if len(sources.Commits) != 1 {
+		t.Errorf("commits = %+v, want the session commit", sources.Commits)
+	}
+	// The test is on the session branch, not in the working tree.
+	if len(sources.Tests) != 1 || !slices.Equal(sources.Tests[0].IDs, []string{"AC-01"}) {
+		t.Errorf("tests = %+v, want the test on the session branch", sources.Tests)
+	}
+}
+
+func TestCollect_WithoutSessionBranch(t *testing.T) {
+	dir := newTestRepository(t)
+	store := session.NewStore(dir, testSessionID, time.Now())
+	if err := store.SaveApprovedSpec(testSpec); err != nil {
+		t.Fatalf("SaveApprovedSpec() error = %v", err)
+	}
+
+	sources, err := Collect(dir, testSessionID)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(sources.Tasks) != 0 || len(sources.Commits) != 0 || len(sources.Tests) != 0 {
+		t.Errorf("sources = %+v, want only the spec", sources)
+	}
+}
+
+func TestCollect_WithoutApprovedSpec(t *testing.T) {
+	dir := newTestRepository(t)
+	if err := session.NewStore(dir, testSessionID, time.Now()).SaveUserRequest("export"); err != nil {
+		t.Fatalf("SaveUserRequest() error = %v", err)
+	}
+
+	if _, err := Collect(dir, testSessionID); !errors.Is(err, session.ErrArtifactNotFound) {
+		t.Errorf("Collect() error = %v, want ErrArtifactNotFound", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package trace links the requirements and acceptance criteria of an approved
+// spec to the plan tasks, commits and tests that refer to their IDs, so that
+// "where is requirement X tested?" has an answer.
+package trace
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/spec"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+// Sources are the artifacts of a session that the matrix is built from.
+type Sources struct {
+	Spec string
+	// Tasks are the plan sections of the tasks, keyed by task ID.
+	Tasks   map[string]string
+	Commits []worktree.Commit
+	Tests   []Test
+}
+
+// Row traces one requirement or acceptance criterion.
+type Row struct {
+	spec.Item
+	// Criteria are the acceptance criteria that verify a requirement.
+	Criteria []string `json:"criteria,omitempty"`
+	Tasks    []string `json:"tasks"`
+	Commits  []string `json:"commits"`
+	Tests    []Test   `json:"tests"`
+	// Tested is true if a test refers to the criterion, or for a requirement,
+	// to the requirement or one of its criteria.
+	Tested bool `json:"tested"`
+}
+
+type Matrix struct {
+	Rows []Row `json:"rows"`
+}
+
+// Build traces every item of the spec. Items that appear more than once keep
+// their first appearance.
+func Build(sources Sources) Matrix {
+	var matrix Matrix
+	index := map[string]int{}
+	for _, item := range spec.ParseApprovedItems(sources.Spec) {
+		if _, ok := index[item.ID]; ok {
+			continue
+		}
+		index[item.ID] = len(matrix.Rows)
+		matrix.Rows = append(matrix.Rows, Row{Item: item, Tasks: []string{}, Commits: []string{}, Tests: []Test{}})
+	}
+	rowOf := func(id string) *Row {
+		if i, ok := index[id]; ok {
+			return &matrix.Rows[i]
+		}
+		return nil
+	}
+
+	for _, row := range matrix.Rows {
+		for _, id := range row.Covers {
+			if requirement := rowOf(id); requirement != nil {
+				requirement.Criteria = append(requirement.Criteria, row.ID)
+			}
+		}
+	}
+	for _, taskID := range slices.Sorted(maps.Keys(sources.Tasks)) {
+		for _, id := range spec.ExtractIDs(sources.Tasks[taskID]) {
+			if row := rowOf(id); row != nil {
+				row.Tasks = append(row.Tasks, taskID)
+			}
+		}
+	}
+	for _, commit := range sources.Commits {
+		for _, id := range spec.ExtractIDs(withoutSessionTrailers(commit.Message)) {
+			if row := rowOf(id); row != nil {
+				row.Commits = append(row.Commits, commit.Hash)
+			}
+		}
+	}
+	for _, test := range sources.Tests {
+		for _, id := range test.IDs {
+			if row := rowOf(id); row != nil {
+				row.Tests = append(row.Tests, test)
+			}
+		}
+	}
+
+	for i := range matrix.Rows {
+		row := &matrix.Rows[i]
+		row.Tested = len(row.Tests) > 0
+		for _, id := range row.Criteria {
+			if criterion := rowOf(id); criterion != nil && len(criterion.Tests) > 0 {
+				row.Tested = true
+			}
+		}
+	}
+	return matrix
+}
+
+// withoutSessionTrailers drops the trailers whose values are session IDs and
+// paths, whose hex digits could otherwise read as an ID such as "ac12".
+func withoutSessionTrailers(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, "Bear-Session:") || strings.HasPrefix(line, "Bear-Spec:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Untested returns the rows that no test covers.
+func (m Matrix) Untested() []Row {
+	var rows []Row
+	for _, row := range m.Rows {
+		if !row.Tested {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// maxTextLength bounds the item text in the Markdown table, which is for
+// finding an item, not for reading it.
+const maxTextLength = 60
+
+// Markdown renders the matrix as a table, followed by the items that no test
+// covers.
+func (m Matrix) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Traceability matrix\n\n")
+	if len(m.Rows) == 0 {
+		b.WriteString("The spec has no requirement or acceptance criterion with an ID.\n")
+		return b.String()
+	}
+
+	b.WriteString("| ID | Text | Criteria | Tasks | Commits | Tests | Tested |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, row := range m.Rows {
+		commits := make([]string, 0, len(row.Commits))
+		for _, commit := range row.Commits {
+			commits = append(commits, shortHash(commit))
+		}
+		tests := make([]string, 0, len(row.Tests))
+		for _, test := range row.Tests {
+			tests = append(tests, test.String())
+		}
+		tested := "no"
+		if row.Tested {
+			tested = "yes"
+		}
+		fmt.Fprintf(&b, "| %v | %v | %v | %v | %v | %v | %v |\n",
+			row.ID, tableCell(truncate(row.Text, maxTextLength)), listCell(row.Criteria),
+			listCell(row.Tasks), listCell(commits), listCell(tests), tested)
+	}
+
+	untested := m.Untested()
+	b.WriteString("\n## Not covered by tests\n\n")
+	if len(untested) == 0 {
+		b.WriteString("Every requirement and acceptance criterion is covered by a test.\n")
+	}
+	for _, row := range untested {
+		fmt.Fprintf(&b, "- %v: %v\n", row.ID, row.Text)
+	}
+	return b.String()
+}
+
+func shortHash(hash string) string {
+	return hash[:min(len(hash), 12)]
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
+
+func listCell(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return tableCell(strings.Join(values, ", "))
+}
+
+func tableCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", `\|`), "\n", " ")
+}
@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+const testSpec = `# Export
+
+## Requirements
+
+- REQ-01: Exporting a report produces a CSV download.
+- REQ-02: Empty reports export the header row.
+- REQ-03: Exports are logged.
+
+## Acceptance Criteria
+
+- AC-01: (REQ-01) the download has one row per entry.
+- AC-02: (REQ-02) an empty report has only the header.
+`
+
+func testSources() Sources {
+	return Sources{
+		Spec: testSpec,
+		Tasks: map[string]string{
+			"TASK-02": "## TASK-02\n\nHandle empty reports (REQ-02, AC-02).",
+			"TASK-01": "## TASK-01\n\nImplement the export (REQ-01, AC-01).",
+		},
+		Commits: []worktree.Commit{
+			{Hash: "aaaaaaaaaaaaaaaa", Message: "TASK-01: export reports (REQ-01)\n\nBear-Session: 3f2a-ac12-9e"},
+			{Hash: "bbbbbbbbbbbbbbbb", Message: "TASK-03: log exports\n\nBear-Covers: REQ-03"},
+		},
+		Tests: []Test{
+			{Path: "export_test.go", Line: 10, Name: "TestExport_AC01", IDs: []string{"AC-01"}},
+			{Path: "log_test.go", Line: 4, Name: "TestLog_REQ03", IDs: []string{"REQ-03"}},
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	matrix := Build(testSources())
+
+	rows := map[string]Row{}
+	var ids []string
+	for _, row := range matrix.Rows {
+		rows[row.ID] = row
+		ids = append(ids, row.ID)
+	}
+	if !slices.Equal(ids, []string{"REQ-01", "REQ-02", "REQ-03", "AC-01", "AC-02"}) {
+		t.Fatalf("ids = %v", ids)
+	}
+
+	if got := rows["REQ-01"].Criteria; !slices.Equal(got, []string{"AC-01"}) {
+		t.Errorf("REQ-01 criteria = %v, want [AC-01]", got)
+	}
+	if got := rows["REQ-02"].Tasks; !slices.Equal(got, []string{"TASK-02"}) {
+		t.Errorf("REQ-02 tasks = %v, want [TASK-02]", got)
+	}
+	if got := rows["REQ-01"].Commits; !slices.Equal(got, []string{"aaaaaaaaaaaaaaaa"}) {
+		t.Errorf("REQ-01 commits = %v, want the first commit", got)
+	}
+	if got := rows["REQ-03"].Commits; !slices.Equal(got, []string{"bbbbbbbbbbbbbbbb"}) {
+		t.Errorf("REQ-03 commits = %v, want the commit with the trailer", got)
+	}
+	if got := rows["AC-01"].Commits; len(got) != 0 {
+		t.Errorf("AC-01 commits = %v, want none from the session trailer", got)
+	}
+
+	tested := map[string]bool{}
+	for id, row := range rows {
+		tested[id] = row.Tested
+	}
+	want := map[string]bool{"REQ-01": true, "REQ-02": false, "REQ-03": true, "AC-01": true, "AC-02": false}
+	for id, wantTested := range want {
+		if tested[id] != wantTested {
+			t.Errorf("%v tested = %v, want %v", id, tested[id], wantTested)
+		}
+	}
+
+	var untested []string
+	for _, row := range matrix.Untested() {
+		untested = append(untested, row.ID)
+	}
+	if !slices.Equal(untested, []string{"REQ-02", "AC-02"}) {
+		t.Errorf("untested = %v, want [REQ-02 AC-02]", untested)
+	}
+}
+
+func TestMatrix_Markdown(t *testing.T) {
+	markdown := Build(testSources()).Markdown()
+
+	for _, want := range []string{
+		"| REQ-01 | Exporting a report produces a CSV download. | AC-01 | TASK-01 | aaaaaaaaaaaa | - | yes |",
+		"| AC-01 | (REQ-01) the download has one row per entry. | - | TASK-01 | - | TestExport_AC01 (export_test.go:10) | yes |",
+		"## Not covered by tests\n\n- REQ-02: Empty reports export the header row.\n- AC-02:",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown does not contain %q:\n%v", want, markdown)
+		}
+	}
+}
+
+func TestMatrix_MarkdownWithoutIDs(t *testing.T) {
+	markdown := Build(Sources{Spec: "# Export\n\n- Export reports.\n"}).Markdown()
+	if !strings.Contains(markdown, "no requirement or acceptance criterion with an ID") {
+		t.Errorf("markdown = %q, want a note about the missing IDs", markdown)
+	}
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+// Test is a line of a test file that refers to requirement or criterion IDs,
+// usually the declaration of a test such as "TestExport_AC01".
+type Test struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	// Name is the identifier on the line that contains an ID, or the trimmed
+	// line if the ID is inside a string, as in `it("AC-01: exports")`.
+	Name string   `json:"name"`
+	IDs  []string `json:"ids"`
+}
+
+func (t Test) String() string {
+	return fmt.Sprintf("%v (%v:%d)", t.Name, t.Path, t.Line)
+}
+
+// testDirs are the directories whose files are all tests.
+var testDirs = []string{"test", "tests", "__tests__"}
+
+// specDirs are the directories whose files are all tests in the languages
+// whose test frameworks call tests specs, such as RSpec and Jasmine. In any
+// other language they are code, such as the spec package of Bear.
+var specDirs = []string{"spec", "specs"}
+
+var specDirExtensions = []string{".rb", ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"}
+
+// isTestFile reports whether the path is a test by the naming conventions of
+// the common languages, since the repository may be in any of them.
+func isTestFile(filePath string) bool {
+	base := path.Base(filePath)
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	switch {
+	case strings.HasSuffix(stem, "_test"), strings.HasSuffix(stem, "_spec"),
+		strings.HasPrefix(stem, "test_"),
+		strings.HasSuffix(stem, ".test"), strings.HasSuffix(stem, ".spec"),
+		strings.HasSuffix(stem, "Test"), strings.HasSuffix(stem, "Tests"):
+		return true
+	}
+	dirs := strings.Split(path.Dir(filePath), "/")
+	if slices.ContainsFunc(dirs, func(dir string) bool { return slices.Contains(testDirs, dir) }) {
+		return true
+	}
+	return slices.Contains(specDirExtensions, path.Ext(base)) &&
+		slices.ContainsFunc(dirs, func(dir string) bool { return slices.Contains(specDirs, dir) })
+}
+
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// FindTests returns the lines of the test files in the repository that refer
+// to requirement or criterion IDs. It searches the tree of rev, or the working
+// tree with the files that are not committed yet if rev is empty.
+func FindTests(repoDir, rev string) ([]Test, error) {
+	args := []string{"grep", "-n", "-I", "-i", "-E"}
+	if rev == "" {
+		args = append(args, "--untracked")
+	}
+	args = append(args, "-e", `(req|ac)[-_]?[0-9]`)
+	if rev != "" {
+		args = append(args, rev)
+	}
+	output, err := gitcmd.Run(repoDir, args...)
+	if err != nil {
+		// git grep exits with 1 if nothing matches.
+		var gitErr *gitcmd.Error
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 && gitErr.Stderr == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tests []Test
+	for _, line := range strings.Split(output, "\n") {
+		if rev != "" {
+			line = strings.TrimPrefix(line, rev+":")
+		}
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 || !isTestFile(fields[0]) {
+			continue
+		}
+		number, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		ids := spec.ExtractIDs(fields[2])
+		if len(ids) == 0 {
+			continue
+		}
+		tests = append(tests, Test{Path: fields[0], Line: number, Name: testName(fields[2]), IDs: ids})
+	}
+	return tests, nil
+}
+
+func testName(line string) string {
+	for _, identifier := range identifierPattern.FindAllString(line, -1) {
+		if len(spec.ExtractIDs(identifier)) > 0 {
+			return identifier
+		}
+	}
+	return truncate(strings.TrimSpace(line), maxTextLength)
+}
@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+)
+
+func TestIsTestFile(t *testing.T) {
+	tests := map[string]bool{
+		"export/export_test.go":          true,
+		"tests/test_export.py":           true,
+		"src/export.test.ts":             true,
+		"src/export.spec.js":             true,
+		"src/main/java/ExportTest.java":  true,
+		"spec/export_spec.rb":            true,
+		"spec/models/report.rb":          true,
+		"specs/report.js":                true,
+		"spec/trace.go":                  false,
+		"specs/schema.json":              false,
+		"test/fixtures/report.csv":       true,
+		"export/export.go":               false,
+		"docs/requirements.md":           false,
+		"src/main/java/ExportTests.java": true,
+	}
+	for path, want := range tests {
+		if got := isTestFile(path); got != want {
+			t.Errorf("isTestFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func newTestRepository(t *testing.T) string {
+	t.Helper()
+	dir := testutil.InitRepository(t)
+	testutil.WriteFile(t, dir, "README.md", "REQ-01 is documented here, not tested.\n")
+	testutil.Git(t, dir, "add", "-A")
+	testutil.Git(t, dir, "commit", "--quiet", "-m", "initial")
+	return dir
+}
+
+func TestFindTests(t *testing.T) {
+	dir := newTestRepository(t)
+	testutil.WriteFile(t, dir, "export/export_test.go", "package export\n\nfunc TestExport_AC01(t *testing.T) {}\n")
+	testutil.WriteFile(t, dir, "web/export.test.ts", "it('AC-02: exports an empty report (REQ-02)', () => {})\n")
+
+	tests, err := FindTests(dir, "")
+	if err != nil {
+		t.Fatalf("FindTests() error = %v", err)
+	}
+	if len(tests) != 2 {
+		t.Fatalf("got %d tests, want 2: %+v", len(tests), tests)
+	}
+	if got := tests[0]; got.Path != "export/export_test.go" || got.Line != 3 || got.Name != "TestExport_AC01" || !slices.Equal(got.IDs, []string{"AC-01"}) {
+		t.Errorf("tests[0] = %+v", got)
+	}
+	if got := tests[1]; got.Name != "it('AC-02: exports an empty report (REQ-02)', () => {})" || !slices.Equal(got.IDs, []string{"AC-02", "REQ-02"}) {
+		t.Errorf("tests[1] = %+v", got)
+	}
+
+	// The working tree files are not in HEAD yet.
+	tests, err = FindTests(dir, "HEAD")
+	if err != nil {
+		t.Fatalf("FindTests(HEAD) error = %v", err)
+	}
+	if len(tests) != 0 {
+		t.Errorf("FindTests(HEAD) = %+v, want none", tests)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sds-lab-dev/bear-go/trace"
+)
+
+func runTrace(_ config, args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("trace", stderr)
+	format := flags.String("format", "markdown", "output format: markdown or json")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bear trace [-format markdown|json] <session-id>")
+		fmt.Fprintln(stderr, "Run it in the workspace of the session.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || (*format != "markdown" && *format != "json") {
+		flags.Usage()
+		return 2
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to get current working directory: %v\n", err)
+		return 1
+	}
+	sources, err := trace.Collect(cwd, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to collect the session artifacts: %v\n", err)
+		return 1
+	}
+	matrix := trace.Build(sources)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(matrix); err != nil {
+			fmt.Fprintf(stderr, "failed to write the matrix: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprint(stdout, matrix.Markdown())
+	return 0
+}
@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+// agentStream carries the events of an agent that runs off the UI thread to
+// the model of its stage, and shows what the agent does: each complete block
+// is printed above the view, and the block that the agent is still
+// generating is shown live in it.
+type agentStream struct {
+	eventCh chan tea.Msg
+	// partialOutput accumulates the fragments of the block that the agent is
+	// still generating, until the complete block arrives.
+	partialOutput   string
+	partialType     ai.StreamMessageType
+	partialToolName string
+}
+
+func newAgentStream() agentStream {
+	return agentStream{eventCh: make(chan tea.Msg, 64)}
+}
+
+// streamCallback is the stream callback handler of the agent.
+func (s agentStream) streamCallback(msg ai.StreamMessage) {
+	log.Debug(fmt.Sprintf("sending a stream message to the event channel: %#v", msg))
+	if msg.Type == ai.StreamMessageTypeToolCallStructuredOutput {
+		// We don't render anything for StructuredOutput tool call messages.
+		log.Debug("received a StructuredOutput tool call stream message")
+		return
+	}
+	s.eventCh <- streamEventMsg{StreamMessage: msg}
+	log.Debug("stream message sent to event channel")
+}
+
+// toolApprovalHandler is the tool approval handler of the agent. It blocks
+// until the user decides or the request expires.
+func (s agentStream) toolApprovalHandler(
+	request ai.ToolApprovalRequest,
+) ai.ToolApprovalDecision {
+	log.Debug(fmt.Sprintf("sending a tool approval request to the event channel: %#v", request))
+	reply := make(chan ai.ToolApprovalDecision, 1)
+	s.eventCh <- toolApprovalRequestMsg{request: request, reply: reply}
+	select {
+	case decision := <-reply:
+		return decision
+	case <-request.Expired:
+		log.Debug(fmt.Sprintf("tool approval request expired: %v", request.ToolName))
+		s.eventCh <- toolApprovalExpiredMsg{reply: reply}
+		return ai.ToolApprovalDecisionDeny
+	}
+}
+
+// run runs the work of the agent off the UI thread and waits for its first
+// event.
+func (s agentStream) run(work func()) tea.Cmd {
+	return func() tea.Msg {
+		go work()
+		return <-s.eventCh
+	}
+}
+
+func (s agentStream) waitForNext() tea.Cmd {
+	return func() tea.Msg {
+		log.Debug("waiting for event in waitForNext()")
+		v := <-s.eventCh
+		log.Debug(fmt.Sprintf("received event in waitForNext(): %#v", v))
+		return v
+	}
+}
+
+// update prints a complete block, or keeps a fragment to show live, and waits
+// for the next event.
+func (s agentStream) update(msg streamEventMsg) (agentStream, tea.Cmd) {
+	log.Debug(fmt.Sprintf(
+		"received stream event message: type=%v, content=%v", msg.Type, msg.Content))
+
+	if msg.Type.IsIncremental() {
+		if msg.Type != s.partialType || msg.ToolName != s.partialToolName {
+			s.partialOutput = ""
+			s.partialType = msg.Type
+			s.partialToolName = msg.ToolName
+		}
+		s.partialOutput += msg.Content
+		return s, s.waitForNext()
+	}
+	// The complete block replaces the fragments shown so far.
+	s.partialOutput = ""
+
+	var content string
+	switch msg.Type {
+	case ai.StreamMessageTypeThinking:
+		content = renderStreamMessageThinking(msg.Content)
+	case ai.StreamMessageTypeToolCall:
+		content = renderStreamMessageToolCall(msg.Content)
+	case ai.StreamMessageTypeToolCallResult:
+		content = renderStreamMessageToolCallResult(msg.Content, msg.ToolName)
+	case ai.StreamMessageTypeWarning:
+		content = renderStreamMessageWarning(msg.Content)
+	case ai.StreamMessageTypeProgress:
+		content = renderStreamMessageProgress(msg.Progress)
+	case ai.StreamMessageTypeText:
+		// Fallthrough to default case.
+	default:
+		content = renderStreamMessageText(msg.Content)
+	}
+	return s, tea.Sequence(
+		tea.Printf("%v\n", content),
+		s.waitForNext(),
+	)
+}
+
+// writePartial writes the block that the agent is still generating, if any.
+func (s agentStream) writePartial(b *wrappedStringBuilder, width int) {
+	if s.partialOutput == "" {
+		return
+	}
+	b.WriteByte('\n')
+	b.WriteString(renderStreamMessagePartial(
+		s.partialType, s.partialToolName, s.partialOutput, width,
+	))
+	b.WriteByte('\n')
+}
@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/coding"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+// TaskRunner codes the tasks of the approved plan and merges them into the
+// session branch.
+type TaskRunner interface {
+	ai.StreamCallbackHandler
+	ai.ToolApprovalHandler
+	Run(plan ai.Plan) (coding.Result, error)
+	SessionBranch() string
+}
+
+type CodingProgressResult struct {
+	Err    error
+	Result coding.Result
+}
+
+type codingDoneMsg struct {
+	result coding.Result
+}
+
+// CodingProgressModel shows what the coding agents do while they implement the
+// tasks of the approved plan, and the outcome of merging their work. Once the
+// agents are done, the user finishes the session or rolls the workspace back
+// to its state before the coding.
+type CodingProgressModel struct {
+	spinner         spinner.Model
+	runner          TaskRunner
+	stream          agentStream
+	taskCount       int
+	windowSize      tea.WindowSizeMsg
+	toolApprovals   toolApprovalQueue
+	rollbackConfirm rollbackConfirm
+	// result is set once the agents are done.
+	result *coding.Result
+}
+
+func NewCodingProgressModel(plan ai.Plan, runner TaskRunner) CodingProgressModel {
+	terminalSize := GetTerminalSize()
+
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+
+	model := CodingProgressModel{
+		spinner:    s,
+		runner:     runner,
+		stream:     newAgentStream(),
+		taskCount:  len(plan.Tasks),
+		windowSize: tea.WindowSizeMsg{Width: terminalSize.Width, Height: terminalSize.Height},
+	}
+	model.runner.SetStreamCallbackHandler(model.stream.streamCallback)
+	model.runner.SetToolApprovalHandler(model.stream.toolApprovalHandler)
+	go model.run(plan)
+
+	return model
+}
+
+func (m CodingProgressModel) run(plan ai.Plan) {
+	log.Debug(fmt.Sprintf("coding plan of %d tasks", len(plan.Tasks)))
+	result, err := m.runner.Run(plan)
+	if err != nil {
+		m.stream.eventCh <- streamErrorMsg{err: err}
+		return
+	}
+	m.stream.eventCh <- codingDoneMsg{result: result}
+}
+
+func (m CodingProgressModel) Init() tea.Cmd {
+	return tea.Sequence(m.spinner.Tick, m.stream.waitForNext())
+}
+
+func (m CodingProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	log.Debug(fmt.Sprintf("received update message in CodingProgressModel: %#v", msg))
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.windowSize = msg
+		return m, nil
+	case streamEventMsg:
+		var cmd tea.Cmd
+		m.stream, cmd = m.stream.update(msg)
+		return m, cmd
+	case codingDoneMsg:
+		m.result = &msg.result
+		return m, tea.Println(RenderCodingResult(msg.result, m.runner.SessionBranch()))
+	case streamErrorMsg:
+		log.Debug(fmt.Sprintf("received stream error message: %v", msg.err))
+		return m, func() tea.Msg {
+			return CodingProgressResult{Err: msg.err}
+		}
+	case toolApprovalRequestMsg:
+		m.toolApprovals = m.toolApprovals.push(msg)
+		return m, m.stream.waitForNext()
+	case toolApprovalExpiredMsg:
+		toolApprovals, toolName, ok := m.toolApprovals.expire(msg.reply)
+		if !ok {
+			return m, m.stream.waitForNext()
+		}
+		m.toolApprovals = toolApprovals
+		cmd := tea.Sequence(
+			tea.Println(renderStreamMessageWarning(
+				fmt.Sprintf("The agent stopped waiting for the approval of %v, so the tool call was denied.", toolName),
+			)),
+			m.stream.waitForNext(),
+		)
+		return m, cmd
+	case tea.KeyMsg:
+		if m.toolApprovals.len() > 0 {
+			var cmd tea.Cmd
+			m.toolApprovals, cmd = m.toolApprovals.update(msg)
+			return m, cmd
+		}
+		if m.rollbackConfirm.open {
+			var cmd tea.Cmd
+			m.rollbackConfirm, cmd = m.rollbackConfirm.update(msg)
+			return m, cmd
+		}
+		return m.handleKeyMsg(msg)
+	}
+
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return m, cmd
+}
+
+func (m CodingProgressModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.result == nil {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "enter":
+		result := *m.result
+		return m, func() tea.Msg {
+			return CodingProgressResult{Result: result}
+		}
+	case "ctrl+r":
+		m.rollbackConfirm.open = true
+	}
+	return m, nil
+}
+
+func (m CodingProgressModel) View() string {
+	if m.toolApprovals.len() > 0 {
+		return m.toolApprovals.view(m.windowSize.Width)
+	}
+	if m.rollbackConfirm.open {
+		return m.rollbackConfirm.view(m.windowSize.Width)
+	}
+
+	b := newWrappedStringBuilder(m.windowSize.Width)
+	if m.result != nil {
+		b.WriteString(renderAgentActivePrompt(
+			"Press Enter to finish, or Ctrl+R to roll the workspace back to its state before the coding.",
+			true,
+		))
+		b.WriteByte('\n')
+		return b.String()
+	}
+	b.WriteString(renderAgentActivePrompt(
+		fmt.Sprintf("%vCoding the %d tasks of the plan, each in a worktree of its own...", m.spinner.View(), m.taskCount),
+		false,
+	))
+	b.WriteByte('\n')
+	m.stream.writePartial(b, m.windowSize.Width)
+	return b.String()
+}
+
+// RenderCodingResult renders the merge result followed by the tasks whose
+// agents failed, and tells where the merged work is.
+func RenderCodingResult(result coding.Result, sessionBranch string) string {
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+
+	var b strings.Builder
+	b.WriteString(RenderMergeResult(result.Merge))
+	for _, failure := range result.Failed {
+		reason, _, _ := strings.Cut(failure.Err.Error(), "\n")
+		b.WriteString("\n  " + errorStyle.Render("failed    ") + failure.TaskID)
+		b.WriteString(bodyStyle.Render(fmt.Sprintf(" (%v)", reason)))
+	}
+	fmt.Fprintf(&b, "\n\nThe merged work is on the branch %v; your checkout is untouched.", sessionBranch)
+	return b.String()
+}
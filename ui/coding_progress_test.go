@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/coding"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+type mockTaskRunner struct {
+	result coding.Result
+	err    error
+}
+
+func (r *mockTaskRunner) Run(ai.Plan) (coding.Result, error) {
+	return r.result, r.err
+}
+
+func (r *mockTaskRunner) SessionBranch() string {
+	return "bear/session-1"
+}
+
+func (r *mockTaskRunner) SetStreamCallbackHandler(func(ai.StreamMessage)) {}
+
+func (r *mockTaskRunner) SetToolApprovalHandler(func(ai.ToolApprovalRequest) ai.ToolApprovalDecision) {
+}
+
+func TestCodingProgressModel_ReportsTheResult(t *testing.T) {
+	runner := &mockTaskRunner{result: coding.Result{Merge: worktree.MergeResult{Merged: []string{"TASK-01"}}}}
+	var m tea.Model = NewCodingProgressModel(planWithTitle("Export"), runner)
+
+	done, ok := findMsg[codingDoneMsg](runCmd(m.(CodingProgressModel).stream.waitForNext()))
+	if !ok {
+		t.Fatal("expected the coding to finish")
+	}
+	m, cmd := m.Update(done)
+	if _, ok := findMsg[CodingProgressResult](runCmd(cmd)); ok {
+		t.Fatal("the result must wait for the user to finish")
+	}
+	if view := stripANSI(m.View()); !strings.Contains(view, "Ctrl+R") {
+		t.Errorf("expected the rollback in the view, got %q", view)
+	}
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	result, ok := findMsg[CodingProgressResult](runCmd(cmd))
+	if !ok || result.Err != nil || len(result.Result.Merge.Merged) != 1 {
+		t.Errorf("expected the coding result, got %#v", result)
+	}
+}
+
+func TestCodingProgressModel_CtrlRConfirmsRollbackOnlyWhenDone(t *testing.T) {
+	var m tea.Model = NewCodingProgressModel(planWithTitle("Export"), &mockTaskRunner{})
+	ctrlR := tea.KeyMsg{Type: tea.KeyCtrlR}
+
+	m, _ = m.Update(ctrlR)
+	if m.(CodingProgressModel).rollbackConfirm.open {
+		t.Error("ctrl+r must be ignored while the agents are working")
+	}
+
+	m, _ = m.Update(codingDoneMsg{})
+	m, cmd := m.Update(ctrlR)
+	if cmd != nil || !m.(CodingProgressModel).rollbackConfirm.open {
+		t.Fatal("ctrl+r should ask for confirmation before rolling back")
+	}
+	if !strings.Contains(m.View(), "[y] Roll back") {
+		t.Errorf("expected the confirmation in the view, got %q", m.View())
+	}
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if cmd == nil {
+		t.Fatal("expected a command after confirming")
+	}
+	if _, ok := cmd().(RollbackRequestMsg); !ok {
+		t.Error("expected a RollbackRequestMsg")
+	}
+}
+
+func TestCodingProgressModel_RollbackCanBeCancelled(t *testing.T) {
+	var m tea.Model = NewCodingProgressModel(planWithTitle("Export"), &mockTaskRunner{})
+	m, _ = m.Update(codingDoneMsg{})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+
+	if cmd != nil || m.(CodingProgressModel).rollbackConfirm.open {
+		t.Error("n should cancel the rollback")
+	}
+}
+
+func TestCodingProgressModel_ReportsTheError(t *testing.T) {
+	runner := &mockTaskRunner{err: errors.New("merge failed")}
+	var m tea.Model = NewCodingProgressModel(planWithTitle("Export"), runner)
+
+	failed, ok := findMsg[streamErrorMsg](runCmd(m.(CodingProgressModel).stream.waitForNext()))
+	if !ok {
+		t.Fatal("expected the coding to fail")
+	}
+	_, cmd := m.Update(failed)
+	result, ok := findMsg[CodingProgressResult](runCmd(cmd))
+	if !ok || result.Err == nil {
+		t.Errorf("expected the error, got %#v", result)
+	}
+}
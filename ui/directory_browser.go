@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	// browserMaxDepth and browserMaxDirectories bound the scan of the current
+	// directory, which may be a large tree such as the home directory.
+	browserMaxDepth       = 4
+	browserMaxDirectories = 5000
+	// browserRows is the number of matching directories shown at once.
+	browserRows = 10
+)
+
+// directoryBrowser picks a directory below the current directory by fuzzy
+// matching the typed query against the relative paths.
+type directoryBrowser struct {
+	dirs     []string
+	matches  []string
+	selected int
+	// scanning tells that the directories are still being listed, which is
+	// done off the UI thread since the tree may be large.
+	scanning bool
+}
+
+func newDirectoryBrowser(dirs []string) *directoryBrowser {
+	b := &directoryBrowser{dirs: dirs}
+	b.filter("")
+	return b
+}
+
+func (b *directoryBrowser) filter(query string) {
+	type scored struct {
+		dir   string
+		score int
+	}
+	var candidates []scored
+	for _, dir := range b.dirs {
+		if score, ok := fuzzyScore(query, dir); ok {
+			candidates = append(candidates, scored{dir, score})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b scored) int {
+		return cmp.Or(cmp.Compare(a.score, b.score), cmp.Compare(len(a.dir), len(b.dir)))
+	})
+
+	b.matches = b.matches[:0]
+	for _, candidate := range candidates {
+		b.matches = append(b.matches, candidate.dir)
+	}
+	b.selected = 0
+}
+
+func (b *directoryBrowser) move(delta int) {
+	if len(b.matches) == 0 {
+		return
+	}
+	b.selected = (b.selected + delta + len(b.matches)) % len(b.matches)
+}
+
+// selection returns the selected directory, if any directory matches.
+func (b *directoryBrowser) selection() (string, bool) {
+	if len(b.matches) == 0 {
+		return "", false
+	}
+	return b.matches[b.selected], true
+}
+
+// visibleMatches returns the window of matches around the selection and the
+// index of the selection in it.
+func (b *directoryBrowser) visibleMatches() ([]string, int) {
+	start := max(0, b.selected-browserRows+1)
+	end := min(len(b.matches), start+browserRows)
+	return b.matches[start:end], b.selected - start
+}
+
+// fuzzyScore reports whether the characters of query appear in candidate in
+// order, ignoring case, and scores the match. A lower score is a better match:
+// every skipped character costs one, except the characters skipped to reach
+// the start of a path element or a word.
+func fuzzyScore(query, candidate string) (int, bool) {
+	queryRunes := []rune(strings.ToLower(query))
+	if len(queryRunes) == 0 {
+		return 0, true
+	}
+
+	score := 0
+	gap := 0
+	matched := 0
+	previous := '/'
+	for _, r := range strings.ToLower(candidate) {
+		if matched < len(queryRunes) && r == queryRunes[matched] {
+			if !isWordStart(previous, r) {
+				score += gap
+			}
+			gap = 0
+			matched++
+		} else {
+			gap++
+		}
+		previous = r
+	}
+	return score, matched == len(queryRunes)
+}
+
+func isWordStart(previous, r rune) bool {
+	return !unicode.IsLetter(previous) && !unicode.IsDigit(previous) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+// specDraft is a version of the spec in the revision loop.
+type specDraft struct {
+	text     string
+	findings []spec.Finding
+}
+
+// draftHistory keeps the versions of the spec in the order they were drafted,
+// so that a revision is shown as a diff against the draft it started from,
+// and the user can go back to an earlier draft.
+type draftHistory struct {
+	drafts []specDraft
+	// current is the index of the draft that the user is reviewing. The next
+	// revision starts from it.
+	current int
+}
+
+func (h draftHistory) len() int {
+	return len(h.drafts)
+}
+
+// reviewed returns the draft that the user is reviewing.
+func (h draftHistory) reviewed() specDraft {
+	return h.drafts[h.current]
+}
+
+// restore returns the text of the draft that the next revision must start
+// from, or "" if it is the latest draft, from which the agent starts anyway.
+func (h draftHistory) restore() string {
+	if h.current < len(h.drafts)-1 {
+		return h.drafts[h.current].text
+	}
+	return ""
+}
+
+// add makes the new draft the one being reviewed, and returns the view that
+// presents it: the whole spec for the first draft, and the changes to the
+// draft it started from for a revision, which is easier to review than the
+// whole spec again.
+func (h draftHistory) add(draft specDraft) (draftHistory, string) {
+	base := h.current
+	h.drafts = append(h.drafts, draft)
+	h.current = len(h.drafts) - 1
+	if len(h.drafts) == 1 {
+		return h, successStyle.Render("Draft spec:\n"+draft.text) + "\n" + renderSpecFindings(draft.findings)
+	}
+
+	header := successStyle.Render(fmt.Sprintf("Draft %d, changed from draft %d:", len(h.drafts), base+1))
+	diff := renderSpecDiff(spec.DiffSections(h.drafts[base].text, draft.text))
+	return h, header + "\n" + diff + "\n" + renderSpecFindings(draft.findings)
+}
+
+// show makes the draft with the index the one being reviewed, and returns the
+// view that prints it in full. It returns false if there is no such draft.
+func (h draftHistory) show(index, width int) (draftHistory, string, bool) {
+	if index < 0 || index >= len(h.drafts) {
+		return h, "", false
+	}
+	h.current = index
+	draft := h.drafts[index]
+
+	b := newWrappedStringBuilder(width)
+	b.WriteString(successStyle.Render(fmt.Sprintf("Draft %d of %d:", index+1, len(h.drafts))))
+	b.WriteByte('\n')
+	b.WriteString(draft.text)
+	b.WriteByte('\n')
+	b.WriteString(renderSpecFindings(draft.findings))
+	return h, b.String(), true
+}
+
+// writeStatus tells which draft the user is reviewing when there is more than
+// one.
+func (h draftHistory) writeStatus(b *wrappedStringBuilder) {
+	if len(h.drafts) <= 1 {
+		return
+	}
+	fmt.Fprintf(b,
+		"Reviewing draft %d of %d. Press Ctrl+P/Ctrl+N to go back and forth between the drafts; your feedback revises the one you are reviewing.",
+		h.current+1, len(h.drafts),
+	)
+	b.WriteByte('\n')
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDraftHistory_RevisionStartsFromReviewedDraft(t *testing.T) {
+	var history draftHistory
+	history, _ = history.add(specDraft{text: "# Spec\n\nfirst"})
+	history, view := history.add(specDraft{text: "# Spec\n\nsecond"})
+	if plain := stripANSI(view); !strings.Contains(plain, "Draft 2, changed from draft 1") {
+		t.Errorf("a revision should be shown as changes, got %q", plain)
+	}
+	if restore := history.restore(); restore != "" {
+		t.Errorf("restore() = %q, want nothing for the latest draft", restore)
+	}
+
+	history, _, ok := history.show(0, 80)
+	if !ok {
+		t.Fatal("expected the first draft to be shown")
+	}
+	if restore := history.restore(); restore != "# Spec\n\nfirst" {
+		t.Errorf("restore() = %q, want the reviewed draft", restore)
+	}
+	if _, _, ok := history.show(2, 80); ok {
+		t.Error("expected no draft after the latest")
+	}
+
+	history, view = history.add(specDraft{text: "# Spec\n\nthird"})
+	if plain := stripANSI(view); !strings.Contains(plain, "Draft 3, changed from draft 1") {
+		t.Errorf("the revision should be compared with the draft it started from, got %q", plain)
+	}
+}
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+// RenderMergeResult renders the outcome of merging the task branches into the
+// integration branch. Conflicts are listed with the files to resolve and the
+// branch to merge by hand.
+func RenderMergeResult(result worktree.MergeResult) string {
+	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000"))
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#000000")).Italic(true)
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+
+	var b strings.Builder
+	b.WriteString(prefixStyle.Render("● ") + headerStyle.Render("Merge result:"))
+	for _, taskID := range result.Merged {
+		b.WriteString("\n  " + successStyle.Render("merged    ") + taskID)
+	}
+	for _, conflict := range result.Conflicts {
+		b.WriteString("\n  " + errorStyle.Render("conflict  ") + conflict.TaskID)
+		b.WriteString(bodyStyle.Render(fmt.Sprintf(" (merge %v by hand)", conflict.Branch)))
+		for _, file := range conflict.Files {
+			b.WriteString("\n" + bodyStyle.Render("      "+file))
+		}
+	}
+	for _, taskID := range result.Skipped {
+		b.WriteString("\n  " + bodyStyle.Render("skipped   ") + taskID)
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/coding"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+func TestRenderMergeResult(t *testing.T) {
+	rendered := stripANSI(RenderMergeResult(worktree.MergeResult{
+		Merged: []string{"TASK-00"},
+		Conflicts: []worktree.MergeConflict{{
+			TaskID: "TASK-01",
+			Branch: "bear/task/session-1/TASK-01",
+			Files:  []string{"main.go", "go.mod"},
+		}},
+		Skipped: []string{"TASK-02"},
+	}))
+
+	lines := strings.Split(rendered, "\n")
+	expected := []string{
+		"● Merge result:",
+		"  merged    TASK-00",
+		"  conflict  TASK-01 (merge bear/task/session-1/TASK-01 by hand)",
+		"      main.go",
+		"      go.mod",
+		"  skipped   TASK-02",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %q", len(expected), len(lines), rendered)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestRenderCodingResult(t *testing.T) {
+	rendered := stripANSI(RenderCodingResult(coding.Result{
+		Merge: worktree.MergeResult{Merged: []string{"TASK-00"}, Skipped: []string{"TASK-02"}},
+		Failed: []coding.TaskFailure{{
+			TaskID: "TASK-01",
+			Err:    fmt.Errorf("%w: Start the export\n\nThe spec does not say which columns.", coding.ErrTaskNotDone),
+		}},
+	}, "bear/session-1"))
+
+	expected := "● Merge result:\n" +
+		"  merged    TASK-00\n" +
+		"  skipped   TASK-02\n" +
+		"  failed    TASK-01 (coding agent did not finish the task: Start the export)\n" +
+		"\n" +
+		"The merged work is on the branch bear/session-1; your checkout is untouched."
+	if rendered != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, rendered)
+	}
+}
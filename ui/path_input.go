@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sds-lab-dev/bear-go/workspace"
+)
+
+// pathInput is the one-line input that takes the path of a file to attach.
+// Tab completes the path against baseDir, as a shell does.
+type pathInput struct {
+	textarea    textarea.Model
+	baseDir     string
+	completions []string
+}
+
+func newPathInput(baseDir, placeholder string, width int) pathInput {
+	ta := textarea.New()
+	ta.Placeholder = placeholder
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.SetWidth(width)
+	ta.SetHeight(1)
+	ta.KeyMap.InsertNewline.SetEnabled(false)
+	return pathInput{textarea: ta, baseDir: baseDir}
+}
+
+func (p pathInput) open() (pathInput, tea.Cmd) {
+	p.textarea.Reset()
+	p.completions = nil
+	return p, p.textarea.Focus()
+}
+
+func (p pathInput) close() pathInput {
+	p.textarea.Reset()
+	p.textarea.Blur()
+	p.completions = nil
+	return p
+}
+
+func (p pathInput) value() string {
+	return strings.TrimSpace(p.textarea.Value())
+}
+
+func (p pathInput) setWidth(width int) pathInput {
+	p.textarea.SetWidth(width)
+	return p
+}
+
+// update handles the keys other than Enter and Esc, which the owner of the
+// input handles.
+func (p pathInput) update(msg tea.KeyMsg) (pathInput, tea.Cmd) {
+	if msg.String() == "tab" {
+		completed, matches := workspace.CompleteFilePath(p.baseDir, p.textarea.Value())
+		p.textarea.SetValue(completed)
+		p.textarea.CursorEnd()
+		p.completions = nil
+		if len(matches) > 1 {
+			p.completions = matches
+		}
+		return p, nil
+	}
+
+	p.completions = nil
+	var cmd tea.Cmd
+	p.textarea, cmd = p.textarea.Update(msg)
+	return p, cmd
+}
+
+func (p pathInput) view() string {
+	if len(p.completions) == 0 {
+		return p.textarea.View()
+	}
+	return p.textarea.View() + "\n" + renderPathCompletions(p.completions)
+}
@@ -0,0 +1,243 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+type PlanPromptResult struct {
+	Err          error
+	ApprovedPlan ai.Plan
+}
+
+type planDraftMsg struct {
+	plan ai.Plan
+}
+
+type planFeedbackMsg struct {
+	feedback string
+}
+
+type planApprovedMsg struct{}
+
+type planPromptModelState int
+
+const (
+	planStateDrafting planPromptModelState = iota
+	planStateWaitUserFeedback
+	planStateApproved
+)
+
+// PlanPromptModel has the agent break the approved spec down into tasks, and
+// revises the plan with the user's feedback until the user approves it.
+type PlanPromptModel struct {
+	textarea      textarea.Model
+	spinner       spinner.Model
+	planWriter    ai.PlanWriter
+	stream        agentStream
+	state         planPromptModelState
+	errorMessage  string
+	windowSize    tea.WindowSizeMsg
+	toolApprovals toolApprovalQueue
+	// plan is the latest draft, which the user reviews.
+	plan ai.Plan
+}
+
+func NewPlanPromptModel(approvedSpec string, planWriter ai.PlanWriter) PlanPromptModel {
+	terminalSize := GetTerminalSize()
+
+	ta := textarea.New()
+	ta.Placeholder = "Split the second task, merge the last two, ..."
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.SetWidth(terminalSize.Width)
+	ta.SetHeight(min(10, terminalSize.Height/2))
+	ta.KeyMap.InsertNewline.SetEnabled(false)
+	ta.Focus()
+
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+
+	model := PlanPromptModel{
+		textarea:   ta,
+		spinner:    s,
+		planWriter: planWriter,
+		stream:     newAgentStream(),
+		state:      planStateDrafting,
+		windowSize: tea.WindowSizeMsg{Width: terminalSize.Width, Height: terminalSize.Height},
+	}
+	model.planWriter.SetStreamCallbackHandler(model.stream.streamCallback)
+	model.planWriter.SetToolApprovalHandler(model.stream.toolApprovalHandler)
+	go model.draftPlan(approvedSpec)
+
+	return model
+}
+
+func (m PlanPromptModel) draftPlan(approvedSpec string) {
+	log.Debug("drafting plan")
+	plan, err := m.planWriter.DraftPlan(approvedSpec)
+	m.sendPlan(plan, err)
+}
+
+func (m PlanPromptModel) revisePlan(feedback string) {
+	log.Debug(fmt.Sprintf("revising plan with user feedback: %s", feedback))
+	plan, err := m.planWriter.RevisePlan(feedback)
+	m.sendPlan(plan, err)
+}
+
+func (m PlanPromptModel) sendPlan(plan ai.Plan, err error) {
+	if err != nil {
+		m.stream.eventCh <- streamErrorMsg{err: err}
+		return
+	}
+	m.stream.eventCh <- planDraftMsg{plan: plan}
+}
+
+func (m PlanPromptModel) Init() tea.Cmd {
+	return tea.Sequence(m.spinner.Tick, m.stream.waitForNext())
+}
+
+func (m PlanPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	log.Debug(fmt.Sprintf("received update message in PlanPromptModel: %#v", msg))
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.textarea.SetWidth(msg.Width)
+		m.textarea.SetHeight(min(10, msg.Height/2))
+		m.windowSize = msg
+		return m, nil
+	case streamEventMsg:
+		var cmd tea.Cmd
+		m.stream, cmd = m.stream.update(msg)
+		return m, cmd
+	case planDraftMsg:
+		m.state = planStateWaitUserFeedback
+		m.plan = msg.plan
+		return m, tea.Println(successStyle.Render("Draft plan:\n" + msg.plan.Markdown()))
+	case planFeedbackMsg:
+		m.state = planStateDrafting
+		m.textarea.Reset()
+		cmd := tea.Sequence(
+			tea.Printf("Your feedback:\n%v\n", strings.Join(wrapWords(msg.feedback, m.windowSize.Width), "\n")),
+			m.stream.run(func() { m.revisePlan(msg.feedback) }),
+		)
+		return m, cmd
+	case planApprovedMsg:
+		return m.handlePlanApprovedMsg()
+	case streamErrorMsg:
+		log.Debug(fmt.Sprintf("received stream error message: %v", msg.err))
+		return m, func() tea.Msg {
+			return PlanPromptResult{Err: msg.err}
+		}
+	case toolApprovalRequestMsg:
+		m.toolApprovals = m.toolApprovals.push(msg)
+		return m, m.stream.waitForNext()
+	case toolApprovalExpiredMsg:
+		toolApprovals, toolName, ok := m.toolApprovals.expire(msg.reply)
+		if !ok {
+			return m, m.stream.waitForNext()
+		}
+		m.toolApprovals = toolApprovals
+		cmd := tea.Sequence(
+			tea.Println(renderStreamMessageWarning(
+				fmt.Sprintf("The agent stopped waiting for the approval of %v, so the tool call was denied.", toolName),
+			)),
+			m.stream.waitForNext(),
+		)
+		return m, cmd
+	case tea.KeyMsg:
+		if m.toolApprovals.len() > 0 {
+			var cmd tea.Cmd
+			m.toolApprovals, cmd = m.toolApprovals.update(msg)
+			return m, cmd
+		}
+		return m.handleKeyMsg(msg)
+	}
+
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return m, cmd
+}
+
+func (m PlanPromptModel) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.state != planStateWaitUserFeedback {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "enter":
+		feedback := strings.TrimSpace(m.textarea.Value())
+		if feedback == "" {
+			m.errorMessage = "Please enter your feedback."
+			return m, nil
+		}
+		return m, func() tea.Msg {
+			return planFeedbackMsg{feedback: feedback}
+		}
+	case "ctrl+y":
+		return m, func() tea.Msg {
+			return planApprovedMsg{}
+		}
+	case "shift+enter", "alt+enter":
+		m.textarea.InsertString("\n")
+		return m, nil
+	}
+
+	m.errorMessage = ""
+	var cmd tea.Cmd
+	m.textarea, cmd = m.textarea.Update(msg)
+	return m, cmd
+}
+
+func (m PlanPromptModel) handlePlanApprovedMsg() (tea.Model, tea.Cmd) {
+	log.Debug(fmt.Sprintf("received plan approved message: %d tasks", len(m.plan.Tasks)))
+	if err := m.planWriter.ApprovePlan(); err != nil {
+		return m, func() tea.Msg {
+			return PlanPromptResult{Err: err}
+		}
+	}
+	m.state = planStateApproved
+	plan := m.plan
+	cmd := tea.Sequence(
+		tea.Println(successStyle.Render(fmt.Sprintf("Approved the plan of %d tasks.", len(plan.Tasks)))),
+		func() tea.Msg {
+			return PlanPromptResult{ApprovedPlan: plan}
+		},
+	)
+	return m, cmd
+}
+
+func (m PlanPromptModel) View() string {
+	if m.toolApprovals.len() > 0 {
+		return m.toolApprovals.view(m.windowSize.Width)
+	}
+
+	b := newWrappedStringBuilder(m.windowSize.Width)
+	switch m.state {
+	case planStateDrafting:
+		b.WriteString(renderAgentActivePrompt(
+			fmt.Sprintf("%vBreaking the approved spec down into tasks...", m.spinner.View()),
+			false,
+		))
+		b.WriteByte('\n')
+		m.stream.writePartial(b, m.windowSize.Width)
+	case planStateWaitUserFeedback:
+		b.WriteString(renderAgentActivePrompt(
+			"Please review the plan above and provide your feedback. Press Enter when you're done, or Ctrl+Y to approve the plan and start coding.",
+			true,
+		))
+		b.WriteByte('\n')
+		b.WriteString(m.textarea.View())
+		if m.errorMessage != "" {
+			b.WriteByte('\n')
+			b.WriteString(errorStyle.Render(m.errorMessage))
+		}
+	}
+	return b.String()
+}
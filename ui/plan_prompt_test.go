@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+type mockPlanWriter struct {
+	plans    []ai.Plan
+	feedback []string
+	approved bool
+	err      error
+}
+
+func (m *mockPlanWriter) DraftPlan(_ string) (ai.Plan, error) {
+	return m.next()
+}
+
+func (m *mockPlanWriter) RevisePlan(userFeedback string) (ai.Plan, error) {
+	m.feedback = append(m.feedback, userFeedback)
+	return m.next()
+}
+
+func (m *mockPlanWriter) next() (ai.Plan, error) {
+	if m.err != nil {
+		return ai.Plan{}, m.err
+	}
+	plan := m.plans[0]
+	m.plans = m.plans[1:]
+	return plan, nil
+}
+
+func (m *mockPlanWriter) ApprovePlan() error {
+	m.approved = true
+	return nil
+}
+
+func (m *mockPlanWriter) SetStreamCallbackHandler(_ func(ai.StreamMessage)) {}
+
+func (m *mockPlanWriter) SetToolApprovalHandler(_ func(ai.ToolApprovalRequest) ai.ToolApprovalDecision) {
+}
+
+func planWithTitle(title string) ai.Plan {
+	return ai.Plan{Tasks: []ai.Task{{ID: "TASK-01", Title: title, Covers: []string{"REQ-01"}}}}
+}
+
+func typeText(m tea.Model, text string) tea.Model {
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+	return updated
+}
+
+func TestPlanPromptModel_RevisesAndApprovesPlan(t *testing.T) {
+	writer := &mockPlanWriter{plans: []ai.Plan{planWithTitle("Export"), planWithTitle("Export as CSV")}}
+	var m tea.Model = NewPlanPromptModel("# Spec", writer)
+
+	draft, ok := findMsg[planDraftMsg](runCmd(m.(PlanPromptModel).stream.waitForNext()))
+	if !ok {
+		t.Fatal("expected the first draft")
+	}
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 200, Height: 24})
+	m, _ = m.Update(draft)
+	if !strings.Contains(stripANSI(m.View()), "Ctrl+Y to approve the plan") {
+		t.Errorf("expected the feedback prompt, got %q", stripANSI(m.View()))
+	}
+
+	m = typeText(m, "Name the format.")
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	feedback, ok := findMsg[planFeedbackMsg](runCmd(cmd))
+	if !ok {
+		t.Fatal("expected the feedback to be sent")
+	}
+	m, cmd = m.Update(feedback)
+	revised, ok := findMsg[planDraftMsg](runCmd(cmd))
+	if !ok || revised.plan.Tasks[0].Title != "Export as CSV" {
+		t.Fatalf("expected the revised plan, got %#v", revised)
+	}
+	if len(writer.feedback) != 1 || writer.feedback[0] != "Name the format." {
+		t.Errorf("unexpected feedback: %v", writer.feedback)
+	}
+	m, _ = m.Update(revised)
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlY})
+	approved, ok := findMsg[planApprovedMsg](runCmd(cmd))
+	if !ok {
+		t.Fatal("expected Ctrl+Y to approve the plan")
+	}
+	_, cmd = m.Update(approved)
+	result, ok := findMsg[PlanPromptResult](runCmd(cmd))
+	if !ok || result.Err != nil || result.ApprovedPlan.Tasks[0].Title != "Export as CSV" {
+		t.Errorf("expected the revised plan to be approved, got %#v", result)
+	}
+	if !writer.approved {
+		t.Error("the plan writer should be told about the approval")
+	}
+}
+
+func TestPlanPromptModel_DraftFailureEndsWithError(t *testing.T) {
+	writer := &mockPlanWriter{err: errors.New("planner failed")}
+	var m tea.Model = NewPlanPromptModel("# Spec", writer)
+
+	failure, ok := findMsg[streamErrorMsg](runCmd(m.(PlanPromptModel).stream.waitForNext()))
+	if !ok {
+		t.Fatal("expected the failure")
+	}
+	_, cmd := m.Update(failure)
+	result, ok := findMsg[PlanPromptResult](runCmd(cmd))
+	if !ok || result.Err == nil {
+		t.Errorf("expected an error result, got %#v", result)
+	}
+}
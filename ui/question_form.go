@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+// questionForm is the form in which the user answers the clarifying questions
+// one at a time. A choice question is answered by picking its options, and can
+// always be answered with an option of the user's own, typed next to "Other".
+// The answers start as the defaults that the agent recommends.
+type questionForm struct {
+	questions []ai.Question
+	answers   []questionAnswer
+	// current is the index of the question being answered.
+	current int
+	// input edits the text of the current question: the answer of a text
+	// question, or the own option of a choice question.
+	input textarea.Model
+}
+
+// questionAnswer is the answer to a question as the user is giving it.
+type questionAnswer struct {
+	text string
+	// selected tells which options are picked, where the last index is the
+	// own option. Used for choice questions.
+	selected []bool
+	// cursor is the option under the cursor. Used for choice questions.
+	cursor int
+}
+
+func newQuestionForm(questions []ai.Question, width int) questionForm {
+	input := textarea.New()
+	input.ShowLineNumbers = false
+	input.CharLimit = 0
+	input.SetWidth(width)
+	input.KeyMap.InsertNewline.SetEnabled(false)
+
+	f := questionForm{
+		questions: questions,
+		answers:   make([]questionAnswer, len(questions)),
+		input:     input,
+	}
+	for i, question := range questions {
+		answer := &f.answers[i]
+		if !isChoiceQuestion(question) {
+			if len(question.Default) > 0 {
+				answer.text = question.Default[0]
+			}
+			continue
+		}
+		answer.selected = make([]bool, len(question.Options)+1)
+		answer.cursor = -1
+		for j, option := range question.Options {
+			if isDefaultOption(question, option) {
+				answer.selected[j] = true
+				if answer.cursor < 0 {
+					answer.cursor = j
+				}
+			}
+		}
+		answer.cursor = max(answer.cursor, 0)
+	}
+	f.load()
+	return f
+}
+
+func isChoiceQuestion(question ai.Question) bool {
+	return len(question.Options) > 0
+}
+
+// isDefaultOption reports whether the agent recommends the option.
+func isDefaultOption(question ai.Question, option string) bool {
+	return slices.ContainsFunc(question.Default, func(d string) bool {
+		return strings.EqualFold(d, option)
+	})
+}
+
+func (f questionForm) focus() (questionForm, tea.Cmd) {
+	cmd := f.input.Focus()
+	return f, cmd
+}
+
+func (f questionForm) blur() questionForm {
+	f.input.Blur()
+	return f
+}
+
+func (f questionForm) setWidth(width int) questionForm {
+	f.input.SetWidth(width)
+	return f
+}
+
+func (f questionForm) isLast() bool {
+	return f.current == len(f.questions)-1
+}
+
+// move goes to the question delta away from the current one, keeping the
+// text typed so far.
+func (f questionForm) move(delta int) questionForm {
+	f.save()
+	f.current = max(0, min(len(f.questions)-1, f.current+delta))
+	f.load()
+	return f
+}
+
+func (f *questionForm) save() {
+	f.answers[f.current].text = f.input.Value()
+}
+
+func (f *questionForm) load() {
+	if isChoiceQuestion(f.questions[f.current]) {
+		f.input.Placeholder = "Type your own answer."
+		f.input.SetHeight(1)
+	} else {
+		f.input.Placeholder = "Type your answer."
+		f.input.SetHeight(3)
+	}
+	f.input.SetValue(f.answers[f.current].text)
+	f.input.CursorEnd()
+}
+
+// onOwnOption reports whether the cursor of the current choice question is on
+// the own option, where the typed keys go to the input.
+func (f questionForm) onOwnOption() bool {
+	question := f.questions[f.current]
+	return isChoiceQuestion(question) && f.answers[f.current].cursor == len(question.Options)
+}
+
+// update handles the keys other than Enter, which the owner of the form
+// handles. Tab and Shift+Tab go between the questions, ↑/↓ move between the
+// options of a choice question and Space picks the option under the cursor.
+// A single choice is picked by moving to it.
+func (f questionForm) update(msg tea.KeyMsg) (questionForm, tea.Cmd) {
+	switch msg.String() {
+	case "tab":
+		return f.move(1), nil
+	case "shift+tab":
+		return f.move(-1), nil
+	}
+
+	question := f.questions[f.current]
+	if !isChoiceQuestion(question) {
+		var cmd tea.Cmd
+		f.input, cmd = f.input.Update(msg)
+		return f, cmd
+	}
+
+	answer := &f.answers[f.current]
+	switch msg.String() {
+	case "up", "down":
+		delta := 1
+		if msg.String() == "up" {
+			delta = -1
+		}
+		answer.cursor = max(0, min(len(question.Options), answer.cursor+delta))
+		if question.Type != ai.QuestionTypeMultipleChoice {
+			f.pick(answer.cursor)
+		}
+		return f, nil
+	case " ":
+		if !f.onOwnOption() {
+			f.pick(answer.cursor)
+			return f, nil
+		}
+	case "y", "n":
+		if question.Type == ai.QuestionTypeYesNo && !f.onOwnOption() {
+			answer.cursor = map[string]int{"y": 0, "n": 1}[msg.String()]
+			f.pick(answer.cursor)
+			return f, nil
+		}
+	}
+
+	if !f.onOwnOption() {
+		return f, nil
+	}
+	// Typing an own option picks it.
+	answer.selected[len(question.Options)] = true
+	if question.Type != ai.QuestionTypeMultipleChoice {
+		f.pick(len(question.Options))
+	}
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return f, cmd
+}
+
+// insertNewline breaks the line of the text being typed, if any.
+func (f questionForm) insertNewline() questionForm {
+	if !isChoiceQuestion(f.questions[f.current]) || f.onOwnOption() {
+		f.input.InsertString("\n")
+	}
+	return f
+}
+
+// pick selects the option of the current question, which toggles it in a
+// multiple choice question and replaces the selection in the others.
+func (f *questionForm) pick(option int) {
+	answer := &f.answers[f.current]
+	if f.questions[f.current].Type == ai.QuestionTypeMultipleChoice {
+		answer.selected[option] = !answer.selected[option]
+		return
+	}
+	for i := range answer.selected {
+		answer.selected[i] = i == option
+	}
+}
+
+// validate returns the index of the first question that is not answered yet,
+// with the reason, or -1 if every question is answered. A text question left
+// empty is answered by the attached files, if any.
+func (f questionForm) validate(attached bool) (int, string) {
+	for i, question := range f.questions {
+		answer := f.answers[i]
+		text := f.textOf(i)
+		if !isChoiceQuestion(question) {
+			if text == "" && !attached {
+				return i, fmt.Sprintf("Please answer question %d.", i+1)
+			}
+			continue
+		}
+		if !slices.Contains(answer.selected, true) {
+			return i, fmt.Sprintf("Please pick an answer to question %d.", i+1)
+		}
+		if answer.selected[len(question.Options)] && text == "" {
+			return i, fmt.Sprintf("Please type your own answer to question %d, or pick one of the options.", i+1)
+		}
+	}
+	return -1, ""
+}
+
+// textOf returns the text typed for the question, which for the current
+// question is still in the input.
+func (f questionForm) textOf(i int) string {
+	if i == f.current {
+		return strings.TrimSpace(f.input.Value())
+	}
+	return strings.TrimSpace(f.answers[i].text)
+}
+
+// answerOf returns the answer to the question as text, with the picked options
+// separated by commas.
+func (f questionForm) answerOf(i int, attached bool) string {
+	question, answer := f.questions[i], f.answers[i]
+	text := f.textOf(i)
+	if !isChoiceQuestion(question) {
+		if text == "" && attached {
+			return attachmentsOnlyAnswer
+		}
+		return text
+	}
+	var picked []string
+	for j, option := range question.Options {
+		if answer.selected[j] {
+			picked = append(picked, option)
+		}
+	}
+	if answer.selected[len(question.Options)] && text != "" {
+		picked = append(picked, text)
+	}
+	return strings.Join(picked, ", ")
+}
+
+// formatAnswers writes the questions with their answers, for the agent and
+// the transcript. The questions that the user skipped are marked as not
+// answered.
+func (f questionForm) formatAnswers(attached bool) string {
+	var b strings.Builder
+	for i, question := range f.questions {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		answer := f.answerOf(i, attached)
+		if answer == "" {
+			answer = "(not answered)"
+		}
+		fmt.Fprintf(&b, "%d. %v\nAnswer: %v\n", i+1, question.Text, answer)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
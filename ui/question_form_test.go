@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+func TestQuestionForm_DefaultsArePicked(t *testing.T) {
+	form := newQuestionForm([]ai.Question{
+		{Text: "Which formats?", Type: ai.QuestionTypeMultipleChoice, Options: []string{"CSV", "JSON", "XML"}, Default: []string{"xml", "csv"}},
+		{Text: "Where?", Type: ai.QuestionTypeText, Default: []string{"In the CLI"}},
+	}, 80)
+
+	if got := form.answerOf(0, false); got != "CSV, XML" {
+		t.Errorf("answer to the choice question = %q, want the defaults", got)
+	}
+	if got := form.answerOf(1, false); got != "In the CLI" {
+		t.Errorf("answer to the text question = %q, want the default", got)
+	}
+	if index, reason := form.validate(false); index >= 0 {
+		t.Errorf("the defaults should answer every question, got %v", reason)
+	}
+}
+
+func TestQuestionForm_MultipleChoiceWithOwnOption(t *testing.T) {
+	form := newQuestionForm([]ai.Question{
+		{Text: "Which formats?", Type: ai.QuestionTypeMultipleChoice, Options: []string{"CSV", "JSON"}},
+	}, 80)
+	form, _ = form.focus()
+
+	keys := []tea.KeyMsg{
+		{Type: tea.KeySpace, Runes: []rune(" ")},
+		{Type: tea.KeyDown},
+		{Type: tea.KeyDown},
+	}
+	for _, key := range keys {
+		form, _ = form.update(key)
+	}
+	if !form.onOwnOption() {
+		t.Fatal("the cursor should be on the own option")
+	}
+	form, _ = form.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("YAML")})
+
+	if got := form.answerOf(0, false); got != "CSV, YAML" {
+		t.Errorf("answer = %q, want %q", got, "CSV, YAML")
+	}
+}
+
+func TestQuestionForm_OwnOptionMustBeTyped(t *testing.T) {
+	form := newQuestionForm([]ai.Question{
+		{Text: "Which database?", Type: ai.QuestionTypeSingleChoice, Options: []string{"Postgres", "SQLite"}, Default: []string{"Postgres"}},
+	}, 80)
+
+	form, _ = form.update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := form.answerOf(0, false); got != "SQLite" {
+		t.Fatalf("moving should pick a single choice, got %q", got)
+	}
+	form, _ = form.update(tea.KeyMsg{Type: tea.KeyDown})
+	form, _ = form.update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+
+	if index, _ := form.validate(false); index != 0 {
+		t.Errorf("an own option without text should not be an answer, got index %d", index)
+	}
+}
@@ -0,0 +1,32 @@
+package ui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// RollbackRequestMsg asks the caller to put the workspace back to the latest
+// snapshot. It is sent only once the coding agents are done.
+type RollbackRequestMsg struct{}
+
+// rollbackConfirm asks the user to confirm the rollback that Ctrl+R starts,
+// shown as a modal like the tool approval.
+type rollbackConfirm struct {
+	open bool
+}
+
+// update sends RollbackRequestMsg on y, and closes the modal without it on n
+// or Esc.
+func (c rollbackConfirm) update(msg tea.KeyMsg) (rollbackConfirm, tea.Cmd) {
+	switch msg.String() {
+	case "y":
+		c.open = false
+		return c, func() tea.Msg {
+			return RollbackRequestMsg{}
+		}
+	case "n", "esc":
+		c.open = false
+	}
+	return c, nil
+}
+
+func (c rollbackConfirm) view(width int) string {
+	return renderRollbackConfirmModal(width) + "\n"
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sds-lab-dev/bear-go/ai"
 	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/spec"
 )
 
 type SpecPromptResult struct {
@@ -21,17 +23,33 @@ type streamEventMsg struct {
 }
 
 type clarifyingQuestionsMsg struct {
-	questions []string
+	questions []ai.Question
 }
 
 type userAnswersMsg struct {
-	answers string
+	answers     string
+	attachments []ai.Attachment
+	// focus is the topic that the user wants more questions about. Used when
+	// the user asked for a follow-up round.
+	focus string
 }
 
+// skipQuestionsMsg ends the clarification with the answers so far and drafts
+// the spec right away.
+type skipQuestionsMsg struct {
+	answers     string
+	attachments []ai.Attachment
+}
+
+// attachmentsOnlyAnswer is the answer when the user attached files but typed
+// nothing.
+const attachmentsOnlyAnswer = "See the attached files."
+
 type clarifyingQuestionsDoneMsg struct{}
 
 type specDraftMsg struct {
-	draft string
+	draft    string
+	findings []spec.Finding
 }
 
 type userFeedbackMsg struct {
@@ -57,18 +75,37 @@ const (
 )
 
 type SpecPromptModel struct {
-	textarea     textarea.Model
-	spinner      spinner.Model
-	specWriter   ai.SpecWriter
-	eventCh      chan tea.Msg
-	state        specPromptModelState
-	errorMessage string
-	windowSize   tea.WindowSizeMsg
+	textarea      textarea.Model
+	spinner       spinner.Model
+	specWriter    ai.SpecWriter
+	stream        agentStream
+	state         specPromptModelState
+	errorMessage  string
+	windowSize    tea.WindowSizeMsg
+	toolApprovals toolApprovalQueue
+	drafts        draftHistory
+	// loadAttachment stages the file at the path as an attachment.
+	loadAttachment func(ref string) (ai.Attachment, error)
+	// attachInput takes the path of a file to attach to the answers. Used
+	// when attaching is true.
+	attachInput pathInput
+	attaching   bool
+	// answerAttachments are the files attached to the answers being typed.
+	answerAttachments []ai.Attachment
+	// form takes the answers to the clarifying questions. Used in
+	// specStateWaitUserAnswers.
+	form questionForm
+	// topicInput takes the topic that the user wants more questions about.
+	// Used when askingMore is true.
+	topicInput textarea.Model
+	askingMore bool
 }
 
 func NewSpecPromptModel(
 	userRequest string,
 	specWriter ai.SpecWriter,
+	workspaceDir string,
+	loadAttachment func(ref string) (ai.Attachment, error),
 ) SpecPromptModel {
 	terminalSize := GetTerminalSize()
 
@@ -79,80 +116,129 @@ func NewSpecPromptModel(
 	ta.SetWidth(terminalSize.Width)
 	ta.SetHeight(min(10, terminalSize.Height/2))
 	ta.KeyMap.InsertNewline.SetEnabled(false)
+	// Ctrl+P/Ctrl+N go back and forth between the drafts instead.
+	ta.KeyMap.LinePrevious = key.NewBinding(key.WithKeys("up"))
+	ta.KeyMap.LineNext = key.NewBinding(key.WithKeys("down"))
 	ta.Focus()
 
+	topic := textarea.New()
+	topic.Placeholder = "the error handling, the permissions, ..."
+	topic.ShowLineNumbers = false
+	topic.CharLimit = 0
+	topic.SetWidth(terminalSize.Width)
+	topic.SetHeight(1)
+	topic.KeyMap.InsertNewline.SetEnabled(false)
+
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 
 	model := SpecPromptModel{
-		textarea:   ta,
-		spinner:    s,
-		specWriter: specWriter,
-		eventCh:    make(chan tea.Msg, 64),
-		state:      specStatePrepareClarifyingQuestions,
+		textarea:       ta,
+		spinner:        s,
+		specWriter:     specWriter,
+		stream:         newAgentStream(),
+		state:          specStatePrepareClarifyingQuestions,
+		loadAttachment: loadAttachment,
+		attachInput:    newPathInput(workspaceDir, "screenshot.png, mockup.jpg or error.log", terminalSize.Width),
+		topicInput:     topic,
 	}
-	model.specWriter.SetStreamCallbackHandler(model.defaultStreamCallback)
+	model.specWriter.SetStreamCallbackHandler(model.stream.streamCallback)
+	model.specWriter.SetToolApprovalHandler(model.stream.toolApprovalHandler)
 	go model.getClarifyingQuestions(userRequest)
 
 	return model
 }
 
-func (m SpecPromptModel) defaultStreamCallback(msg ai.StreamMessage) {
-	log.Debug(fmt.Sprintf("sending a stream message to the event channel: %#v", msg))
-	if msg.Type == ai.StreamMessageTypeToolCallStructuredOutput {
-		// We don't render anything for StructuredOutput tool call messages.
-		log.Debug("received a StructuredOutput tool call stream message")
-		return
-	}
-	m.eventCh <- streamEventMsg{StreamMessage: msg}
-	log.Debug("stream message sent to event channel")
-}
-
 func (m SpecPromptModel) getClarifyingQuestions(input string) {
 	log.Debug(fmt.Sprintf("getting clarifying questions for input: %s", input))
 
 	questions, err := m.specWriter.GetInitialClarifyingQuestions(input)
+	m.sendClarifyingQuestions(questions, err)
+}
+
+// getNextClarifyingQuestions asks for the next questions, focused on the
+// topic if the user asked for more questions about one.
+func (m SpecPromptModel) getNextClarifyingQuestions(answers string, attachments []ai.Attachment, focus string) {
+	log.Debug(fmt.Sprintf("getting next clarifying questions for answers: %s (focus: %s)", answers, focus))
+
+	if len(attachments) > 0 {
+		m.specWriter.AddAttachments(attachments)
+	}
+	var questions []ai.Question
+	var err error
+	if focus != "" {
+		questions, err = m.specWriter.AskMoreAbout(answers, focus)
+	} else {
+		questions, err = m.specWriter.GetNextClarifyingQuestions(answers)
+	}
+	m.sendClarifyingQuestions(questions, err)
+}
+
+func (m SpecPromptModel) skipQuestionsAndDraftSpec(answers string, attachments []ai.Attachment) {
+	log.Debug(fmt.Sprintf("skipping the remaining clarifying questions with answers: %s", answers))
+
+	if len(attachments) > 0 {
+		m.specWriter.AddAttachments(attachments)
+	}
+	if err := m.specWriter.SkipClarifyingQuestions(answers); err != nil {
+		m.stream.eventCh <- streamErrorMsg{err: err}
+		return
+	}
+	m.draftSpec()
+}
+
+func (m SpecPromptModel) sendClarifyingQuestions(questions []ai.Question, err error) {
 	if err != nil {
 		log.Debug(fmt.Sprintf("sending streamErrorMsg to the event channel: %#v", err))
-		m.eventCh <- streamErrorMsg{err: err}
+		m.stream.eventCh <- streamErrorMsg{err: err}
 		return
 	}
 	log.Debug(fmt.Sprintf("received clarifying questions: %v", questions))
 
 	if len(questions) > 0 {
-		m.eventCh <- clarifyingQuestionsMsg{questions: questions}
+		m.stream.eventCh <- clarifyingQuestionsMsg{questions: questions}
 	} else {
-		m.eventCh <- clarifyingQuestionsDoneMsg{}
+		m.stream.eventCh <- clarifyingQuestionsDoneMsg{}
 	}
 }
 
 func (m SpecPromptModel) draftSpec() {
 	log.Debug("drafting spec")
 
-	spec, err := m.specWriter.DraftSpec()
+	draft, err := m.specWriter.DraftSpec()
 	if err != nil {
-		m.eventCh <- streamErrorMsg{err: err}
+		m.stream.eventCh <- streamErrorMsg{err: err}
 		return
 	}
-	log.Debug(fmt.Sprintf("received drafted spec: %v", spec))
+	log.Debug(fmt.Sprintf("received drafted spec: %v", draft))
+	findings := m.specWriter.LintSpec(draft)
 
 	log.Debug("sending drafted spec to event channel")
-	m.eventCh <- specDraftMsg{draft: spec}
+	m.stream.eventCh <- specDraftMsg{draft: draft, findings: findings}
 	log.Debug("drafted spec sent to event channel")
 }
 
-func (m SpecPromptModel) reviseSpec(feedback string) {
+// reviseSpec revises the latest draft, or the earlier draft restore if the
+// user went back to one.
+func (m SpecPromptModel) reviseSpec(feedback, restore string) {
 	log.Debug(fmt.Sprintf("revising spec with user feedback: %s", feedback))
 
-	spec, err := m.specWriter.ReviseSpec(feedback)
+	if restore != "" {
+		if err := m.specWriter.RestoreSpecDraft(restore); err != nil {
+			m.stream.eventCh <- streamErrorMsg{err: err}
+			return
+		}
+	}
+	draft, err := m.specWriter.ReviseSpec(feedback)
 	if err != nil {
-		m.eventCh <- streamErrorMsg{err: err}
+		m.stream.eventCh <- streamErrorMsg{err: err}
 		return
 	}
-	log.Debug(fmt.Sprintf("received revised spec: %v", spec))
+	log.Debug(fmt.Sprintf("received revised spec: %v", draft))
+	findings := m.specWriter.LintSpec(draft)
 
 	log.Debug("sending revised spec to event channel")
-	m.eventCh <- specDraftMsg{draft: spec}
+	m.stream.eventCh <- specDraftMsg{draft: draft, findings: findings}
 	log.Debug("revised spec sent to event channel")
 }
 
@@ -161,7 +247,7 @@ func (m SpecPromptModel) Init() tea.Cmd {
 		m.spinner.Tick,
 		func() tea.Msg {
 			log.Debug("waiting for event in Init()")
-			v := <-m.eventCh
+			v := <-m.stream.eventCh
 			log.Debug(fmt.Sprintf("received event in Init(): %#v", v))
 			return v
 		},
@@ -169,15 +255,6 @@ func (m SpecPromptModel) Init() tea.Cmd {
 	return cmd
 }
 
-func (m SpecPromptModel) waitForNext() tea.Cmd {
-	return func() tea.Msg {
-		log.Debug("waiting for event in waitForNext()")
-		v := <-m.eventCh
-		log.Debug(fmt.Sprintf("received event in waitForNext(): %#v", v))
-		return v
-	}
-}
-
 func (m SpecPromptModel) handleWindowSizeMsg(
 	msg tea.WindowSizeMsg,
 ) (tea.Model, tea.Cmd) {
@@ -185,36 +262,15 @@ func (m SpecPromptModel) handleWindowSizeMsg(
 		"received window size message: width=%d, height=%d", msg.Width, msg.Height))
 	m.textarea.SetWidth(msg.Width)
 	m.textarea.SetHeight(min(10, msg.Height/2))
+	m.attachInput = m.attachInput.setWidth(msg.Width)
+	m.topicInput.SetWidth(msg.Width)
+	if m.state == specStateWaitUserAnswers {
+		m.form = m.form.setWidth(msg.Width)
+	}
 	m.windowSize = msg
 	return m, nil
 }
 
-func (m SpecPromptModel) handleStreamEventMsg(
-	msg streamEventMsg,
-) (tea.Model, tea.Cmd) {
-	log.Debug(fmt.Sprintf(
-		"received stream event message: type=%v, content=%v", msg.Type, msg.Content))
-
-	var content string
-	switch msg.Type {
-	case ai.StreamMessageTypeThinking:
-		content = renderStreamMessageThinking(msg.Content)
-	case ai.StreamMessageTypeToolCall:
-		content = renderStreamMessageToolCall(msg.Content)
-	case ai.StreamMessageTypeToolCallResult:
-		content = renderStreamMessageToolCallResult(msg.Content)
-	case ai.StreamMessageTypeText:
-		// Fallthrough to default case.
-	default:
-		content = renderStreamMessageText(msg.Content)
-	}
-	cmd := tea.Sequence(
-		tea.Printf("%v\n", content),
-		m.waitForNext(),
-	)
-	return m, cmd
-}
-
 func (m SpecPromptModel) handleClarifyingQuestionsMsg(
 	msg clarifyingQuestionsMsg,
 ) (tea.Model, tea.Cmd) {
@@ -224,19 +280,15 @@ func (m SpecPromptModel) handleClarifyingQuestionsMsg(
 	log.Debug(fmt.Sprintf(
 		"received clarifying questions message: %v", msg.questions))
 	m.state = specStateWaitUserAnswers
-	b := newWrappedStringBuilder(m.windowSize.Width)
-	for i, s := range msg.questions {
-		fmt.Fprintf(b, "%v. %v\n", i+1, s)
-		if i+1 < len(msg.questions) {
-			fmt.Fprint(b, "\n")
-		}
-	}
-	questions := fmt.Sprintf("%v\n%v",
-		renderAgentInactivePrompt(
-			successStyle.Render("Clarifying questions:"), true,
-		), b.String(),
+	m.form = newQuestionForm(msg.questions, m.windowSize.Width)
+	var focus tea.Cmd
+	m.form, focus = m.form.focus()
+	// The questions are printed with the answers once they are submitted; the
+	// form shows them until then.
+	header := renderAgentInactivePrompt(
+		successStyle.Render(fmt.Sprintf("Clarifying questions (%d):", len(msg.questions))), true,
 	)
-	return m, tea.Println(questions)
+	return m, tea.Batch(tea.Println(header), focus)
 }
 
 func (m SpecPromptModel) handleUserAnswersMsg(
@@ -245,26 +297,53 @@ func (m SpecPromptModel) handleUserAnswersMsg(
 	log.Debug(fmt.Sprintf("received user answers message: %v", msg.answers))
 	// Go to next state to prepare clarifying questions based on user's answers.
 	m.state = specStatePrepareClarifyingQuestions
+	m.answerAttachments = nil
+	m.form = m.form.blur()
+	answers := m.renderAnswers(msg.answers, msg.attachments)
+	if msg.focus != "" {
+		answers += "\n" + successStyle.Render("Asked for more questions about: "+msg.focus)
+	}
 	cmd := tea.Sequence(
-		tea.Printf("Your answers:\n%v\n", wrapWords(msg.answers, m.windowSize.Width)),
-		func() tea.Msg {
-			go m.getClarifyingQuestions(msg.answers)
-			return <-m.eventCh
-		},
+		tea.Printf("Your answers:\n%v\n", answers),
+		m.stream.run(func() { m.getNextClarifyingQuestions(msg.answers, msg.attachments, msg.focus) }),
 	)
 	return m, cmd
 }
 
+func (m SpecPromptModel) handleSkipQuestionsMsg(
+	msg skipQuestionsMsg,
+) (tea.Model, tea.Cmd) {
+	log.Debug(fmt.Sprintf("received skip questions message: %v", msg.answers))
+	m.state = specStateSpecDrafting
+	m.answerAttachments = nil
+	m.form = m.form.blur()
+	answers := m.renderAnswers(msg.answers, msg.attachments) + "\n" + successStyle.Render(
+		"Skipped the remaining questions; the spec records them as assumptions or open questions.",
+	)
+	cmd := tea.Sequence(
+		tea.Printf("Your answers:\n%v\n", answers),
+		m.stream.run(func() { m.skipQuestionsAndDraftSpec(msg.answers, msg.attachments) }),
+	)
+	return m, cmd
+}
+
+func (m SpecPromptModel) renderAnswers(answers string, attachments []ai.Attachment) string {
+	rendered := strings.Join(wrapWords(answers, m.windowSize.Width), "\n")
+	if len(attachments) > 0 {
+		rendered += "\n" + renderRequestSources(nil, attachments)
+	}
+	return rendered
+}
+
 func (m SpecPromptModel) handleUserFeedbackMsg(
 	msg userFeedbackMsg,
 ) (tea.Model, tea.Cmd) {
 	log.Debug(fmt.Sprintf("received user feedback message: %v", msg.feedback))
+	restore := m.drafts.restore()
+	m.state = specStateSpecDrafting
 	cmd := tea.Sequence(
 		tea.Printf("Your feedback:\n%v\n", wrapWords(msg.feedback, m.windowSize.Width)),
-		func() tea.Msg {
-			go m.reviseSpec(msg.feedback)
-			return <-m.eventCh
-		},
+		m.stream.run(func() { m.reviseSpec(msg.feedback, restore) }),
 	)
 	return m, cmd
 }
@@ -274,10 +353,7 @@ func (m SpecPromptModel) handleClarifyingQuestionsDoneMsg() (tea.Model, tea.Cmd)
 	m.state = specStateSpecDrafting
 	cmd := tea.Sequence(
 		tea.Println(successStyle.Render("No more clarifying questions.")),
-		func() tea.Msg {
-			go m.draftSpec()
-			return <-m.eventCh
-		},
+		m.stream.run(m.draftSpec),
 	)
 	return m, cmd
 }
@@ -287,15 +363,47 @@ func (m SpecPromptModel) handleSpecDraftMsg(
 ) (tea.Model, tea.Cmd) {
 	log.Debug(fmt.Sprintf("received spec draft message: %v", msg.draft))
 	m.state = specStateWaitUserFeedback
-	return m, tea.Println(successStyle.Render("Draft spec:\n" + msg.draft))
+	var view string
+	m.drafts, view = m.drafts.add(specDraft{text: msg.draft, findings: msg.findings})
+	return m, tea.Println(view)
+}
+
+// browsingDrafts reports whether the user is reviewing one of several drafts,
+// so that Ctrl+P/Ctrl+N go between them.
+func (m SpecPromptModel) browsingDrafts() bool {
+	return m.state == specStateWaitUserFeedback && m.drafts.len() > 1
+}
+
+// showDraft prints the draft with the index in full and makes it the draft
+// that the next revision starts from.
+func (m SpecPromptModel) showDraft(index int) (tea.Model, tea.Cmd) {
+	if m.state != specStateWaitUserFeedback {
+		return m, nil
+	}
+	log.Debug(fmt.Sprintf("showing spec draft %d of %d", index+1, m.drafts.len()))
+	drafts, view, ok := m.drafts.show(index, m.windowSize.Width)
+	if !ok {
+		return m, nil
+	}
+	m.drafts = drafts
+	return m, tea.Println(view)
 }
 
 func (m SpecPromptModel) handleSpecApprovedMsg(
 	msg specApprovedMsg,
 ) (tea.Model, tea.Cmd) {
 	log.Debug(fmt.Sprintf("received spec approved message: %v", msg.spec))
+	if err := m.specWriter.ApproveSpec(msg.spec); err != nil {
+		return m.handleStreamErrorMsg(streamErrorMsg{err: err})
+	}
 	m.state = specStateSpecApproved
-	return m, tea.Println(successStyle.Render("Approved spec:\n" + msg.spec))
+	cmd := tea.Sequence(
+		tea.Println(successStyle.Render("Approved spec:\n"+msg.spec)),
+		func() tea.Msg {
+			return SpecPromptResult{ApprovedSpec: msg.spec}
+		},
+	)
+	return m, cmd
 }
 
 func (m SpecPromptModel) handleStreamErrorMsg(
@@ -310,22 +418,202 @@ func (m SpecPromptModel) handleStreamErrorMsg(
 	}
 }
 
+func (m SpecPromptModel) handleToolApprovalRequestMsg(
+	msg toolApprovalRequestMsg,
+) (tea.Model, tea.Cmd) {
+	log.Debug(fmt.Sprintf("received tool approval request message: %#v", msg.request))
+	m.toolApprovals = m.toolApprovals.push(msg)
+	// The agent is blocked on this decision, but keep waiting for events to
+	// learn if the request expires before the user decides.
+	return m, m.stream.waitForNext()
+}
+
+func (m SpecPromptModel) handleToolApprovalExpiredMsg(
+	msg toolApprovalExpiredMsg,
+) (tea.Model, tea.Cmd) {
+	toolApprovals, toolName, ok := m.toolApprovals.expire(msg.reply)
+	if !ok {
+		// The user decided before the request expired.
+		return m, m.stream.waitForNext()
+	}
+	m.toolApprovals = toolApprovals
+	cmd := tea.Sequence(
+		tea.Println(renderStreamMessageWarning(
+			fmt.Sprintf("The agent stopped waiting for the approval of %v, so the tool call was denied.", toolName),
+		)),
+		m.stream.waitForNext(),
+	)
+	return m, cmd
+}
+
 // TODO: external editor (Ctrl+G)
 func (m SpecPromptModel) handleKeyMsg(
 	msg tea.KeyMsg,
 ) (tea.Model, tea.Cmd) {
 	log.Debug(fmt.Sprintf("received key message: type=%v", msg.String()))
 
+	if m.attaching {
+		return m.handleAttachKeyMsg(msg)
+	}
+	if m.askingMore {
+		return m.handleAskMoreKeyMsg(msg)
+	}
+
 	switch msg.String() {
 	// Go to next step on Enter
 	case "enter":
 		return m.handleEnter()
+	case "ctrl+t":
+		if m.state != specStateWaitUserAnswers {
+			return m, nil
+		}
+		m.attaching = true
+		m.errorMessage = ""
+		m.form = m.form.blur()
+		var cmd tea.Cmd
+		m.attachInput, cmd = m.attachInput.open()
+		return m, cmd
+	case "ctrl+s":
+		if m.state != specStateWaitUserAnswers {
+			return m, nil
+		}
+		answers := m.form.formatAnswers(len(m.answerAttachments) > 0)
+		attachments := m.answerAttachments
+		return m, func() tea.Msg {
+			return skipQuestionsMsg{answers: answers, attachments: attachments}
+		}
+	case "ctrl+q":
+		if m.state != specStateWaitUserAnswers {
+			return m, nil
+		}
+		m.askingMore = true
+		m.errorMessage = ""
+		m.form = m.form.blur()
+		m.topicInput.Reset()
+		cmd := m.topicInput.Focus()
+		return m, cmd
+	case "ctrl+x":
+		if m.state == specStateWaitUserAnswers && len(m.answerAttachments) > 0 {
+			m.answerAttachments = m.answerAttachments[:len(m.answerAttachments)-1]
+		}
+		return m, nil
 	case "shift+enter", "alt+enter":
+		if m.state == specStateWaitUserAnswers {
+			m.form = m.form.insertNewline()
+			return m, nil
+		}
 		m.textarea.InsertString("\n")
 		return m, nil
-	default:
-		return m, nil
+	case "ctrl+p":
+		if m.browsingDrafts() {
+			return m.showDraft(m.drafts.current - 1)
+		}
+	case "ctrl+n":
+		if m.browsingDrafts() {
+			return m.showDraft(m.drafts.current + 1)
+		}
+	case "ctrl+y":
+		if m.state != specStateWaitUserFeedback {
+			return m, nil
+		}
+		approved := m.drafts.reviewed().text
+		return m, func() tea.Msg {
+			return specApprovedMsg{spec: approved}
+		}
+	}
+
+	m.errorMessage = ""
+	var cmd tea.Cmd
+	switch m.state {
+	case specStateWaitUserAnswers:
+		m.form, cmd = m.form.update(msg)
+	case specStateWaitUserFeedback:
+		m.textarea, cmd = m.textarea.Update(msg)
+	}
+	return m, cmd
+}
+
+// handleAttachKeyMsg handles the keys of the path input that Ctrl+T opens.
+// Enter attaches the file to the answers and Esc goes back to them.
+func (m SpecPromptModel) handleAttachKeyMsg(
+	msg tea.KeyMsg,
+) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m = m.closeAttachInput()
+		var cmd tea.Cmd
+		m.form, cmd = m.form.focus()
+		return m, cmd
+	case "enter":
+		ref := m.attachInput.value()
+		if ref == "" {
+			m.errorMessage = "Please enter the path of the file to attach."
+			return m, nil
+		}
+		attachment, err := m.loadAttachment(ref)
+		if err != nil {
+			log.Warning(fmt.Sprintf("failed to attach %v: %v", ref, err))
+			m.errorMessage = err.Error()
+			return m, nil
+		}
+		m.answerAttachments = append(m.answerAttachments, attachment)
+		m = m.closeAttachInput()
+		var cmd tea.Cmd
+		m.form, cmd = m.form.focus()
+		return m, cmd
+	}
+
+	m.errorMessage = ""
+	var cmd tea.Cmd
+	m.attachInput, cmd = m.attachInput.update(msg)
+	return m, cmd
+}
+
+// handleAskMoreKeyMsg handles the keys of the topic input that Ctrl+Q opens.
+// Enter sends the answers so far with the topic, and Esc goes back to the
+// answers.
+func (m SpecPromptModel) handleAskMoreKeyMsg(
+	msg tea.KeyMsg,
+) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m = m.closeTopicInput()
+		var cmd tea.Cmd
+		m.form, cmd = m.form.focus()
+		return m, cmd
+	case "enter":
+		topic := strings.TrimSpace(m.topicInput.Value())
+		if topic == "" {
+			m.errorMessage = "Please enter what the agent should ask more about."
+			return m, nil
+		}
+		answers := m.form.formatAnswers(len(m.answerAttachments) > 0)
+		attachments := m.answerAttachments
+		m = m.closeTopicInput()
+		return m, func() tea.Msg {
+			return userAnswersMsg{answers: answers, attachments: attachments, focus: topic}
+		}
 	}
+
+	m.errorMessage = ""
+	var cmd tea.Cmd
+	m.topicInput, cmd = m.topicInput.Update(msg)
+	return m, cmd
+}
+
+func (m SpecPromptModel) closeTopicInput() SpecPromptModel {
+	m.askingMore = false
+	m.topicInput.Reset()
+	m.topicInput.Blur()
+	m.errorMessage = ""
+	return m
+}
+
+func (m SpecPromptModel) closeAttachInput() SpecPromptModel {
+	m.attaching = false
+	m.attachInput = m.attachInput.close()
+	m.errorMessage = ""
+	return m
 }
 
 func (m SpecPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -335,11 +623,15 @@ func (m SpecPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		return m.handleWindowSizeMsg(msg)
 	case streamEventMsg:
-		return m.handleStreamEventMsg(msg)
+		var cmd tea.Cmd
+		m.stream, cmd = m.stream.update(msg)
+		return m, cmd
 	case clarifyingQuestionsMsg:
 		return m.handleClarifyingQuestionsMsg(msg)
 	case userAnswersMsg:
 		return m.handleUserAnswersMsg(msg)
+	case skipQuestionsMsg:
+		return m.handleSkipQuestionsMsg(msg)
 	case userFeedbackMsg:
 		return m.handleUserFeedbackMsg(msg)
 	case clarifyingQuestionsDoneMsg:
@@ -350,7 +642,18 @@ func (m SpecPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleSpecApprovedMsg(msg)
 	case streamErrorMsg:
 		return m.handleStreamErrorMsg(msg)
+	case toolApprovalRequestMsg:
+		return m.handleToolApprovalRequestMsg(msg)
+	case toolApprovalExpiredMsg:
+		return m.handleToolApprovalExpiredMsg(msg)
 	case tea.KeyMsg:
+		// The modal takes over the key handling. The events are still being
+		// waited for since the tool approval request arrived.
+		var cmd tea.Cmd
+		if m.toolApprovals.len() > 0 {
+			m.toolApprovals, cmd = m.toolApprovals.update(msg)
+			return m, cmd
+		}
 		return m.handleKeyMsg(msg)
 	}
 
@@ -367,6 +670,10 @@ func (m SpecPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m SpecPromptModel) handleEnter() (tea.Model, tea.Cmd) {
+	if m.state == specStateWaitUserAnswers {
+		return m.handleAnswersEnter()
+	}
+
 	value := strings.TrimSpace(m.textarea.Value())
 	if value == "" {
 		m.errorMessage = "Please enter your feedback."
@@ -374,10 +681,6 @@ func (m SpecPromptModel) handleEnter() (tea.Model, tea.Cmd) {
 	}
 
 	switch m.state {
-	case specStateWaitUserAnswers:
-		return m, func() tea.Msg {
-			return userAnswersMsg{answers: value}
-		}
 	case specStateWaitUserFeedback:
 		return m, func() tea.Msg {
 			return userFeedbackMsg{feedback: value}
@@ -387,9 +690,35 @@ func (m SpecPromptModel) handleEnter() (tea.Model, tea.Cmd) {
 	}
 }
 
+// handleAnswersEnter goes to the next question, or sends the answers on the
+// last one. If a question is not answered, it goes back to that question.
+func (m SpecPromptModel) handleAnswersEnter() (tea.Model, tea.Cmd) {
+	m.errorMessage = ""
+	if !m.form.isLast() {
+		m.form = m.form.move(1)
+		return m, nil
+	}
+
+	attached := len(m.answerAttachments) > 0
+	if index, reason := m.form.validate(attached); index >= 0 {
+		m.form = m.form.move(index - m.form.current)
+		m.errorMessage = reason
+		return m, nil
+	}
+	answers := m.form.formatAnswers(attached)
+	attachments := m.answerAttachments
+	return m, func() tea.Msg {
+		return userAnswersMsg{answers: answers, attachments: attachments}
+	}
+}
+
 func (m SpecPromptModel) View() string {
 	log.Debug(fmt.Sprintf("rendering spec prompt view: state=%v, errorMessage=%v", m.state, m.errorMessage))
 
+	if m.toolApprovals.len() > 0 {
+		return m.toolApprovals.view(m.windowSize.Width)
+	}
+
 	b := newWrappedStringBuilder(m.windowSize.Width)
 
 	switch m.state {
@@ -401,21 +730,10 @@ func (m SpecPromptModel) View() string {
 			),
 		)
 		b.WriteByte('\n')
+		m.stream.writePartial(b, m.windowSize.Width)
 		return b.String()
 	case specStateWaitUserAnswers:
-		b.WriteString(
-			renderAgentActivePrompt(
-				"Please answer the clarifying questions above. Press Enter when you're done.",
-				true,
-			),
-		)
-		b.WriteByte('\n')
-		b.WriteByte('\n')
-		b.WriteString(m.textarea.View())
-		if m.errorMessage != "" {
-			b.WriteByte('\n')
-			b.WriteString(errorStyle.Render(m.errorMessage))
-		}
+		m.writeAnswersView(b)
 		return b.String()
 	case specStateSpecDrafting:
 		b.WriteString(
@@ -425,15 +743,17 @@ func (m SpecPromptModel) View() string {
 			),
 		)
 		b.WriteByte('\n')
+		m.stream.writePartial(b, m.windowSize.Width)
 		return b.String()
 	case specStateWaitUserFeedback:
 		b.WriteString(
 			renderAgentActivePrompt(
-				"Please review the drafted spec above and provide your feedback. Press Enter when you're done.",
+				"Please review the drafted spec above and provide your feedback. Press Enter when you're done, or Ctrl+Y to approve the draft you are reviewing.",
 				true,
 			),
 		)
 		b.WriteByte('\n')
+		m.drafts.writeStatus(b)
 		b.WriteByte('\n')
 		b.WriteString(m.textarea.View())
 		if m.errorMessage != "" {
@@ -447,3 +767,50 @@ func (m SpecPromptModel) View() string {
 		panic(fmt.Sprintf("Unexpected state in View(): %v", m.state))
 	}
 }
+
+// writeAnswersView writes the question form with its help, or the input that
+// replaces it while the user attaches a file or names a topic.
+func (m SpecPromptModel) writeAnswersView(b *wrappedStringBuilder) {
+	switch {
+	case m.attaching:
+		b.WriteString(renderAgentActivePrompt("Attach a file to your answers:", true))
+		b.WriteByte('\n')
+		b.WriteString("Press Enter to attach, Tab to complete the path, or Esc to cancel.")
+		b.WriteByte('\n')
+		b.WriteByte('\n')
+		b.WriteString(m.attachInput.view())
+	case m.askingMore:
+		b.WriteString(renderAgentActivePrompt("What should the agent ask more about?", true))
+		b.WriteByte('\n')
+		b.WriteString("Press Enter to send your answers so far with the topic, or Esc to cancel.")
+		b.WriteByte('\n')
+		b.WriteByte('\n')
+		b.WriteString(m.topicInput.View())
+	default:
+		writeQuestionFormHelp(b)
+		b.WriteByte('\n')
+		b.WriteString(renderQuestionForm(m.form))
+	}
+	if len(m.answerAttachments) > 0 {
+		b.WriteByte('\n')
+		b.WriteString(renderRequestSources(nil, m.answerAttachments))
+	}
+	if m.errorMessage != "" {
+		b.WriteByte('\n')
+		b.WriteString(errorStyle.Render(m.errorMessage))
+	}
+}
+
+func writeQuestionFormHelp(b *wrappedStringBuilder) {
+	b.WriteString(
+		renderAgentActivePrompt(
+			"Please answer the clarifying questions. Press Enter for the next question and to submit on the last one, Tab/Shift+Tab to go between the questions, or Ctrl+T to attach a file.",
+			true,
+		),
+	)
+	b.WriteByte('\n')
+	b.WriteString("Use ↑/↓ to move between the options and Space to pick one; the recommended answers are picked to start with.")
+	b.WriteByte('\n')
+	b.WriteString("Press Ctrl+S to skip the remaining questions and draft the spec now, or Ctrl+Q to ask for more questions about a topic.")
+	b.WriteByte('\n')
+}
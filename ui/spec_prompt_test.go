@@ -2,28 +2,54 @@ package ui
 
 import (
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/spec"
 )
 
-type mockSpecWriter struct{}
+type mockSpecWriter struct {
+	restoredDraft string
+	attachments   []ai.Attachment
+	topic         string
+	skippedAnswer string
+	approvedDraft string
+	approveErr    error
+}
 
 func (m *mockSpecWriter) GetInitialClarifyingQuestions(
 	_ string,
-) ([]string, error) {
+) ([]ai.Question, error) {
 	return nil, nil
 }
 
 func (m *mockSpecWriter) GetNextClarifyingQuestions(
 	userAnswer string,
-) ([]string, error) {
+) ([]ai.Question, error) {
 	return nil, nil
 }
 
+func (m *mockSpecWriter) AskMoreAbout(
+	_ string, topic string,
+) ([]ai.Question, error) {
+	m.topic = topic
+	return nil, nil
+}
+
+func (m *mockSpecWriter) SkipClarifyingQuestions(userAnswer string) error {
+	m.skippedAnswer = userAnswer
+	return nil
+}
+
+func (m *mockSpecWriter) AddAttachments(attachments []ai.Attachment) {
+	m.attachments = append(m.attachments, attachments...)
+}
+
 func (m *mockSpecWriter) DraftSpec() (string, error) {
 	return "", nil
 }
@@ -32,19 +58,39 @@ func (m *mockSpecWriter) ReviseSpec(_ string) (string, error) {
 	return "", nil
 }
 
+func (m *mockSpecWriter) RestoreSpecDraft(draft string) error {
+	m.restoredDraft = draft
+	return nil
+}
+
+func (m *mockSpecWriter) ApproveSpec(draft string) error {
+	m.approvedDraft = draft
+	return m.approveErr
+}
+
+func (m *mockSpecWriter) LintSpec(_ string) []spec.Finding {
+	return nil
+}
+
 func (m *mockSpecWriter) SetStreamCallbackHandler(_ func(ai.StreamMessage)) {
 	// no-op for mock
 }
 
+func (m *mockSpecWriter) SetToolApprovalHandler(
+	_ func(ai.ToolApprovalRequest) ai.ToolApprovalDecision,
+) {
+	// no-op for mock
+}
+
 func readySpecPromptModel(t *testing.T) SpecPromptModel {
 	t.Helper()
-	m := NewSpecPromptModel("test request", &mockSpecWriter{})
+	m := NewSpecPromptModel("test request", &mockSpecWriter{}, t.TempDir(), nil)
 	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
 	return updated.(SpecPromptModel)
 }
 
 func TestSpecPromptModel_InitReturnsCommand(t *testing.T) {
-	m := NewSpecPromptModel("test request", &mockSpecWriter{})
+	m := NewSpecPromptModel("test request", &mockSpecWriter{}, t.TempDir(), nil)
 	cmd := m.Init()
 	if cmd == nil {
 		t.Error("Init should return a non-nil command")
@@ -71,7 +117,7 @@ func TestSpecPromptModel_ClarifyingQuestionsTransitionsState(t *testing.T) {
 	m := readySpecPromptModel(t)
 
 	msg := clarifyingQuestionsMsg{
-		questions: []string{"What is the scope?", "Who is the primary user?"},
+		questions: []ai.Question{{Text: "What is the scope?"}, {Text: "Who is the primary user?"}},
 	}
 
 	updated, cmd := m.Update(msg)
@@ -130,7 +176,7 @@ func TestSpecPromptModel_ErrorReturnsResult(t *testing.T) {
 }
 
 func TestSpecPromptModel_ViewInInitialState(t *testing.T) {
-	m := NewSpecPromptModel("test request", &mockSpecWriter{})
+	m := NewSpecPromptModel("test request", &mockSpecWriter{}, t.TempDir(), nil)
 	view := m.View()
 	plain := stripANSI(view)
 
@@ -144,21 +190,624 @@ func TestSpecPromptModel_EnterWithEmptyTextShowsError(t *testing.T) {
 
 	// Transition to WaitUserAnswers state
 	updated, _ := m.Update(clarifyingQuestionsMsg{
-		questions: []string{"What is the scope?"},
+		questions: []ai.Question{{Text: "What is the scope?"}},
 	})
 	m = updated.(SpecPromptModel)
 
-	// Press Enter with empty textarea
+	// Press Enter without answering
 	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
 	m = updated.(SpecPromptModel)
 
 	if cmd != nil {
-		t.Error("expected nil command when textarea is empty")
+		t.Error("expected nil command when the question is not answered")
 	}
 
 	view := m.View()
 	plain := stripANSI(view)
-	if !strings.Contains(plain, "Please enter your feedback") {
+	if !strings.Contains(plain, "Please answer question 1.") {
 		t.Error("view should show error message for empty input")
 	}
 }
+
+func TestSpecPromptModel_PartialStreamEventsRenderedLive(t *testing.T) {
+	m := readySpecPromptModel(t)
+
+	for _, fragment := range []string{"Reading the ", "workspace files"} {
+		updated, cmd := m.Update(streamEventMsg{
+			StreamMessage: ai.StreamMessage{
+				Type:    ai.StreamMessageTypeTextDelta,
+				Content: fragment,
+			},
+		})
+		m = updated.(SpecPromptModel)
+		if cmd == nil {
+			t.Fatal("expected command to wait for the next event")
+		}
+	}
+
+	plain := stripANSI(m.View())
+	if !strings.Contains(plain, "Reading the workspace files") {
+		t.Errorf("view should contain the accumulated partial text, got %q", plain)
+	}
+
+	updated, _ := m.Update(streamEventMsg{
+		StreamMessage: ai.StreamMessage{
+			Type:    ai.StreamMessageTypeText,
+			Content: "Reading the workspace files",
+		},
+	})
+	m = updated.(SpecPromptModel)
+
+	plain = stripANSI(m.View())
+	if strings.Contains(plain, "Reading the workspace files") {
+		t.Error("partial text should be cleared once the complete block arrives")
+	}
+}
+
+func TestSpecPromptModel_PartialStreamEventOfNewBlockReplacesPrevious(t *testing.T) {
+	m := readySpecPromptModel(t)
+
+	updated, _ := m.Update(streamEventMsg{
+		StreamMessage: ai.StreamMessage{
+			Type:    ai.StreamMessageTypeThinkingDelta,
+			Content: "pondering",
+		},
+	})
+	m = updated.(SpecPromptModel)
+	updated, _ = m.Update(streamEventMsg{
+		StreamMessage: ai.StreamMessage{
+			Type:     ai.StreamMessageTypeToolCallInputDelta,
+			Content:  `{"pattern"`,
+			ToolName: "Grep",
+		},
+	})
+	m = updated.(SpecPromptModel)
+
+	plain := stripANSI(m.View())
+	if strings.Contains(plain, "pondering") {
+		t.Error("fragments of the previous block should be replaced")
+	}
+	if !strings.Contains(plain, "Tool Call (Grep)...") {
+		t.Errorf("view should show the tool call header, got %q", plain)
+	}
+}
+
+func TestSpecPromptModel_ToolApprovalRequestShowsModal(t *testing.T) {
+	m := readySpecPromptModel(t)
+
+	reply := make(chan ai.ToolApprovalDecision, 1)
+	updated, cmd := m.Update(toolApprovalRequestMsg{
+		request: ai.ToolApprovalRequest{ToolName: "Bash", Input: "command: rm -rf build\n"},
+		reply:   reply,
+	})
+	m = updated.(SpecPromptModel)
+
+	if cmd == nil {
+		t.Error("expected a command that waits for the request to expire")
+	}
+	plain := stripANSI(m.View())
+	for _, want := range []string{"Bash", "rm -rf build", "Approve", "Deny", "Always allow"} {
+		if !strings.Contains(plain, want) {
+			t.Errorf("modal should contain %q, got %q", want, plain)
+		}
+	}
+}
+
+func TestSpecPromptModel_ToolApprovalKeysSendDecision(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected ai.ToolApprovalDecision
+	}{
+		{"y", ai.ToolApprovalDecisionApprove},
+		{"n", ai.ToolApprovalDecisionDeny},
+		{"a", ai.ToolApprovalDecisionAlwaysAllow},
+	}
+
+	for _, tt := range tests {
+		m := readySpecPromptModel(t)
+		reply := make(chan ai.ToolApprovalDecision, 1)
+		updated, _ := m.Update(toolApprovalRequestMsg{
+			request: ai.ToolApprovalRequest{ToolName: "Write", Input: "file_path: /tmp/x\n"},
+			reply:   reply,
+		})
+		m = updated.(SpecPromptModel)
+
+		updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.key)})
+		m = updated.(SpecPromptModel)
+
+		if cmd == nil {
+			t.Errorf("key %q: expected command to print the decision", tt.key)
+		}
+		select {
+		case decision := <-reply:
+			if decision != tt.expected {
+				t.Errorf("key %q: expected decision %v, got %v", tt.key, tt.expected, decision)
+			}
+		default:
+			t.Errorf("key %q: expected a decision to be sent", tt.key)
+		}
+		if strings.Contains(stripANSI(m.View()), "Always allow") {
+			t.Errorf("key %q: modal should be closed after the decision", tt.key)
+		}
+	}
+}
+
+func TestSpecPromptModel_ToolApprovalExpiryClosesModal(t *testing.T) {
+	m := readySpecPromptModel(t)
+	expired := make(chan struct{})
+	decisionCh := make(chan ai.ToolApprovalDecision, 1)
+	go func() {
+		decisionCh <- m.stream.toolApprovalHandler(ai.ToolApprovalRequest{ToolName: "Bash", Expired: expired})
+	}()
+
+	updated, _ := m.Update(nextEventOfType[toolApprovalRequestMsg](t, m.stream.eventCh))
+	m = updated.(SpecPromptModel)
+	if m.toolApprovals.len() != 1 {
+		t.Fatal("expected the modal to be open")
+	}
+
+	close(expired)
+	if decision := <-decisionCh; decision != ai.ToolApprovalDecisionDeny {
+		t.Errorf("an expired request should be denied, got %v", decision)
+	}
+	updated, cmd := m.Update(nextEventOfType[toolApprovalExpiredMsg](t, m.stream.eventCh))
+	m = updated.(SpecPromptModel)
+	if m.toolApprovals.len() != 0 {
+		t.Error("the modal should be closed when the request expires")
+	}
+	if cmd == nil {
+		t.Error("expected a command to resume waiting for events")
+	}
+}
+
+// nextEventOfType returns the next event of type T sent to the event channel,
+// skipping the events of the clarifying questions that the model asks for as
+// soon as it is created.
+func nextEventOfType[T tea.Msg](t *testing.T, eventCh <-chan tea.Msg) T {
+	t.Helper()
+	for {
+		select {
+		case msg := <-eventCh:
+			if event, ok := msg.(T); ok {
+				return event
+			}
+		case <-time.After(5 * time.Second):
+			var zero T
+			t.Fatalf("timed out waiting for %T", zero)
+			return zero
+		}
+	}
+}
+
+func TestSpecPromptModel_ToolApprovalRequestsAreQueued(t *testing.T) {
+	m := readySpecPromptModel(t)
+	first := make(chan ai.ToolApprovalDecision, 1)
+	second := make(chan ai.ToolApprovalDecision, 1)
+	for _, msg := range []toolApprovalRequestMsg{
+		{request: ai.ToolApprovalRequest{ToolName: "Bash", Input: "command: make test\n"}, reply: first},
+		{request: ai.ToolApprovalRequest{ToolName: "Write", Input: "file_path: /tmp/x\n"}, reply: second},
+	} {
+		updated, _ := m.Update(msg)
+		m = updated.(SpecPromptModel)
+	}
+
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "make test") || strings.Contains(plain, "/tmp/x") {
+		t.Errorf("the first request should be shown, got %q", plain)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = updated.(SpecPromptModel)
+	if decision := <-first; decision != ai.ToolApprovalDecisionApprove {
+		t.Errorf("expected the first request to be approved, got %v", decision)
+	}
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "/tmp/x") {
+		t.Errorf("the second request should be shown next, got %q", plain)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m = updated.(SpecPromptModel)
+	if decision := <-second; decision != ai.ToolApprovalDecisionDeny {
+		t.Errorf("expected the second request to be denied, got %v", decision)
+	}
+	if m.toolApprovals.len() != 0 {
+		t.Error("no request should be left waiting")
+	}
+}
+
+func TestSpecPromptModel_AlwaysAllowAnswersWaitingRequestsInScope(t *testing.T) {
+	m := readySpecPromptModel(t)
+	replies := []chan ai.ToolApprovalDecision{
+		make(chan ai.ToolApprovalDecision, 1),
+		make(chan ai.ToolApprovalDecision, 1),
+		make(chan ai.ToolApprovalDecision, 1),
+	}
+	for i, scope := range []string{"Bash(ls)", "Bash(ls)", "Bash(rm -rf /)"} {
+		updated, _ := m.Update(toolApprovalRequestMsg{
+			request: ai.ToolApprovalRequest{ToolName: "Bash", AlwaysAllowScope: scope},
+			reply:   replies[i],
+		})
+		m = updated.(SpecPromptModel)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = updated.(SpecPromptModel)
+
+	for i := range 2 {
+		select {
+		case decision := <-replies[i]:
+			if decision != ai.ToolApprovalDecisionAlwaysAllow {
+				t.Errorf("request %d: expected always allow, got %v", i, decision)
+			}
+		default:
+			t.Errorf("request %d: expected a decision to be sent", i)
+		}
+	}
+	if len(replies[2]) != 0 || m.toolApprovals.len() != 1 {
+		t.Error("a request outside the scope should still wait for the user")
+	}
+}
+
+func TestSpecPromptModel_ToolApprovalIgnoresOtherKeys(t *testing.T) {
+	m := readySpecPromptModel(t)
+	reply := make(chan ai.ToolApprovalDecision, 1)
+	updated, _ := m.Update(toolApprovalRequestMsg{
+		request: ai.ToolApprovalRequest{ToolName: "Bash"},
+		reply:   reply,
+	})
+	m = updated.(SpecPromptModel)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(SpecPromptModel)
+
+	if m.toolApprovals.len() != 1 {
+		t.Error("modal should stay open for unrelated keys")
+	}
+	if len(reply) != 0 {
+		t.Error("no decision should be sent for unrelated keys")
+	}
+}
+
+func TestSpecPromptModel_DraftsCanBeRevisitedAfterRevision(t *testing.T) {
+	m := readySpecPromptModel(t)
+	for _, draft := range []string{"# Spec\n\nfirst", "# Spec\n\nsecond"} {
+		updated, cmd := m.Update(specDraftMsg{draft: draft})
+		m = updated.(SpecPromptModel)
+		if cmd == nil {
+			t.Fatal("expected a command that prints the draft")
+		}
+	}
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "Reviewing draft 2 of 2") {
+		t.Errorf("view should show the latest draft is reviewed, got %q", plain)
+	}
+
+	ctrlP := tea.KeyMsg{Type: tea.KeyCtrlP}
+	updated, cmd := m.Update(ctrlP)
+	m = updated.(SpecPromptModel)
+	if cmd == nil {
+		t.Fatal("expected a command that prints the earlier draft")
+	}
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "Reviewing draft 1 of 2") {
+		t.Errorf("view should show the first draft is reviewed, got %q", plain)
+	}
+	if _, cmd := m.Update(ctrlP); cmd != nil {
+		t.Error("ctrl+p must be ignored on the first draft")
+	}
+
+	updated, _ = m.Update(userFeedbackMsg{feedback: "shorter"})
+	m = updated.(SpecPromptModel)
+	if m.state != specStateSpecDrafting {
+		t.Errorf("state = %v, want drafting while the spec is revised", m.state)
+	}
+}
+
+func TestSpecPromptModel_ApproveDraftBeingReviewed(t *testing.T) {
+	writer := &mockSpecWriter{}
+	m := NewSpecPromptModel("test request", writer, t.TempDir(), nil)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = updated.(SpecPromptModel)
+	ctrlY := tea.KeyMsg{Type: tea.KeyCtrlY}
+	if _, cmd := m.Update(ctrlY); cmd != nil {
+		t.Error("ctrl+y must be ignored before there is a draft")
+	}
+	for _, draft := range []string{"# Spec\n\nfirst", "# Spec\n\nsecond"} {
+		updated, _ := m.Update(specDraftMsg{draft: draft})
+		m = updated.(SpecPromptModel)
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m = updated.(SpecPromptModel)
+
+	_, cmd := m.Update(ctrlY)
+	if cmd == nil {
+		t.Fatal("expected a command that approves the draft")
+	}
+	approved, ok := cmd().(specApprovedMsg)
+	if !ok {
+		t.Fatal("expected a specApprovedMsg")
+	}
+	updated, cmd = m.Update(approved)
+	m = updated.(SpecPromptModel)
+	if m.state != specStateSpecApproved {
+		t.Errorf("state = %v, want approved", m.state)
+	}
+	if writer.approvedDraft != "# Spec\n\nfirst" {
+		t.Errorf("approved draft = %q, want the draft being reviewed", writer.approvedDraft)
+	}
+	result, ok := findMsg[SpecPromptResult](runCmd(cmd))
+	if !ok {
+		t.Fatal("expected a SpecPromptResult")
+	}
+	if result.Err != nil || result.ApprovedSpec != "# Spec\n\nfirst" {
+		t.Errorf("result = %+v, want the first draft approved", result)
+	}
+}
+
+func TestSpecPromptModel_ApprovalFailureEndsWithError(t *testing.T) {
+	writer := &mockSpecWriter{approveErr: errors.New("unexpected session state")}
+	m := NewSpecPromptModel("test request", writer, t.TempDir(), nil)
+	updated, _ := m.Update(specDraftMsg{draft: "# Spec"})
+	m = updated.(SpecPromptModel)
+
+	_, cmd := m.Update(specApprovedMsg{spec: "# Spec"})
+	result, ok := findMsg[SpecPromptResult](runCmd(cmd))
+	if !ok {
+		t.Fatal("expected a SpecPromptResult")
+	}
+	if result.Err == nil || result.ApprovedSpec != "" {
+		t.Errorf("result = %+v, want the error without an approved spec", result)
+	}
+}
+
+// runCmd runs the command and the commands that it sequences or batches, and
+// returns the messages that they produce.
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	value := reflect.ValueOf(msg)
+	if value.Kind() != reflect.Slice || value.Type().Elem() != reflect.TypeFor[tea.Cmd]() {
+		return []tea.Msg{msg}
+	}
+	var msgs []tea.Msg
+	for i := range value.Len() {
+		msgs = append(msgs, runCmd(value.Index(i).Interface().(tea.Cmd))...)
+	}
+	return msgs
+}
+
+func findMsg[T tea.Msg](msgs []tea.Msg) (T, bool) {
+	for _, msg := range msgs {
+		if found, ok := msg.(T); ok {
+			return found, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func TestSpecPromptModel_CursorKeysReachFeedbackWhileBrowsingDrafts(t *testing.T) {
+	m := readySpecPromptModel(t)
+	for _, draft := range []string{"# Spec\n\nfirst", "# Spec\n\nsecond"} {
+		updated, _ := m.Update(specDraftMsg{draft: draft})
+		m = updated.(SpecPromptModel)
+	}
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("ab")},
+		{Type: tea.KeyCtrlB},
+		{Type: tea.KeyRunes, Runes: []rune("X")},
+	} {
+		updated, _ := m.Update(key)
+		m = updated.(SpecPromptModel)
+	}
+
+	if got := m.textarea.Value(); got != "aXb" {
+		t.Errorf("ctrl+b should move the cursor of the feedback, got %q", got)
+	}
+	if m.drafts.current != 1 {
+		t.Errorf("ctrl+b should not change the draft, got %d", m.drafts.current)
+	}
+}
+
+func TestSpecPromptModel_ReviseSpecRestoresEarlierDraft(t *testing.T) {
+	writer := &mockSpecWriter{}
+	m := NewSpecPromptModel("test request", writer, t.TempDir(), nil)
+	<-m.stream.eventCh
+
+	m.reviseSpec("shorter", "# Spec\n\nfirst")
+	if _, ok := (<-m.stream.eventCh).(specDraftMsg); !ok {
+		t.Fatal("expected a specDraftMsg")
+	}
+	if writer.restoredDraft != "# Spec\n\nfirst" {
+		t.Errorf("restored draft = %q, want the first draft", writer.restoredDraft)
+	}
+}
+
+func TestSpecPromptModel_AttachFileToAnswers(t *testing.T) {
+	writer := &mockSpecWriter{}
+	m := NewSpecPromptModel("test request", writer, t.TempDir(), func(ref string) (ai.Attachment, error) {
+		return ai.Attachment{ID: "ATT-2", Kind: ai.AttachmentKindText, Name: ref, Text: "panic: nil map"}, nil
+	})
+	<-m.stream.eventCh
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = updated.(SpecPromptModel)
+
+	ctrlT := tea.KeyMsg{Type: tea.KeyCtrlT}
+	if updated, _ := m.Update(ctrlT); updated.(SpecPromptModel).attaching {
+		t.Fatal("ctrl+t must be ignored while the agent is working")
+	}
+
+	updated, _ = m.Update(clarifyingQuestionsMsg{questions: []ai.Question{{Text: "What does the log show?", Attachments: []string{"ATT-1"}}}})
+	m = updated.(SpecPromptModel)
+	updated, _ = m.Update(ctrlT)
+	m = updated.(SpecPromptModel)
+	if !m.attaching {
+		t.Fatal("ctrl+t should open the attach input while waiting for answers")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("error.log")})
+	m = updated.(SpecPromptModel)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(SpecPromptModel)
+	if m.attaching || len(m.answerAttachments) != 1 {
+		t.Fatalf("enter should attach the file, got %v", m.answerAttachments)
+	}
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "ATT-2: error.log (text)") {
+		t.Errorf("view should list the attached file, got %q", plain)
+	}
+
+	// The attachment alone is an answer.
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command that sends the answers")
+	}
+	answers, ok := cmd().(userAnswersMsg)
+	if !ok || !strings.Contains(answers.answers, "Answer: "+attachmentsOnlyAnswer) || len(answers.attachments) != 1 {
+		t.Fatalf("unexpected answers message: %#v", answers)
+	}
+
+	m.getNextClarifyingQuestions(answers.answers, answers.attachments, "")
+	<-m.stream.eventCh
+	if len(writer.attachments) != 1 || writer.attachments[0].ID != "ATT-2" {
+		t.Errorf("the attachments should be given to the spec writer, got %v", writer.attachments)
+	}
+}
+
+func TestSpecPromptModel_AnswerQuestionsInForm(t *testing.T) {
+	m := readySpecPromptModel(t)
+	updated, _ := m.Update(clarifyingQuestionsMsg{questions: []ai.Question{
+		{Text: "Who uses it?", Type: ai.QuestionTypeText},
+		{Text: "Keep the old API?", Type: ai.QuestionTypeYesNo, Options: []string{"Yes", "No"}, Default: []string{"Yes"}},
+	}})
+	m = updated.(SpecPromptModel)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("admins")})
+	m = updated.(SpecPromptModel)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(SpecPromptModel)
+	if cmd != nil || m.form.current != 1 {
+		t.Fatalf("enter should go to the next question, got question %d", m.form.current+1)
+	}
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "(•) Yes (recommended)") || !strings.Contains(plain, "1. Who uses it?: admins") {
+		t.Errorf("view should show the recommended answer and the earlier answer, got %q", plain)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m = updated.(SpecPromptModel)
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command that sends the answers")
+	}
+	answers, ok := cmd().(userAnswersMsg)
+	want := "1. Who uses it?\nAnswer: admins\n\n2. Keep the old API?\nAnswer: No"
+	if !ok || answers.answers != want {
+		t.Errorf("answers = %q, want %q", answers.answers, want)
+	}
+}
+
+func TestSpecPromptModel_UnansweredQuestionIsShownAgain(t *testing.T) {
+	m := readySpecPromptModel(t)
+	updated, _ := m.Update(clarifyingQuestionsMsg{questions: []ai.Question{
+		{Text: "Who uses it?", Type: ai.QuestionTypeText},
+		{Text: "Which formats?", Type: ai.QuestionTypeMultipleChoice, Options: []string{"CSV", "JSON"}, Default: []string{"json"}},
+	}})
+	m = updated.(SpecPromptModel)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(SpecPromptModel)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(SpecPromptModel)
+	if cmd != nil {
+		t.Fatal("the answers must not be sent with a question unanswered")
+	}
+	if m.form.current != 0 || m.errorMessage != "Please answer question 1." {
+		t.Errorf("expected to go back to question 1 with an error, got question %d and %q", m.form.current+1, m.errorMessage)
+	}
+}
+
+func TestSpecPromptModel_FeedbackKeysReachTextarea(t *testing.T) {
+	m := readySpecPromptModel(t)
+	updated, _ := m.Update(specDraftMsg{draft: "# Spec"})
+	m = updated.(SpecPromptModel)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("more detail")})
+	m = updated.(SpecPromptModel)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command that sends the feedback")
+	}
+	if feedback, ok := cmd().(userFeedbackMsg); !ok || feedback.feedback != "more detail" {
+		t.Errorf("unexpected feedback message: %#v", feedback)
+	}
+}
+
+func TestSpecPromptModel_SkipRemainingQuestions(t *testing.T) {
+	writer := &mockSpecWriter{}
+	m := NewSpecPromptModel("test request", writer, t.TempDir(), nil)
+	<-m.stream.eventCh
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = updated.(SpecPromptModel)
+	updated, _ = m.Update(clarifyingQuestionsMsg{questions: []ai.Question{{Text: "Who uses it?"}, {Text: "Which formats?"}}})
+	m = updated.(SpecPromptModel)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("admins")})
+	m = updated.(SpecPromptModel)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if cmd == nil {
+		t.Fatal("expected a command that skips the remaining questions")
+	}
+	skip, ok := cmd().(skipQuestionsMsg)
+	if !ok || skip.answers != "1. Who uses it?\nAnswer: admins\n\n2. Which formats?\nAnswer: (not answered)" {
+		t.Fatalf("unexpected skip message: %#v", skip)
+	}
+
+	updated, _ = m.Update(skip)
+	m = updated.(SpecPromptModel)
+	if m.state != specStateSpecDrafting {
+		t.Errorf("expected to draft the spec, got state %v", m.state)
+	}
+	m.skipQuestionsAndDraftSpec(skip.answers, skip.attachments)
+	if _, ok := (<-m.stream.eventCh).(specDraftMsg); !ok {
+		t.Error("expected the draft after skipping")
+	}
+	if writer.skippedAnswer != skip.answers {
+		t.Errorf("the answers so far should be given to the spec writer, got %q", writer.skippedAnswer)
+	}
+}
+
+func TestSpecPromptModel_AskMoreAboutTopic(t *testing.T) {
+	writer := &mockSpecWriter{}
+	m := NewSpecPromptModel("test request", writer, t.TempDir(), nil)
+	<-m.stream.eventCh
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = updated.(SpecPromptModel)
+	updated, _ = m.Update(clarifyingQuestionsMsg{questions: []ai.Question{{Text: "Who uses it?"}}})
+	m = updated.(SpecPromptModel)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlQ})
+	m = updated.(SpecPromptModel)
+	if !m.askingMore {
+		t.Fatal("ctrl+q should open the topic input")
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(SpecPromptModel)
+	if cmd != nil || m.errorMessage == "" {
+		t.Fatal("an empty topic should be rejected")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("permissions")})
+	m = updated.(SpecPromptModel)
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(SpecPromptModel)
+	if m.askingMore || cmd == nil {
+		t.Fatal("enter should send the answers with the topic")
+	}
+	answers, ok := cmd().(userAnswersMsg)
+	if !ok || answers.focus != "permissions" {
+		t.Fatalf("unexpected answers message: %#v", answers)
+	}
+
+	m.getNextClarifyingQuestions(answers.answers, answers.attachments, answers.focus)
+	<-m.stream.eventCh
+	if writer.topic != "permissions" {
+		t.Errorf("the topic should be given to the spec writer, got %q", writer.topic)
+	}
+}
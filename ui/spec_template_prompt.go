@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+type SpecTemplatePromptResult struct {
+	Template spec.Template
+}
+
+// SpecTemplatePromptModel lets the user choose the template of the spec, with
+// the template suggested for the request selected at first.
+type SpecTemplatePromptModel struct {
+	templates  []spec.Template
+	selected   int
+	suggested  int
+	windowSize tea.WindowSizeMsg
+}
+
+func NewSpecTemplatePromptModel(templates []spec.Template, suggested spec.Template) SpecTemplatePromptModel {
+	m := SpecTemplatePromptModel{templates: templates}
+	for i, template := range templates {
+		if template.Name == suggested.Name {
+			m.selected = i
+			m.suggested = i
+		}
+	}
+	return m
+}
+
+func (m SpecTemplatePromptModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m SpecTemplatePromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	log.Debug(fmt.Sprintf("received update message in SpecTemplatePromptModel: %#v", msg))
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.windowSize = msg
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up":
+			m.selected = (m.selected - 1 + len(m.templates)) % len(m.templates)
+		case "down":
+			m.selected = (m.selected + 1) % len(m.templates)
+		case "enter":
+			return m.handleEnter()
+		}
+	}
+	return m, nil
+}
+
+func (m SpecTemplatePromptModel) handleEnter() (tea.Model, tea.Cmd) {
+	template := m.templates[m.selected]
+	log.Info(fmt.Sprintf("spec template chosen: %v (%v)", template.Name, template.Source))
+
+	b := newWrappedStringBuilder(m.windowSize.Width)
+	b.WriteString(renderAgentInactivePrompt(successStyle.Render("Spec template:"), true))
+	b.WriteByte('\n')
+	b.WriteString(template.Title)
+
+	cmd := tea.Sequence(
+		tea.Printf("%v\n", b.String()),
+		func() tea.Msg {
+			return SpecTemplatePromptResult{Template: template}
+		},
+	)
+	return m, cmd
+}
+
+func (m SpecTemplatePromptModel) View() string {
+	b := newWrappedStringBuilder(m.windowSize.Width)
+	b.WriteString(renderAgentActivePrompt("Choose the spec template:", true))
+	b.WriteByte('\n')
+	b.WriteString("Press ↑/↓ to pick and Enter to confirm.")
+	b.WriteByte('\n')
+	b.WriteByte('\n')
+	b.WriteString(renderSpecTemplates(m.templates, m.selected, m.suggested))
+	b.WriteByte('\n')
+	return b.String()
+}
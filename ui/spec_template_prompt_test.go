@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sds-lab-dev/bear-go/spec"
+)
+
+func testSpecTemplates() []spec.Template {
+	return []spec.Template{
+		{Name: "general", Title: "General feature"},
+		{Name: "cli-feature", Title: "CLI feature", Description: "A command or flag."},
+		{Name: "library-api", Title: "Library API"},
+	}
+}
+
+func TestSpecTemplatePromptModel_SuggestedTemplateIsSelected(t *testing.T) {
+	m := NewSpecTemplatePromptModel(testSpecTemplates(), spec.Template{Name: "cli-feature"})
+
+	view := m.View()
+	if !strings.Contains(view, "> CLI feature (cli-feature) - suggested") {
+		t.Errorf("expected the suggested template to be selected, got:\n%v", view)
+	}
+	if !strings.Contains(view, "A command or flag.") {
+		t.Errorf("expected the description of the selected template, got:\n%v", view)
+	}
+}
+
+func TestSpecTemplatePromptModel_ArrowKeysMoveSelection(t *testing.T) {
+	var model tea.Model = NewSpecTemplatePromptModel(testSpecTemplates(), spec.Template{Name: "general"})
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyUp})
+	if got := model.(SpecTemplatePromptModel).selected; got != 2 {
+		t.Errorf("expected up to wrap to the last template, got %d", got)
+	}
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := model.(SpecTemplatePromptModel).selected; got != 1 {
+		t.Errorf("expected the second template to be selected, got %d", got)
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected Enter to confirm the selection")
+	}
+}
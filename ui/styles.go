@@ -1,10 +1,15 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
+
+	"github.com/sds-lab-dev/bear-go/ai"
+	"github.com/sds-lab-dev/bear-go/spec"
+	"github.com/sds-lab-dev/bear-go/workspace"
 )
 
 var (
@@ -69,7 +74,7 @@ func renderStreamMessageToolCall(msg string) string {
 		bodyStyle.Render(msg)
 }
 
-func renderStreamMessageToolCallResult(msg string) string {
+func renderStreamMessageToolCallResult(msg string, toolName string) string {
 	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000"))
 	headerStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#000000")).Italic(true)
@@ -79,8 +84,13 @@ func renderStreamMessageToolCallResult(msg string) string {
 	terminalWidth := GetTerminalSize().Width
 	truncatedMsg := truncateToVisualLines(msg, maxVisualLines, terminalWidth)
 
+	header := "Tool Call Result:"
+	if toolName != "" {
+		header = fmt.Sprintf("Tool Call Result (%v):", toolName)
+	}
+
 	return prefixStyle.Render("● ") +
-		headerStyle.Render("Tool Call Result:") +
+		headerStyle.Render(header) +
 		"\n" +
 		bodyStyle.Render(truncatedMsg)
 }
@@ -162,6 +172,40 @@ func truncateToVisualWidth(line string, maxWidth int) string {
 	return line
 }
 
+func renderStreamMessageWarning(msg string) string {
+	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("3")).Bold(true)
+
+	return prefixStyle.Render("● ") +
+		headerStyle.Render("Warning:") +
+		"\n" +
+		errorStyle.Render(msg)
+}
+
+func renderStreamMessageProgress(report ai.ProgressReport) string {
+	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000"))
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#000000")).Italic(true)
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+
+	var status string
+	switch report.Status {
+	case ai.ProgressStatusDone:
+		status = successStyle.Render(string(report.Status))
+	case ai.ProgressStatusBlocked:
+		status = errorStyle.Render(string(report.Status))
+	default:
+		status = string(report.Status)
+	}
+
+	return prefixStyle.Render("● ") +
+		headerStyle.Render(fmt.Sprintf("Progress (%v): ", report.TaskID)) +
+		status +
+		"\n" +
+		bodyStyle.Render(report.Message)
+}
+
 func renderStreamMessageText(msg string) string {
 	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000"))
 	headerStyle := lipgloss.NewStyle().
@@ -173,3 +217,431 @@ func renderStreamMessageText(msg string) string {
 		"\n" +
 		bodyStyle.Render(msg)
 }
+
+// renderStreamMessagePartial renders the block that the agent is still
+// generating. Only the most recent lines are shown because the block keeps
+// growing while it is rendered.
+func renderStreamMessagePartial(
+	messageType ai.StreamMessageType,
+	toolName string,
+	msg string,
+	terminalWidth int,
+) string {
+	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000"))
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#000000")).Italic(true)
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+
+	var header string
+	switch messageType {
+	case ai.StreamMessageTypeThinkingDelta:
+		header = "Thinking..."
+	case ai.StreamMessageTypeToolCallInputDelta:
+		header = fmt.Sprintf("Tool Call (%v)...", toolName)
+	default:
+		header = "Text..."
+	}
+
+	const maxVisualLines = 5
+	return prefixStyle.Render("● ") +
+		headerStyle.Render(header) +
+		"\n" +
+		bodyStyle.Render(tailToVisualLines(msg, maxVisualLines, terminalWidth))
+}
+
+// tailToVisualLines keeps the last maxLines visual lines of text based on the
+// given terminal width, counting visual lines the same way as
+// truncateToVisualLines does.
+func tailToVisualLines(text string, maxLines int, terminalWidth int) string {
+	if maxLines < 1 {
+		maxLines = 1
+	}
+	if terminalWidth <= 0 {
+		terminalWidth = 80
+	}
+
+	logicalLines := strings.Split(text, "\n")
+	visualLinesUsed := 0
+	first := len(logicalLines)
+	for first > 0 {
+		lineWidth := runewidth.StringWidth(logicalLines[first-1])
+		visualLines := visualLineCount(lineWidth, terminalWidth)
+		if visualLinesUsed+visualLines > maxLines {
+			break
+		}
+		visualLinesUsed += visualLines
+		first--
+	}
+
+	// A single logical line can be longer than the whole budget; keep its tail
+	// in that case so that the newest output is always visible.
+	if first == len(logicalLines) {
+		lastLine := logicalLines[len(logicalLines)-1]
+		return truncateToVisualWidthFromEnd(lastLine, maxLines*terminalWidth)
+	}
+	return strings.Join(logicalLines[first:], "\n")
+}
+
+func truncateToVisualWidthFromEnd(line string, maxWidth int) string {
+	runes := []rune(line)
+	width := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		w := runewidth.RuneWidth(runes[i])
+		if width+w > maxWidth {
+			return string(runes[i+1:])
+		}
+		width += w
+	}
+	return line
+}
+
+func renderToolApprovalModal(toolName string, input string, alwaysAllowScope string, terminalWidth int) string {
+	if terminalWidth <= 0 {
+		terminalWidth = 80
+	}
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("3")).
+		Padding(0, 1).
+		Width(terminalWidth - 2)
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Bold(true)
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+
+	// Leave room for the border and the padding of the box.
+	const maxVisualLines = 15
+	contentWidth := max(terminalWidth-6, 1)
+	body := truncateToVisualLines(strings.TrimRight(input, "\n"), maxVisualLines, contentWidth)
+
+	content := headerStyle.Render(fmt.Sprintf("The agent wants to run %v:", toolName)) +
+		"\n\n" +
+		bodyStyle.Render(body) +
+		"\n\n" +
+		"[y] Approve   [n] Deny   [a] Always allow " + alwaysAllowScope + " in this session"
+
+	return boxStyle.Render(content)
+}
+
+func renderToolApprovalDecision(toolName string, decision ai.ToolApprovalDecision) string {
+	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000"))
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#000000")).Italic(true)
+
+	var result string
+	switch decision {
+	case ai.ToolApprovalDecisionApprove:
+		result = successStyle.Render("approved")
+	case ai.ToolApprovalDecisionAlwaysAllow:
+		result = successStyle.Render("always allowed in this session")
+	default:
+		result = errorStyle.Render("denied")
+	}
+
+	return prefixStyle.Render("● ") +
+		headerStyle.Render(fmt.Sprintf("Tool call %v: ", toolName)) +
+		result
+}
+
+// renderRollbackConfirmModal asks the user to confirm rolling the workspace
+// back, since the changes made after the snapshot are discarded.
+func renderRollbackConfirmModal(terminalWidth int) string {
+	if terminalWidth <= 0 {
+		terminalWidth = 80
+	}
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("3")).
+		Padding(0, 1).
+		Width(terminalWidth - 2)
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Bold(true)
+
+	content := headerStyle.Render("Roll the workspace back to the latest snapshot?") +
+		"\n\n" +
+		"The changes made to the workspace since the snapshot will be discarded." +
+		"\n\n" +
+		"[y] Roll back   [n] Cancel"
+
+	return boxStyle.Render(content)
+}
+
+// RenderRollbackResult renders the outcome of rolling the workspace back to
+// the snapshot taken before stage.
+func RenderRollbackResult(stage string, err error) string {
+	prefixStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#000000"))
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#000000")).Italic(true)
+
+	if err != nil {
+		return prefixStyle.Render("● ") +
+			headerStyle.Render("Rollback failed:") +
+			"\n" +
+			errorStyle.Render(err.Error())
+	}
+	return prefixStyle.Render("● ") +
+		headerStyle.Render("Rollback: ") +
+		successStyle.Render(fmt.Sprintf("the workspace is back to its state before %v", stage))
+}
+
+// renderWorkspaceInfo renders what was detected about the workspace, so that
+// the user can tell at a glance whether the agent is sent to the right place.
+func renderWorkspaceInfo(info workspace.Info) string {
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+
+	orNone := func(values []string) string {
+		if len(values) == 0 {
+			return "none detected"
+		}
+		return strings.Join(values, ", ")
+	}
+
+	repository := info.RepositoryRoot
+	if repository == "" {
+		repository = "none (not a git repository)"
+	}
+
+	lines := []string{
+		fmt.Sprintf("Repository: %v", repository),
+		fmt.Sprintf("Languages: %v", orNone(info.Languages)),
+		fmt.Sprintf("Build files: %v", orNone(info.BuildFiles)),
+	}
+	return bodyStyle.Render(strings.Join(lines, "\n"))
+}
+
+func renderWorkspaceWarnings(warnings []string) string {
+	lines := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		lines = append(lines, renderStreamMessageWarning(warning))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderRecentWorkspaces lists the recently used workspaces with the one
+// selected by the arrow keys marked.
+func renderRecentWorkspaces(recent []string, selected int) string {
+	return renderSelectableList("Recent workspaces (↑/↓ to pick):", recent, selected)
+}
+
+func renderDirectoryBrowser(browser *directoryBrowser) string {
+	if browser.scanning {
+		return "Listing the directories..."
+	}
+	if len(browser.matches) == 0 {
+		return errorStyle.Render("No matching directories.")
+	}
+	visible, selected := browser.visibleMatches()
+	header := fmt.Sprintf("Directories (%d matching):", len(browser.matches))
+	return renderSelectableList(header, visible, selected)
+}
+
+func renderSelectableList(header string, items []string, selected int) string {
+	headerStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#000000")).Italic(true)
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true)
+
+	lines := []string{headerStyle.Render(header)}
+	for i, item := range items {
+		if i == selected {
+			lines = append(lines, selectedStyle.Render("> "+item))
+		} else {
+			lines = append(lines, bodyStyle.Render("  "+item))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderPathCompletions(completions []string) string {
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+	return bodyStyle.Render(strings.Join(completions, "  "))
+}
+
+// renderRequestSources lists the sources and files attached to the request,
+// with the size of the source texts so that a truncated or nearly empty one
+// stands out.
+func renderRequestSources(sources []ai.RequestSource, attachments []ai.Attachment) string {
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+	lines := []string{"Attached:"}
+	for _, source := range sources {
+		lines = append(lines, fmt.Sprintf("  - %v (%v, %v lines)", source.Name, source.Kind, strings.Count(source.Text, "\n")+1))
+	}
+	for _, attachment := range attachments {
+		lines = append(lines, fmt.Sprintf("  - %v: %v (%v)", attachment.ID, attachment.Name, attachment.Kind))
+	}
+	return bodyStyle.Render(strings.Join(lines, "\n"))
+}
+
+// renderQuestionForm shows the question being answered with its options, and
+// the other questions in a line each with their answers so far.
+func renderQuestionForm(form questionForm) string {
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true)
+
+	var lines []string
+	for i, question := range form.questions {
+		if i != form.current {
+			answer := form.answerOf(i, false)
+			if answer == "" {
+				answer = "(not answered)"
+			}
+			lines = append(lines, bodyStyle.Render(fmt.Sprintf("  %d. %v: %v", i+1, question.Text, answer)))
+			continue
+		}
+
+		header := fmt.Sprintf("Question %d of %d", i+1, len(form.questions))
+		if question.Coverage != "" {
+			header += " · " + strings.ReplaceAll(string(question.Coverage), "_", " ")
+		}
+		text := fmt.Sprintf("%d. %v", i+1, question.Text)
+		if len(question.Attachments) > 0 {
+			text += fmt.Sprintf(" (see %v)", strings.Join(question.Attachments, ", "))
+		}
+		lines = append(lines, "", descriptionStyle.Render(header), selectedStyle.Render(text))
+		if !isChoiceQuestion(question) {
+			lines = append(lines, form.input.View(), "")
+			continue
+		}
+
+		answer := form.answers[i]
+		for j := 0; j <= len(question.Options); j++ {
+			mark := "( )"
+			if answer.selected[j] {
+				mark = "(•)"
+			}
+			if question.Type == ai.QuestionTypeMultipleChoice {
+				mark = "[ ]"
+				if answer.selected[j] {
+					mark = "[x]"
+				}
+			}
+			cursor := "  "
+			if j == answer.cursor {
+				cursor = "› "
+			}
+
+			if j == len(question.Options) {
+				other := answer.text
+				if j == answer.cursor {
+					other = form.input.View()
+				}
+				lines = append(lines, fmt.Sprintf("%v%v Other: %v", cursor, mark, other))
+				continue
+			}
+			option := question.Options[j]
+			if isDefaultOption(question, option) {
+				option += " (recommended)"
+			}
+			line := fmt.Sprintf("%v%v %v", cursor, mark, option)
+			if j == answer.cursor {
+				line = selectedStyle.Render(line)
+			}
+			lines = append(lines, line)
+		}
+		lines = append(lines, "")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// renderSpecTemplates lists the spec templates with the one selected by the
+// arrow keys marked, and the description of the selected template below.
+func renderSpecTemplates(templates []spec.Template, selected, suggested int) string {
+	items := make([]string, 0, len(templates))
+	for i, template := range templates {
+		item := fmt.Sprintf("%v (%v)", template.Title, template.Name)
+		if i == suggested {
+			item += " - suggested"
+		}
+		items = append(items, item)
+	}
+	list := renderSelectableList("Spec templates:", items, selected)
+
+	description := templates[selected].Description
+	if description == "" {
+		return list
+	}
+	bodyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+	return list + "\n\n" + bodyStyle.Render(strings.TrimSpace(description))
+}
+
+// renderSpecFindings lists the lint findings of a draft below it, so that the
+// user can take them into account in the feedback.
+func renderSpecFindings(findings []spec.Finding) string {
+	if len(findings) == 0 {
+		return successStyle.Render("The spec lint found no problems.")
+	}
+	warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+	lines := []string{fmt.Sprintf("The spec lint found %d problem(s):", len(findings))}
+	for _, finding := range findings {
+		style := warningStyle
+		if finding.Severity == spec.SeverityError {
+			style = errorStyle
+		}
+		lines = append(lines, style.Render("- "+finding.String()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// specDiffContext is the number of unchanged lines shown around a change.
+const specDiffContext = 2
+
+// renderSpecDiff shows what a revision changed, section by section. An
+// unchanged section is reduced to its heading, and a long run of unchanged
+// lines to the lines next to the changes.
+func renderSpecDiff(diffs []spec.SectionDiff) string {
+	headingStyle := lipgloss.NewStyle().Bold(true)
+	contextStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#8D8D8D"))
+	insertStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	deleteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	var lines []string
+	changed := false
+	for _, diff := range diffs {
+		heading := diff.Heading
+		if heading == "" {
+			heading = "(title)"
+		}
+		switch {
+		case !diff.Changed():
+			lines = append(lines, contextStyle.Render(heading+" (unchanged)"))
+			continue
+		case diff.Added:
+			heading += " (added)"
+		case diff.Removed:
+			heading += " (removed)"
+		}
+		changed = true
+		lines = append(lines, headingStyle.Render(heading))
+
+		skipped := false
+		for i, line := range diff.Lines {
+			switch line.Op {
+			case spec.DiffInsert:
+				lines = append(lines, insertStyle.Render("+ "+line.Text))
+			case spec.DiffDelete:
+				lines = append(lines, deleteStyle.Render("- "+line.Text))
+			default:
+				if !nearSpecChange(diff.Lines, i) {
+					if !skipped {
+						lines = append(lines, contextStyle.Render("  ..."))
+					}
+					skipped = true
+					continue
+				}
+				lines = append(lines, contextStyle.Render("  "+line.Text))
+			}
+			skipped = false
+		}
+	}
+	if !changed {
+		return successStyle.Render("The revision did not change the spec.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func nearSpecChange(lines []spec.DiffLine, index int) bool {
+	for i := max(0, index-specDiffContext); i < min(len(lines), index+specDiffContext+1); i++ {
+		if lines[i].Op != spec.DiffEqual {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,8 @@ package ui
 import (
 	"strings"
 	"testing"
+
+	"github.com/sds-lab-dev/bear-go/spec"
 )
 
 func TestTruncateToVisualLines_NoTruncationNeeded(t *testing.T) {
@@ -157,3 +159,67 @@ func TestTruncateToVisualWidth(t *testing.T) {
 		})
 	}
 }
+
+func TestTailToVisualLines_NoTruncationNeeded(t *testing.T) {
+	text := "line1\nline2"
+	result := tailToVisualLines(text, 5, 80)
+	if result != text {
+		t.Errorf("expected no truncation, got %q", result)
+	}
+}
+
+func TestTailToVisualLines_KeepsLastLines(t *testing.T) {
+	text := "line1\nline2\nline3\nline4"
+	result := tailToVisualLines(text, 2, 80)
+	if result != "line3\nline4" {
+		t.Errorf("expected last 2 lines, got %q", result)
+	}
+}
+
+func TestTailToVisualLines_LongLastLineKeepsTail(t *testing.T) {
+	text := "head\n" + strings.Repeat("a", 200) + "tail"
+	result := tailToVisualLines(text, 2, 80)
+	if len(result) != 160 {
+		t.Errorf("expected 160 characters, got %d", len(result))
+	}
+	if !strings.HasSuffix(result, "tail") {
+		t.Errorf("expected result to end with the newest output, got %q", result)
+	}
+}
+
+func TestRenderSpecFindings(t *testing.T) {
+	if got := renderSpecFindings(nil); !strings.Contains(got, "no problems") {
+		t.Errorf("expected a clean result, got %q", got)
+	}
+
+	got := renderSpecFindings([]spec.Finding{
+		{Rule: spec.RuleMissingSection, Severity: spec.SeverityError, Section: "overview", Message: "missing"},
+		{Rule: spec.RuleCritique, Severity: spec.SeverityWarning, Message: "vague"},
+	})
+	for _, want := range []string{"2 problem(s)", "error [missing-section] overview: missing", "warning [critique] vague"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestRenderSpecDiff(t *testing.T) {
+	oldDraft := "# Spec\n\n## Overview\n\nExport reports.\n\n## Requirements\n\n- a\n- b\n- c\n- d\n- e\n- f\n"
+	newDraft := "# Spec\n\n## Overview\n\nExport reports.\n\n## Requirements\n\n- a\n- b\n- c\n- d\n- e\n- F\n\n## Notes\n\nNew.\n"
+
+	got := stripANSI(renderSpecDiff(spec.DiffSections(oldDraft, newDraft)))
+	for _, want := range []string{
+		"(title) (unchanged)",
+		"## Overview (unchanged)",
+		"## Requirements\n  ...\n  - d\n  - e\n- - f\n+ - F",
+		"## Notes (added)\n+ New.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%v", want, got)
+		}
+	}
+
+	if got := renderSpecDiff(spec.DiffSections(oldDraft, oldDraft)); !strings.Contains(got, "did not change") {
+		t.Errorf("expected an unchanged result, got %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sds-lab-dev/bear-go/ai"
+)
+
+// toolApprovalRequestMsg asks the user to decide on a tool call. The agent is
+// blocked until the decision is sent to reply.
+type toolApprovalRequestMsg struct {
+	request ai.ToolApprovalRequest
+	reply   chan<- ai.ToolApprovalDecision
+}
+
+// toolApprovalExpiredMsg tells that the agent stopped waiting for the
+// decision on the tool call with the reply channel.
+type toolApprovalExpiredMsg struct {
+	reply chan<- ai.ToolApprovalDecision
+}
+
+// toolApprovalQueue holds the tool calls waiting for the user's decision, in
+// the order they arrived, since the agent can make several calls at once. The
+// first one is shown as a modal that takes over the key handling until
+// answered; the others wait for their turn.
+type toolApprovalQueue struct {
+	pending []toolApprovalRequestMsg
+}
+
+func (q toolApprovalQueue) len() int {
+	return len(q.pending)
+}
+
+func (q toolApprovalQueue) push(msg toolApprovalRequestMsg) toolApprovalQueue {
+	q.pending = append(slices.Clone(q.pending), msg)
+	return q
+}
+
+// expire removes the request with the reply channel, and returns its tool
+// name. It returns false if the user already decided on the request.
+func (q toolApprovalQueue) expire(reply chan<- ai.ToolApprovalDecision) (toolApprovalQueue, string, bool) {
+	index := slices.IndexFunc(q.pending, func(pending toolApprovalRequestMsg) bool {
+		return pending.reply == reply
+	})
+	if index < 0 {
+		return q, "", false
+	}
+	toolName := q.pending[index].request.ToolName
+	q.pending = slices.Delete(slices.Clone(q.pending), index, index+1)
+	return q, toolName, true
+}
+
+// update answers the first request on y, n or a, and ignores the other keys.
+// An "always allow" answers the waiting requests in the same scope along with
+// it, instead of asking the user again.
+func (q toolApprovalQueue) update(msg tea.KeyMsg) (toolApprovalQueue, tea.Cmd) {
+	var decision ai.ToolApprovalDecision
+	switch msg.String() {
+	case "y":
+		decision = ai.ToolApprovalDecisionApprove
+	case "n":
+		decision = ai.ToolApprovalDecisionDeny
+	case "a":
+		decision = ai.ToolApprovalDecisionAlwaysAllow
+	default:
+		return q, nil
+	}
+
+	current := q.pending[0]
+	current.reply <- decision
+	printed := []tea.Cmd{tea.Println(renderToolApprovalDecision(current.request.ToolName, decision))}
+
+	var remaining []toolApprovalRequestMsg
+	for _, pending := range q.pending[1:] {
+		if decision == ai.ToolApprovalDecisionAlwaysAllow && pending.request.AlwaysAllowScope == current.request.AlwaysAllowScope {
+			pending.reply <- decision
+			printed = append(printed, tea.Println(renderToolApprovalDecision(pending.request.ToolName, decision)))
+			continue
+		}
+		remaining = append(remaining, pending)
+	}
+	q.pending = remaining
+	return q, tea.Sequence(printed...)
+}
+
+func (q toolApprovalQueue) view(width int) string {
+	request := q.pending[0].request
+	view := renderToolApprovalModal(request.ToolName, request.Input, request.AlwaysAllowScope, width) + "\n"
+	if waiting := len(q.pending) - 1; waiting > 0 {
+		view += descriptionStyle.Render(fmt.Sprintf("Tool calls waiting for approval after this one: %d", waiting)) + "\n"
+	}
+	return view
+}
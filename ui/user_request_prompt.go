@@ -8,13 +8,32 @@ import (
 
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sds-lab-dev/bear-go/ai"
 	"github.com/sds-lab-dev/bear-go/log"
 )
 
 type UserRequestPromptResult struct {
 	Text string
+	// Sources are the documents that the user attached to the request.
+	Sources []ai.RequestSource
+	// Attachments are the files, such as screenshots or logs, that the user
+	// attached to the request. They are already staged.
+	Attachments []ai.Attachment
 }
 
+// sourcesOnlyRequest is the request when the user attached sources but typed
+// nothing, so that the sources alone describe the request.
+const sourcesOnlyRequest = "See the attached sources."
+
+// attachMode is what the path input attaches.
+type attachMode int
+
+const (
+	attachModeNone attachMode = iota
+	attachModeSource
+	attachModeFile
+)
+
 type editorFinishedMsg struct {
 	err error
 }
@@ -28,9 +47,24 @@ type UserRequestPromptModel struct {
 	tempFilePath    string
 	launchingEditor bool
 	windowSize      tea.WindowSizeMsg
+	// loadSource reads a file path, or a "spec:<session-id>" reference, into
+	// a source.
+	loadSource func(ref string) (ai.RequestSource, error)
+	// loadAttachment stages the file at the path as an attachment.
+	loadAttachment func(ref string) (ai.Attachment, error)
+	sources        []ai.RequestSource
+	attachments    []ai.Attachment
+	// attachInput takes the path of the source or file to attach. Used when
+	// attachMode is not attachModeNone.
+	attachInput pathInput
+	attachMode  attachMode
 }
 
-func NewUserRequestPromptModel() UserRequestPromptModel {
+func NewUserRequestPromptModel(
+	workspaceDir string,
+	loadSource func(ref string) (ai.RequestSource, error),
+	loadAttachment func(ref string) (ai.Attachment, error),
+) UserRequestPromptModel {
 	terminalSize := GetTerminalSize()
 
 	ta := textarea.New()
@@ -47,6 +81,9 @@ func NewUserRequestPromptModel() UserRequestPromptModel {
 		resolveEditor: func() (editorCommand, error) {
 			return resolveEditor(os.LookupEnv, commandExistsOnSystem)
 		},
+		loadSource:     loadSource,
+		loadAttachment: loadAttachment,
+		attachInput:    newPathInput(workspaceDir, "", terminalSize.Width),
 	}
 }
 
@@ -67,6 +104,7 @@ func (m UserRequestPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		log.Debug(fmt.Sprintf("received window size message: width=%d, height=%d", msg.Width, msg.Height))
 		m.textarea.SetWidth(msg.Width)
 		m.textarea.SetHeight(min(10, msg.Height/2))
+		m.attachInput = m.attachInput.setWidth(msg.Width)
 		m.windowSize = msg
 		return m, nil
 	}
@@ -88,6 +126,10 @@ func (m UserRequestPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m UserRequestPromptModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	log.Debug(fmt.Sprintf("received key message: type=%v", msg.String()))
 
+	if m.attachMode != attachModeNone {
+		return m.handleAttachKey(msg)
+	}
+
 	switch msg.String() {
 	case "enter":
 		return m.handleEnter()
@@ -97,6 +139,20 @@ func (m UserRequestPromptModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "ctrl+g":
 		return m.prepareEditorLaunch()
+	case "ctrl+o":
+		return m.openAttachInput(attachModeSource)
+	case "ctrl+t":
+		return m.openAttachInput(attachModeFile)
+	case "ctrl+x":
+		// The attachments are listed after the sources, so the last one
+		// listed goes first.
+		if len(m.attachments) > 0 {
+			m.attachments = m.attachments[:len(m.attachments)-1]
+		} else if len(m.sources) > 0 {
+			m.sources = m.sources[:len(m.sources)-1]
+		}
+		m.errorMessage = ""
+		return m, nil
 	}
 
 	m.errorMessage = ""
@@ -109,6 +165,9 @@ func (m UserRequestPromptModel) handleEnter() (tea.Model, tea.Cmd) {
 	log.Debug("Enter key pressed, handling user request confirmation")
 
 	value := strings.TrimSpace(m.textarea.Value())
+	if value == "" && len(m.sources) > 0 {
+		value = sourcesOnlyRequest
+	}
 	if value == "" {
 		m.errorMessage = "Please enter your request."
 		return m, nil
@@ -119,19 +178,94 @@ func (m UserRequestPromptModel) handleEnter() (tea.Model, tea.Cmd) {
 	b := newWrappedStringBuilder(m.windowSize.Width)
 	b.WriteString(renderAgentInactivePrompt(successStyle.Render("You requested as follows:"), true))
 	b.WriteByte('\n')
-	b.WriteString(m.textarea.Value())
+	b.WriteString(value)
+	if len(m.sources) > 0 || len(m.attachments) > 0 {
+		b.WriteString("\n\n")
+		b.WriteString(renderRequestSources(m.sources, m.attachments))
+	}
 
+	sources, attachments := m.sources, m.attachments
 	cmd := tea.Sequence(
 		tea.Printf("%v\n", b.String()),
 		func() tea.Msg {
 			return UserRequestPromptResult{
-				Text: value,
+				Text:        value,
+				Sources:     sources,
+				Attachments: attachments,
 			}
 		},
 	)
 	return m, cmd
 }
 
+func (m UserRequestPromptModel) openAttachInput(mode attachMode) (tea.Model, tea.Cmd) {
+	m.attachMode = mode
+	m.errorMessage = ""
+	m.textarea.Blur()
+	if mode == attachModeSource {
+		m.attachInput.textarea.Placeholder = "docs/request.md, issue.json, design.pdf or spec:<session-id>"
+	} else {
+		m.attachInput.textarea.Placeholder = "screenshot.png, mockup.jpg or error.log"
+	}
+	var cmd tea.Cmd
+	m.attachInput, cmd = m.attachInput.open()
+	return m, cmd
+}
+
+// handleAttachKey handles the keys of the path input that Ctrl+O and Ctrl+T
+// open. Enter attaches the source or file and Esc goes back to the request.
+func (m UserRequestPromptModel) handleAttachKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m = m.closeAttachInput()
+		cmd := m.textarea.Focus()
+		return m, cmd
+	case "enter":
+		ref := m.attachInput.value()
+		if ref == "" {
+			m.errorMessage = "Please enter the path of the file to attach."
+			return m, nil
+		}
+		if err := m.attach(ref); err != nil {
+			log.Warning(fmt.Sprintf("failed to attach %v: %v", ref, err))
+			m.errorMessage = err.Error()
+			return m, nil
+		}
+		m = m.closeAttachInput()
+		cmd := m.textarea.Focus()
+		return m, cmd
+	}
+
+	m.errorMessage = ""
+	var cmd tea.Cmd
+	m.attachInput, cmd = m.attachInput.update(msg)
+	return m, cmd
+}
+
+func (m *UserRequestPromptModel) attach(ref string) error {
+	if m.attachMode == attachModeSource {
+		source, err := m.loadSource(ref)
+		if err != nil {
+			return err
+		}
+		m.sources = append(m.sources, source)
+		return nil
+	}
+	attachment, err := m.loadAttachment(ref)
+	if err != nil {
+		return err
+	}
+	m.attachments = append(m.attachments, attachment)
+	return nil
+}
+
+func (m UserRequestPromptModel) closeAttachInput() UserRequestPromptModel {
+	m.attachMode = attachModeNone
+	m.attachInput = m.attachInput.close()
+	m.errorMessage = ""
+	return m
+}
+
 func (m UserRequestPromptModel) prepareEditorLaunch() (tea.Model, tea.Cmd) {
 	log.Debug("preparing to launch external editor for user request input")
 	m.launchingEditor = true
@@ -216,12 +350,36 @@ func (m UserRequestPromptModel) View() string {
 	}
 
 	b := newWrappedStringBuilder(m.windowSize.Width)
-	b.WriteString(renderAgentActivePrompt("Enter your request:", true))
-	b.WriteByte('\n')
-	b.WriteString("Press Enter to confirm, Shift+Enter or Alt+Enter for newline, Ctrl+G for external editor.")
-	b.WriteByte('\n')
-	b.WriteByte('\n')
-	b.WriteString(m.textarea.View())
+	switch m.attachMode {
+	case attachModeSource:
+		b.WriteString(renderAgentActivePrompt("Import a document that describes the request:", true))
+		b.WriteByte('\n')
+		b.WriteString("Markdown, text, PDF or issue JSON, or spec:<session-id> for an approved spec. Press Enter to import, Tab to complete the path, or Esc to cancel.")
+		b.WriteByte('\n')
+		b.WriteByte('\n')
+		b.WriteString(m.attachInput.view())
+	case attachModeFile:
+		b.WriteString(renderAgentActivePrompt("Attach a file to the request:", true))
+		b.WriteByte('\n')
+		b.WriteString("A screenshot, mockup, log or any other file for the agent to look at. Press Enter to attach, Tab to complete the path, or Esc to cancel.")
+		b.WriteByte('\n')
+		b.WriteByte('\n')
+		b.WriteString(m.attachInput.view())
+	default:
+		b.WriteString(renderAgentActivePrompt("Enter your request:", true))
+		b.WriteByte('\n')
+		b.WriteString("Press Enter to confirm, Shift+Enter or Alt+Enter for newline, Ctrl+G for external editor, Ctrl+O to import a document, Ctrl+T to attach a file.")
+		if len(m.sources) > 0 || len(m.attachments) > 0 {
+			b.WriteString(" Ctrl+X removes the last one attached.")
+		}
+		b.WriteByte('\n')
+		b.WriteByte('\n')
+		b.WriteString(m.textarea.View())
+	}
+	if len(m.sources) > 0 || len(m.attachments) > 0 {
+		b.WriteByte('\n')
+		b.WriteString(renderRequestSources(m.sources, m.attachments))
+	}
 	if m.errorMessage != "" {
 		b.WriteByte('\n')
 		b.WriteString(errorStyle.Render(m.errorMessage))
@@ -1,16 +1,20 @@
 package ui
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sds-lab-dev/bear-go/ai"
 )
 
 func readyModel(t *testing.T) UserRequestPromptModel {
 	t.Helper()
-	m := NewUserRequestPromptModel()
+	m := NewUserRequestPromptModel(t.TempDir(), nil, nil)
 	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
 	return updated.(UserRequestPromptModel)
 }
@@ -26,7 +30,7 @@ func sendSpecialKey(m UserRequestPromptModel, keyType tea.KeyType) (UserRequestP
 }
 
 func TestUserRequestPromptModel_InitReturnsBlinkCmd(t *testing.T) {
-	m := NewUserRequestPromptModel()
+	m := NewUserRequestPromptModel(t.TempDir(), nil, nil)
 	cmd := m.Init()
 	if cmd == nil {
 		t.Error("Init should return a non-nil command")
@@ -91,7 +95,7 @@ func TestUserRequestPromptModel_ViewShowsLabelAndHelp(t *testing.T) {
 }
 
 func TestUserRequestPromptModel_ViewShowsPromptImmediately(t *testing.T) {
-	m := NewUserRequestPromptModel()
+	m := NewUserRequestPromptModel(t.TempDir(), nil, nil)
 	view := m.View()
 	plain := stripANSI(view)
 
@@ -154,3 +158,93 @@ func TestUserRequestPromptModel_EditorFinishedWithError(t *testing.T) {
 		t.Errorf("error message should mention editor failure, got %q", m.errorMessage)
 	}
 }
+
+func TestUserRequestPromptModel_AttachSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("notes"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	var loaded []string
+	m := NewUserRequestPromptModel(dir, func(ref string) (ai.RequestSource, error) {
+		loaded = append(loaded, ref)
+		if ref != "notes.md" {
+			return ai.RequestSource{}, errors.New("no such file")
+		}
+		return ai.RequestSource{Kind: ai.RequestSourceKindText, Name: ref, Text: "Export the report as CSV."}, nil
+	}, nil)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = updated.(UserRequestPromptModel)
+
+	m, _ = sendSpecialKey(m, tea.KeyCtrlO)
+	if m.attachMode != attachModeSource || !strings.Contains(stripANSI(m.View()), "Import a document") {
+		t.Fatal("Ctrl+O should open the import input")
+	}
+	m, _ = sendKey(m, "missing")
+	m, _ = sendSpecialKey(m, tea.KeyEnter)
+	if m.errorMessage == "" || m.attachMode != attachModeSource {
+		t.Fatal("a source that fails to load should show an error and keep the input open")
+	}
+
+	for range len("missing") {
+		m, _ = sendSpecialKey(m, tea.KeyBackspace)
+	}
+	m, _ = sendKey(m, "no")
+	m, _ = sendSpecialKey(m, tea.KeyTab)
+	if m.attachInput.value() != "notes.md" {
+		t.Fatalf("Tab should complete the file name, got %q", m.attachInput.value())
+	}
+	m, _ = sendSpecialKey(m, tea.KeyEnter)
+	if m.attachMode != attachModeNone || len(m.sources) != 1 {
+		t.Fatalf("Enter should attach the source and close the input, got mode=%v sources=%v", m.attachMode, m.sources)
+	}
+	if !strings.Contains(stripANSI(m.View()), "notes.md (text, 1 lines)") {
+		t.Error("view should list the attached source")
+	}
+	if !slices.Equal(loaded, []string{"missing", "notes.md"}) {
+		t.Errorf("unexpected loaded refs: %v", loaded)
+	}
+
+	// The sources alone are a request.
+	m, cmd := sendSpecialKey(m, tea.KeyEnter)
+	if m.errorMessage != "" || cmd == nil {
+		t.Fatalf("Enter with only sources should confirm the request, got error %q", m.errorMessage)
+	}
+
+	m, _ = sendSpecialKey(m, tea.KeyCtrlX)
+	if len(m.sources) != 0 {
+		t.Error("Ctrl+X should remove the last source")
+	}
+}
+
+func TestUserRequestPromptModel_AttachFile(t *testing.T) {
+	m := NewUserRequestPromptModel(t.TempDir(), nil, func(ref string) (ai.Attachment, error) {
+		return ai.Attachment{ID: "ATT-1", Kind: ai.AttachmentKindImage, Name: ref, Path: "/staged/ATT-1-" + ref}, nil
+	})
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = updated.(UserRequestPromptModel)
+
+	m, _ = sendSpecialKey(m, tea.KeyCtrlT)
+	if m.attachMode != attachModeFile {
+		t.Fatal("Ctrl+T should open the attach input")
+	}
+	m, _ = sendKey(m, "mockup.png")
+	m, _ = sendSpecialKey(m, tea.KeyEnter)
+	if len(m.attachments) != 1 || m.attachMode != attachModeNone {
+		t.Fatalf("Enter should attach the file, got %v", m.attachments)
+	}
+	if !strings.Contains(stripANSI(m.View()), "ATT-1: mockup.png (image)") {
+		t.Error("view should list the attached file")
+	}
+
+	m, _ = sendKey(m, "Match the mockup.")
+	_, cmd := sendSpecialKey(m, tea.KeyEnter)
+	if cmd == nil {
+		t.Fatal("expected a command that confirms the request")
+	}
+
+	m, _ = sendSpecialKey(m, tea.KeyCtrlT)
+	m, _ = sendSpecialKey(m, tea.KeyEsc)
+	if m.attachMode != attachModeNone || len(m.attachments) != 1 {
+		t.Error("Esc should close the input without attaching")
+	}
+}
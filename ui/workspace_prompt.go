@@ -2,28 +2,69 @@ package ui
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/workspace"
 )
 
 type WorkspacePromptResult struct {
 	Path string
 }
 
+// directoriesScannedMsg carries the directories below the current directory
+// for the directory browser.
+type directoriesScannedMsg struct {
+	dirs []string
+}
+
+// workspaceInspectedMsg carries the result of inspecting the path that the user
+// entered.
+type workspaceInspectedMsg struct {
+	path string
+	// confirmed tells that Enter was pressed again on the path whose warnings
+	// were shown, which confirms them.
+	confirmed bool
+	info      workspace.Info
+	err       error
+}
+
 type WorkspacePromptModel struct {
-	textarea      textarea.Model
-	currentDir    string
-	validatePath  func(string) error
-	errorMessage  string
+	textarea    textarea.Model
+	currentDir  string
+	inspectPath func(string) (workspace.Info, error)
+	// recent are the recently used workspaces, most recent first, and
+	// selectedRecent is the one picked with the arrow keys, or -1.
+	recent         []string
+	selectedRecent int
+	// completions are the directories that the last Tab press could not
+	// decide between.
+	completions []string
+	// browser is the open directory browser, or nil.
+	browser      *directoryBrowser
+	errorMessage string
+	// inspecting tells that the entered path is being inspected, which runs
+	// git and may walk the whole directory. Enter is ignored until it ends.
+	inspecting bool
+	// pending is the inspected workspace whose warnings the user has to
+	// confirm by pressing Enter again. Used when it has warnings.
+	pending *workspace.Info
+	// pendingPath is the path as typed for pending, which differs from its
+	// Path if the typed path contains a symbolic link.
+	pendingPath   string
 	confirmedPath string
 	windowSize    tea.WindowSizeMsg
 }
 
-func NewWorkspacePromptModel(currentDir string, validatePath func(string) error) WorkspacePromptModel {
+func NewWorkspacePromptModel(
+	currentDir string,
+	recent []string,
+	inspectPath func(string) (workspace.Info, error),
+) WorkspacePromptModel {
 	terminalSize := GetTerminalSize()
 
 	ta := textarea.New()
@@ -36,9 +77,11 @@ func NewWorkspacePromptModel(currentDir string, validatePath func(string) error)
 	ta.Focus()
 
 	return WorkspacePromptModel{
-		textarea:     ta,
-		currentDir:   currentDir,
-		validatePath: validatePath,
+		textarea:       ta,
+		currentDir:     currentDir,
+		inspectPath:    inspectPath,
+		recent:         recent,
+		selectedRecent: -1,
 	}
 }
 
@@ -66,16 +109,41 @@ func (m WorkspacePromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.textarea.SetWidth(msg.Width)
 		m.windowSize = msg
 		return m, nil
+	case workspaceInspectedMsg:
+		return m.handleWorkspaceInspectedMsg(msg)
+	case directoriesScannedMsg:
+		if m.browser != nil && m.browser.scanning {
+			m.browser = newDirectoryBrowser(msg.dirs)
+			m.browser.filter(m.textarea.Value())
+		}
+		return m, nil
 	}
 
 	// If the message is a reserved key event, handle it with our custom key
 	// handling logic.
 	if msg, ok := msg.(tea.KeyMsg); ok {
-		switch msg.String() {
-		// Go to next step on Enter
-		case "enter":
-			log.Debug("Enter key pressed, handling workspace confirmation")
-			return m.handleEnter()
+		if m.browser != nil {
+			if model, cmd, handled := m.handleBrowserKeyMsg(msg); handled {
+				return model, cmd
+			}
+		} else {
+			switch msg.String() {
+			// Go to next step on Enter
+			case "enter":
+				log.Debug("Enter key pressed, handling workspace confirmation")
+				if m.inspecting {
+					return m, nil
+				}
+				return m.handleEnter()
+			case "tab":
+				return m.completePath(), nil
+			case "up":
+				return m.selectRecent(-1), nil
+			case "down":
+				return m.selectRecent(1), nil
+			case "ctrl+f":
+				return m.openBrowser()
+			}
 		}
 		// Fall through.
 	}
@@ -85,30 +153,136 @@ func (m WorkspacePromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// handle them in the text area.
 	log.Debug(fmt.Sprintf("passing message of type %T to textarea component", msg))
 	m.textarea, cmd = m.textarea.Update(msg)
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.completions = nil
+		if m.browser != nil {
+			m.browser.filter(m.textarea.Value())
+		}
+	}
 	return m, cmd
 }
 
+// completePath completes the typed path to the directories that start with
+// it, as a shell does.
+func (m WorkspacePromptModel) completePath() WorkspacePromptModel {
+	completed, matches := workspace.CompletePath(m.currentDir, m.textarea.Value())
+	m.textarea.SetValue(completed)
+	m.textarea.CursorEnd()
+	if len(matches) > 1 {
+		m.completions = matches
+	} else {
+		m.completions = nil
+	}
+	return m
+}
+
+// selectRecent moves the selection in the recent workspaces by delta and
+// puts the selected workspace into the textarea. Moving up from the first
+// workspace clears the selection and the textarea.
+func (m WorkspacePromptModel) selectRecent(delta int) WorkspacePromptModel {
+	if len(m.recent) == 0 {
+		return m
+	}
+	m.selectedRecent = max(-1, min(len(m.recent)-1, m.selectedRecent+delta))
+	m.completions = nil
+	if m.selectedRecent == -1 {
+		m.textarea.Reset()
+	} else {
+		m.textarea.SetValue(m.recent[m.selectedRecent])
+		m.textarea.CursorEnd()
+	}
+	return m
+}
+
+// openBrowser lists the directories below the current directory, and uses
+// the textarea as the query that filters them.
+func (m WorkspacePromptModel) openBrowser() (tea.Model, tea.Cmd) {
+	m.browser = &directoryBrowser{scanning: true}
+	m.completions = nil
+	m.textarea.Reset()
+	currentDir := m.currentDir
+	return m, func() tea.Msg {
+		return directoriesScannedMsg{
+			dirs: workspace.ScanDirectories(currentDir, browserMaxDepth, browserMaxDirectories),
+		}
+	}
+}
+
+func (m WorkspacePromptModel) handleBrowserKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case "up":
+		m.browser.move(-1)
+	case "down", "tab":
+		m.browser.move(1)
+	case "esc", "ctrl+f":
+		m.browser = nil
+		m.textarea.Reset()
+	case "enter":
+		// The picked directory is put into the textarea to be confirmed with
+		// Enter as if it had been typed.
+		dir, ok := m.browser.selection()
+		if !ok {
+			return m, nil, true
+		}
+		m.browser = nil
+		m.textarea.SetValue(filepath.Join(m.currentDir, dir))
+		m.textarea.CursorEnd()
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
 func (m WorkspacePromptModel) handleEnter() (tea.Model, tea.Cmd) {
 	value := strings.TrimSpace(m.textarea.Value())
 
 	// If the user just presses Enter without typing anything, we treat it as
-	// confirming the current directory. Otherwise, we validate the entered
-	// path and set it as the confirmed workspace if it's valid.
-	if value == "" {
-		m.confirmedPath = m.currentDir
-	} else {
-		if err := m.validatePath(value); err != nil {
+	// confirming the current directory. Either way the path is inspected,
+	// because the current directory is as likely to be wrong as a typed one.
+	path := m.currentDir
+	if value != "" {
+		resolved, err := workspace.ResolvePath(m.currentDir, value)
+		if err != nil {
 			m.errorMessage = err.Error()
 			return m, nil
 		}
-		m.confirmedPath = value
+		path = resolved
 	}
 
-	// We're done here.
+	// Pressing Enter again on the same path confirms its warnings.
+	confirmed := m.pending != nil && m.pendingPath == path
+	m.inspecting = true
 	m.errorMessage = ""
+	inspectPath := m.inspectPath
+	return m, func() tea.Msg {
+		info, err := inspectPath(path)
+		return workspaceInspectedMsg{path: path, confirmed: confirmed, info: info, err: err}
+	}
+}
+
+func (m WorkspacePromptModel) handleWorkspaceInspectedMsg(msg workspaceInspectedMsg) (tea.Model, tea.Cmd) {
+	m.inspecting = false
+	if msg.err != nil {
+		m.errorMessage = msg.err.Error()
+		m.pending = nil
+		return m, nil
+	}
+	info := msg.info
+	if len(info.Warnings()) > 0 && !msg.confirmed {
+		log.Info(fmt.Sprintf("workspace needs confirmation: %#v", info))
+		m.pending = &info
+		m.pendingPath = msg.path
+		return m, nil
+	}
+
+	// We're done here.
+	m.pending = nil
+	m.confirmedPath = msg.path
 	b := newWrappedStringBuilder(m.windowSize.Width)
 	b.WriteString(renderAgentInactivePrompt(successStyle.Render(fmt.Sprintf("Workspace set to: %s", m.confirmedPath)), true))
-	log.Info(fmt.Sprintf("Workspace confirmed: %s", m.confirmedPath))
+	b.WriteByte('\n')
+	b.WriteString(renderWorkspaceInfo(info))
+	log.Info(fmt.Sprintf("Workspace confirmed: %#v", info))
 	cmd := tea.Sequence(
 		tea.Printf("%v\n", b.String()),
 		func() tea.Msg {
@@ -127,14 +301,41 @@ func (m WorkspacePromptModel) View() string {
 	b := newWrappedStringBuilder(m.windowSize.Width)
 	b.WriteString(renderAgentActivePrompt(fmt.Sprintf("Current directory: %s", m.currentDir), true))
 	b.WriteByte('\n')
-	b.WriteString("Press Enter to confirm, or type an absolute path.")
+	if m.browser != nil {
+		b.WriteString("Type to filter the directories below the current directory. Press ↑/↓ to select, Enter to pick, or Esc to close.")
+	} else {
+		b.WriteString("Press Enter to confirm, or type a path. Tab completes the path and Ctrl+F browses the directories.")
+	}
 	b.WriteByte('\n')
 	b.WriteByte('\n')
 	b.WriteString(m.textarea.View())
+	switch {
+	case m.browser != nil:
+		b.WriteByte('\n')
+		b.WriteString(renderDirectoryBrowser(m.browser))
+	case len(m.completions) > 0:
+		b.WriteByte('\n')
+		b.WriteString(renderPathCompletions(m.completions))
+	case len(m.recent) > 0:
+		b.WriteByte('\n')
+		b.WriteString(renderRecentWorkspaces(m.recent, m.selectedRecent))
+	}
 	if m.errorMessage != "" {
 		b.WriteByte('\n')
 		b.WriteString(errorStyle.Render(m.errorMessage))
 	}
+	if m.inspecting {
+		b.WriteByte('\n')
+		b.WriteString("Inspecting the workspace...")
+	}
+	if m.pending != nil {
+		b.WriteByte('\n')
+		b.WriteString(renderWorkspaceInfo(*m.pending))
+		b.WriteByte('\n')
+		b.WriteString(renderWorkspaceWarnings(m.pending.Warnings()))
+		b.WriteByte('\n')
+		b.WriteString("Press Enter again to use this workspace anyway, or type another path.")
+	}
 	b.WriteByte('\n')
 
 	return b.String()
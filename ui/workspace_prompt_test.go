@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sds-lab-dev/bear-go/workspace"
+)
+
+func readyWorkspaceModel(t *testing.T, inspect func(string) (workspace.Info, error)) WorkspacePromptModel {
+	t.Helper()
+	return readyWorkspaceModelIn(t, "/work/project", nil, inspect)
+}
+
+func readyWorkspaceModelIn(
+	t *testing.T,
+	currentDir string,
+	recent []string,
+	inspect func(string) (workspace.Info, error),
+) WorkspacePromptModel {
+	t.Helper()
+	m := NewWorkspacePromptModel(currentDir, recent, inspect)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return updated.(WorkspacePromptModel)
+}
+
+// pressEnter presses Enter and delivers the result of the inspection that it
+// starts, as the Bubble Tea runtime would.
+func pressEnter(m WorkspacePromptModel) (WorkspacePromptModel, tea.Cmd) {
+	m, cmd := pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		return m, nil
+	}
+	msg, ok := cmd().(workspaceInspectedMsg)
+	if !ok {
+		return m, cmd
+	}
+	updated, cmd := m.Update(msg)
+	return updated.(WorkspacePromptModel), cmd
+}
+
+func pressWorkspaceKey(m WorkspacePromptModel, key tea.KeyMsg) (WorkspacePromptModel, tea.Cmd) {
+	updated, cmd := m.Update(key)
+	return updated.(WorkspacePromptModel), cmd
+}
+
+func typeWorkspacePath(m WorkspacePromptModel, text string) WorkspacePromptModel {
+	m, _ = pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+	return m
+}
+
+func TestWorkspacePromptModel_InspectsCurrentDirectory(t *testing.T) {
+	var inspected []string
+	m := readyWorkspaceModel(t, func(path string) (workspace.Info, error) {
+		inspected = append(inspected, path)
+		return workspace.Info{}, errors.New("refused")
+	})
+
+	m, cmd := pressEnter(m)
+
+	if len(inspected) != 1 || inspected[0] != "/work/project" {
+		t.Errorf("expected the current directory to be inspected, got %v", inspected)
+	}
+	if cmd != nil {
+		t.Error("expected no command for a refused workspace")
+	}
+	if !strings.Contains(stripANSI(m.View()), "refused") {
+		t.Error("view should show the inspection error")
+	}
+}
+
+func TestWorkspacePromptModel_InspectsOffTheUI(t *testing.T) {
+	inspected := false
+	m := readyWorkspaceModel(t, func(path string) (workspace.Info, error) {
+		inspected = true
+		return acceptingInspect(path)
+	})
+
+	m, cmd := pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	if inspected || cmd == nil {
+		t.Fatal("expected the inspection to run in a command")
+	}
+	if !strings.Contains(stripANSI(m.View()), "Inspecting") {
+		t.Error("view should show that the workspace is being inspected")
+	}
+	if _, again := pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyEnter}); again != nil {
+		t.Error("Enter should be ignored while inspecting")
+	}
+	cmd()
+	if !inspected {
+		t.Error("expected the command to inspect the workspace")
+	}
+}
+
+func TestWorkspacePromptModel_WarningsNeedConfirmation(t *testing.T) {
+	m := readyWorkspaceModel(t, func(path string) (workspace.Info, error) {
+		return workspace.Info{
+			Path:               path,
+			RepositoryRoot:     path,
+			UncommittedChanges: 3,
+			Languages:          []string{"Go"},
+			BuildFiles:         []string{"go.mod"},
+		}, nil
+	})
+
+	m, cmd := pressEnter(m)
+	if cmd != nil {
+		t.Fatal("expected the first Enter to ask for confirmation")
+	}
+	plain := stripANSI(m.View())
+	for _, expected := range []string{"3 uncommitted change(s)", "Languages: Go", "Build files: go.mod", "Press Enter again"} {
+		if !strings.Contains(plain, expected) {
+			t.Errorf("view should contain %q:\n%v", expected, plain)
+		}
+	}
+
+	_, cmd = pressEnter(m)
+	if cmd == nil {
+		t.Fatal("expected the second Enter to confirm the workspace")
+	}
+}
+
+func TestWorkspacePromptModel_NoWarningsConfirmsImmediately(t *testing.T) {
+	m := readyWorkspaceModel(t, func(path string) (workspace.Info, error) {
+		return workspace.Info{Path: path, RepositoryRoot: path}, nil
+	})
+
+	if _, cmd := pressEnter(m); cmd == nil {
+		t.Error("expected Enter to confirm a workspace without warnings")
+	}
+}
+
+func acceptingInspect(path string) (workspace.Info, error) {
+	return workspace.Info{Path: path, RepositoryRoot: path}, nil
+}
+
+func TestWorkspacePromptModel_ResolvesRelativePath(t *testing.T) {
+	m := readyWorkspaceModel(t, acceptingInspect)
+
+	m = typeWorkspacePath(m, "../other")
+	m, _ = pressEnter(m)
+
+	if m.confirmedPath != "/work/other" {
+		t.Errorf("expected the path to be resolved against the current directory, got %q", m.confirmedPath)
+	}
+}
+
+func TestWorkspacePromptModel_TabCompletesPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"project-api", "project-web", "notes"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	m := readyWorkspaceModelIn(t, dir, nil, acceptingInspect)
+
+	m = typeWorkspacePath(m, "pro")
+	m, _ = pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyTab})
+	if value := m.textarea.Value(); value != "project-" {
+		t.Errorf("expected the common prefix to be completed, got %q", value)
+	}
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "project-api  project-web") {
+		t.Errorf("view should list the completions:\n%v", plain)
+	}
+
+	m = typeWorkspacePath(m, "w")
+	m, _ = pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyTab})
+	if value := m.textarea.Value(); value != "project-web/" {
+		t.Errorf("expected the single match to be completed, got %q", value)
+	}
+}
+
+func TestWorkspacePromptModel_ArrowKeysPickRecentWorkspace(t *testing.T) {
+	recent := []string{"/work/first", "/work/second"}
+	m := readyWorkspaceModelIn(t, "/work/project", recent, acceptingInspect)
+
+	m, _ = pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyDown})
+	if value := m.textarea.Value(); value != "/work/second" {
+		t.Errorf("expected the selection to stop at the last workspace, got %q", value)
+	}
+
+	m, _ = pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyUp})
+	m, _ = pressEnter(m)
+	if m.confirmedPath != "/work/first" {
+		t.Errorf("expected the picked workspace to be confirmed, got %q", m.confirmedPath)
+	}
+}
+
+func TestWorkspacePromptModel_BrowserPicksFuzzyMatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"services/billing-api", "services/search", "tools"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	m := readyWorkspaceModelIn(t, dir, nil, acceptingInspect)
+
+	m, cmd := pressWorkspaceKey(m, tea.KeyMsg{Type: tea.KeyCtrlF})
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "Listing the directories...") {
+		t.Errorf("view should show the scan in progress:\n%v", plain)
+	}
+	m = typeWorkspacePath(m, "svcbil")
+	updated, _ := m.Update(cmd())
+	m = updated.(WorkspacePromptModel)
+	if plain := stripANSI(m.View()); !strings.Contains(plain, "> services/billing-api") {
+		t.Errorf("view should select the fuzzy match:\n%v", plain)
+	}
+
+	m, _ = pressEnter(m)
+	if m.browser != nil {
+		t.Fatal("expected Enter to close the browser")
+	}
+	m, _ = pressEnter(m)
+	if expected := filepath.Join(dir, "services/billing-api"); m.confirmedPath != expected {
+		t.Errorf("expected %q, got %q", expected, m.confirmedPath)
+	}
+}
@@ -0,0 +1,208 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+const (
+	// MaxProjectContextBytes bounds the summary that goes into a prompt, so
+	// that a large repository cannot crowd out the user's request.
+	MaxProjectContextBytes = 24 * 1024
+	// maxFileExcerptBytes bounds each module file and document in the summary.
+	maxFileExcerptBytes = 4 * 1024
+	// layoutDepth is the depth of the directory layout in the summary, and
+	// maxLayoutEntries the number of its directories.
+	layoutDepth      = 2
+	maxLayoutEntries = 80
+)
+
+// agentDocPatterns are the documents, relative to the workspace or the
+// repository root, that describe the project to humans and coding agents.
+var agentDocPatterns = []string{
+	"README.md", "README", "CLAUDE.md", "GEMINI.md", "AGENTS.md", "docs/agents/*.md",
+}
+
+// FileExcerpt is the beginning of a file in the workspace.
+type FileExcerpt struct {
+	// Path is relative to the workspace. It starts with ".." for a document
+	// at the root of the repository that the workspace is in.
+	Path      string
+	Content   string
+	Truncated bool
+}
+
+// ProjectContext is what the agent would otherwise learn from the workspace
+// with its first tool calls.
+type ProjectContext struct {
+	Languages   []string
+	ModuleFiles []FileExcerpt
+	// Layout are the directories with files, relative to the workspace, down
+	// to layoutDepth levels.
+	Layout []string
+	Docs   []FileExcerpt
+}
+
+// AnalyzeProject collects the project context of the workspace.
+func AnalyzeProject(dir string) (ProjectContext, error) {
+	// Outside a repository the files are walked instead; any other git
+	// failure would make the context wrong, so it is reported.
+	repoRoot, err := worktree.RepositoryRoot(dir)
+	if err != nil && !errors.Is(err, worktree.ErrNotGitRepository) && !errors.Is(err, gitcmd.ErrNotFound) {
+		return ProjectContext{}, err
+	}
+	files, err := projectFiles(dir, repoRoot != "")
+	if err != nil {
+		return ProjectContext{}, err
+	}
+	layout := directoryLayout(files)
+
+	var moduleFiles []FileExcerpt
+	for _, name := range detectBuildFiles(dir) {
+		if excerpt, ok := readExcerpt(dir, name); ok {
+			moduleFiles = append(moduleFiles, excerpt)
+		}
+	}
+
+	// RepositoryRoot resolves symbolic links, so the workspace is resolved
+	// too for the documents at the root to get paths relative to it.
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		resolved = dir
+	}
+	docs := agentDocs(resolved, resolved)
+	// In a workspace below the repository root, such as a package of a
+	// monorepo, the documents for agents are usually at the root.
+	if repoRoot != "" && repoRoot != resolved {
+		docs = append(docs, agentDocs(resolved, repoRoot)...)
+	}
+
+	return ProjectContext{
+		Languages:   detectLanguages(files),
+		ModuleFiles: moduleFiles,
+		Layout:      layout,
+		Docs:        docs,
+	}, nil
+}
+
+// agentDocs reads the documents that match agentDocPatterns in searchDir,
+// with paths relative to the workspace.
+func agentDocs(dir, searchDir string) []FileExcerpt {
+	var docs []FileExcerpt
+	for _, pattern := range agentDocPatterns {
+		paths, _ := filepath.Glob(filepath.Join(searchDir, pattern))
+		for _, path := range paths {
+			relative, err := filepath.Rel(dir, path)
+			if err != nil {
+				continue
+			}
+			if excerpt, ok := readExcerpt(dir, relative); ok {
+				docs = append(docs, excerpt)
+			}
+		}
+	}
+	return docs
+}
+
+// directoryLayout lists the directories of the files. In a git repository
+// they are the tracked files, so that build output and dependencies that are
+// ignored by git stay out of the summary.
+func directoryLayout(files []string) []string {
+	seen := map[string]bool{}
+	var layout []string
+	for _, file := range files {
+		parts := strings.Split(filepath.ToSlash(file), "/")
+		for depth := 1; depth < len(parts) && depth <= layoutDepth; depth++ {
+			directory := strings.Join(parts[:depth], "/") + "/"
+			if !seen[directory] {
+				seen[directory] = true
+				layout = append(layout, directory)
+			}
+		}
+	}
+	slices.Sort(layout)
+	if len(layout) > maxLayoutEntries {
+		layout = layout[:maxLayoutEntries]
+	}
+	return layout
+}
+
+func readExcerpt(dir, relativePath string) (FileExcerpt, bool) {
+	content, err := os.ReadFile(filepath.Join(dir, relativePath))
+	if err != nil {
+		return FileExcerpt{}, false
+	}
+	excerpt := FileExcerpt{Path: filepath.ToSlash(relativePath), Content: string(content)}
+	if len(content) > maxFileExcerptBytes {
+		excerpt.Content = strings.ToValidUTF8(string(content[:maxFileExcerptBytes]), "")
+		excerpt.Truncated = true
+	}
+	return excerpt, true
+}
+
+// Summary renders the project context as Markdown of at most maxBytes bytes.
+// The sections are ordered by how much they tell about the project, so that
+// the documents are cut first.
+func (p ProjectContext) Summary(maxBytes int) string {
+	var b strings.Builder
+
+	b.WriteString("## Languages\n\n")
+	if len(p.Languages) == 0 {
+		b.WriteString("None detected.\n")
+	} else {
+		b.WriteString(strings.Join(p.Languages, ", ") + "\n")
+	}
+
+	b.WriteString("\n## Directory layout\n\n")
+	if len(p.Layout) == 0 {
+		b.WriteString("No subdirectories with tracked files.\n")
+	}
+	for _, directory := range p.Layout {
+		depth := strings.Count(strings.TrimSuffix(directory, "/"), "/")
+		fmt.Fprintf(&b, "%v- %v\n", strings.Repeat("  ", depth), directory)
+	}
+
+	for _, section := range []struct {
+		title    string
+		excerpts []FileExcerpt
+	}{
+		{"Module and build files", p.ModuleFiles},
+		{"Project documents", p.Docs},
+	} {
+		if len(section.excerpts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %v\n", section.title)
+		for _, excerpt := range section.excerpts {
+			writeExcerpt(&b, excerpt)
+		}
+	}
+
+	summary := b.String()
+	if len(summary) > maxBytes {
+		const marker = "\n\n[The project context was cut off here.]\n"
+		summary = strings.ToValidUTF8(summary[:max(0, maxBytes-len(marker))], "") + marker
+	}
+	return summary
+}
+
+func writeExcerpt(b *strings.Builder, excerpt FileExcerpt) {
+	fmt.Fprintf(b, "\n### %v\n\n", excerpt.Path)
+	// A fence longer than any backtick run in the content keeps the content
+	// from closing it.
+	fence := "```"
+	for strings.Contains(excerpt.Content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%v\n%v\n%v\n", fence, strings.TrimRight(excerpt.Content, "\n"), fence)
+	if excerpt.Truncated {
+		fmt.Fprintf(b, "\n(Only the first %d bytes are shown.)\n", maxFileExcerptBytes)
+	}
+}
@@ -0,0 +1,132 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+)
+
+func TestAnalyzeProject(t *testing.T) {
+	dir := testutil.NewRepository(t,
+		"go.mod", "README.md", "CLAUDE.md", "docs/agents/conventions.md", "docs/design.md",
+		".bear/20260218/session-1/request.md",
+		"cmd/bear/main.go", "internal/store/store.go", "internal/store/deep/nested/x.go",
+	)
+	// Ignored and untracked files are not part of the layout.
+	testutil.WriteFiles(t, dir, "untracked/file.go")
+
+	context, err := AnalyzeProject(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(context.Languages, []string{"Go"}) {
+		t.Errorf("unexpected languages: %v", context.Languages)
+	}
+	expectedLayout := []string{"cmd/", "cmd/bear/", "docs/", "docs/agents/", "internal/", "internal/store/"}
+	if !slices.Equal(context.Layout, expectedLayout) {
+		t.Errorf("expected layout %v, got %v", expectedLayout, context.Layout)
+	}
+	if len(context.ModuleFiles) != 1 || context.ModuleFiles[0].Path != "go.mod" {
+		t.Errorf("unexpected module files: %#v", context.ModuleFiles)
+	}
+	var docs []string
+	for _, doc := range context.Docs {
+		docs = append(docs, doc.Path)
+	}
+	if expected := []string{"README.md", "CLAUDE.md", "docs/agents/conventions.md"}; !slices.Equal(docs, expected) {
+		t.Errorf("expected docs %v, got %v", expected, docs)
+	}
+}
+
+func TestAnalyzeProject_ReadsDocsAtRepositoryRoot(t *testing.T) {
+	root := testutil.NewRepository(t, "README.md", "AGENTS.md", "services/billing/README.md", "services/billing/go.mod")
+
+	context, err := AnalyzeProject(filepath.Join(root, "services", "billing"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var docs []string
+	for _, doc := range context.Docs {
+		docs = append(docs, doc.Path)
+	}
+	if expected := []string{"README.md", "../../README.md", "../../AGENTS.md"}; !slices.Equal(docs, expected) {
+		t.Errorf("expected docs %v, got %v", expected, docs)
+	}
+}
+
+func TestAnalyzeProject_NotGitRepository(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, "pyproject.toml", "app/main.py", ".venv/lib/x.py")
+
+	context, err := AnalyzeProject(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(context.Languages, []string{"Python"}) {
+		t.Errorf("unexpected languages: %v", context.Languages)
+	}
+	if !slices.Equal(context.Layout, []string{"app/"}) {
+		t.Errorf("expected the layout without hidden directories, got %v", context.Layout)
+	}
+}
+
+func TestAnalyzeProject_ReportsGitFailures(t *testing.T) {
+	_, err := AnalyzeProject(filepath.Join(t.TempDir(), "missing"))
+
+	if !errors.Is(err, gitcmd.ErrCommandFailed) {
+		t.Errorf("expected the git failure, got %v", err)
+	}
+}
+
+func TestAnalyzeProject_TruncatesLargeFiles(t *testing.T) {
+	dir := testutil.NewRepository(t, "main.go")
+	content := strings.Repeat("x", maxFileExcerptBytes+100)
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write README: %v", err)
+	}
+
+	context, err := AnalyzeProject(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(context.Docs) != 1 || !context.Docs[0].Truncated || len(context.Docs[0].Content) != maxFileExcerptBytes {
+		t.Errorf("expected the README to be truncated: %#v", context.Docs)
+	}
+}
+
+func TestProjectContext_Summary(t *testing.T) {
+	context := ProjectContext{
+		Languages:   []string{"Go", "Shell"},
+		Layout:      []string{"cmd/", "cmd/bear/"},
+		ModuleFiles: []FileExcerpt{{Path: "go.mod", Content: "module example\n"}},
+		Docs:        []FileExcerpt{{Path: "README.md", Content: "Use ``` fences.\n", Truncated: true}},
+	}
+
+	summary := context.Summary(MaxProjectContextBytes)
+
+	for _, expected := range []string{
+		"## Languages\n\nGo, Shell\n",
+		"- cmd/\n  - cmd/bear/\n",
+		"### go.mod\n\n```\nmodule example\n```\n",
+		"### README.md\n\n````\nUse ``` fences.\n````\n",
+		"Only the first 4096 bytes are shown.",
+	} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("summary should contain %q:\n%v", expected, summary)
+		}
+	}
+
+	short := context.Summary(100)
+	if len(short) > 100 || !strings.HasSuffix(short, "[The project context was cut off here.]\n") {
+		t.Errorf("expected a summary cut off at 100 bytes, got %d bytes:\n%v", len(short), short)
+	}
+}
@@ -0,0 +1,308 @@
+// Package workspace inspects the directory that the user chose as the
+// workspace before an agent with full permissions is sent into it.
+package workspace
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/log"
+	"github.com/sds-lab-dev/bear-go/session"
+	"github.com/sds-lab-dev/bear-go/worktree"
+)
+
+var (
+	ErrDangerousPath = errors.New("refusing to use a system or home directory as the workspace")
+	ErrNotWritable   = errors.New("workspace is not writable")
+)
+
+// Info describes a workspace that passed inspection.
+type Info struct {
+	Path string
+	// RepositoryRoot is the top-level directory of the git repository that
+	// contains Path, or empty if Path is not in a git repository.
+	RepositoryRoot string
+	// UncommittedChanges is the number of changed, staged, and untracked files
+	// in the repository, not counting the session artifacts.
+	UncommittedChanges int
+	// BuildFiles are the build and manifest files found in Path, or in the
+	// repository root if Path has none.
+	BuildFiles []string
+	// Languages are the programming languages of the files under Path, most
+	// files first. In a git repository only the tracked files are counted.
+	Languages []string
+}
+
+// InSubdirectory reports whether the workspace is below the repository root.
+func (i Info) InSubdirectory() bool {
+	return i.RepositoryRoot != "" && i.Path != i.RepositoryRoot
+}
+
+// Warnings returns the findings that do not prevent using the workspace but
+// that the user should confirm before the agent starts.
+func (i Info) Warnings() []string {
+	var warnings []string
+	if i.RepositoryRoot == "" {
+		warnings = append(warnings,
+			"The workspace is not in a git repository; the agent's changes can only be rolled back to a full copy of the workspace taken before it starts.",
+		)
+	}
+	if i.InSubdirectory() {
+		warnings = append(warnings, fmt.Sprintf(
+			"The workspace is a subdirectory of the repository at %v; the agent can still change files anywhere in the repository.",
+			i.RepositoryRoot,
+		))
+	}
+	if i.UncommittedChanges > 0 {
+		warnings = append(warnings, fmt.Sprintf(
+			"The repository has %d uncommitted change(s); commit or stash them to keep them apart from the agent's changes.",
+			i.UncommittedChanges,
+		))
+	}
+	return warnings
+}
+
+// dangerousPaths are directories that are never a project workspace. The
+// home directory is added at inspection time.
+var dangerousPaths = []string{
+	"/", "/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/lib64", "/opt",
+	"/proc", "/root", "/run", "/sbin", "/srv", "/sys", "/tmp", "/usr",
+	"/usr/bin", "/usr/lib", "/usr/local", "/usr/sbin", "/var",
+	"/Applications", "/Library", "/System", "/Users", "/Volumes", "/private",
+}
+
+// systemTrees are directories whose whole tree belongs to the operating
+// system.
+var systemTrees = []string{"/boot", "/dev", "/etc", "/proc", "/sys", "/System"}
+
+// Inspect checks that path, an existing absolute directory, is safe to give
+// to the agent, and collects what the user should know about it.
+func Inspect(path string) (Info, error) {
+	path, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to resolve workspace path: %w", err)
+	}
+	if err := checkNotDangerous(path); err != nil {
+		return Info{}, err
+	}
+
+	if err := checkWritable(path); err != nil {
+		return Info{}, err
+	}
+
+	repoRoot, err := worktree.RepositoryRoot(path)
+	if errors.Is(err, worktree.ErrNotGitRepository) || errors.Is(err, gitcmd.ErrNotFound) {
+		log.Info(fmt.Sprintf("workspace is not in a git repository: %v", err))
+		files, err := projectFiles(path, false)
+		if err != nil {
+			return Info{}, err
+		}
+		return Info{
+			Path:       path,
+			BuildFiles: detectBuildFiles(path),
+			Languages:  detectLanguages(files),
+		}, nil
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	repoRoot, err = filepath.EvalSymlinks(repoRoot)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to resolve repository root: %w", err)
+	}
+	if err := checkNotDangerous(repoRoot); err != nil {
+		return Info{}, fmt.Errorf("%w (repository root of %v)", err, path)
+	}
+
+	changes, err := uncommittedChanges(repoRoot)
+	if err != nil {
+		return Info{}, err
+	}
+	files, err := projectFiles(path, true)
+	if err != nil {
+		return Info{}, err
+	}
+	buildFiles := detectBuildFiles(path)
+	if len(buildFiles) == 0 && path != repoRoot {
+		buildFiles = detectBuildFiles(repoRoot)
+	}
+
+	return Info{
+		Path:               path,
+		RepositoryRoot:     repoRoot,
+		UncommittedChanges: changes,
+		BuildFiles:         buildFiles,
+		Languages:          detectLanguages(files),
+	}, nil
+}
+
+func checkNotDangerous(path string) error {
+	if slices.Contains(dangerousPaths, path) {
+		return fmt.Errorf("%w: %v", ErrDangerousPath, path)
+	}
+	for _, tree := range systemTrees {
+		if strings.HasPrefix(path, tree+string(filepath.Separator)) {
+			return fmt.Errorf("%w: %v is inside %v", ErrDangerousPath, path, tree)
+		}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	if homeDir, err = filepath.EvalSymlinks(homeDir); err == nil && path == homeDir {
+		return fmt.Errorf("%w: %v is the home directory", ErrDangerousPath, path)
+	}
+	return nil
+}
+
+// checkWritable creates and removes a file in the directory, which is the only
+// check that also accounts for ACLs and read-only mounts.
+func checkWritable(path string) error {
+	file, err := os.CreateTemp(path, ".bear-write-check-*")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotWritable, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return nil
+}
+
+// sessionArtifactsPathspec excludes the session artifacts from git commands,
+// wherever the session directory is in the repository.
+var sessionArtifactsPathspec = ":(glob,exclude)**/" + session.DirName + "/**"
+
+func uncommittedChanges(repoRoot string) (int, error) {
+	output, err := gitcmd.Run(repoRoot, "status", "--porcelain", "--", ".", sessionArtifactsPathspec)
+	if err != nil {
+		return 0, err
+	}
+	if output == "" {
+		return 0, nil
+	}
+	return len(strings.Split(output, "\n")), nil
+}
+
+// buildFiles are the build and manifest files that tell the user, and the
+// agent, how the project is built.
+var buildFiles = []string{
+	"go.mod", "Cargo.toml", "package.json", "tsconfig.json", "pyproject.toml",
+	"setup.py", "requirements.txt", "pom.xml", "build.gradle",
+	"build.gradle.kts", "Gemfile", "composer.json", "mix.exs", "Package.swift",
+	"build.zig", "CMakeLists.txt", "meson.build", "Makefile", "Dockerfile",
+}
+
+func detectBuildFiles(dir string) []string {
+	var found []string
+	for _, buildFile := range buildFiles {
+		if _, err := os.Stat(filepath.Join(dir, buildFile)); err == nil {
+			found = append(found, buildFile)
+		}
+	}
+	return found
+}
+
+var extensionLanguages = map[string]string{
+	".go": "Go", ".rs": "Rust", ".js": "JavaScript", ".jsx": "JavaScript",
+	".mjs": "JavaScript", ".ts": "TypeScript", ".tsx": "TypeScript",
+	".py": "Python", ".java": "Java", ".kt": "Kotlin", ".kts": "Kotlin",
+	".rb": "Ruby", ".php": "PHP", ".ex": "Elixir", ".exs": "Elixir",
+	".swift": "Swift", ".zig": "Zig", ".c": "C", ".h": "C", ".cc": "C++",
+	".cpp": "C++", ".hpp": "C++", ".cs": "C#", ".scala": "Scala",
+	".sh": "Shell", ".lua": "Lua", ".dart": "Dart",
+}
+
+// maxLanguages is the number of languages reported, so that a few stray
+// scripts do not clutter the prompt.
+const maxLanguages = 3
+
+// detectLanguages counts the files by language.
+func detectLanguages(files []string) []string {
+	counts := map[string]int{}
+	for _, file := range files {
+		if language, ok := extensionLanguages[strings.ToLower(filepath.Ext(file))]; ok {
+			counts[language]++
+		}
+	}
+
+	languages := make([]string, 0, len(counts))
+	for language := range counts {
+		languages = append(languages, language)
+	}
+	slices.SortFunc(languages, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	if len(languages) > maxLanguages {
+		languages = languages[:maxLanguages]
+	}
+	return languages
+}
+
+// projectFiles lists the files under dir, relative to dir: the files tracked
+// by git if tracked is set, or else the files found by walking dir.
+func projectFiles(dir string, tracked bool) ([]string, error) {
+	if tracked {
+		return trackedFiles(dir)
+	}
+	return walkFiles(dir)
+}
+
+// trackedFiles lists the files tracked by git under dir, relative to dir,
+// without the session artifacts.
+func trackedFiles(dir string) ([]string, error) {
+	output, err := gitcmd.Run(dir, "ls-files", "--", ".", sessionArtifactsPathspec)
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// maxWalkedFiles bounds the files counted in a workspace that is not a git
+// repository, which may be large.
+const maxWalkedFiles = 10000
+
+// walkFiles lists the files under dir, relative to dir, without the hidden
+// directories, which include the session artifacts, and the installed
+// dependencies. An entry that cannot be read, such as a directory of another
+// user, is skipped, since the files only serve to detect the languages.
+func walkFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			log.Warning(fmt.Sprintf("skipping unreadable workspace entry: %v", err))
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(files) >= maxWalkedFiles {
+			return filepath.SkipAll
+		}
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, relative)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,133 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+)
+
+func TestInspect_CleanRepository(t *testing.T) {
+	dir := testutil.NewRepository(t, "go.mod", "Makefile", "main.go", "cmd/tool/main.go", "scripts/build.sh", "README.md")
+
+	info, err := Inspect(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.InSubdirectory() {
+		t.Error("expected the workspace to be the repository root")
+	}
+	if info.UncommittedChanges != 0 {
+		t.Errorf("expected no uncommitted changes, got %d", info.UncommittedChanges)
+	}
+	if !slices.Equal(info.Languages, []string{"Go", "Shell"}) {
+		t.Errorf("unexpected languages: %v", info.Languages)
+	}
+	if !slices.Equal(info.BuildFiles, []string{"go.mod", "Makefile"}) {
+		t.Errorf("unexpected build files: %v", info.BuildFiles)
+	}
+	if warnings := info.Warnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+func TestInspect_SubdirectoryWithUncommittedChanges(t *testing.T) {
+	dir := testutil.NewRepository(t, "go.mod", "service/main.py")
+	testutil.WriteFiles(t, dir, "service/new.py")
+	testutil.WriteFiles(t, dir, ".bear/20260218/session-1/request.md")
+
+	info, err := Inspect(filepath.Join(dir, "service"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !info.InSubdirectory() {
+		t.Error("expected the workspace to be a subdirectory")
+	}
+	if info.UncommittedChanges != 1 {
+		t.Errorf("expected one uncommitted change, got %d", info.UncommittedChanges)
+	}
+	if !slices.Equal(info.Languages, []string{"Python"}) {
+		t.Errorf("expected the languages of the subdirectory only, got %v", info.Languages)
+	}
+	if !slices.Equal(info.BuildFiles, []string{"go.mod"}) {
+		t.Errorf("expected the build files of the repository root, got %v", info.BuildFiles)
+	}
+	if warnings := info.Warnings(); len(warnings) != 2 {
+		t.Errorf("expected two warnings, got %v", warnings)
+	}
+}
+
+func TestInspect_NotGitRepository(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, "Cargo.toml", "src/main.rs", "src/lib.rs", "node_modules/x/index.js", ".cache/a.py")
+
+	info, err := Inspect(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.RepositoryRoot != "" || info.InSubdirectory() {
+		t.Errorf("expected no repository, got %+v", info)
+	}
+	if !slices.Equal(info.Languages, []string{"Rust"}) {
+		t.Errorf("expected the languages without the hidden and dependency directories, got %v", info.Languages)
+	}
+	if !slices.Equal(info.BuildFiles, []string{"Cargo.toml"}) {
+		t.Errorf("unexpected build files: %v", info.BuildFiles)
+	}
+	if warnings := info.Warnings(); len(warnings) != 1 {
+		t.Errorf("expected a warning about the missing repository, got %v", warnings)
+	}
+}
+
+func TestInspect_RefusesDangerousPaths(t *testing.T) {
+	home := testutil.NewRepository(t)
+	t.Setenv("HOME", home)
+
+	for _, path := range []string{"/", "/usr", "/etc/ssh", home} {
+		if _, err := Inspect(path); !errors.Is(err, ErrDangerousPath) {
+			t.Errorf("%v: expected ErrDangerousPath, got %v", path, err)
+		}
+	}
+}
+
+func TestInspect_RefusesReadOnlyWorkspace(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	dir := testutil.NewRepository(t, "main.go")
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatalf("failed to make directory read-only: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+
+	if _, err := Inspect(dir); !errors.Is(err, ErrNotWritable) {
+		t.Errorf("expected ErrNotWritable, got %v", err)
+	}
+}
+
+func TestInspect_SkipsUnreadableDirectories(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, "main.go", "private/secret.py")
+	private := filepath.Join(dir, "private")
+	if err := os.Chmod(private, 0o000); err != nil {
+		t.Fatalf("failed to make directory unreadable: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(private, 0o755) })
+
+	info, err := Inspect(dir)
+	if err != nil {
+		t.Fatalf("an unreadable directory should be skipped, got %v", err)
+	}
+	if !slices.Equal(info.Languages, []string{"Go"}) {
+		t.Errorf("expected the languages of the readable files, got %v", info.Languages)
+	}
+}
@@ -0,0 +1,169 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ResolvePath turns the path typed by the user into an absolute path. A
+// leading "~" is the home directory and a relative path is resolved against
+// baseDir.
+func ResolvePath(baseDir, input string) (string, error) {
+	path, err := expandHome(input)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+func expandHome(input string) (string, error) {
+	if input != "~" && !strings.HasPrefix(input, "~"+string(filepath.Separator)) {
+		return input, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to expand ~: %w", err)
+	}
+	return homeDir + strings.TrimPrefix(input, "~"), nil
+}
+
+// CompletePath completes the last element of the typed path to the
+// directories that start with it. It returns the input extended by the
+// longest common prefix of the matches, and the matches themselves. A
+// single match is completed with a trailing separator so that the next
+// completion lists its subdirectories. The input keeps its form, so "~"
+// and relative paths stay as typed.
+func CompletePath(baseDir, input string) (string, []string) {
+	return completePath(baseDir, input, false)
+}
+
+// CompleteFilePath is CompletePath for picking a file: it also offers the
+// files, and a single file match is completed without a separator.
+func CompleteFilePath(baseDir, input string) (string, []string) {
+	return completePath(baseDir, input, true)
+}
+
+func completePath(baseDir, input string, includeFiles bool) (string, []string) {
+	typedDir, prefix := splitTypedPath(input)
+	dir, err := ResolvePath(baseDir, typedDir)
+	if err != nil {
+		return input, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return input, nil
+	}
+
+	var matches []string
+	isMatchDir := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		isDir := isDirectory(dir, entry)
+		if !strings.HasPrefix(name, prefix) || (!isDir && !includeFiles) {
+			continue
+		}
+		// Hidden directories are only offered when asked for.
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		matches = append(matches, name)
+		isMatchDir[name] = isDir
+	}
+	slices.Sort(matches)
+
+	switch len(matches) {
+	case 0:
+		return input, nil
+	case 1:
+		if !isMatchDir[matches[0]] {
+			return typedDir + matches[0], matches
+		}
+		return typedDir + matches[0] + string(filepath.Separator), matches
+	default:
+		return typedDir + commonPrefix(matches), matches
+	}
+}
+
+// splitTypedPath splits the input after its last separator. "~" alone is a
+// directory to complete into rather than a name to complete.
+func splitTypedPath(input string) (string, string) {
+	if input == "~" {
+		return "~" + string(filepath.Separator), ""
+	}
+	i := strings.LastIndex(input, string(filepath.Separator))
+	return input[:i+1], input[i+1:]
+}
+
+// isDirectory also follows symbolic links, which are common for project
+// directories.
+func isDirectory(dir string, entry fs.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, entry.Name()))
+	return err == nil && info.IsDir()
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// skippedDirectories are never offered by ScanDirectories because they hold
+// dependencies or build output rather than projects.
+var skippedDirectories = []string{"node_modules", "vendor", "target", "dist", "build", "__pycache__"}
+
+// ScanDirectories lists the directories below root, relative to root, down to
+// maxDepth levels and at most limit of them. Hidden directories and the
+// contents of git repositories are skipped, because a repository is a
+// workspace candidate as a whole.
+func ScanDirectories(root string, maxDepth, limit int) []string {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if len(dirs) >= limit {
+			return filepath.SkipAll
+		}
+		if path == root {
+			return nil
+		}
+
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || slices.Contains(skippedDirectories, name) {
+			return filepath.SkipDir
+		}
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		dirs = append(dirs, relative)
+
+		if strings.Count(relative, string(filepath.Separator))+1 >= maxDepth || isRepository(path) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs
+}
+
+func isRepository(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
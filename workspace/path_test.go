@@ -0,0 +1,112 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func mkdirAll(t *testing.T, root string, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("failed to create %v: %v", dir, err)
+		}
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	t.Setenv("HOME", "/home/bear")
+
+	tests := map[string]string{
+		"/abs/path":    "/abs/path",
+		"relative/dir": "/work/project/relative/dir",
+		"../sibling":   "/work/sibling",
+		".":            "/work/project",
+		"~":            "/home/bear",
+		"~/code/":      "/home/bear/code",
+		"~other":       "/work/project/~other",
+	}
+	for input, expected := range tests {
+		resolved, err := ResolvePath("/work/project", input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", input, err)
+			continue
+		}
+		if resolved != expected {
+			t.Errorf("%q: expected %q, got %q", input, expected, resolved)
+		}
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	root := t.TempDir()
+	mkdirAll(t, root, "bear-go", "bear-web", "cat", ".hidden")
+	if err := os.WriteFile(filepath.Join(root, "bear.txt"), nil, 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	t.Setenv("HOME", root)
+
+	tests := []struct {
+		input     string
+		completed string
+		matches   []string
+	}{
+		{"be", "bear-", []string{"bear-go", "bear-web"}},
+		{"bear-g", "bear-go/", []string{"bear-go"}},
+		{"~/c", "~/cat/", []string{"cat"}},
+		{"~", "~/", []string{"bear-go", "bear-web", "cat"}},
+		{".h", ".hidden/", []string{".hidden"}},
+		{"x", "x", nil},
+		{"missing/b", "missing/b", nil},
+	}
+	for _, tt := range tests {
+		completed, matches := CompletePath(root, tt.input)
+		if completed != tt.completed || !slices.Equal(matches, tt.matches) {
+			t.Errorf("%q: expected %q %v, got %q %v", tt.input, tt.completed, tt.matches, completed, matches)
+		}
+	}
+}
+
+func TestCompleteFilePath(t *testing.T) {
+	root := t.TempDir()
+	mkdirAll(t, root, "docs")
+	for _, file := range []string{"docs/request.md", "docs/requirements.pdf", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		input     string
+		completed string
+		matches   []string
+	}{
+		{"no", "notes.txt", []string{"notes.txt"}},
+		{"d", "docs/", []string{"docs"}},
+		{"docs/req", "docs/requ", []string{"request.md", "requirements.pdf"}},
+		{"docs/requi", "docs/requirements.pdf", []string{"requirements.pdf"}},
+	}
+	for _, tt := range tests {
+		completed, matches := CompleteFilePath(root, tt.input)
+		if completed != tt.completed || !slices.Equal(matches, tt.matches) {
+			t.Errorf("%q: expected %q %v, got %q %v", tt.input, tt.completed, tt.matches, completed, matches)
+		}
+	}
+}
+
+func TestScanDirectories(t *testing.T) {
+	root := t.TempDir()
+	mkdirAll(t, root, "a/b/c/d", "repo/.git", "repo/internal", "web/node_modules/pkg", ".cache/x")
+
+	dirs := ScanDirectories(root, 3, 100)
+
+	expected := []string{"a", "a/b", "a/b/c", "repo", "web"}
+	if !slices.Equal(dirs, expected) {
+		t.Errorf("expected %v, got %v", expected, dirs)
+	}
+	if dirs := ScanDirectories(root, 3, 2); len(dirs) != 2 {
+		t.Errorf("expected the limit to be applied, got %v", dirs)
+	}
+}
@@ -0,0 +1,66 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// recentFileName is the file in Bear's state directory that lists the
+// recently used workspaces.
+const recentFileName = "recent_workspaces.json"
+
+// maxRecent is the number of recently used workspaces that are kept.
+const maxRecent = 10
+
+// LoadRecent returns the recently used workspaces, most recent first. The
+// workspaces that no longer exist are left out.
+func LoadRecent(stateDir string) ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(stateDir, recentFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent workspaces: %w", err)
+	}
+
+	var paths []string
+	if err := json.Unmarshal(content, &paths); err != nil {
+		return nil, fmt.Errorf("invalid recent workspaces file: %w", err)
+	}
+	return slices.DeleteFunc(paths, func(path string) bool {
+		info, err := os.Stat(path)
+		return err != nil || !info.IsDir()
+	}), nil
+}
+
+// AddRecent moves the workspace to the front of the recently used workspaces.
+func AddRecent(stateDir, path string) error {
+	paths, err := LoadRecent(stateDir)
+	if err != nil {
+		// A broken file must not keep the user from saving new entries.
+		paths = nil
+	}
+	paths = slices.DeleteFunc(paths, func(recent string) bool {
+		return recent == path
+	})
+	paths = append([]string{path}, paths...)
+	if len(paths) > maxRecent {
+		paths = paths[:maxRecent]
+	}
+
+	content, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recent workspaces: %w", err)
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, recentFileName), content, 0o644); err != nil {
+		return fmt.Errorf("failed to write recent workspaces: %w", err)
+	}
+	return nil
+}
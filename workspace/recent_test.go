@@ -0,0 +1,64 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRecent_MostRecentFirstWithoutDuplicates(t *testing.T) {
+	stateDir := filepath.Join(t.TempDir(), "state")
+	root := t.TempDir()
+	first, second := filepath.Join(root, "first"), filepath.Join(root, "second")
+	mkdirAll(t, root, "first", "second")
+
+	for _, path := range []string{first, second, first} {
+		if err := AddRecent(stateDir, path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	recent, err := LoadRecent(stateDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{first, second}; !slices.Equal(recent, expected) {
+		t.Errorf("expected %v, got %v", expected, recent)
+	}
+}
+
+func TestRecent_SkipsRemovedWorkspacesAndKeepsLimit(t *testing.T) {
+	stateDir := t.TempDir()
+	root := t.TempDir()
+	for i := range maxRecent + 2 {
+		name := fmt.Sprintf("dir-%d", i)
+		mkdirAll(t, root, name)
+		if err := AddRecent(stateDir, filepath.Join(root, name)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	removed := filepath.Join(root, fmt.Sprintf("dir-%d", maxRecent+1))
+	if err := os.Remove(removed); err != nil {
+		t.Fatalf("failed to remove directory: %v", err)
+	}
+
+	recent, err := LoadRecent(stateDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recent) != maxRecent-1 {
+		t.Errorf("expected %d workspaces, got %v", maxRecent-1, recent)
+	}
+	if slices.Contains(recent, removed) {
+		t.Errorf("expected %v to be skipped", removed)
+	}
+}
+
+func TestLoadRecent_NoFile(t *testing.T) {
+	recent, err := LoadRecent(t.TempDir())
+	if err != nil || recent != nil {
+		t.Errorf("expected no workspaces and no error, got %v, %v", recent, err)
+	}
+}
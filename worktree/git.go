@@ -0,0 +1,32 @@
+// Package worktree keeps the work of a session on a git branch of its own, so
+// that the user's branch is never touched, and reads its history. Coding
+// tasks that run in parallel each get a worktree and branch of their own,
+// which are merged into the session branch in dependency order.
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+)
+
+var ErrNotGitRepository = errors.New("path is not inside a git repository")
+
+// RepositoryRoot returns the top-level directory of the git work tree that
+// contains dir. It returns ErrNotGitRepository only if git tells that dir is
+// not in a repository; any other failure, such as a repository that git
+// refuses to use, is returned as it is.
+func RepositoryRoot(dir string) (string, error) {
+	// The message is matched below, so it must not be translated.
+	root, err := gitcmd.RunWithEnv(dir, []string{"LC_ALL=C"}, "rev-parse", "--show-toplevel")
+	var gitErr *gitcmd.Error
+	if errors.As(err, &gitErr) && strings.Contains(gitErr.Stderr, "not a git repository") {
+		return "", fmt.Errorf("%w: %v", ErrNotGitRepository, dir)
+	}
+	if err != nil {
+		return "", err
+	}
+	return root, nil
+}
@@ -0,0 +1,52 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+)
+
+func writeAndCommit(t *testing.T, dir, name, content, message string) {
+	t.Helper()
+	testutil.WriteFile(t, dir, name, content)
+	testutil.Git(t, dir, "add", name)
+	testutil.Git(t, dir, "commit", "--quiet", "-m", message)
+}
+
+func TestRepositoryRoot(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	subdir := filepath.Join(repo, "sub")
+	if err := os.Mkdir(subdir, 0o755); err != nil {
+		t.Fatalf("failed to create subdirectory: %v", err)
+	}
+
+	root, err := RepositoryRoot(subdir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected, _ := filepath.EvalSymlinks(repo)
+	actual, _ := filepath.EvalSymlinks(root)
+	if actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	empty := t.TempDir()
+	testutil.Git(t, empty, "init", "--quiet")
+	if _, err := RepositoryRoot(empty); err != nil {
+		t.Errorf("a repository without commits has a root too, got %v", err)
+	}
+
+	if _, err := RepositoryRoot(t.TempDir()); !errors.Is(err, ErrNotGitRepository) {
+		t.Errorf("expected ErrNotGitRepository, got %v", err)
+	}
+
+	// A failure other than a missing repository must not look like one.
+	_, err = RepositoryRoot(filepath.Join(t.TempDir(), "missing"))
+	if errors.Is(err, ErrNotGitRepository) || !errors.Is(err, gitcmd.ErrCommandFailed) {
+		t.Errorf("expected a git failure, got %v", err)
+	}
+}
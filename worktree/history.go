@@ -0,0 +1,264 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+var (
+	ErrNothingToCommit = errors.New("no changes to commit")
+	ErrWrongBranch     = errors.New("worktree is not on the expected branch")
+	ErrNoSessionCommit = errors.New("session branch has no commits of its own")
+)
+
+// SessionBranch returns the branch that collects the work of the session. Bear
+// commits only to this branch and to the task branches, never to the branch
+// the user has checked out.
+func SessionBranch(sessionID string) string {
+	return "bear/" + sessionID
+}
+
+// CommitMessage describes a coding or revision round of a task. The generated
+// commit message refers to the task and the spec so that a reviewer bisecting
+// the history can find why a change was made.
+type CommitMessage struct {
+	SessionID string
+	TaskID    string
+	// Round is 1 for the first coding round and increments with each
+	// revision.
+	Round   int
+	Summary string
+	// SpecPath is the path of the approved spec, relative to the repository
+	// root if it is inside the repository.
+	SpecPath string
+	// Covers are the IDs of the spec requirements and acceptance criteria that
+	// the change implements, for example "REQ-01" and "AC-02".
+	Covers []string
+}
+
+func (c CommitMessage) String() string {
+	var b strings.Builder
+
+	subject := fmt.Sprintf("%v: %v", c.TaskID, firstLine(c.Summary))
+	if c.Round > 1 {
+		subject = fmt.Sprintf("%v (revision %d)", subject, c.Round-1)
+	}
+	b.WriteString(subject)
+
+	if body := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(c.Summary), firstLine(c.Summary))); body != "" {
+		b.WriteString("\n\n" + body)
+	}
+
+	b.WriteString("\n\n")
+	if c.SpecPath != "" {
+		fmt.Fprintf(&b, "Bear-Spec: %v\n", c.SpecPath)
+	}
+	fmt.Fprintf(&b, "Bear-Session: %v\n", c.SessionID)
+	fmt.Fprintf(&b, "Bear-Task: %v\n", c.TaskID)
+	if len(c.Covers) > 0 {
+		fmt.Fprintf(&b, "Bear-Covers: %v\n", strings.Join(c.Covers, ", "))
+	}
+	fmt.Fprintf(&b, "Bear-Round: %d\n", c.Round)
+	return b.String()
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
+
+// CommitTask commits every change in dir, a worktree of the task, to its
+// branch and returns the commit hash. It returns ErrNothingToCommit if the
+// agent changed nothing in the round.
+func CommitTask(dir, branch string, message CommitMessage) (string, error) {
+	// The agent may have switched branches; never commit anywhere else.
+	current, err := gitcmd.Run(dir, "branch", "--show-current")
+	if err != nil {
+		return "", err
+	}
+	if current != branch {
+		return "", fmt.Errorf("%w: %v is on %q instead of %q", ErrWrongBranch, dir, current, branch)
+	}
+
+	if _, err := gitcmd.Run(dir, "add", "--all"); err != nil {
+		return "", err
+	}
+	if status, err := gitcmd.Run(dir, "status", "--porcelain"); err != nil {
+		return "", err
+	} else if status == "" {
+		return "", ErrNothingToCommit
+	}
+
+	if err := commitWithMessage(dir, message.String()); err != nil {
+		return "", err
+	}
+	commit, err := gitcmd.Run(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	log.Info(fmt.Sprintf("committed round %d of %v: %v", message.Round, message.TaskID, commit))
+	return commit, nil
+}
+
+// commitWithMessage commits the index with a message that may have any length
+// and content, by passing it through a file instead of the command line.
+func commitWithMessage(dir, message string) error {
+	file, err := os.CreateTemp("", "bear-commit-message-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create commit message file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(message); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write commit message file: %w", err)
+	}
+	file.Close()
+
+	_, err = gitcmd.Run(dir, "commit", "--quiet", "--file", file.Name())
+	return err
+}
+
+// sessionBase returns the commit that the session branch started from, as
+// seen from the branch the user has checked out in repoDir.
+func sessionBase(repoDir, sessionID string) (string, error) {
+	branch := SessionBranch(sessionID)
+	if _, err := gitcmd.Run(repoDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		return "", fmt.Errorf("branch %v does not exist: %w", branch, err)
+	}
+
+	base, err := gitcmd.Run(repoDir, "merge-base", "HEAD", branch)
+	if err != nil {
+		return "", err
+	}
+	tip, err := gitcmd.Run(repoDir, "rev-parse", branch)
+	if err != nil {
+		return "", err
+	}
+	if base == tip {
+		return "", fmt.Errorf("%w: %v", ErrNoSessionCommit, branch)
+	}
+	return base, nil
+}
+
+// Squash replaces the commits of the session branch since it forked from the
+// user's current branch with a single commit that has the same content, and
+// returns the new commit hash. It works without a checkout of the branch, so
+// the user's working tree is not touched.
+func Squash(repoDir, sessionID, message string) (string, error) {
+	base, err := sessionBase(repoDir, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	branch := SessionBranch(sessionID)
+	oldTip, err := gitcmd.Run(repoDir, "rev-parse", branch)
+	if err != nil {
+		return "", err
+	}
+	commit, err := gitcmd.Run(repoDir, "commit-tree", branch+"^{tree}", "-p", base, "-m", message)
+	if err != nil {
+		return "", err
+	}
+	// Passing the old tip makes the update fail if the branch moved meanwhile.
+	if _, err := gitcmd.Run(repoDir, "update-ref", "-m", "bear: squash session", "refs/heads/"+branch, commit, oldTip); err != nil {
+		return "", err
+	}
+	log.Info(fmt.Sprintf("squashed %v from %v to %v", branch, oldTip, commit))
+	return commit, nil
+}
+
+// SquashMessage returns the default message of the squashed commit: the
+// subjects of the commits it replaces, and the trailers that the trace reads,
+// with the IDs that the commits cover together.
+func SquashMessage(sessionID string, commits []Commit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Apply Bear session %v\n\n", sessionID)
+	var covers []string
+	for _, commit := range commits {
+		fmt.Fprintf(&b, "- %v\n", firstLine(commit.Message))
+		for _, line := range strings.Split(commit.Message, "\n") {
+			ids, ok := strings.CutPrefix(line, "Bear-Covers:")
+			if !ok {
+				continue
+			}
+			for _, id := range strings.Split(ids, ",") {
+				if id = strings.TrimSpace(id); id != "" && !slices.Contains(covers, id) {
+					covers = append(covers, id)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\nBear-Session: %v\n", sessionID)
+	if len(covers) > 0 {
+		fmt.Fprintf(&b, "Bear-Covers: %v\n", strings.Join(covers, ", "))
+	}
+	return b.String()
+}
+
+// ExportPatches writes the commits of the session branch since it forked from
+// the user's current branch as a patch series into outputDir, and returns the
+// paths of the patch files in order. Merge commits are left out, as with
+// `git format-patch`.
+func ExportPatches(repoDir, sessionID, outputDir string) ([]string, error) {
+	base, err := sessionBase(repoDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// git runs in repoDir, which would otherwise resolve a relative path.
+	outputDir, err = filepath.Abs(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve patch directory: %w", err)
+	}
+
+	output, err := gitcmd.Run(repoDir, "format-patch", "--output-directory", outputDir,
+		base+".."+SessionBranch(sessionID))
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// Commit is a commit of the session branch.
+type Commit struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+// SessionCommits returns the commits of the session branch since it forked
+// from the user's current branch, oldest first. Merge commits are left out.
+func SessionCommits(repoDir, sessionID string) ([]Commit, error) {
+	base, err := sessionBase(repoDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// With -z the commits are separated by NUL, which git never allows in a
+	// commit message.
+	output, err := gitcmd.Run(repoDir, "log", "-z", "--no-merges", "--reverse", "--format=%H%n%B",
+		base+".."+SessionBranch(sessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(output, "\x00") {
+		hash, message, _ := strings.Cut(strings.TrimSpace(record), "\n")
+		if hash == "" {
+			continue
+		}
+		commits = append(commits, Commit{Hash: hash, Message: strings.TrimSpace(message)})
+	}
+	return commits, nil
+}
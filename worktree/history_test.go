@@ -0,0 +1,209 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+)
+
+func TestCommitMessage_String(t *testing.T) {
+	message := CommitMessage{
+		SessionID: "session-1",
+		TaskID:    "TASK-01",
+		Round:     2,
+		Summary:   "Add the client\n\nThe client wraps the CLI.",
+		SpecPath:  ".bear/20260218/session-1/spec.md",
+		Covers:    []string{"REQ-01", "AC-02"},
+	}
+
+	expected := "TASK-01: Add the client (revision 1)\n\n" +
+		"The client wraps the CLI.\n\n" +
+		"Bear-Spec: .bear/20260218/session-1/spec.md\n" +
+		"Bear-Session: session-1\n" +
+		"Bear-Task: TASK-01\n" +
+		"Bear-Covers: REQ-01, AC-02\n" +
+		"Bear-Round: 2\n"
+	if message.String() != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, message.String())
+	}
+}
+
+// addWorktree checks out a new branch from HEAD of the repository in a
+// worktree of its own.
+func addWorktree(t *testing.T, repo, branch string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "worktree")
+	testutil.Git(t, repo, "worktree", "add", "--quiet", "-b", branch, dir, "HEAD")
+	return dir
+}
+
+func TestCommitTask_CommitsOnTaskBranchOnly(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	userHead := testutil.Git(t, repo, "rev-parse", "HEAD")
+	dir := addWorktree(t, repo, "bear/task/TASK-00")
+	testutil.WriteFile(t, dir, "a.txt", "a\n")
+
+	message := CommitMessage{SessionID: "session-1", TaskID: "TASK-00", Round: 1, Summary: "Add a"}
+	commit, err := CommitTask(dir, "bear/task/TASK-00", message)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tip := testutil.Git(t, repo, "rev-parse", "bear/task/TASK-00"); tip != commit {
+		t.Errorf("expected the task branch at %v, got %v", commit, tip)
+	}
+	if head := testutil.Git(t, repo, "rev-parse", "HEAD"); head != userHead {
+		t.Error("the user's branch must not move")
+	}
+	body := testutil.Git(t, repo, "log", "-1", "--format=%B", commit)
+	if !strings.HasPrefix(body, "TASK-00: Add a") || !strings.Contains(body, "Bear-Session: session-1") {
+		t.Errorf("unexpected commit message: %q", body)
+	}
+
+	message.Round = 2
+	if _, err := CommitTask(dir, "bear/task/TASK-00", message); !errors.Is(err, ErrNothingToCommit) {
+		t.Errorf("expected ErrNothingToCommit, got %v", err)
+	}
+}
+
+func TestCommitTask_RefusesOtherBranch(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	dir := addWorktree(t, repo, "bear/task/TASK-00")
+	testutil.Git(t, dir, "switch", "--quiet", "-c", "elsewhere")
+	testutil.WriteFile(t, dir, "a.txt", "a\n")
+
+	message := CommitMessage{SessionID: "session-1", TaskID: "TASK-00", Round: 1, Summary: "Add a"}
+	if _, err := CommitTask(dir, "bear/task/TASK-00", message); !errors.Is(err, ErrWrongBranch) {
+		t.Errorf("expected ErrWrongBranch, got %v", err)
+	}
+}
+
+// newSessionWithTwoTasks commits two tasks to the session branch, which is
+// not checked out in the repository.
+func newSessionWithTwoTasks(t *testing.T) string {
+	t.Helper()
+	repo := testutil.NewRepository(t, "README.md")
+	branch := SessionBranch("session-1")
+	dir := addWorktree(t, repo, branch)
+	for _, taskID := range []string{"TASK-00", "TASK-01"} {
+		testutil.WriteFile(t, dir, taskID+".txt", taskID+"\n")
+		message := CommitMessage{SessionID: "session-1", TaskID: taskID, Round: 1, Summary: "Add " + taskID}
+		if _, err := CommitTask(dir, branch, message); err != nil {
+			t.Fatalf("failed to commit %v: %v", taskID, err)
+		}
+	}
+	return repo
+}
+
+func TestSquash(t *testing.T) {
+	repo := newSessionWithTwoTasks(t)
+	base := testutil.Git(t, repo, "rev-parse", "HEAD")
+	tree := testutil.Git(t, repo, "rev-parse", SessionBranch("session-1")+"^{tree}")
+
+	commit, err := Squash(repo, "session-1", "Apply session")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if parents := testutil.Git(t, repo, "log", "-1", "--format=%P", commit); parents != base {
+		t.Errorf("expected the squashed commit to have the base %v as its only parent, got %q", base, parents)
+	}
+	if squashedTree := testutil.Git(t, repo, "rev-parse", commit+"^{tree}"); squashedTree != tree {
+		t.Error("the squashed commit must have the same content")
+	}
+	if tip := testutil.Git(t, repo, "rev-parse", SessionBranch("session-1")); tip != commit {
+		t.Errorf("expected the session branch at %v, got %v", commit, tip)
+	}
+}
+
+func TestSquashMessage_KeepsCoveredIDs(t *testing.T) {
+	commits := []Commit{
+		{Message: CommitMessage{SessionID: "session-1", TaskID: "TASK-01", Round: 1, Summary: "Add the writer", Covers: []string{"REQ-01", "AC-01"}}.String()},
+		{Message: CommitMessage{SessionID: "session-1", TaskID: "TASK-02", Round: 1, Summary: "Add the button", Covers: []string{"AC-01", "AC-02"}}.String()},
+	}
+
+	expected := "Apply Bear session session-1\n\n" +
+		"- TASK-01: Add the writer\n" +
+		"- TASK-02: Add the button\n\n" +
+		"Bear-Session: session-1\n" +
+		"Bear-Covers: REQ-01, AC-01, AC-02\n"
+	if message := SquashMessage("session-1", commits); message != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, message)
+	}
+}
+
+func TestExportPatches(t *testing.T) {
+	repo := newSessionWithTwoTasks(t)
+	outputDir := t.TempDir()
+
+	patches, err := ExportPatches(repo, "session-1", outputDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(patches) != 2 {
+		t.Fatalf("expected 2 patches, got %v", patches)
+	}
+	for i, taskID := range []string{"TASK-00", "TASK-01"} {
+		content, err := os.ReadFile(patches[i])
+		if err != nil {
+			t.Fatalf("failed to read patch: %v", err)
+		}
+		if !strings.Contains(string(content), "Subject: [PATCH "+string(rune('1'+i))+"/2] "+taskID) {
+			t.Errorf("patch %d should be %v, got:\n%s", i, taskID, content)
+		}
+	}
+}
+
+func TestSessionBase_Errors(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+
+	if _, err := SessionCommits(repo, "missing"); err == nil || errors.Is(err, ErrNoSessionCommit) {
+		t.Errorf("expected an error for a missing session branch, got %v", err)
+	}
+	testutil.Git(t, repo, "branch", SessionBranch("session-1"))
+	if _, err := SessionCommits(repo, "session-1"); !errors.Is(err, ErrNoSessionCommit) {
+		t.Errorf("expected ErrNoSessionCommit, got %v", err)
+	}
+}
+
+func TestSessionCommits(t *testing.T) {
+	repo := newSessionWithTwoTasks(t)
+
+	commits, err := SessionCommits(repo, "session-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %#v", commits)
+	}
+	for i, taskID := range []string{"TASK-00", "TASK-01"} {
+		if !strings.HasPrefix(commits[i].Message, taskID+": Add "+taskID) || !strings.Contains(commits[i].Message, "Bear-Task: "+taskID) {
+			t.Errorf("commit %d should be %v, got %q", i, taskID, commits[i].Message)
+		}
+		if len(commits[i].Hash) != 40 {
+			t.Errorf("unexpected hash %q", commits[i].Hash)
+		}
+	}
+}
+
+func TestSessionCommits_MessageWithControlCharacters(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	dir := filepath.Join(t.TempDir(), "worktree")
+	testutil.Git(t, repo, "worktree", "add", "--quiet", "-b", SessionBranch("session-1"), dir, "HEAD")
+	writeAndCommit(t, dir, "a.txt", "a\n", "TASK-00: Add a\n\nSplit \x1e here")
+
+	commits, err := SessionCommits(repo, "session-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(commits) != 1 || commits[0].Message != "TASK-00: Add a\n\nSplit \x1e here" {
+		t.Errorf("expected the message kept whole, got %#v", commits)
+	}
+}
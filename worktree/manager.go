@@ -0,0 +1,348 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sds-lab-dev/bear-go/gitcmd"
+	"github.com/sds-lab-dev/bear-go/log"
+)
+
+var (
+	ErrUnknownTask       = errors.New("unknown task")
+	ErrUnknownDependency = errors.New("task depends on an unknown task")
+	ErrDependencyCycle   = errors.New("task dependencies form a cycle")
+)
+
+// Task is a plan task whose branch is merged after the branches of the tasks
+// it depends on.
+type Task struct {
+	ID           string
+	Dependencies []string
+}
+
+// TaskWorktree is the worktree that a single task's agent works in.
+type TaskWorktree struct {
+	TaskID string
+	Path   string
+	Branch string
+	// WorkspaceDir is the directory of the worktree that corresponds to the
+	// workspace, which is Path unless the workspace is a subdirectory of the
+	// repository. The agent of the task works there.
+	WorkspaceDir string
+}
+
+// MergeConflict is a task whose branch cannot be merged without a human
+// resolving the conflicts in Files.
+type MergeConflict struct {
+	TaskID string
+	Branch string
+	Files  []string
+}
+
+type MergeResult struct {
+	// Merged are the IDs of the merged tasks, in merge order.
+	Merged    []string
+	Conflicts []MergeConflict
+	// Skipped are the IDs of the tasks that were not merged because a task
+	// they depend on was not merged or because they have no worktree.
+	Skipped []string
+}
+
+// Manager creates the task worktrees of a session and merges them into the
+// integration branch, which is the session branch returned by SessionBranch.
+// The integration branch is checked out in a worktree of its own so that the
+// user's checkout and current branch are never touched.
+//
+// The worktrees live in the git directory of the repository, under
+// "bear/<session-id>/", where neither the user's checkout nor its parent
+// directory gets cluttered with them.
+type Manager struct {
+	mu                sync.Mutex
+	repoRoot          string
+	sessionID         string
+	worktreesDir      string
+	integrationBranch string
+	integrationPath   string
+	// workspacePath is the path of the workspace relative to the repository
+	// root, which is "." unless the workspace is a subdirectory.
+	workspacePath string
+	worktrees     map[string]TaskWorktree
+}
+
+// NewManager creates the integration branch of the session from HEAD of the
+// repository that contains workspaceDir, and checks it out in a new worktree.
+func NewManager(workspaceDir, sessionID string) (*Manager, error) {
+	repoRoot, err := RepositoryRoot(workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+	commonDir, err := gitcmd.Run(repoRoot, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(repoRoot, commonDir)
+	}
+	workspacePath, err := relativeToRoot(repoRoot, workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	worktreesDir := filepath.Join(commonDir, "bear", sessionID)
+	m := &Manager{
+		repoRoot:          repoRoot,
+		sessionID:         sessionID,
+		worktreesDir:      worktreesDir,
+		integrationBranch: SessionBranch(sessionID),
+		integrationPath:   filepath.Join(worktreesDir, "integration"),
+		workspacePath:     workspacePath,
+		worktrees:         map[string]TaskWorktree{},
+	}
+	if _, err := gitcmd.Run(repoRoot, "worktree", "add", "--quiet", "-b", m.integrationBranch, m.integrationPath, "HEAD"); err != nil {
+		return nil, fmt.Errorf("failed to create integration worktree: %w", err)
+	}
+	log.Info(fmt.Sprintf("created integration worktree: branch=%v, path=%v", m.integrationBranch, m.integrationPath))
+
+	return m, nil
+}
+
+// relativeToRoot returns the path relative to the repository root, which git
+// reports with the symbolic links resolved.
+func relativeToRoot(repoRoot, path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %v: %w", path, err)
+	}
+	relative, err := filepath.Rel(repoRoot, resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate %v in repository: %w", path, err)
+	}
+	return relative, nil
+}
+
+// RepositoryPath returns the path of a file of the user's checkout relative
+// to the repository root, as the commits of the session refer to it.
+func (m *Manager) RepositoryPath(path string) (string, error) {
+	return relativeToRoot(m.repoRoot, path)
+}
+
+func (m *Manager) IntegrationBranch() string {
+	return m.integrationBranch
+}
+
+// IntegrationPath returns the worktree in which the integration branch is
+// checked out.
+func (m *Manager) IntegrationPath() string {
+	return m.integrationPath
+}
+
+// CreateTaskWorktree creates the worktree and branch of the task from the
+// current tip of the integration branch, so that the task starts from the
+// merged work of the tasks it depends on.
+func (m *Manager) CreateTaskWorktree(taskID string) (TaskWorktree, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if worktree, ok := m.worktrees[taskID]; ok {
+		return worktree, nil
+	}
+
+	worktree := TaskWorktree{
+		TaskID: taskID,
+		Path:   filepath.Join(m.worktreesDir, taskID),
+		Branch: fmt.Sprintf("bear/task/%v/%v", m.sessionID, taskID),
+	}
+	worktree.WorkspaceDir = filepath.Join(worktree.Path, m.workspacePath)
+	if _, err := gitcmd.Run(m.repoRoot, "worktree", "add", "--quiet", "-b", worktree.Branch, worktree.Path, m.integrationBranch); err != nil {
+		return TaskWorktree{}, fmt.Errorf("failed to create worktree for %v: %w", taskID, err)
+	}
+	log.Info(fmt.Sprintf("created task worktree: task=%v, branch=%v, path=%v", taskID, worktree.Branch, worktree.Path))
+
+	m.worktrees[taskID] = worktree
+	return worktree, nil
+}
+
+// TaskWorktree returns the worktree of the task if it has been created.
+func (m *Manager) TaskWorktree(taskID string) (TaskWorktree, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	worktree, ok := m.worktrees[taskID]
+	return worktree, ok
+}
+
+// MergeTasks merges the task branches into the integration branch in
+// dependency order. A conflicting merge is aborted and reported, and the
+// tasks that depend on it are skipped, so the integration branch is always
+// left in a clean state.
+func (m *Manager) MergeTasks(tasks []Task) (MergeResult, error) {
+	sorted, err := SortTasks(tasks)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result MergeResult
+	notMerged := map[string]bool{}
+	for _, task := range sorted {
+		worktree, ok := m.worktrees[task.ID]
+		if !ok || dependsOnAny(task, notMerged) {
+			notMerged[task.ID] = true
+			result.Skipped = append(result.Skipped, task.ID)
+			continue
+		}
+
+		conflictFiles, err := m.merge(worktree)
+		if err != nil {
+			return result, err
+		}
+		if len(conflictFiles) > 0 {
+			notMerged[task.ID] = true
+			result.Conflicts = append(result.Conflicts, MergeConflict{
+				TaskID: task.ID,
+				Branch: worktree.Branch,
+				Files:  conflictFiles,
+			})
+			continue
+		}
+		result.Merged = append(result.Merged, task.ID)
+	}
+
+	log.Info(fmt.Sprintf("merged task branches: %#v", result))
+	return result, nil
+}
+
+// merge merges the task branch into the integration branch and returns the
+// conflicting files if the merge had to be aborted.
+func (m *Manager) merge(worktree TaskWorktree) ([]string, error) {
+	message := fmt.Sprintf("Merge %v into %v", worktree.TaskID, m.integrationBranch)
+	_, mergeErr := gitcmd.Run(m.integrationPath, "merge", "--no-ff", "--no-edit", "-m", message, worktree.Branch)
+	if mergeErr == nil {
+		return nil, nil
+	}
+
+	output, err := gitcmd.Run(m.integrationPath, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gitcmd.Run(m.integrationPath, "merge", "--abort"); err != nil {
+		return nil, fmt.Errorf("failed to abort merge of %v: %w", worktree.TaskID, err)
+	}
+	if output == "" {
+		// The merge failed for a reason other than a conflict.
+		return nil, fmt.Errorf("failed to merge %v: %w", worktree.TaskID, mergeErr)
+	}
+
+	log.Warning(fmt.Sprintf("merge of %v conflicts: %v", worktree.TaskID, output))
+	return strings.Split(output, "\n"), nil
+}
+
+// RemoveTaskWorktree removes the worktree of the task but keeps its branch, so
+// that its work can still be inspected or merged by hand.
+func (m *Manager) RemoveTaskWorktree(taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	worktree, ok := m.worktrees[taskID]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownTask, taskID)
+	}
+	if err := removeWorktree(m.repoRoot, worktree.Path); err != nil {
+		return err
+	}
+	delete(m.worktrees, taskID)
+	return nil
+}
+
+// Close removes every worktree of the session, including the integration
+// worktree. The branches are kept.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for taskID, worktree := range m.worktrees {
+		if err := removeWorktree(m.repoRoot, worktree.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(m.worktrees, taskID)
+	}
+	if err := removeWorktree(m.repoRoot, m.integrationPath); err != nil {
+		errs = append(errs, err)
+	}
+	// Only the directories that the worktrees were in are left.
+	if err := os.RemoveAll(m.worktreesDir); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove worktree directory: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func removeWorktree(repoRoot, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		_, err := gitcmd.Run(repoRoot, "worktree", "prune")
+		return err
+	}
+	if _, err := gitcmd.Run(repoRoot, "worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("failed to remove worktree %v: %w", path, err)
+	}
+	return nil
+}
+
+func dependsOnAny(task Task, taskIDs map[string]bool) bool {
+	for _, dependency := range task.Dependencies {
+		if taskIDs[dependency] {
+			return true
+		}
+	}
+	return false
+}
+
+// SortTasks orders the tasks so that every task comes after the tasks it
+// depends on. Independent tasks keep their relative order.
+func SortTasks(tasks []Task) ([]Task, error) {
+	known := map[string]bool{}
+	for _, task := range tasks {
+		known[task.ID] = true
+	}
+	for _, task := range tasks {
+		for _, dependency := range task.Dependencies {
+			if !known[dependency] {
+				return nil, fmt.Errorf("%w: %v depends on %v", ErrUnknownDependency, task.ID, dependency)
+			}
+		}
+	}
+
+	sorted := make([]Task, 0, len(tasks))
+	done := map[string]bool{}
+	for len(sorted) < len(tasks) {
+		progressed := false
+		for _, task := range tasks {
+			if done[task.ID] || !dependenciesDone(task, done) {
+				continue
+			}
+			done[task.ID] = true
+			sorted = append(sorted, task)
+			progressed = true
+		}
+		if !progressed {
+			return nil, ErrDependencyCycle
+		}
+	}
+	return sorted, nil
+}
+
+func dependenciesDone(task Task, done map[string]bool) bool {
+	for _, dependency := range task.Dependencies {
+		if !done[dependency] {
+			return false
+		}
+	}
+	return true
+}
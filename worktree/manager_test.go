@@ -0,0 +1,199 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sds-lab-dev/bear-go/internal/testutil"
+)
+
+func newTestManager(t *testing.T, workspaceDir string) *Manager {
+	t.Helper()
+	m, err := NewManager(workspaceDir, "session-1")
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestManager_TaskWorktreesAreIsolated(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	m := newTestManager(t, repo)
+
+	first, err := m.CreateTaskWorktree("TASK-00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := m.CreateTaskWorktree("TASK-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first.Path == second.Path || first.Branch == second.Branch {
+		t.Fatalf("tasks must not share a worktree: %+v, %+v", first, second)
+	}
+	testutil.WriteFile(t, first.Path, "a.txt", "a\n")
+	if _, err := os.Stat(filepath.Join(second.Path, "a.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("an edit in one task worktree must not appear in another")
+	}
+	if _, err := os.Stat(filepath.Join(repo, "a.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("an edit in a task worktree must not appear in the user's checkout")
+	}
+
+	again, err := m.CreateTaskWorktree("TASK-00")
+	if err != nil || again != first {
+		t.Errorf("expected the existing worktree to be returned, got %+v, %v", again, err)
+	}
+}
+
+func TestManager_WorktreesLiveInGitDirectory(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md", "app/main.go")
+	m := newTestManager(t, filepath.Join(repo, "app"))
+
+	worktree, err := m.CreateTaskWorktree("TASK-00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gitDir, _ := filepath.EvalSymlinks(filepath.Join(repo, ".git"))
+	for _, path := range []string{worktree.Path, m.IntegrationPath()} {
+		if !strings.HasPrefix(path, filepath.Join(gitDir, "bear", "session-1")+string(filepath.Separator)) {
+			t.Errorf("expected %v under the git directory", path)
+		}
+	}
+	if worktree.WorkspaceDir != filepath.Join(worktree.Path, "app") {
+		t.Errorf("expected the workspace in the worktree, got %v", worktree.WorkspaceDir)
+	}
+	if _, err := os.Stat(filepath.Join(worktree.WorkspaceDir, "main.go")); err != nil {
+		t.Errorf("expected the workspace files in the worktree: %v", err)
+	}
+	if status := testutil.Git(t, repo, "status", "--porcelain"); status != "" {
+		t.Errorf("the worktrees must not show up in the user's checkout, got %q", status)
+	}
+}
+
+func TestManager_MergeTasksInDependencyOrder(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	m := newTestManager(t, repo)
+
+	base, _ := m.CreateTaskWorktree("TASK-00")
+	writeAndCommit(t, base.Path, "base.txt", "base\n", "base")
+	other, _ := m.CreateTaskWorktree("TASK-02")
+	writeAndCommit(t, other.Path, "other.txt", "other\n", "other")
+
+	result, err := m.MergeTasks([]Task{
+		{ID: "TASK-02", Dependencies: []string{"TASK-00"}},
+		{ID: "TASK-00"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(result.Merged, []string{"TASK-00", "TASK-02"}) {
+		t.Errorf("unexpected merge order: %v", result.Merged)
+	}
+	for _, name := range []string{"base.txt", "other.txt"} {
+		if _, err := os.Stat(filepath.Join(m.IntegrationPath(), name)); err != nil {
+			t.Errorf("expected %v on the integration branch: %v", name, err)
+		}
+	}
+	if branch := testutil.Git(t, repo, "branch", "--show-current"); branch != "main" {
+		t.Errorf("the user's checkout must stay on main, got %v", branch)
+	}
+}
+
+func TestManager_MergeConflictIsReportedAndDependentsSkipped(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	m := newTestManager(t, repo)
+
+	first, _ := m.CreateTaskWorktree("TASK-00")
+	writeAndCommit(t, first.Path, "README.md", "first\n", "first")
+	second, _ := m.CreateTaskWorktree("TASK-01")
+	writeAndCommit(t, second.Path, "README.md", "second\n", "second")
+	dependent, _ := m.CreateTaskWorktree("TASK-02")
+	writeAndCommit(t, dependent.Path, "dependent.txt", "dependent\n", "dependent")
+
+	result, err := m.MergeTasks([]Task{
+		{ID: "TASK-00"},
+		{ID: "TASK-01"},
+		{ID: "TASK-02", Dependencies: []string{"TASK-01"}},
+		{ID: "TASK-03"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(result.Merged, []string{"TASK-00"}) {
+		t.Errorf("unexpected merged tasks: %v", result.Merged)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].TaskID != "TASK-01" ||
+		!slices.Equal(result.Conflicts[0].Files, []string{"README.md"}) {
+		t.Errorf("unexpected conflicts: %+v", result.Conflicts)
+	}
+	if !slices.Equal(result.Skipped, []string{"TASK-02", "TASK-03"}) {
+		t.Errorf("unexpected skipped tasks: %v", result.Skipped)
+	}
+	if status := testutil.Git(t, m.IntegrationPath(), "status", "--porcelain"); status != "" {
+		t.Errorf("the integration worktree must be clean after an aborted merge, got %q", status)
+	}
+}
+
+func TestManager_CloseRemovesWorktreesAndKeepsBranches(t *testing.T) {
+	repo := testutil.NewRepository(t, "README.md")
+	m, err := NewManager(repo, "session-1")
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	worktree, _ := m.CreateTaskWorktree("TASK-00")
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, path := range []string{worktree.Path, m.IntegrationPath()} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %v to be removed", path)
+		}
+	}
+	if branches := testutil.Git(t, repo, "branch", "--list", worktree.Branch, m.IntegrationBranch()); branches == "" {
+		t.Error("expected the branches to be kept")
+	}
+}
+
+func TestSortTasks(t *testing.T) {
+	sorted, err := SortTasks([]Task{
+		{ID: "C", Dependencies: []string{"A", "B"}},
+		{ID: "A"},
+		{ID: "B", Dependencies: []string{"A"}},
+		{ID: "D"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ids []string
+	for _, task := range sorted {
+		ids = append(ids, task.ID)
+	}
+	if !slices.Equal(ids, []string{"A", "B", "D", "C"}) {
+		t.Errorf("unexpected order: %v", ids)
+	}
+}
+
+func TestSortTasks_Errors(t *testing.T) {
+	if _, err := SortTasks([]Task{{ID: "A", Dependencies: []string{"Z"}}}); !errors.Is(err, ErrUnknownDependency) {
+		t.Errorf("expected ErrUnknownDependency, got %v", err)
+	}
+	_, err := SortTasks([]Task{
+		{ID: "A", Dependencies: []string{"B"}},
+		{ID: "B", Dependencies: []string{"A"}},
+	})
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("expected ErrDependencyCycle, got %v", err)
+	}
+}